@@ -2,13 +2,20 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // schedule timezones must resolve on hosts without zoneinfo
 
 	"github.com/pandeptwidyaop/http-remote/internal/config"
 	"github.com/pandeptwidyaop/http-remote/internal/database"
@@ -50,6 +57,9 @@ func main() {
 			case "uninstall-service":
 				runUninstallService()
 				os.Exit(0)
+			case "terminal-helper":
+				runTerminalHelper(os.Args[2:])
+				os.Exit(0)
 			case "service":
 				if len(os.Args) > 2 {
 					runServiceCommand(os.Args[2])
@@ -133,18 +143,35 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize crypto service: %v", err)
 	}
-	log.Println("Encryption enabled for sensitive data (TOTP secrets)")
+	log.Println("Encryption enabled for sensitive data (TOTP secrets, app secrets)")
 
 	authService := services.NewAuthService(db, cfg, cryptoService)
 	appService := services.NewAppService(db)
-	executorService := services.NewExecutorService(db, cfg, appService)
+	envService := services.NewEnvService(db, cryptoService)
+	gitService := services.NewGitService(db, cryptoService)
+	executorService := services.NewExecutorService(db, cfg, appService, envService, gitService)
+	notificationService := services.NewNotificationService(db, &cfg.Notifications, cryptoService)
+	executorService.SetNotificationService(notificationService)
 	auditService := services.NewAuditService(db)
+	policyService := services.NewPolicyService(db, auditService)
+	executorService.SetPolicyService(policyService)
+	schedulerService := services.NewSchedulerService(db, appService, executorService, auditService)
 
 	// Initialize metrics collector
 	metricsCollector := services.NewMetricsCollector(db.DB, &cfg.Metrics)
 	metricsCollector.Start()
 	defer metricsCollector.Stop()
 
+	// Prune execution history alongside the metrics cleanup
+	executionRetention := services.NewExecutionRetention(executorService, &cfg.Execution)
+	executionRetention.Start()
+	defer executionRetention.Stop()
+
+	terminalRecordings := services.NewTerminalRecordingService(db, &cfg.Terminal.Recording)
+	recordingRetention := services.NewTerminalRecordingRetention(terminalRecordings, &cfg.Terminal.Recording)
+	recordingRetention.Start()
+	defer recordingRetention.Stop()
+
 	if err := authService.EnsureAdminUser(); err != nil {
 		if errors.Is(err, services.ErrDefaultPassword) {
 			log.Println("")
@@ -165,14 +192,52 @@ func main() {
 		log.Fatalf("Failed to ensure admin user: %v", err)
 	}
 
-	r := router.New(cfg, authService, appService, executorService, auditService, metricsCollector)
+	// Executions of a previous process that stopped without draining can no longer finish
+	if reruns, err := executorService.RecoverInterrupted(); err != nil {
+		log.Printf("Failed to recover interrupted executions: %v", err)
+	} else if len(reruns) > 0 {
+		log.Printf("Re-running %d interrupted executions", len(reruns))
+	}
+
+	schedulerService.Start()
+
+	r := router.New(cfg, authService, appService, envService, gitService, executorService, notificationService, schedulerService, auditService, terminalRecordings, policyService, metricsCollector)
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	log.Printf("HTTP Remote %s starting on %s", version.Version, addr)
 	log.Printf("Access at: http://%s%s", addr, cfg.Server.PathPrefix)
 
-	if err := r.Run(addr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	drainTimeout := cfg.Execution.GetDrainTimeout()
+	log.Printf("Shutting down, waiting up to %s for running executions", drainTimeout)
+	schedulerService.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	// Stop accepting requests; open streams end when their executions finish
+	shutdownDone := make(chan struct{})
+	go func() {
+		_ = srv.Shutdown(ctx)
+		close(shutdownDone)
+	}()
+
+	if active := executorService.Drain(ctx); active > 0 {
+		log.Printf("Drain timeout reached with %d executions still active, they will be marked interrupted on the next start", active)
+	}
+	select {
+	case <-shutdownDone:
+	case <-time.After(5 * time.Second):
 	}
 }
 
@@ -287,6 +352,8 @@ func printHelp() {
 	fmt.Println("  uninstall-service  Uninstall systemd service (Linux only, requires sudo)")
 	fmt.Println("  service <cmd>      Manage systemd service (Linux only)")
 	fmt.Println("                     Commands: status, start, stop, restart")
+	fmt.Println("  terminal-helper    Run the terminal session helper (started by the server")
+	fmt.Println("                     when terminal.detached.enabled is set)")
 	fmt.Println("")
 	fmt.Println("Options:")
 	fmt.Println("  -config string     Path to config file (default \"config.yaml\")")
@@ -301,6 +368,44 @@ func printHelp() {
 	fmt.Println("  sudo http-remote install-service     # Install as systemd service")
 }
 
+// runTerminalHelper runs the terminal session helper, which keeps persistent terminal sessions
+// running across server restarts. The socket and the shells sessions may run are taken from -socket
+// and -shells, or from the config file.
+func runTerminalHelper(args []string) {
+	flags := flag.NewFlagSet("terminal-helper", flag.ExitOnError)
+	socket := flags.String("socket", "", "path to the unix socket of the session helper")
+	shellList := flags.String("shells", "", "comma-separated shells that sessions may run")
+	configPath := flags.String("config", "config.yaml", "path to config file, used if -socket or -shells is not set")
+	_ = flags.Parse(args)
+
+	var shells []string
+	if *shellList != "" {
+		shells = strings.Split(*shellList, ",")
+	}
+	if *socket == "" || len(shells) == 0 {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not load config from %s: %v\n", *configPath, err)
+			os.Exit(1)
+		}
+		if *socket == "" {
+			*socket = cfg.Terminal.Detached.Socket
+		}
+		if len(shells) == 0 {
+			shells = cfg.Terminal.Shells()
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	signal.Ignore(syscall.SIGHUP)
+
+	if err := services.RunTerminalHelper(*socket, shells, signals); err != nil {
+		fmt.Fprintf(os.Stderr, "Terminal session helper failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 // runServiceCommand handles service management subcommands.
 func runServiceCommand(cmd string) {
 	if !service.IsLinux() {
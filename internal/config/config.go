@@ -3,6 +3,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -10,15 +12,16 @@ import (
 
 // Config represents the main application configuration structure.
 type Config struct {
-	Admin     AdminConfig     `yaml:"admin"`
-	Database  DatabaseConfig  `yaml:"database"`
-	Server    ServerConfig    `yaml:"server"`
-	Auth      AuthConfig      `yaml:"auth"`
-	Execution ExecutionConfig `yaml:"execution"`
-	Terminal  TerminalConfig  `yaml:"terminal"`
-	Security  SecurityConfig  `yaml:"security"`
-	Files     FilesConfig     `yaml:"files"`
-	Metrics   MetricsConfig   `yaml:"metrics"`
+	Admin         AdminConfig         `yaml:"admin"`
+	Database      DatabaseConfig      `yaml:"database"`
+	Server        ServerConfig        `yaml:"server"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Execution     ExecutionConfig     `yaml:"execution"`
+	Terminal      TerminalConfig      `yaml:"terminal"`
+	Security      SecurityConfig      `yaml:"security"`
+	Files         FilesConfig         `yaml:"files"`
+	Metrics       MetricsConfig       `yaml:"metrics"`
+	Notifications NotificationsConfig `yaml:"notifications"`
 }
 
 // FilesConfig holds file browser security configuration.
@@ -35,6 +38,119 @@ type TerminalConfig struct {
 	Args    []string `yaml:"args"`    // Shell arguments (default: ["-l"])
 	Env     []string `yaml:"env"`     // Additional environment variables
 	Enabled *bool    `yaml:"enabled"` // Enable terminal feature (default: true)
+
+	AllowedShells []string `yaml:"allowed_shells"` // Shells users may choose instead of shell (default: none)
+	AdminOnly     bool     `yaml:"admin_only"`     // Disable the terminal for users other than admins (default: false)
+
+	Limits     TerminalLimitsConfig            `yaml:"limits"`      // Limits of every role
+	RoleLimits map[string]TerminalLimitsConfig `yaml:"role_limits"` // Limits per role (admin, operator), overriding the fields they set
+
+	Recording TerminalRecordingConfig `yaml:"recording"`
+	Detached  TerminalDetachedConfig  `yaml:"detached"`
+}
+
+// TerminalLimitsConfig holds the limits of terminal sessions. Limits apply to the sessions of a user by
+// the role of the owner, except IdleTimeout, which applies to each connection by the role of its user.
+type TerminalLimitsConfig struct {
+	SessionTTL  string `yaml:"session_ttl"`  // Close persistent sessions without activity for this long (default: 24h)
+	IdleTimeout string `yaml:"idle_timeout"` // Disconnect clients of sessions without activity for this long (default: never)
+	MaxLifetime string `yaml:"max_lifetime"` // Close sessions this long after they started (default: never)
+	MaxSessions int    `yaml:"max_sessions"` // Sessions per user (default: 10)
+	BufferSize  int    `yaml:"buffer_size"`  // Bytes of scrollback replayed to clients per session (default: 65536)
+}
+
+// GetSessionTTL returns the session TTL as time.Duration.
+func (c *TerminalLimitsConfig) GetSessionTTL() time.Duration {
+	d, err := time.ParseDuration(c.SessionTTL)
+	if err != nil || d <= 0 {
+		return 24 * time.Hour
+	}
+	return d
+}
+
+// GetIdleTimeout returns the idle timeout as time.Duration, or 0 if idle clients stay connected.
+func (c *TerminalLimitsConfig) GetIdleTimeout() time.Duration {
+	d, err := time.ParseDuration(c.IdleTimeout)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetMaxLifetime returns the maximum session lifetime as time.Duration, or 0 if sessions may run forever.
+func (c *TerminalLimitsConfig) GetMaxLifetime() time.Duration {
+	d, err := time.ParseDuration(c.MaxLifetime)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetMaxSessions returns the maximum number of sessions per user.
+func (c *TerminalLimitsConfig) GetMaxSessions() int {
+	if c.MaxSessions <= 0 {
+		return 10
+	}
+	return c.MaxSessions
+}
+
+// GetBufferSize returns the scrollback size of a session in bytes.
+func (c *TerminalLimitsConfig) GetBufferSize() int {
+	if c.BufferSize <= 0 {
+		return 64 * 1024
+	}
+	return c.BufferSize
+}
+
+// LimitsFor returns the limits of users with role: the limits of the role where set, and the limits
+// of every role otherwise.
+func (c *TerminalConfig) LimitsFor(role string) TerminalLimitsConfig {
+	limits := c.Limits
+	override, ok := c.RoleLimits[role]
+	if !ok {
+		return limits
+	}
+	if override.SessionTTL != "" {
+		limits.SessionTTL = override.SessionTTL
+	}
+	if override.IdleTimeout != "" {
+		limits.IdleTimeout = override.IdleTimeout
+	}
+	if override.MaxLifetime != "" {
+		limits.MaxLifetime = override.MaxLifetime
+	}
+	if override.MaxSessions > 0 {
+		limits.MaxSessions = override.MaxSessions
+	}
+	if override.BufferSize > 0 {
+		limits.BufferSize = override.BufferSize
+	}
+	return limits
+}
+
+// Shells returns the shells users may start sessions with, the default shell first.
+func (c *TerminalConfig) Shells() []string {
+	shells := []string{c.Shell}
+	for _, shell := range c.AllowedShells {
+		if shell != "" && !slices.Contains(shells, shell) {
+			shells = append(shells, shell)
+		}
+	}
+	return shells
+}
+
+// TerminalRecordingConfig holds terminal session recording configuration.
+type TerminalRecordingConfig struct {
+	Dir           string `yaml:"dir"`            // Directory for asciicast recordings (default: "recordings" next to the database)
+	Enabled       bool   `yaml:"enabled"`        // Record output, input and resizes of every terminal session (default: false)
+	RetentionDays int    `yaml:"retention_days"` // Delete recordings older than this many days (default: 0, keep forever)
+}
+
+// TerminalDetachedConfig holds configuration of the terminal session helper, which runs persistent
+// sessions in a separate process so that they survive restarts of the server.
+type TerminalDetachedConfig struct {
+	Socket  string `yaml:"socket"`  // Unix socket of the session helper (default: "terminal.sock" next to the database)
+	Enabled bool   `yaml:"enabled"` // Run persistent sessions in the session helper (default: false)
 }
 
 // IsEnabled returns whether terminal is enabled (defaults to true).
@@ -94,9 +210,96 @@ type AuthConfig struct {
 
 // ExecutionConfig holds command execution configuration.
 type ExecutionConfig struct {
-	DefaultTimeout int `yaml:"default_timeout"`
-	MaxTimeout     int `yaml:"max_timeout"`
-	MaxOutputSize  int `yaml:"max_output_size"`
+	KillGracePeriod     string `yaml:"kill_grace_period"` // Time between SIGTERM and SIGKILL when stopping a command (default: 10s)
+	DrainTimeout        string `yaml:"drain_timeout"`     // How long shutdown waits for pending and running executions (default: 60s)
+	ApprovalTimeout     string `yaml:"approval_timeout"`  // How long an execution may await approval before it is rejected (default: never)
+	DefaultTimeout      int    `yaml:"default_timeout"`
+	MaxTimeout          int    `yaml:"max_timeout"`
+	MaxOutputSize       int    `yaml:"max_output_size"`
+	RetentionDays       int    `yaml:"retention_days"`        // Delete finished executions older than this many days (default: 0, keep forever)
+	RetentionPerCommand int    `yaml:"retention_per_command"` // Keep only this many finished executions per command (default: 0, unlimited)
+}
+
+// GetKillGracePeriod returns the kill grace period as time.Duration.
+func (c *ExecutionConfig) GetKillGracePeriod() time.Duration {
+	if c.KillGracePeriod == "" {
+		return 10 * time.Second
+	}
+	d, err := time.ParseDuration(c.KillGracePeriod)
+	if err != nil {
+		return 10 * time.Second
+	}
+	return d
+}
+
+// GetDrainTimeout returns the drain timeout as time.Duration.
+func (c *ExecutionConfig) GetDrainTimeout() time.Duration {
+	if c.DrainTimeout == "" {
+		return 60 * time.Second
+	}
+	d, err := time.ParseDuration(c.DrainTimeout)
+	if err != nil {
+		return 60 * time.Second
+	}
+	return d
+}
+
+// GetApprovalTimeout returns the approval timeout as time.Duration, or 0 if approvals do not expire.
+func (c *ExecutionConfig) GetApprovalTimeout() time.Duration {
+	d, err := time.ParseDuration(c.ApprovalTimeout)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// NotificationsConfig holds outbound execution notification configuration.
+type NotificationsConfig struct {
+	SMTP        SMTPConfig `yaml:"smtp"`
+	MaxAttempts int        `yaml:"max_attempts"` // Delivery attempts per notification (default: 3)
+	RetryDelay  string     `yaml:"retry_delay"`  // Wait before the first retry, doubled after each attempt (default: 30s)
+	Timeout     string     `yaml:"timeout"`      // Timeout of a single delivery attempt (default: 10s)
+}
+
+// SMTPConfig holds the mail server used for email notifications.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"` // default: 587
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// GetMaxAttempts returns the delivery attempts per notification (defaults to 3).
+func (c *NotificationsConfig) GetMaxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return 3
+	}
+	return c.MaxAttempts
+}
+
+// GetRetryDelay returns the delay before the first retry as time.Duration.
+func (c *NotificationsConfig) GetRetryDelay() time.Duration {
+	if c.RetryDelay == "" {
+		return 30 * time.Second
+	}
+	d, err := time.ParseDuration(c.RetryDelay)
+	if err != nil {
+		return 30 * time.Second
+	}
+	return d
+}
+
+// GetTimeout returns the timeout of a single delivery attempt as time.Duration.
+func (c *NotificationsConfig) GetTimeout() time.Duration {
+	if c.Timeout == "" {
+		return 10 * time.Second
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil {
+		return 10 * time.Second
+	}
+	return d
 }
 
 // AdminConfig holds admin user credentials.
@@ -225,4 +428,13 @@ func setDefaults(cfg *Config) {
 	if len(cfg.Terminal.Args) == 0 {
 		cfg.Terminal.Args = []string{"-l"}
 	}
+	if cfg.Terminal.Recording.Dir == "" {
+		cfg.Terminal.Recording.Dir = filepath.Join(filepath.Dir(cfg.Database.Path), "recordings")
+	}
+	if cfg.Terminal.Detached.Socket == "" {
+		cfg.Terminal.Detached.Socket = filepath.Join(filepath.Dir(cfg.Database.Path), "terminal.sock")
+	}
+	if cfg.Notifications.SMTP.Port == 0 {
+		cfg.Notifications.SMTP.Port = 587
+	}
 }
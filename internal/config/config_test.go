@@ -211,6 +211,12 @@ func TestLoad_Defaults(t *testing.T) {
 	if len(cfg.Terminal.Args) != 1 || cfg.Terminal.Args[0] != "-l" {
 		t.Errorf("expected default args ['-l'], got %v", cfg.Terminal.Args)
 	}
+	if cfg.Terminal.Recording.Enabled || cfg.Terminal.Recording.Dir != "data/recordings" {
+		t.Errorf("expected recording to be disabled with dir 'data/recordings', got %+v", cfg.Terminal.Recording)
+	}
+	if cfg.Terminal.Detached.Enabled || cfg.Terminal.Detached.Socket != "data/terminal.sock" {
+		t.Errorf("expected the session helper to be disabled with socket 'data/terminal.sock', got %+v", cfg.Terminal.Detached)
+	}
 
 	// Files config should be empty by default
 	if len(cfg.Files.AllowedPaths) != 0 {
@@ -270,6 +276,36 @@ func TestTerminalConfig_IsEnabled(t *testing.T) {
 	}
 }
 
+func TestTerminalConfig_LimitsFor(t *testing.T) {
+	cfg := &TerminalConfig{
+		Shell:         "/bin/bash",
+		AllowedShells: []string{"/bin/sh", "/bin/bash", "/usr/bin/zsh"},
+		Limits:        TerminalLimitsConfig{IdleTimeout: "30m", MaxSessions: 5},
+		RoleLimits: map[string]TerminalLimitsConfig{
+			"operator": {MaxSessions: 2, MaxLifetime: "8h"},
+		},
+	}
+
+	admin := cfg.LimitsFor("admin")
+	if admin.GetMaxSessions() != 5 || admin.GetIdleTimeout() != 30*time.Minute || admin.GetMaxLifetime() != 0 {
+		t.Errorf("expected the limits of every role for admins, got %+v", admin)
+	}
+	if admin.GetSessionTTL() != 24*time.Hour || admin.GetBufferSize() != 64*1024 {
+		t.Errorf("expected the default TTL and buffer size, got %v and %d", admin.GetSessionTTL(), admin.GetBufferSize())
+	}
+
+	// Fields the role does not set are taken from the limits of every role
+	operator := cfg.LimitsFor("operator")
+	if operator.GetMaxSessions() != 2 || operator.GetMaxLifetime() != 8*time.Hour || operator.GetIdleTimeout() != 30*time.Minute {
+		t.Errorf("expected the operator limits to override max sessions and lifetime, got %+v", operator)
+	}
+
+	shells := cfg.Shells()
+	if len(shells) != 3 || shells[0] != "/bin/bash" || shells[1] != "/bin/sh" || shells[2] != "/usr/bin/zsh" {
+		t.Errorf("expected the default shell first without duplicates, got %v", shells)
+	}
+}
+
 func TestSecurityConfig_GetLockoutDuration(t *testing.T) {
 	// Test default (empty string)
 	cfg := &SecurityConfig{}
@@ -290,6 +326,26 @@ func TestSecurityConfig_GetLockoutDuration(t *testing.T) {
 	}
 }
 
+func TestExecutionConfig_GetKillGracePeriod(t *testing.T) {
+	// Test default (empty string)
+	cfg := &ExecutionConfig{}
+	if cfg.GetKillGracePeriod() != 10*time.Second {
+		t.Errorf("expected default kill grace period 10s, got %v", cfg.GetKillGracePeriod())
+	}
+
+	// Test valid duration
+	cfg.KillGracePeriod = "3s"
+	if cfg.GetKillGracePeriod() != 3*time.Second {
+		t.Errorf("expected kill grace period 3s, got %v", cfg.GetKillGracePeriod())
+	}
+
+	// Test invalid duration (should return default)
+	cfg.KillGracePeriod = "invalid"
+	if cfg.GetKillGracePeriod() != 10*time.Second {
+		t.Errorf("expected default kill grace period for invalid input, got %v", cfg.GetKillGracePeriod())
+	}
+}
+
 func TestSecurityConfig_GetMaxLoginAttempts(t *testing.T) {
 	// Test default (zero value)
 	cfg := &SecurityConfig{}
@@ -22,7 +22,8 @@ func New(dbPath string) (*DB, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
+	// Executions write their output concurrently, so writers wait for the lock instead of failing
+	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on&_busy_timeout=5000")
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,12 @@
 package database
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
 )
 
 var migrations = []string{
@@ -97,7 +102,12 @@ func runMigrations(db *sql.DB) error {
 	}
 
 	// Run versioned migrations with tracking
-	return runVersionedMigrations(db)
+	if err := runVersionedMigrations(db); err != nil {
+		return err
+	}
+
+	// The search index depends on the build rather than the schema version, so it is checked on every start
+	return createExecutionSearchIndex(db)
 }
 
 // createMigrationsTable creates the migrations tracking table
@@ -296,6 +306,338 @@ func runVersionedMigrations(db *sql.DB) error {
 		}
 	}
 
+	// Migration: Add pipeline steps and per-step execution results
+	migrationName = "2026_10_16_000001_add_pipelines"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addPipelineTables(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Record who cancelled an execution
+	migrationName = "2026_10_16_000002_add_cancelled_by_to_executions"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addColumnIfMissing(db, "executions", "cancelled_by", "TEXT"); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add per-app concurrency policy
+	migrationName = "2026_10_16_000003_add_concurrency_policy_to_apps"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addColumnIfMissing(db, "apps", "concurrency_policy", "TEXT NOT NULL DEFAULT 'queue'"); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add typed command parameters and the values used by each execution
+	migrationName = "2026_10_16_000004_add_command_parameters"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addColumnIfMissing(db, "commands", "parameters", "TEXT"); err != nil {
+			return err
+		}
+		if err := addColumnIfMissing(db, "executions", "parameters", "TEXT"); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add per-app and per-command environment variables and secrets
+	migrationName = "2026_10_16_000005_add_env_vars"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addEnvVarsTable(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add run_as user/group and resource limits to apps
+	migrationName = "2026_10_16_000006_add_run_as_to_apps"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addColumnIfMissing(db, "apps", "run_as_user", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		if err := addColumnIfMissing(db, "apps", "run_as_group", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		if err := addColumnIfMissing(db, "apps", "resource_limits", "TEXT"); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add git source to apps and the deployed commit to executions
+	migrationName = "2026_10_16_000007_add_git_source"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addGitSourceColumns(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add provider webhook settings to apps
+	migrationName = "2026_10_16_000008_add_webhook_settings"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addWebhookColumns(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add notification rules and their delivery log
+	migrationName = "2026_10_16_000009_add_notifications"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addNotificationTables(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Add cron schedules for commands
+	migrationName = "2026_10_16_000010_add_command_schedules"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addCommandSchedulesTable(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Store execution output as numbered stdout/stderr lines
+	migrationName = "2026_10_16_000011_add_execution_output"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addExecutionOutputTable(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Re-run executions interrupted by a server restart
+	migrationName = "2026_10_16_000012_add_command_rerun_on_interrupt"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addColumnIfMissing(db, "commands", "rerun_on_interrupt", "BOOLEAN NOT NULL DEFAULT FALSE"); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Manual approval of executions
+	migrationName = "2026_10_16_000013_add_execution_approvals"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addApprovalColumns(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Link re-runs and rollbacks to the execution they repeat
+	migrationName = "2026_10_16_000014_add_execution_rerun_of"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := addColumnIfMissing(db, "executions", "rerun_of", "TEXT"); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Indexes for filtering and pruning execution history by date
+	migrationName = "2026_10_16_000015_add_execution_history_indexes"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := createExecutionHistoryIndexes(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Multiple hashed deploy tokens per app, starting with each app's existing token
+	migrationName = "2026_10_16_000016_create_deploy_tokens"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := createDeployTokensTable(db); err != nil {
+			return err
+		}
+		if err := migrateAppTokens(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Personal API tokens
+	migrationName = "2026_10_16_000017_create_api_tokens"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := createAPITokensTable(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Groups and per-app access control
+	migrationName = "2026_10_16_000018_create_app_members"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := createAppMembersTables(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Terminal session recordings
+	migrationName = "2026_10_16_000019_create_terminal_recordings_table"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := createTerminalRecordingsTable(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
+	// Migration: Command policies
+	migrationName = "2026_10_16_000020_create_command_policies_table"
+	hasRun, err = hasMigrationRun(db, migrationName)
+	if err != nil {
+		return err
+	}
+
+	if !hasRun {
+		if err := createCommandPoliciesTable(db); err != nil {
+			return err
+		}
+		if err := recordMigration(db, migrationName, batch); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -824,3 +1166,489 @@ func addSwapToMetricsTables(db *sql.DB) error {
 
 	return nil
 }
+
+// addPipelineTables creates tables for pipeline steps and their execution results
+func addPipelineTables(db *sql.DB) error {
+	// pipeline_id references the pipeline command, command_id the command run by the step
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pipeline_steps (
+			id TEXT PRIMARY KEY,
+			pipeline_id TEXT NOT NULL,
+			command_id TEXT NOT NULL,
+			step_order INTEGER NOT NULL DEFAULT 0,
+			continue_on_error BOOLEAN NOT NULL DEFAULT FALSE,
+			FOREIGN KEY (pipeline_id) REFERENCES commands(id) ON DELETE CASCADE,
+			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS execution_steps (
+			id TEXT PRIMARY KEY,
+			execution_id TEXT NOT NULL,
+			command_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			step_order INTEGER NOT NULL DEFAULT 0,
+			continue_on_error BOOLEAN NOT NULL DEFAULT FALSE,
+			status TEXT NOT NULL DEFAULT 'pending',
+			output TEXT,
+			exit_code INTEGER,
+			started_at DATETIME,
+			finished_at DATETIME,
+			FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_pipeline_steps_pipeline_id ON pipeline_steps(pipeline_id, step_order)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_execution_steps_execution_id ON execution_steps(execution_id, step_order)`)
+	return err
+}
+
+// addEnvVarsTable creates the table for app and command environment variables.
+// Secret values are stored encrypted; command_id is NULL for app-wide variables.
+func addEnvVarsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS env_vars (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			command_id TEXT,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL DEFAULT '',
+			is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE,
+			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_env_vars_scope_name ON env_vars(app_id, COALESCE(command_id, ''), name)`)
+	return err
+}
+
+// addGitSourceColumns adds the git source of apps and the ref and commit deployed by executions.
+// The deploy key is stored encrypted.
+func addGitSourceColumns(db *sql.DB) error {
+	columns := []struct{ table, column, definition string }{
+		{"apps", "git_repo_url", "TEXT NOT NULL DEFAULT ''"},
+		{"apps", "git_ref", "TEXT NOT NULL DEFAULT ''"},
+		{"apps", "git_deploy_key", "TEXT"},
+		{"executions", "git_ref", "TEXT"},
+		{"executions", "commit_sha", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addWebhookColumns adds the encrypted webhook secret and the branch and tag filters to apps
+func addWebhookColumns(db *sql.DB) error {
+	columns := []struct{ column, definition string }{
+		{"webhook_secret", "TEXT"},
+		{"webhook_branches", "TEXT"},
+		{"webhook_tags", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, "apps", c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addNotificationTables creates the notification rules of apps and the delivery log
+func addNotificationTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS notification_rules (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			target TEXT NOT NULL,
+			secret TEXT,
+			template TEXT NOT NULL DEFAULT '',
+			statuses TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Deliveries keep the rule name so the log survives deleting the rule
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS notification_deliveries (
+			id TEXT PRIMARY KEY,
+			rule_id TEXT,
+			rule_name TEXT NOT NULL,
+			app_id TEXT NOT NULL,
+			execution_id TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			status TEXT NOT NULL,
+			execution_status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (rule_id) REFERENCES notification_rules(id) ON DELETE SET NULL,
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_notification_deliveries_app ON notification_deliveries(app_id, created_at)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_notification_deliveries_execution ON notification_deliveries(execution_id)`)
+	return err
+}
+
+// addCommandSchedulesTable creates the table for cron schedules of commands
+func addCommandSchedulesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS command_schedules (
+			id TEXT PRIMARY KEY,
+			command_id TEXT NOT NULL,
+			cron TEXT NOT NULL,
+			timezone TEXT NOT NULL DEFAULT 'UTC',
+			overlap_policy TEXT NOT NULL DEFAULT 'skip',
+			parameters TEXT,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			next_run_at DATETIME,
+			last_run_at DATETIME,
+			last_execution_id TEXT,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_command_schedules_command ON command_schedules(command_id)`)
+	return err
+}
+
+// addExecutionOutputTable creates the table for timestamped output lines of executions
+func addExecutionOutputTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS execution_output (
+			execution_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			stream TEXT NOT NULL,
+			line TEXT NOT NULL,
+			elapsed_ms INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (execution_id, seq),
+			FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+		)
+	`)
+	return err
+}
+
+// addApprovalColumns adds the approval flags of apps and commands and the review of executions
+func addApprovalColumns(db *sql.DB) error {
+	columns := []struct{ table, column, definition string }{
+		{"apps", "requires_approval", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"commands", "requires_approval", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"executions", "reviewed_by", "TEXT"},
+		{"executions", "reviewed_at", "DATETIME"},
+		{"executions", "review_comment", "TEXT"},
+		{"executions", "approval_expires_at", "DATETIME"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(db, c.table, c.column, c.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createExecutionHistoryIndexes creates the indexes used to list executions by date and prune old ones
+func createExecutionHistoryIndexes(db *sql.DB) error {
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_executions_created_at ON executions(created_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_executions_command_created_at ON executions(command_id, created_at)`)
+	return err
+}
+
+// createDeployTokensTable creates the deploy_tokens table
+func createDeployTokensTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS deploy_tokens (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			token_prefix TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			command_ids TEXT,
+			expires_at DATETIME,
+			last_used_at DATETIME,
+			last_used_ip TEXT,
+			created_by TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (app_id, name),
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_deploy_tokens_app_id ON deploy_tokens(app_id)`)
+	return err
+}
+
+// migrateAppTokens turns the plaintext token of each app into a hashed deploy token named "default" with all
+// scopes, so existing deploy scripts keep working. The apps.token column keeps only the hash.
+func migrateAppTokens(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, token FROM apps WHERE token != ''`)
+	if err != nil {
+		return err
+	}
+	tokens := map[string]string{}
+	for rows.Next() {
+		var appID, token string
+		if err := rows.Scan(&appID, &token); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		tokens[appID] = token
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for appID, token := range tokens {
+		// Same hash as services.hashToken
+		sum := sha256.Sum256([]byte(token))
+		hash := hex.EncodeToString(sum[:])
+		prefix := token
+		if len(prefix) > 8 {
+			prefix = prefix[:8]
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO deploy_tokens (id, app_id, name, token_hash, token_prefix, scopes) VALUES (?, ?, 'default', ?, ?, '["deploy","status","cancel"]')`,
+			uuid.New().String(), appID, hash, prefix,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE apps SET token = ? WHERE id = ?`, hash, appID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`,
+		table, column,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	// Column already exists, skip migration
+	if count > 0 {
+		return nil
+	}
+
+	// #nosec G201 - table, column and definition are constants from migrations
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+// createAPITokensTable creates the api_tokens table for personal API tokens.
+func createAPITokensTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			token_prefix TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			last_used_at DATETIME,
+			last_used_ip TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id)`)
+	return err
+}
+
+// createAppMembersTables creates the user_groups, user_group_members and app_members tables.
+// Users other than admins only see the apps they are members of, so existing users become
+// members of every existing app with their role to keep their access.
+func createAppMembersTables(db *sql.DB) error {
+	tables := []string{`
+		CREATE TABLE IF NOT EXISTS user_groups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`, `
+		CREATE TABLE IF NOT EXISTS user_group_members (
+			group_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			PRIMARY KEY (group_id, user_id),
+			FOREIGN KEY (group_id) REFERENCES user_groups(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`, `
+		CREATE TABLE IF NOT EXISTS app_members (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			user_id INTEGER,
+			group_id INTEGER,
+			role TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			CHECK ((user_id IS NULL) != (group_id IS NULL)),
+			UNIQUE (app_id, user_id),
+			UNIQUE (app_id, group_id),
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (group_id) REFERENCES user_groups(id) ON DELETE CASCADE
+		)
+	`,
+		`CREATE INDEX IF NOT EXISTS idx_user_group_members_user_id ON user_group_members(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_app_members_user_id ON app_members(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_app_members_group_id ON app_members(group_id)`,
+	}
+	for _, table := range tables {
+		if _, err := db.Exec(table); err != nil {
+			return err
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT a.id, u.id, COALESCE(u.role, 'operator') FROM apps a, users u
+		WHERE COALESCE(u.role, 'operator') != 'admin' AND NOT COALESCE(u.is_admin, 0)
+	`)
+	if err != nil {
+		return err
+	}
+	type membership struct {
+		appID  string
+		userID int64
+		role   string
+	}
+	var memberships []membership
+	for rows.Next() {
+		var m membership
+		if err := rows.Scan(&m.appID, &m.userID, &m.role); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		memberships = append(memberships, m)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, m := range memberships {
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO app_members (id, app_id, user_id, role) VALUES (?, ?, ?, ?)`,
+			uuid.New().String(), m.appID, m.userID, m.role,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// createTerminalRecordingsTable creates the terminal_recordings table for asciicast recordings of terminal sessions.
+// Like audit logs, recordings are kept when their user is deleted.
+func createTerminalRecordingsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS terminal_recordings (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			user_id INTEGER,
+			username TEXT NOT NULL,
+			shell TEXT NOT NULL,
+			path TEXT NOT NULL,
+			size INTEGER NOT NULL DEFAULT 0,
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_terminal_recordings_started_at ON terminal_recordings(started_at)`)
+	return err
+}
+
+// createCommandPoliciesTable creates the command_policies table for the rules that deny, allow or
+// require approval of commands. Roles is a JSON array of the roles a rule applies to, empty for all.
+func createCommandPoliciesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS command_policies (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			description TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			match_type TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			roles TEXT NOT NULL DEFAULT '[]',
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
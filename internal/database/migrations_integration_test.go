@@ -231,4 +231,26 @@ func TestMigrationBackwardCompatibility(t *testing.T) {
 	if count != 1 {
 		t.Error("idx_commands_sort_order index should exist after migration")
 	}
+
+	// Verify the existing app token became a hashed default deploy token
+	var tokenName, tokenHash, appToken string
+	err = db.QueryRow(`
+		SELECT d.name, d.token_hash, a.token FROM deploy_tokens d JOIN apps a ON a.id = d.app_id WHERE d.app_id = 'old-app-1'
+	`).Scan(&tokenName, &tokenHash, &appToken)
+	if err != nil {
+		t.Fatalf("failed to query migrated deploy token: %v", err)
+	}
+	if tokenName != "default" || tokenHash == "old-token-123" || appToken != tokenHash {
+		t.Errorf("expected a hashed default token, got name %q, hash %q, app token %q", tokenName, tokenHash, appToken)
+	}
+
+	// Verify existing users kept access to existing apps
+	var memberRole string
+	err = db.QueryRow(`SELECT role FROM app_members WHERE app_id = 'old-app-1' AND user_id = 1`).Scan(&memberRole)
+	if err != nil {
+		t.Fatalf("failed to query migrated app member: %v", err)
+	}
+	if memberRole != "operator" {
+		t.Errorf("expected the existing user to be an operator of the existing app, got %q", memberRole)
+	}
 }
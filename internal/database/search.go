@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+)
+
+// createExecutionSearchIndex creates the FTS5 index over execution output and fills it with the output of
+// existing executions. FTS5 is only available when built with the sqlite_fts5 tag; without it, the index is
+// not created and execution search falls back to LIKE.
+// The index is contentless: it stores only the execution ID and the search terms, not a copy of the output.
+func createExecutionSearchIndex(db *sql.DB) error {
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'executions_fts'`).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	_, err := db.Exec(`
+		CREATE VIRTUAL TABLE executions_fts USING fts5(
+			execution_id UNINDEXED,
+			output,
+			content = '',
+			contentless_delete = 1,
+			contentless_unindexed = 1
+		)
+	`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			log.Println("[Database] SQLite was built without FTS5, execution search uses LIKE")
+			return nil
+		}
+		return err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO executions_fts (execution_id, output)
+		SELECT id, output FROM executions WHERE output IS NOT NULL AND output != ''
+	`)
+	return err
+}
+
+// HasExecutionSearch reports whether the FTS5 index over execution output exists and can be queried.
+func (db *DB) HasExecutionSearch() bool {
+	rows, err := db.Query(`SELECT execution_id FROM executions_fts LIMIT 0`)
+	if err != nil {
+		return false
+	}
+	_ = rows.Close()
+	return true
+}
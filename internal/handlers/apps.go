@@ -2,6 +2,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -14,23 +15,33 @@ import (
 
 // AppHandler handles HTTP requests for application management.
 type AppHandler struct {
-	appService   *services.AppService
-	auditService *services.AuditService
-	pathPrefix   string
+	appService    *services.AppService
+	gitService    *services.GitService
+	auditService  *services.AuditService
+	policyService *services.PolicyService
+	pathPrefix    string
 }
 
 // NewAppHandler creates a new AppHandler instance.
-func NewAppHandler(appService *services.AppService, auditService *services.AuditService, pathPrefix string) *AppHandler {
+// Commands are checked against the command policy of policyService when they are created.
+func NewAppHandler(appService *services.AppService, gitService *services.GitService, auditService *services.AuditService, policyService *services.PolicyService, pathPrefix string) *AppHandler {
 	return &AppHandler{
-		appService:   appService,
-		auditService: auditService,
-		pathPrefix:   pathPrefix,
+		appService:    appService,
+		gitService:    gitService,
+		auditService:  auditService,
+		policyService: policyService,
+		pathPrefix:    pathPrefix,
 	}
 }
 
-// List returns all applications as JSON.
+// List returns the applications the current user is a member of as JSON.
 func (h *AppHandler) List(c *gin.Context) {
-	apps, err := h.appService.GetAllApps()
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	apps, err := h.appService.GetAllApps(u)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -80,21 +91,36 @@ func (h *AppHandler) Create(c *gin.Context) {
 	req.Description = validation.SanitizeString(req.Description)
 
 	app, err := h.appService.CreateApp(&req)
+	if err == nil && (req.GitDeployKey != "" || req.WebhookSecret != "") {
+		app, err = h.setSecrets(app.ID, &req.GitDeployKey, &req.WebhookSecret)
+	}
 	if err != nil {
 		if err == services.ErrAppExists {
 			c.JSON(http.StatusConflict, gin.H{"error": "app already exists"})
 			return
 		}
+		if errors.Is(err, services.ErrInvalidRunAs) || err == services.ErrInvalidResourceLimits || errors.Is(err, services.ErrInvalidGitSource) || errors.Is(err, services.ErrInvalidWebhookFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Audit log
 	user, _ := c.Get(middleware.UserContextKey)
 	if u, ok := user.(*models.User); ok {
+		// Users other than admins only see the apps they are members of, so the creator becomes one
+		if !u.HasPermission(models.RoleAdmin) {
+			if _, err := h.appService.AddAppMember(app.ID, &models.AddAppMemberRequest{UserID: &u.ID, Role: u.Role}); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// Audit log
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "create",
 			ResourceType: "app",
 			ResourceID:   app.ID,
@@ -134,11 +160,18 @@ func (h *AppHandler) Update(c *gin.Context) {
 	}
 
 	app, err := h.appService.UpdateApp(id, &req)
+	if err == nil && (req.GitDeployKey != nil || req.WebhookSecret != nil) {
+		app, err = h.setSecrets(id, req.GitDeployKey, req.WebhookSecret)
+	}
 	if err != nil {
 		if err == services.ErrAppNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
 			return
 		}
+		if errors.Is(err, services.ErrInvalidRunAs) || err == services.ErrInvalidResourceLimits || errors.Is(err, services.ErrInvalidGitSource) || errors.Is(err, services.ErrInvalidWebhookFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -148,7 +181,7 @@ func (h *AppHandler) Update(c *gin.Context) {
 	if u, ok := user.(*models.User); ok {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "update",
 			ResourceType: "app",
 			ResourceID:   app.ID,
@@ -161,6 +194,25 @@ func (h *AppHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, app)
 }
 
+// setSecrets stores the encrypted deploy key and webhook secret of an app and returns the updated app.
+// A nil value is left unchanged.
+func (h *AppHandler) setSecrets(appID string, deployKey, webhookSecret *string) (*models.App, error) {
+	if h.gitService == nil {
+		return nil, errors.New("git sources are not configured")
+	}
+	if deployKey != nil {
+		if err := h.gitService.SetDeployKey(appID, *deployKey); err != nil {
+			return nil, err
+		}
+	}
+	if webhookSecret != nil {
+		if err := h.gitService.SetWebhookSecret(appID, *webhookSecret); err != nil {
+			return nil, err
+		}
+	}
+	return h.appService.GetAppByID(appID)
+}
+
 // Delete deletes an application.
 func (h *AppHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
@@ -186,7 +238,7 @@ func (h *AppHandler) Delete(c *gin.Context) {
 		}
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "delete",
 			ResourceType: "app",
 			ResourceID:   id,
@@ -199,7 +251,7 @@ func (h *AppHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "app deleted"})
 }
 
-// RegenerateToken generates a new authentication token for an application.
+// RegenerateToken replaces the default deploy token of an application and returns the app with the new token.
 func (h *AppHandler) RegenerateToken(c *gin.Context) {
 	id := c.Param("id")
 
@@ -218,7 +270,7 @@ func (h *AppHandler) RegenerateToken(c *gin.Context) {
 	if u, ok := user.(*models.User); ok {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "regenerate_token",
 			ResourceType: "app",
 			ResourceID:   app.ID,
@@ -231,6 +283,213 @@ func (h *AppHandler) RegenerateToken(c *gin.Context) {
 	c.JSON(http.StatusOK, app)
 }
 
+// ListTokens returns the deploy tokens of an application, without their plaintext.
+func (h *AppHandler) ListTokens(c *gin.Context) {
+	appID := c.Param("id")
+
+	if _, err := h.appService.GetAppByID(appID); err != nil {
+		if err == services.ErrAppNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.appService.ListDeployTokens(appID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates a deploy token for an application. The plaintext is only returned by this request.
+func (h *AppHandler) CreateToken(c *gin.Context) {
+	appID := c.Param("id")
+
+	var req models.CreateDeployTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidateName(req.Name, 100); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token name: " + err.Error()})
+		return
+	}
+	req.Name = validation.SanitizeString(req.Name)
+
+	user, _ := c.Get(middleware.UserContextKey)
+	u, _ := user.(*models.User)
+	createdBy := ""
+	if u != nil {
+		createdBy = u.Username
+	}
+
+	token, err := h.appService.CreateDeployToken(appID, &req, createdBy)
+	if err != nil {
+		switch {
+		case err == services.ErrAppNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+		case err == services.ErrDeployTokenExists:
+			c.JSON(http.StatusConflict, gin.H{"error": "a token with this name already exists"})
+		case errors.Is(err, services.ErrInvalidDeployToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if u != nil {
+		_ = h.auditService.Log(services.AuditLog{
+			UserID:       &u.ID,
+			Username:     u.AuditName(),
+			Action:       "create_token",
+			ResourceType: "app",
+			ResourceID:   appID,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Details:      map[string]interface{}{"token_id": token.ID, "token_name": token.Name, "scopes": token.Scopes},
+		})
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// RevokeToken deletes a deploy token of an application. The app's other tokens keep working.
+func (h *AppHandler) RevokeToken(c *gin.Context) {
+	appID := c.Param("id")
+	tokenID := c.Param("token_id")
+
+	token, err := h.appService.GetDeployToken(appID, tokenID)
+	if err == nil {
+		err = h.appService.RevokeDeployToken(appID, tokenID)
+	}
+	if err != nil {
+		if err == services.ErrDeployTokenNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get(middleware.UserContextKey)
+	if u, ok := user.(*models.User); ok {
+		_ = h.auditService.Log(services.AuditLog{
+			UserID:       &u.ID,
+			Username:     u.AuditName(),
+			Action:       "revoke_token",
+			ResourceType: "app",
+			ResourceID:   appID,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Details:      map[string]interface{}{"token_id": token.ID, "token_name": token.Name},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked"})
+}
+
+// ListMembers returns the users and groups that are members of an application.
+func (h *AppHandler) ListMembers(c *gin.Context) {
+	appID := c.Param("id")
+
+	if _, err := h.appService.GetAppByID(appID); err != nil {
+		if err == services.ErrAppNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	members, err := h.appService.ListAppMembers(appID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddMember adds a user or a group to an application, or changes the role of an existing member.
+func (h *AppHandler) AddMember(c *gin.Context) {
+	appID := c.Param("id")
+
+	var req models.AddAppMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.appService.AddAppMember(appID, &req)
+	if err != nil {
+		switch {
+		case err == services.ErrAppNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+		case errors.Is(err, services.ErrInvalidAppMember):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	user, _ := c.Get(middleware.UserContextKey)
+	if u, ok := user.(*models.User); ok {
+		_ = h.auditService.Log(services.AuditLog{
+			UserID:       &u.ID,
+			Username:     u.AuditName(),
+			Action:       "add_member",
+			ResourceType: "app",
+			ResourceID:   appID,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Details:      map[string]interface{}{"member_id": member.ID, "member_name": member.Name, "role": member.Role},
+		})
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember removes a user or a group from an application.
+func (h *AppHandler) RemoveMember(c *gin.Context) {
+	appID := c.Param("id")
+	memberID := c.Param("member_id")
+
+	member, err := h.appService.GetAppMember(appID, memberID)
+	if err == nil {
+		err = h.appService.RemoveAppMember(appID, memberID)
+	}
+	if err != nil {
+		if err == services.ErrAppMemberNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, _ := c.Get(middleware.UserContextKey)
+	if u, ok := user.(*models.User); ok {
+		_ = h.auditService.Log(services.AuditLog{
+			UserID:       &u.ID,
+			Username:     u.AuditName(),
+			Action:       "remove_member",
+			ResourceType: "app",
+			ResourceID:   appID,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Details:      map[string]interface{}{"member_id": member.ID, "member_name": member.Name},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed"})
+}
+
 // ListCommands returns all commands for an application.
 func (h *AppHandler) ListCommands(c *gin.Context) {
 	appID := c.Param("id")
@@ -259,37 +518,57 @@ func (h *AppHandler) CreateCommand(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid command name: " + err.Error()})
 		return
 	}
-	if err := validation.ValidateCommand(req.Command, 4096); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid command: " + err.Error()})
-		return
+	if len(req.Steps) > 0 {
+		if req.Command != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a command cannot have both a command and pipeline steps"})
+			return
+		}
+	} else {
+		if req.Command == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "command or steps is required"})
+			return
+		}
+		if err := validation.ValidateCommand(req.Command, 4096); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid command: " + err.Error()})
+			return
+		}
 	}
 	req.Name = validation.SanitizeString(req.Name)
 	req.Description = validation.SanitizeString(req.Description)
 
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if req.Command != "" && !enforcePolicy(c, h.policyService, u, req.Command, "command_create", "app", appID) {
+		return
+	}
+
 	cmd, err := h.appService.CreateCommand(appID, &req)
 	if err != nil {
 		if err == services.ErrAppNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
 			return
 		}
+		if err == services.ErrInvalidPipelineStep || errors.Is(err, services.ErrInvalidParameter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Audit log
-	user, _ := c.Get(middleware.UserContextKey)
-	if u, ok := user.(*models.User); ok {
-		_ = h.auditService.Log(services.AuditLog{
-			UserID:       &u.ID,
-			Username:     u.Username,
-			Action:       "create",
-			ResourceType: "command",
-			ResourceID:   cmd.ID,
-			IPAddress:    c.ClientIP(),
-			UserAgent:    c.GetHeader("User-Agent"),
-			Details:      map[string]interface{}{"command_name": cmd.Name, "app_id": appID},
-		})
-	}
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.AuditName(),
+		Action:       "create",
+		ResourceType: "command",
+		ResourceID:   cmd.ID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details:      map[string]interface{}{"command_name": cmd.Name, "app_id": appID},
+	})
 
 	c.JSON(http.StatusCreated, cmd)
 }
@@ -327,7 +606,7 @@ func (h *AppHandler) ReorderCommands(c *gin.Context) {
 	if u, ok := user.(*models.User); ok {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "reorder",
 			ResourceType: "commands",
 			ResourceID:   appID,
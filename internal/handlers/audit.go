@@ -23,8 +23,14 @@ func NewAuditHandler(auditService *services.AuditService, pathPrefix string) *Au
 	}
 }
 
-// List returns audit logs (API)
+// List returns audit logs (API). Users other than admins only see the entries of their apps and
+// their own entries.
 func (h *AuditHandler) List(c *gin.Context) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
 	limit := 50
 	if l, err := strconv.Atoi(c.DefaultQuery("limit", "50")); err == nil {
 		limit = l
@@ -34,7 +40,7 @@ func (h *AuditHandler) List(c *gin.Context) {
 		offset = o
 	}
 
-	logs, err := h.auditService.GetLogs(limit, offset)
+	logs, err := h.auditService.GetMemberLogs(u, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
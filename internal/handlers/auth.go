@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -375,3 +376,111 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		"message": "password changed successfully",
 	})
 }
+
+// currentUser returns the authenticated user. On failure it writes the error response and returns false.
+func currentUser(c *gin.Context) (*models.User, bool) {
+	user, exists := c.Get(middleware.UserContextKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+
+	u, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user context"})
+		return nil, false
+	}
+	return u, true
+}
+
+// ListTokens returns the personal API tokens of the current user, without their plaintext.
+func (h *AuthHandler) ListTokens(c *gin.Context) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates a personal API token for the current user. The plaintext is only returned by this request.
+func (h *AuthHandler) CreateToken(c *gin.Context) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidateName(req.Name, 100); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token name: " + err.Error()})
+		return
+	}
+	req.Name = validation.SanitizeString(req.Name)
+
+	token, err := h.authService.CreateAPIToken(u.ID, &req)
+	if err != nil {
+		switch {
+		case err == services.ErrAPITokenExists:
+			c.JSON(http.StatusConflict, gin.H{"error": "a token with this name already exists"})
+		case errors.Is(err, services.ErrInvalidAPIToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.Username,
+		Action:       "create_token",
+		ResourceType: "api_token",
+		ResourceID:   token.ID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details:      map[string]interface{}{"token_name": token.Name, "scopes": token.Scopes, "expires_at": token.ExpiresAt},
+	})
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// RevokeToken deletes a personal API token of the current user.
+func (h *AuthHandler) RevokeToken(c *gin.Context) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.authService.RevokeAPIToken(u.ID, c.Param("token_id"))
+	if err != nil {
+		if err == services.ErrAPITokenNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.Username,
+		Action:       "revoke_token",
+		ResourceType: "api_token",
+		ResourceID:   token.ID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details:      map[string]interface{}{"token_name": token.Name},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked"})
+}
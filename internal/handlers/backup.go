@@ -13,21 +13,23 @@ import (
 
 // BackupHandler handles backup and restore operations
 type BackupHandler struct {
-	appService   *services.AppService
-	auditService *services.AuditService
+	appService       *services.AppService
+	schedulerService *services.SchedulerService
+	auditService     *services.AuditService
 }
 
 // NewBackupHandler creates a new BackupHandler instance
-func NewBackupHandler(appService *services.AppService, auditService *services.AuditService) *BackupHandler {
+func NewBackupHandler(appService *services.AppService, schedulerService *services.SchedulerService, auditService *services.AuditService) *BackupHandler {
 	return &BackupHandler{
-		appService:   appService,
-		auditService: auditService,
+		appService:       appService,
+		schedulerService: schedulerService,
+		auditService:     auditService,
 	}
 }
 
-// Export exports all apps and their commands as JSON
+// Export exports all apps with their commands and schedules as JSON
 func (h *BackupHandler) Export(c *gin.Context) {
-	apps, err := h.appService.GetAllApps()
+	apps, err := h.appService.GetAllApps(nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get apps"})
 		return
@@ -40,21 +42,20 @@ func (h *BackupHandler) Export(c *gin.Context) {
 			continue
 		}
 
-		cmdBackups := make([]models.CommandBackup, 0, len(commands))
-		for _, cmd := range commands {
-			cmdBackups = append(cmdBackups, models.CommandBackup{
-				Name:           cmd.Name,
-				Description:    cmd.Description,
-				Command:        cmd.Command,
-				TimeoutSeconds: cmd.TimeoutSeconds,
-			})
-		}
-
 		backupApps = append(backupApps, models.AppBackup{
-			Name:        app.Name,
-			Description: app.Description,
-			WorkingDir:  app.WorkingDir,
-			Commands:    cmdBackups,
+			Name:              app.Name,
+			Description:       app.Description,
+			WorkingDir:        app.WorkingDir,
+			ConcurrencyPolicy: app.ConcurrencyPolicy,
+			RunAsUser:         app.RunAsUser,
+			RunAsGroup:        app.RunAsGroup,
+			ResourceLimits:    app.ResourceLimits,
+			GitRepoURL:        app.GitRepoURL,
+			GitRef:            app.GitRef,
+			WebhookBranches:   app.WebhookBranches,
+			WebhookTags:       app.WebhookTags,
+			RequiresApproval:  app.RequiresApproval,
+			Commands:          h.commandBackups(commands),
 		})
 	}
 
@@ -69,7 +70,7 @@ func (h *BackupHandler) Export(c *gin.Context) {
 	if u, ok := user.(*models.User); ok {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "export_backup",
 			ResourceType: "backup",
 			IPAddress:    c.ClientIP(),
@@ -82,7 +83,7 @@ func (h *BackupHandler) Export(c *gin.Context) {
 	c.JSON(http.StatusOK, backup)
 }
 
-// Import imports apps and commands from a backup JSON
+// Import imports apps, commands and schedules from a backup JSON
 func (h *BackupHandler) Import(c *gin.Context) {
 	var backup models.BackupData
 	if err := c.ShouldBindJSON(&backup); err != nil {
@@ -107,11 +108,24 @@ func (h *BackupHandler) Import(c *gin.Context) {
 			continue
 		}
 
-		// Create app
+		// Create app, falling back to the default policy for unknown values
+		policy := appBackup.ConcurrencyPolicy
+		if !policy.IsValid() {
+			policy = ""
+		}
 		app, err := h.appService.CreateApp(&models.CreateAppRequest{
-			Name:        appBackup.Name,
-			Description: appBackup.Description,
-			WorkingDir:  appBackup.WorkingDir,
+			Name:              appBackup.Name,
+			Description:       appBackup.Description,
+			WorkingDir:        appBackup.WorkingDir,
+			ConcurrencyPolicy: policy,
+			RunAsUser:         appBackup.RunAsUser,
+			RunAsGroup:        appBackup.RunAsGroup,
+			ResourceLimits:    appBackup.ResourceLimits,
+			GitRepoURL:        appBackup.GitRepoURL,
+			GitRef:            appBackup.GitRef,
+			WebhookBranches:   appBackup.WebhookBranches,
+			WebhookTags:       appBackup.WebhookTags,
+			RequiresApproval:  appBackup.RequiresApproval,
 		})
 		if err != nil {
 			errors = append(errors, "failed to create app '"+appBackup.Name+"': "+err.Error())
@@ -119,15 +133,62 @@ func (h *BackupHandler) Import(c *gin.Context) {
 		}
 
 		// Create commands for this app
+		commandIDs := make(map[string]string, len(appBackup.Commands))
 		for _, cmdBackup := range appBackup.Commands {
-			_, err := h.appService.CreateCommand(app.ID, &models.CreateCommandRequest{
-				Name:           cmdBackup.Name,
-				Description:    cmdBackup.Description,
-				Command:        cmdBackup.Command,
-				TimeoutSeconds: cmdBackup.TimeoutSeconds,
+			cmd, err := h.appService.CreateCommand(app.ID, &models.CreateCommandRequest{
+				Name:             cmdBackup.Name,
+				Description:      cmdBackup.Description,
+				Command:          cmdBackup.Command,
+				Parameters:       cmdBackup.Parameters,
+				TimeoutSeconds:   cmdBackup.TimeoutSeconds,
+				RerunOnInterrupt: cmdBackup.RerunOnInterrupt,
+				RequiresApproval: cmdBackup.RequiresApproval,
 			})
 			if err != nil {
 				errors = append(errors, "failed to create command '"+cmdBackup.Name+"' for app '"+appBackup.Name+"'")
+				continue
+			}
+			commandIDs[cmdBackup.Name] = cmd.ID
+		}
+
+		// Pipeline steps reference other commands, so they are restored once all commands exist
+		for _, cmdBackup := range appBackup.Commands {
+			pipelineID, ok := commandIDs[cmdBackup.Name]
+			if !ok || len(cmdBackup.Steps) == 0 {
+				continue
+			}
+
+			steps := make([]models.PipelineStepRequest, 0, len(cmdBackup.Steps))
+			for _, step := range cmdBackup.Steps {
+				steps = append(steps, models.PipelineStepRequest{
+					CommandID:       commandIDs[step.CommandName],
+					ContinueOnError: step.ContinueOnError,
+				})
+			}
+
+			if _, err := h.appService.UpdateCommand(pipelineID, &models.UpdateCommandRequest{Steps: steps}); err != nil {
+				errors = append(errors, "failed to restore pipeline steps of '"+cmdBackup.Name+"' for app '"+appBackup.Name+"'")
+			}
+		}
+
+		// Schedules are restored last so that they only start once their command is complete
+		for _, cmdBackup := range appBackup.Commands {
+			commandID, ok := commandIDs[cmdBackup.Name]
+			if !ok {
+				continue
+			}
+			for _, schedule := range cmdBackup.Schedules {
+				enabled := schedule.Enabled
+				_, err := h.schedulerService.CreateSchedule(commandID, &models.CreateScheduleRequest{
+					Cron:          schedule.Cron,
+					Timezone:      schedule.Timezone,
+					OverlapPolicy: schedule.OverlapPolicy,
+					Parameters:    schedule.Parameters,
+					Enabled:       &enabled,
+				})
+				if err != nil {
+					errors = append(errors, "failed to restore schedule '"+schedule.Cron+"' of '"+cmdBackup.Name+"' for app '"+appBackup.Name+"': "+err.Error())
+				}
 			}
 		}
 
@@ -139,7 +200,7 @@ func (h *BackupHandler) Import(c *gin.Context) {
 	if u, ok := user.(*models.User); ok {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "import_backup",
 			ResourceType: "backup",
 			IPAddress:    c.ClientIP(),
@@ -180,21 +241,20 @@ func (h *BackupHandler) ExportApp(c *gin.Context) {
 		return
 	}
 
-	cmdBackups := make([]models.CommandBackup, 0, len(commands))
-	for _, cmd := range commands {
-		cmdBackups = append(cmdBackups, models.CommandBackup{
-			Name:           cmd.Name,
-			Description:    cmd.Description,
-			Command:        cmd.Command,
-			TimeoutSeconds: cmd.TimeoutSeconds,
-		})
-	}
-
 	appBackup := models.AppBackup{
-		Name:        app.Name,
-		Description: app.Description,
-		WorkingDir:  app.WorkingDir,
-		Commands:    cmdBackups,
+		Name:              app.Name,
+		Description:       app.Description,
+		WorkingDir:        app.WorkingDir,
+		ConcurrencyPolicy: app.ConcurrencyPolicy,
+		RunAsUser:         app.RunAsUser,
+		RunAsGroup:        app.RunAsGroup,
+		ResourceLimits:    app.ResourceLimits,
+		GitRepoURL:        app.GitRepoURL,
+		GitRef:            app.GitRef,
+		WebhookBranches:   app.WebhookBranches,
+		WebhookTags:       app.WebhookTags,
+		RequiresApproval:  app.RequiresApproval,
+		Commands:          h.commandBackups(commands),
 	}
 
 	// Audit log
@@ -202,7 +262,7 @@ func (h *BackupHandler) ExportApp(c *gin.Context) {
 	if u, ok := user.(*models.User); ok {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "export_app",
 			ResourceType: "app",
 			ResourceID:   app.ID,
@@ -215,3 +275,43 @@ func (h *BackupHandler) ExportApp(c *gin.Context) {
 	c.Header("Content-Disposition", "attachment; filename="+app.Name+"-backup.json")
 	c.JSON(http.StatusOK, appBackup)
 }
+
+// commandBackups converts commands and their schedules to their backup representation
+func (h *BackupHandler) commandBackups(commands []models.Command) []models.CommandBackup {
+	cmdBackups := make([]models.CommandBackup, 0, len(commands))
+	for _, cmd := range commands {
+		var steps []models.PipelineStepBackup
+		for _, step := range cmd.Steps {
+			steps = append(steps, models.PipelineStepBackup{
+				CommandName:     step.CommandName,
+				ContinueOnError: step.ContinueOnError,
+			})
+		}
+
+		var schedules []models.ScheduleBackup
+		if existing, err := h.schedulerService.ListSchedules(cmd.ID); err == nil {
+			for _, schedule := range existing {
+				schedules = append(schedules, models.ScheduleBackup{
+					Cron:          schedule.Cron,
+					Timezone:      schedule.Timezone,
+					OverlapPolicy: schedule.OverlapPolicy,
+					Parameters:    schedule.Parameters,
+					Enabled:       schedule.Enabled,
+				})
+			}
+		}
+
+		cmdBackups = append(cmdBackups, models.CommandBackup{
+			Name:             cmd.Name,
+			Description:      cmd.Description,
+			Command:          cmd.Command,
+			Steps:            steps,
+			Parameters:       cmd.Parameters,
+			TimeoutSeconds:   cmd.TimeoutSeconds,
+			RerunOnInterrupt: cmd.RerunOnInterrupt,
+			RequiresApproval: cmd.RequiresApproval,
+			Schedules:        schedules,
+		})
+	}
+	return cmdBackups
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -16,15 +21,19 @@ type CommandHandler struct {
 	appService      *services.AppService
 	executorService *services.ExecutorService
 	auditService    *services.AuditService
+	policyService   *services.PolicyService
 	pathPrefix      string
 }
 
 // NewCommandHandler creates a new CommandHandler instance.
-func NewCommandHandler(appService *services.AppService, executorService *services.ExecutorService, auditService *services.AuditService, pathPrefix string) *CommandHandler {
+// Commands are checked against the command policy of policyService when they are updated; the
+// executor service checks them when they run.
+func NewCommandHandler(appService *services.AppService, executorService *services.ExecutorService, auditService *services.AuditService, policyService *services.PolicyService, pathPrefix string) *CommandHandler {
 	return &CommandHandler{
 		appService:      appService,
 		executorService: executorService,
 		auditService:    auditService,
+		policyService:   policyService,
 		pathPrefix:      pathPrefix,
 	}
 }
@@ -65,6 +74,10 @@ func (h *CommandHandler) Update(c *gin.Context) {
 		req.Name = validation.SanitizeString(req.Name)
 	}
 	if req.Command != "" {
+		if len(req.Steps) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a command cannot have both a command and pipeline steps"})
+			return
+		}
 		if err := validation.ValidateCommand(req.Command, 4096); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid command: " + err.Error()})
 			return
@@ -74,21 +87,30 @@ func (h *CommandHandler) Update(c *gin.Context) {
 		req.Description = validation.SanitizeString(req.Description)
 	}
 
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if req.Command != "" && !enforcePolicy(c, h.policyService, u, req.Command, "command_update", "command", id) {
+		return
+	}
+
 	cmd, err := h.appService.UpdateCommand(id, &req)
 	if err != nil {
 		if err == services.ErrCommandNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
 			return
 		}
+		if err == services.ErrInvalidPipelineStep || err == services.ErrInvalidCommand || errors.Is(err, services.ErrInvalidParameter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Audit log
-	user, _ := c.Get(middleware.UserContextKey)
-	if u, ok := user.(*models.User); ok {
-		h.auditService.LogCommandUpdate(u, cmd.ID, cmd.Name, c.ClientIP(), c.GetHeader("User-Agent"))
-	}
+	h.auditService.LogCommandUpdate(u, cmd.ID, cmd.Name, c.ClientIP(), c.GetHeader("User-Agent"))
 
 	c.JSON(http.StatusOK, cmd)
 }
@@ -147,9 +169,25 @@ func (h *CommandHandler) Execute(c *gin.Context) {
 		return
 	}
 
-	execution, err := h.executorService.CreateExecution(cmd.ID, u.ID)
+	var req models.ExecuteCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params, err := services.ParameterValues(req.Parameters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	execution, err := h.executorService.CreateExecutionWithOptions(cmd.ID, u.ID, services.ExecutionOptions{
+		Parameters: params,
+		Ref:        req.Ref,
+		CommitSHA:  req.CommitSHA,
+	})
+	if err != nil {
+		h.writeCreateExecutionError(c, cmd.AppID, err)
 		return
 	}
 
@@ -161,24 +199,176 @@ func (h *CommandHandler) Execute(c *gin.Context) {
 	}
 
 	// Audit log command execution
-	h.auditService.LogCommandExecute(u.Username, &u.ID, cmd.ID, cmd.Name, appName, c.ClientIP(), c.GetHeader("User-Agent"))
+	h.auditService.LogCommandExecute(u.AuditName(), &u.ID, cmd.ID, cmd.Name, appName, c.ClientIP(), c.GetHeader("User-Agent"))
 
-	go func() {
-		_ = h.executorService.Execute(execution.ID)
-	}()
+	h.start(c, execution)
+}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"execution_id": execution.ID,
-		"stream_url":   h.pathPrefix + "/api/executions/" + execution.ID + "/stream",
-	})
+// RerunExecution creates a new execution with the same command, parameters, ref and commit as an
+// earlier execution, linked to it through rerun_of, and runs it.
+func (h *CommandHandler) RerunExecution(c *gin.Context) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	original, err := h.executorService.GetExecutionByID(c.Param("id"))
+	if err != nil {
+		if err == services.ErrExecutionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cmd, err := h.appService.GetCommandByID(original.CommandID)
+	if err != nil {
+		if err == services.ErrCommandNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	execution, err := h.executorService.Rerun(original.ID, u.ID)
+	if err != nil {
+		h.writeCreateExecutionError(c, cmd.AppID, err)
+		return
+	}
+
+	appName := ""
+	if app, err := h.appService.GetAppByID(cmd.AppID); err == nil {
+		appName = app.Name
+	}
+	h.auditService.LogExecutionRerun(u.AuditName(), &u.ID, "rerun", execution, cmd.Name, appName, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	h.start(c, execution)
+}
+
+// Rollback re-runs the last successful execution of an app before its latest successful one,
+// against the ref and commit it recorded.
+func (h *CommandHandler) Rollback(c *gin.Context) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	app, err := h.appService.GetAppByID(c.Param("id"))
+	if err != nil {
+		if err == services.ErrAppNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	execution, err := h.executorService.Rollback(app.ID, u.ID)
+	if err != nil {
+		if err == services.ErrNoRollbackTarget {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.writeCreateExecutionError(c, app.ID, err)
+		return
+	}
+
+	commandName := ""
+	if cmd, err := h.appService.GetCommandByID(execution.CommandID); err == nil {
+		commandName = cmd.Name
+	}
+	h.auditService.LogExecutionRerun(u.AuditName(), &u.ID, "rollback", execution, commandName, app.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	h.start(c, execution)
 }
 
-// ListExecutions lists all executions.
+// writeCreateExecutionError writes the response for an error creating an execution of an app.
+func (h *CommandHandler) writeCreateExecutionError(c *gin.Context, appID string, err error) {
+	if errors.Is(err, services.ErrInvalidParameter) || errors.Is(err, services.ErrInvalidGitRef) || err == services.ErrNoGitSource {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err == services.ErrAppBusy {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                "app already has an active execution",
+			"running_execution_id": h.executorService.ActiveExecutionID(appID),
+		})
+		return
+	}
+	writePolicyError(c, err)
+}
+
+// start runs a new execution in the background, unless it awaits approval, and writes the response.
+func (h *CommandHandler) start(c *gin.Context, execution *models.Execution) {
+	if execution.Status != models.StatusAwaitingApproval {
+		go func() {
+			_ = h.executorService.Execute(execution.ID)
+		}()
+	}
+
+	response := gin.H{
+		"execution_id":   execution.ID,
+		"status":         execution.Status,
+		"queue_position": execution.QueuePosition,
+		"stream_url":     h.pathPrefix + "/api/executions/" + execution.ID + "/stream",
+	}
+	if execution.RerunOf != "" {
+		response["rerun_of"] = execution.RerunOf
+		response["git_ref"] = execution.GitRef
+		response["commit_sha"] = execution.CommitSHA
+	}
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ListExecutions lists executions of the apps the current user is a member of, newest first.
+// Query: app_id, command_id, user_id, status (comma separated), from and to (RFC 3339 or YYYY-MM-DD; a date
+// includes the whole day), q (search in the output), limit (default 50, max 200), offset
 func (h *CommandHandler) ListExecutions(c *gin.Context) {
-	limit := 50
-	offset := 0
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	filter := services.ExecutionFilter{
+		Member:    u,
+		AppID:     c.Query("app_id"),
+		CommandID: c.Query("command_id"),
+		Query:     c.Query("q"),
+		Limit:     50,
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 200 {
+		filter.Limit = l
+	}
+	if o, err := strconv.Atoi(c.Query("offset")); err == nil && o > 0 {
+		filter.Offset = o
+	}
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be a number"})
+			return
+		}
+		filter.UserID = &userID
+	}
+	if v := c.Query("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			status := models.ExecutionStatus(strings.TrimSpace(status))
+			if !status.IsValid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + string(status)})
+				return
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	if filter.From, ok = queryTime(c, "from", false); !ok {
+		return
+	}
+	if filter.To, ok = queryTime(c, "to", true); !ok {
+		return
+	}
 
-	executions, err := h.executorService.GetExecutions(limit, offset)
+	executions, err := h.executorService.ListExecutions(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -187,6 +377,27 @@ func (h *CommandHandler) ListExecutions(c *gin.Context) {
 	c.JSON(http.StatusOK, executions)
 }
 
+// queryTime parses a time query parameter given as RFC 3339 or as a date. Dates at the end of a range
+// include the whole day. On failure it writes the error response and returns false.
+func queryTime(c *gin.Context, name string, end bool) (time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	day, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC 3339 time or a YYYY-MM-DD date"})
+		return time.Time{}, false
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, true
+}
+
 // GetExecution retrieves an execution by ID.
 func (h *CommandHandler) GetExecution(c *gin.Context) {
 	id := c.Param("id")
@@ -203,3 +414,172 @@ func (h *CommandHandler) GetExecution(c *gin.Context) {
 
 	c.JSON(http.StatusOK, execution)
 }
+
+// GetExecutionOutput returns a page of the output lines of an execution in sequence order.
+// Query: after (sequence number of the last line already received, default 0), limit (default 500, max 5000)
+func (h *CommandHandler) GetExecutionOutput(c *gin.Context) {
+	id := c.Param("id")
+
+	after, err := strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64)
+	if err != nil || after < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "after must be a non-negative sequence number"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if limit <= 0 || limit > 5000 {
+		limit = 500
+	}
+
+	chunks, more, err := h.executorService.GetOutput(id, after, limit)
+	if err != nil {
+		if err == services.ErrExecutionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next := after
+	if len(chunks) > 0 {
+		next = chunks[len(chunks)-1].Seq
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chunks":   chunks,
+		"next":     next,
+		"has_more": more,
+	})
+}
+
+// ApproveExecution approves an execution awaiting approval and starts it.
+// Only operators of its app and admins other than the user who started the execution may approve it.
+func (h *CommandHandler) ApproveExecution(c *gin.Context) {
+	h.reviewExecution(c, "approve")
+}
+
+// RejectExecution rejects an execution awaiting approval so that it never runs.
+// Only operators of its app and admins other than the user who started the execution may reject it.
+func (h *CommandHandler) RejectExecution(c *gin.Context) {
+	h.reviewExecution(c, "reject")
+}
+
+func (h *CommandHandler) reviewExecution(c *gin.Context, action string) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var execution *models.Execution
+	var err error
+	if action == "approve" {
+		execution, err = h.executorService.Approve(c.Param("id"), u, req.Comment)
+	} else {
+		execution, err = h.executorService.Reject(c.Param("id"), u, req.Comment)
+	}
+	if err != nil {
+		switch err {
+		case services.ErrExecutionNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		case services.ErrSelfApproval:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case services.ErrNotAwaitingApproval, services.ErrApprovalExpired:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case services.ErrAppBusy:
+			c.JSON(http.StatusConflict, gin.H{
+				"error":                "app already has an active execution",
+				"running_execution_id": h.executorService.ActiveExecutionID(h.appIDOf(c.Param("id"))),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	commandName, appName := "", ""
+	if cmd, err := h.appService.GetCommandByID(execution.CommandID); err == nil {
+		commandName = cmd.Name
+		if app, err := h.appService.GetAppByID(cmd.AppID); err == nil {
+			appName = app.Name
+		}
+	}
+	h.auditService.LogExecutionReview(u.AuditName(), &u.ID, action, execution.ID, commandName, appName, req.Comment, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	if execution.Status == models.StatusPending {
+		go func() {
+			_ = h.executorService.Execute(execution.ID)
+		}()
+	}
+
+	c.JSON(http.StatusOK, execution)
+}
+
+// appIDOf returns the app of an execution, or an empty string if it cannot be found.
+func (h *CommandHandler) appIDOf(executionID string) string {
+	execution, err := h.executorService.GetExecutionByID(executionID)
+	if err != nil {
+		return ""
+	}
+	cmd, err := h.appService.GetCommandByID(execution.CommandID)
+	if err != nil {
+		return ""
+	}
+	return cmd.AppID
+}
+
+// CancelExecution stops a pending or running execution.
+func (h *CommandHandler) CancelExecution(c *gin.Context) {
+	id := c.Param("id")
+
+	user, exists := c.Get(middleware.UserContextKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	u, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user context"})
+		return
+	}
+
+	execution, err := h.executorService.GetExecutionByID(id)
+	if err != nil {
+		if err == services.ErrExecutionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.executorService.Cancel(execution.ID, u.Username); err != nil {
+		if err == services.ErrExecutionNotRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": "execution is not running"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get command and app for audit logging
+	commandName, appName := "", ""
+	if cmd, err := h.appService.GetCommandByID(execution.CommandID); err == nil {
+		commandName = cmd.Name
+		if app, err := h.appService.GetAppByID(cmd.AppID); err == nil {
+			appName = app.Name
+		}
+	}
+
+	h.auditService.LogExecutionCancel(u.AuditName(), &u.ID, execution.ID, commandName, appName, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "cancellation requested",
+		"execution_id": execution.ID,
+	})
+}
@@ -20,14 +20,17 @@ import (
 
 // ContainerHandler handles HTTP requests for container management.
 type ContainerHandler struct {
-	service  *services.ContainerService
-	upgrader websocket.Upgrader
+	service       *services.ContainerService
+	policyService *services.PolicyService
+	upgrader      websocket.Upgrader
 }
 
 // NewContainerHandler creates a new ContainerHandler instance.
-func NewContainerHandler(service *services.ContainerService) *ContainerHandler {
+// Exec commands are checked against the command policy of policyService, which may be nil.
+func NewContainerHandler(service *services.ContainerService, policyService *services.PolicyService) *ContainerHandler {
 	return &ContainerHandler{
-		service: service,
+		service:       service,
+		policyService: policyService,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins for now
@@ -83,13 +86,12 @@ func (h *ContainerHandler) Start(c *gin.Context) {
 		return
 	}
 
-	// Get user info from session
-	userID, _ := c.Get("userID")
-	username, _ := c.Get("username")
-	uid, _ := userID.(int64)
-	uname, _ := username.(string)
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
 
-	if err := h.service.Start(c.Request.Context(), containerID, uid, uname); err != nil {
+	if err := h.service.Start(c.Request.Context(), containerID, u.ID, u.AuditName()); err != nil {
 		if strings.Contains(err.Error(), "No such container") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "container not found"})
 			return
@@ -122,13 +124,12 @@ func (h *ContainerHandler) Stop(c *gin.Context) {
 		}
 	}
 
-	// Get user info from session
-	userID, _ := c.Get("userID")
-	username, _ := c.Get("username")
-	uid, _ := userID.(int64)
-	uname, _ := username.(string)
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
 
-	if err := h.service.Stop(c.Request.Context(), containerID, timeout, uid, uname); err != nil {
+	if err := h.service.Stop(c.Request.Context(), containerID, timeout, u.ID, u.AuditName()); err != nil {
 		if strings.Contains(err.Error(), "No such container") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "container not found"})
 			return
@@ -161,13 +162,12 @@ func (h *ContainerHandler) Restart(c *gin.Context) {
 		}
 	}
 
-	// Get user info from session
-	userID, _ := c.Get("userID")
-	username, _ := c.Get("username")
-	uid, _ := userID.(int64)
-	uname, _ := username.(string)
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
 
-	if err := h.service.Restart(c.Request.Context(), containerID, timeout, uid, uname); err != nil {
+	if err := h.service.Restart(c.Request.Context(), containerID, timeout, u.ID, u.AuditName()); err != nil {
 		if strings.Contains(err.Error(), "No such container") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "container not found"})
 			return
@@ -190,13 +190,12 @@ func (h *ContainerHandler) Remove(c *gin.Context) {
 
 	force := c.DefaultQuery("force", "false") == "true"
 
-	// Get user info from session
-	userID, _ := c.Get("userID")
-	username, _ := c.Get("username")
-	uid, _ := userID.(int64)
-	uname, _ := username.(string)
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
 
-	if err := h.service.Remove(c.Request.Context(), containerID, force, uid, uname); err != nil {
+	if err := h.service.Remove(c.Request.Context(), containerID, force, u.ID, u.AuditName()); err != nil {
 		if strings.Contains(err.Error(), "No such container") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "container not found"})
 			return
@@ -313,11 +312,26 @@ func (h *ContainerHandler) ExecCommand(c *gin.Context) {
 		return
 	}
 
-	// Get user info from session
-	userID, _ := c.Get("userID")
-	username, _ := c.Get("username")
-	uid, _ := userID.(int64)
-	uname, _ := username.(string)
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	// Exec commands cannot wait for approval, so approval rules reject them
+	if h.policyService != nil {
+		_, err := h.policyService.Enforce(strings.Join(req.Cmd, " "), services.PolicyCheck{
+			User:         u,
+			Action:       "container_exec",
+			ResourceType: "container",
+			ResourceID:   containerID,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.GetHeader("User-Agent"),
+		})
+		if err != nil {
+			writePolicyError(c, err)
+			return
+		}
+	}
 
 	result, err := h.service.Exec(c.Request.Context(), containerID, services.ExecConfig{
 		Cmd:          req.Cmd,
@@ -326,7 +340,7 @@ func (h *ContainerHandler) ExecCommand(c *gin.Context) {
 		Tty:          false,
 		WorkingDir:   req.WorkingDir,
 		User:         req.User,
-	}, uid, uname)
+	}, u.ID, u.AuditName())
 	if err != nil {
 		if strings.Contains(err.Error(), "No such container") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "container not found"})
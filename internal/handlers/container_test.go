@@ -28,7 +28,7 @@ func isDockerAvailable() bool {
 
 func TestNewContainerHandler(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	if handler == nil {
 		t.Fatal("expected non-nil handler")
@@ -40,7 +40,7 @@ func TestNewContainerHandler(t *testing.T) {
 
 func TestContainerHandler_CheckDocker(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -70,7 +70,7 @@ func TestContainerHandler_List(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.GET("/api/containers", handler.List)
@@ -105,7 +105,7 @@ func TestContainerHandler_List(t *testing.T) {
 
 func TestContainerHandler_Get_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.GET("/api/containers/:id", handler.Get)
@@ -131,7 +131,7 @@ func TestContainerHandler_Get_NotFound(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.GET("/api/containers/:id", handler.Get)
@@ -148,7 +148,7 @@ func TestContainerHandler_Get_NotFound(t *testing.T) {
 
 func TestContainerHandler_Start_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -168,7 +168,7 @@ func TestContainerHandler_Start_NotFound(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/start", handler.Start)
@@ -185,7 +185,7 @@ func TestContainerHandler_Start_NotFound(t *testing.T) {
 
 func TestContainerHandler_Stop_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -205,7 +205,7 @@ func TestContainerHandler_Stop_NotFound(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/stop", handler.Stop)
@@ -226,7 +226,7 @@ func TestContainerHandler_Stop_WithTimeout(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/stop", handler.Stop)
@@ -243,7 +243,7 @@ func TestContainerHandler_Stop_WithTimeout(t *testing.T) {
 
 func TestContainerHandler_Restart_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -263,7 +263,7 @@ func TestContainerHandler_Restart_NotFound(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/restart", handler.Restart)
@@ -280,7 +280,7 @@ func TestContainerHandler_Restart_NotFound(t *testing.T) {
 
 func TestContainerHandler_Remove_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -300,7 +300,7 @@ func TestContainerHandler_Remove_NotFound(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.DELETE("/api/containers/:id", handler.Remove)
@@ -321,7 +321,7 @@ func TestContainerHandler_Remove_WithForce(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.DELETE("/api/containers/:id", handler.Remove)
@@ -338,7 +338,7 @@ func TestContainerHandler_Remove_WithForce(t *testing.T) {
 
 func TestContainerHandler_StreamLogs_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -354,7 +354,7 @@ func TestContainerHandler_StreamLogs_MissingID(t *testing.T) {
 
 func TestContainerHandler_ExecCommand_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -371,7 +371,7 @@ func TestContainerHandler_ExecCommand_MissingID(t *testing.T) {
 
 func TestContainerHandler_ExecCommand_MissingCmd(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -388,7 +388,7 @@ func TestContainerHandler_ExecCommand_MissingCmd(t *testing.T) {
 
 func TestContainerHandler_ExecCommand_InvalidJSON(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -409,7 +409,7 @@ func TestContainerHandler_ExecCommand_NotFound(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/exec", handler.ExecCommand)
@@ -428,7 +428,7 @@ func TestContainerHandler_ExecCommand_NotFound(t *testing.T) {
 
 func TestContainerHandler_ExecInteractive_MissingID(t *testing.T) {
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -449,7 +449,7 @@ func TestContainerHandler_Stop_InvalidTimeout(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/stop", handler.Stop)
@@ -471,7 +471,7 @@ func TestContainerHandler_Restart_InvalidTimeout(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/restart", handler.Restart)
@@ -494,7 +494,7 @@ func TestContainerHandler_Start_WithUserContext(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.POST("/api/containers/:id/start", func(c *gin.Context) {
@@ -521,7 +521,7 @@ func TestContainerHandler_StreamLogs_Headers(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.GET("/api/containers/:id/logs", handler.StreamLogs)
@@ -550,7 +550,7 @@ func TestContainerHandler_StreamLogs_QueryParams(t *testing.T) {
 	}
 
 	service := services.NewContainerService(nil)
-	handler := NewContainerHandler(service)
+	handler := NewContainerHandler(service, nil)
 
 	r := gin.New()
 	r.GET("/api/containers/:id/logs", handler.StreamLogs)
@@ -1,61 +1,113 @@
 package handlers
 
 import (
-	"crypto/subtle"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/pandeptwidyaop/http-remote/internal/models"
 	"github.com/pandeptwidyaop/http-remote/internal/services"
 )
 
 // DeployHandler handles deployment webhook requests.
 type DeployHandler struct {
 	appService      *services.AppService
+	gitService      *services.GitService
 	executorService *services.ExecutorService
+	auditService    *services.AuditService
 	pathPrefix      string
 }
 
 // NewDeployHandler creates a new DeployHandler instance.
-func NewDeployHandler(appService *services.AppService, executorService *services.ExecutorService, pathPrefix string) *DeployHandler {
+func NewDeployHandler(appService *services.AppService, gitService *services.GitService, executorService *services.ExecutorService, auditService *services.AuditService, pathPrefix string) *DeployHandler {
 	return &DeployHandler{
 		appService:      appService,
+		gitService:      gitService,
 		executorService: executorService,
+		auditService:    auditService,
 		pathPrefix:      pathPrefix,
 	}
 }
 
-// DeployRequest contains the optional command ID for deployment.
-type DeployRequest struct {
-	CommandID string `json:"command_id"`
-}
-
-// Deploy executes the default command for an app using token authentication
-// POST /devops/deploy/:app_id
-// Header: X-Deploy-Token: <token>
-func (h *DeployHandler) Deploy(c *gin.Context) {
-	appID := c.Param("app_id")
+// authenticate verifies the X-Deploy-Token header against the app's deploy tokens and checks that the token
+// grants scope. On failure it writes the error response and returns false.
+func (h *DeployHandler) authenticate(c *gin.Context, appID string, scope models.DeployTokenScope) (*models.App, *models.DeployToken, bool) {
 	token := c.GetHeader("X-Deploy-Token")
 
 	if token == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing X-Deploy-Token header"})
-		return
+		return nil, nil, false
 	}
 
-	// Verify app exists and token matches
 	app, err := h.appService.GetAppByID(appID)
 	if err != nil {
 		if err == services.ErrAppNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
-			return
+			return nil, nil, false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, nil, false
 	}
 
-	// Use constant-time comparison to prevent timing attacks
-	if subtle.ConstantTimeCompare([]byte(app.Token), []byte(token)) != 1 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+	// Tokens are looked up by hash, so the plaintext is never compared
+	deployToken, err := h.appService.AuthenticateDeployToken(appID, token, scope, c.ClientIP())
+	if err != nil {
+		switch {
+		case err == services.ErrInvalidToken || err == services.ErrTokenExpired:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTokenScope):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, nil, false
+	}
+
+	return app, deployToken, true
+}
+
+// appExecution returns an execution if it belongs to the app and is of a command that deployToken may run.
+// On failure it writes the error response and returns false.
+func (h *DeployHandler) appExecution(c *gin.Context, appID, executionID string, deployToken *models.DeployToken) (*models.Execution, *models.Command, bool) {
+	execution, err := h.executorService.GetExecutionByID(executionID)
+	if err != nil {
+		if err == services.ErrExecutionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return nil, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	// A token only grants access to executions of its own app and, if it is limited to some commands, of those
+	cmd, err := h.appService.GetCommandByID(execution.CommandID)
+	if err != nil || cmd.AppID != appID || !deployToken.AllowsCommand(cmd.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		return nil, nil, false
+	}
+
+	return execution, cmd, true
+}
+
+// DeployRequest contains the optional command ID, parameter values and git ref for deployment.
+// Ref (branch or tag) and CommitSHA are only accepted for apps with a git source.
+type DeployRequest struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	CommandID  string                 `json:"command_id"`
+	Ref        string                 `json:"ref"`
+	CommitSHA  string                 `json:"commit_sha"`
+}
+
+// Deploy executes the default command for an app using token authentication
+// POST /devops/deploy/:app_id
+// Header: X-Deploy-Token: <token>
+func (h *DeployHandler) Deploy(c *gin.Context) {
+	appID := c.Param("app_id")
+
+	app, deployToken, ok := h.authenticate(c, appID, models.ScopeDeploy)
+	if !ok {
 		return
 	}
 
@@ -63,49 +115,198 @@ func (h *DeployHandler) Deploy(c *gin.Context) {
 	var req DeployRequest
 	_ = c.ShouldBindJSON(&req) // Ignore error, req is optional
 
-	var commandID string
-	if req.CommandID != "" {
+	commandID, ok := h.resolveCommand(c, appID, req.CommandID)
+	if !ok {
+		return
+	}
+	if !deployToken.AllowsCommand(commandID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "token is not allowed to run this command"})
+		return
+	}
+
+	params, err := services.ParameterValues(req.Parameters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	execution, ok := h.startExecution(c, appID, commandID, services.ExecutionOptions{
+		Parameters: params,
+		Ref:        req.Ref,
+		CommitSHA:  req.CommitSHA,
+	})
+	if !ok {
+		return
+	}
+
+	commandName := ""
+	if cmd, err := h.appService.GetCommandByID(commandID); err == nil {
+		commandName = cmd.Name
+	}
+	h.auditService.LogDeployExecute(deployToken, execution.ID, commandName, app.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":        deployMessage(execution),
+		"execution_id":   execution.ID,
+		"status":         execution.Status,
+		"queue_position": execution.QueuePosition,
+		"app_id":         appID,
+		"app_name":       app.Name,
+		"stream_url":     h.pathPrefix + "/api/executions/" + execution.ID + "/stream",
+		"status_url":     h.pathPrefix + "/api/executions/" + execution.ID,
+	})
+}
+
+// resolveCommand returns commandID if it belongs to the app, or the app's default command if it is empty.
+// On failure it writes the error response and returns false.
+func (h *DeployHandler) resolveCommand(c *gin.Context, appID, commandID string) (string, bool) {
+	if commandID != "" {
 		// Verify command belongs to this app
-		cmd, err := h.appService.GetCommandByID(req.CommandID)
+		cmd, err := h.appService.GetCommandByID(commandID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
-			return
+			return "", false
 		}
 		if cmd.AppID != appID {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "command does not belong to this app"})
+			return "", false
+		}
+		return cmd.ID, true
+	}
+
+	// Get default command (first command)
+	cmd, err := h.appService.GetDefaultCommandByAppID(appID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no commands configured for this app"})
+		return "", false
+	}
+	return cmd.ID, true
+}
+
+// startExecution creates an execution as the system user and runs it in the background,
+// unless it awaits approval. On failure it writes the error response and returns false.
+func (h *DeployHandler) startExecution(c *gin.Context, appID, commandID string, opts services.ExecutionOptions) (*models.Execution, bool) {
+	execution, err := h.executorService.CreateExecutionWithOptions(commandID, services.SystemUserID, opts)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidParameter) || errors.Is(err, services.ErrInvalidGitRef) || err == services.ErrNoGitSource {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		if err == services.ErrAppBusy {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":                "app already has an active execution",
+				"running_execution_id": h.executorService.ActiveExecutionID(appID),
+			})
+			return nil, false
+		}
+		writePolicyError(c, err)
+		return nil, false
+	}
+
+	// Execute in background; executions awaiting approval run once an operator approves them
+	if execution.Status != models.StatusAwaitingApproval {
+		go func() {
+			_ = h.executorService.Execute(execution.ID)
+		}()
+	}
+
+	return execution, true
+}
+
+// deployMessage describes what happens to a deployment that was just created.
+func deployMessage(execution *models.Execution) string {
+	if execution.Status == models.StatusAwaitingApproval {
+		return "deployment awaiting approval"
+	}
+	return "deployment started"
+}
+
+// maxWebhookPayload limits the size of provider webhook bodies.
+const maxWebhookPayload = 5 << 20
+
+// Webhook returns a handler for push webhooks of a git provider
+// POST /devops/deploy/:app_id/github|gitlab|gitea[?command_id=<id>]
+// The request is authenticated with the app's webhook secret instead of X-Deploy-Token.
+// Branch and tag pushes matching the app's webhook filters run the command at the pushed commit.
+func (h *DeployHandler) Webhook(provider services.WebhookProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appID := c.Param("app_id")
+
+		app, err := h.appService.GetAppByID(appID)
+		if err != nil {
+			if err == services.ErrAppNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		commandID = cmd.ID
-	} else {
-		// Get default command (first command)
-		cmd, err := h.appService.GetDefaultCommandByAppID(appID)
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookPayload))
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "no commands configured for this app"})
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "webhook payload too large"})
 			return
 		}
-		commandID = cmd.ID
-	}
 
-	// Create execution with system user (user_id = 0 for API calls)
-	execution, err := h.executorService.CreateExecution(commandID, 0)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+		if err := h.gitService.VerifyWebhook(app, provider, c.Request.Header, body); err != nil {
+			if err == services.ErrWebhookSecretNotSet || err == services.ErrInvalidWebhookSignature {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-	// Execute in background
-	go func() {
-		_ = h.executorService.Execute(execution.ID)
-	}()
+		event, err := services.ParseWebhook(provider, c.Request.Header, body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if event == nil {
+			c.JSON(http.StatusOK, gin.H{"message": "event ignored"})
+			return
+		}
+		if event.Deleted {
+			c.JSON(http.StatusOK, gin.H{"message": "deleted ref ignored", "ref": event.Ref})
+			return
+		}
+		if !event.Matches(app) {
+			c.JSON(http.StatusOK, gin.H{"message": "ref does not match the webhook filters", "ref": event.Ref})
+			return
+		}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message":      "deployment started",
-		"execution_id": execution.ID,
-		"app_id":       appID,
-		"app_name":     app.Name,
-		"stream_url":   h.pathPrefix + "/api/executions/" + execution.ID + "/stream",
-		"status_url":   h.pathPrefix + "/api/executions/" + execution.ID,
-	})
+		commandID, ok := h.resolveCommand(c, appID, c.Query("command_id"))
+		if !ok {
+			return
+		}
+
+		// The pushed ref and commit are always recorded; without a git source the command runs as is
+		execution, ok := h.startExecution(c, appID, commandID, services.ExecutionOptions{
+			Ref:       event.Name(),
+			CommitSHA: event.CommitSHA,
+			RecordRef: true,
+		})
+		if !ok {
+			return
+		}
+
+		commandName := ""
+		if cmd, err := h.appService.GetCommandByID(commandID); err == nil {
+			commandName = cmd.Name
+		}
+		h.auditService.LogWebhookExecute(string(provider), execution.ID, commandName, app.Name, event.Ref, event.CommitSHA, event.Pusher, c.ClientIP(), c.GetHeader("User-Agent"))
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":        deployMessage(execution),
+			"execution_id":   execution.ID,
+			"queue_position": execution.QueuePosition,
+			"app_id":         appID,
+			"app_name":       app.Name,
+			"ref":            event.Ref,
+			"commit_sha":     event.CommitSHA,
+			"status_url":     h.pathPrefix + "/deploy/" + appID + "/status/" + execution.ID,
+		})
+	}
 }
 
 // DeployStatus gets the status of a deployment
@@ -114,37 +315,14 @@ func (h *DeployHandler) Deploy(c *gin.Context) {
 func (h *DeployHandler) DeployStatus(c *gin.Context) {
 	appID := c.Param("app_id")
 	executionID := c.Param("execution_id")
-	token := c.GetHeader("X-Deploy-Token")
-
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing X-Deploy-Token header"})
-		return
-	}
-
-	// Verify app exists and token matches
-	app, err := h.appService.GetAppByID(appID)
-	if err != nil {
-		if err == services.ErrAppNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
 
-	// Use constant-time comparison to prevent timing attacks
-	if subtle.ConstantTimeCompare([]byte(app.Token), []byte(token)) != 1 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+	_, deployToken, ok := h.authenticate(c, appID, models.ScopeStatus)
+	if !ok {
 		return
 	}
 
-	execution, err := h.executorService.GetExecutionByID(executionID)
-	if err != nil {
-		if err == services.ErrExecutionNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	execution, _, ok := h.appExecution(c, appID, executionID, deployToken)
+	if !ok {
 		return
 	}
 
@@ -157,42 +335,54 @@ func (h *DeployHandler) DeployStatus(c *gin.Context) {
 func (h *DeployHandler) DeployStream(c *gin.Context) {
 	appID := c.Param("app_id")
 	executionID := c.Param("execution_id")
-	token := c.GetHeader("X-Deploy-Token")
 
-	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing X-Deploy-Token header"})
+	_, deployToken, ok := h.authenticate(c, appID, models.ScopeStatus)
+	if !ok {
 		return
 	}
 
-	// Verify app exists and token matches
-	app, err := h.appService.GetAppByID(appID)
-	if err != nil {
-		if err == services.ErrAppNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if _, _, ok := h.appExecution(c, appID, executionID, deployToken); !ok {
 		return
 	}
 
-	// Use constant-time comparison to prevent timing attacks
-	if subtle.ConstantTimeCompare([]byte(app.Token), []byte(token)) != 1 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+	// Delegate to stream handler logic
+	streamHandler := NewStreamHandler(h.executorService)
+	streamHandler.streamExecution(c, executionID)
+}
+
+// DeployCancel cancels a pending or running deployment
+// POST /devops/deploy/:app_id/cancel/:execution_id
+// Header: X-Deploy-Token: <token>
+func (h *DeployHandler) DeployCancel(c *gin.Context) {
+	appID := c.Param("app_id")
+	executionID := c.Param("execution_id")
+
+	app, deployToken, ok := h.authenticate(c, appID, models.ScopeCancel)
+	if !ok {
 		return
 	}
 
-	// Verify execution exists
-	_, err = h.executorService.GetExecutionByID(executionID)
-	if err != nil {
-		if err == services.ErrExecutionNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+	execution, cmd, ok := h.appExecution(c, appID, executionID, deployToken)
+	if !ok {
+		return
+	}
+
+	actor := services.DeployTokenActor(deployToken)
+	if err := h.executorService.Cancel(execution.ID, actor); err != nil {
+		if err == services.ErrExecutionNotRunning {
+			c.JSON(http.StatusConflict, gin.H{"error": "execution is not running"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Delegate to stream handler logic
-	streamHandler := NewStreamHandler(h.executorService)
-	streamHandler.streamExecution(c, executionID)
+	h.auditService.LogExecutionCancel(actor, nil, execution.ID, cmd.Name, app.Name, c.ClientIP(), c.GetHeader("User-Agent"))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "cancellation requested",
+		"execution_id": execution.ID,
+		"app_id":       app.ID,
+		"status_url":   h.pathPrefix + "/deploy/" + app.ID + "/status/" + execution.ID,
+	})
 }
@@ -0,0 +1,79 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/handlers"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestDeployHandler_TokenCommandScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	cfg := &config.Config{}
+	appService := services.NewAppService(db)
+	executorService := services.NewExecutorService(db, cfg, appService, nil, nil)
+	handler := handlers.NewDeployHandler(appService, nil, executorService, services.NewAuditService(db), "")
+
+	router := gin.New()
+	router.GET("/deploy/:app_id/status/:execution_id", handler.DeployStatus)
+	router.GET("/deploy/:app_id/stream/:execution_id", handler.DeployStream)
+	router.POST("/deploy/:app_id/cancel/:execution_id", handler.DeployCancel)
+
+	app, _ := appService.CreateApp(&models.CreateAppRequest{Name: "web", WorkingDir: t.TempDir()})
+	deploy, _ := appService.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true"})
+	migrate, _ := appService.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "migrate", Command: "true"})
+	token, err := appService.CreateDeployToken(app.ID, &models.CreateDeployTokenRequest{
+		Name:       "ci",
+		Scopes:     []models.DeployTokenScope{models.ScopeStatus, models.ScopeCancel},
+		CommandIDs: []string{deploy.ID},
+	}, "admin")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	allowed, _ := executorService.CreateExecution(deploy.ID, services.SystemUserID)
+	other, _ := executorService.CreateExecution(migrate.ID, services.SystemUserID)
+
+	request := func(method, action, executionID string) int {
+		req := httptest.NewRequest(method, "/deploy/"+app.ID+"/"+action+"/"+executionID, nil)
+		req.Header.Set("X-Deploy-Token", token.Token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Executions of commands outside of the token's commands are not found
+	for _, tt := range []struct{ method, action string }{
+		{http.MethodGet, "status"},
+		{http.MethodGet, "stream"},
+		{http.MethodPost, "cancel"},
+	} {
+		if code := request(tt.method, tt.action, other.ID); code != http.StatusNotFound {
+			t.Errorf("%s of another command: expected 404, got %d", tt.action, code)
+		}
+	}
+
+	if code := request(http.MethodGet, "status", allowed.ID); code != http.StatusOK {
+		t.Errorf("status of an allowed command: expected 200, got %d", code)
+	}
+	if code := request(http.MethodPost, "cancel", allowed.ID); code == http.StatusNotFound || code == http.StatusForbidden {
+		t.Errorf("cancel of an allowed command: expected it to be allowed, got %d", code)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/middleware"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// EnvHandler handles HTTP requests for app environment variables and secrets.
+type EnvHandler struct {
+	appService   *services.AppService
+	envService   *services.EnvService
+	auditService *services.AuditService
+}
+
+// NewEnvHandler creates a new EnvHandler instance.
+func NewEnvHandler(appService *services.AppService, envService *services.EnvService, auditService *services.AuditService) *EnvHandler {
+	return &EnvHandler{
+		appService:   appService,
+		envService:   envService,
+		auditService: auditService,
+	}
+}
+
+// List returns the environment variables of an app. Secret values are never included.
+func (h *EnvHandler) List(c *gin.Context) {
+	appID := c.Param("id")
+
+	if _, err := h.appService.GetAppByID(appID); err != nil {
+		if err == services.ErrAppNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	vars, err := h.envService.ListEnvVars(appID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, vars)
+}
+
+// Create adds an environment variable or secret to an app or one of its commands.
+func (h *EnvHandler) Create(c *gin.Context) {
+	appID := c.Param("id")
+
+	var req models.CreateEnvVarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.appService.GetAppByID(appID); err != nil {
+		if err == services.ErrAppNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	envVar, err := h.envService.CreateEnvVar(appID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidEnvVar):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err == services.ErrCommandNotFound:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "command does not belong to this app"})
+		case err == services.ErrEnvVarExists:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.audit(c, "create", envVar)
+
+	c.JSON(http.StatusCreated, envVar)
+}
+
+// Update changes the value or secret flag of an environment variable.
+func (h *EnvHandler) Update(c *gin.Context) {
+	appID := c.Param("id")
+	envID := c.Param("env_id")
+
+	var req models.UpdateEnvVarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	envVar, err := h.envService.UpdateEnvVar(appID, envID, &req)
+	if err != nil {
+		switch {
+		case err == services.ErrEnvVarNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidEnvVar):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.audit(c, "update", envVar)
+
+	c.JSON(http.StatusOK, envVar)
+}
+
+// Delete removes an environment variable from an app.
+func (h *EnvHandler) Delete(c *gin.Context) {
+	appID := c.Param("id")
+	envID := c.Param("env_id")
+
+	envVar, err := h.envService.GetEnvVar(appID, envID)
+	if err != nil {
+		if err == services.ErrEnvVarNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.envService.DeleteEnvVar(appID, envID); err != nil {
+		if err == services.ErrEnvVarNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "delete", envVar)
+
+	c.JSON(http.StatusOK, gin.H{"message": "environment variable deleted"})
+}
+
+// audit records a change to an environment variable. Values are never logged.
+func (h *EnvHandler) audit(c *gin.Context, action string, envVar *models.EnvVar) {
+	user, _ := c.Get(middleware.UserContextKey)
+	u, ok := user.(*models.User)
+	if !ok {
+		return
+	}
+
+	details := map[string]interface{}{
+		"name":      envVar.Name,
+		"app_id":    envVar.AppID,
+		"is_secret": envVar.IsSecret,
+	}
+	if envVar.CommandID != "" {
+		details["command_id"] = envVar.CommandID
+	}
+
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.AuditName(),
+		Action:       action,
+		ResourceType: "env_var",
+		ResourceID:   envVar.ID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details:      details,
+	})
+}
@@ -180,7 +180,7 @@ func (h *FileHandler) logFileAction(c *gin.Context, action, path string, details
 
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &u.ID,
-		Username:     u.Username,
+		Username:     u.AuditName(),
 		Action:       action,
 		ResourceType: "file",
 		ResourceID:   path,
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/middleware"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+	"github.com/pandeptwidyaop/http-remote/internal/validation"
+)
+
+// GroupHandler handles user group management endpoints.
+type GroupHandler struct {
+	authService  *services.AuthService
+	auditService *services.AuditService
+}
+
+// NewGroupHandler creates a new GroupHandler instance.
+func NewGroupHandler(authService *services.AuthService, auditService *services.AuditService) *GroupHandler {
+	return &GroupHandler{
+		authService:  authService,
+		auditService: auditService,
+	}
+}
+
+// List returns all groups with their members.
+func (h *GroupHandler) List(c *gin.Context) {
+	groups, err := h.authService.ListGroups()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// Create creates a group.
+func (h *GroupHandler) Create(c *gin.Context) {
+	var req models.GroupRequest
+	if !h.bindRequest(c, &req) {
+		return
+	}
+
+	group, err := h.authService.CreateGroup(&req)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	h.audit(c, "group_create", group)
+	c.JSON(http.StatusCreated, group)
+}
+
+// Update renames a group and replaces its members.
+func (h *GroupHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	var req models.GroupRequest
+	if !h.bindRequest(c, &req) {
+		return
+	}
+
+	group, err := h.authService.UpdateGroup(id, &req)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	h.audit(c, "group_update", group)
+	c.JSON(http.StatusOK, group)
+}
+
+// Delete deletes a group and its app memberships.
+func (h *GroupHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	group, err := h.authService.GetGroup(id)
+	if err == nil {
+		err = h.authService.DeleteGroup(id)
+	}
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	h.audit(c, "group_delete", group)
+	c.JSON(http.StatusOK, gin.H{"message": "group deleted"})
+}
+
+// bindRequest binds and validates a group request. On failure it writes the error response.
+func (h *GroupHandler) bindRequest(c *gin.Context, req *models.GroupRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if err := validation.ValidateName(req.Name, 100); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group name: " + err.Error()})
+		return false
+	}
+	req.Name = validation.SanitizeString(req.Name)
+	return true
+}
+
+// writeError writes the response for an error of the group service.
+func (h *GroupHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case err == services.ErrGroupNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+	case err == services.ErrGroupExists:
+		c.JSON(http.StatusConflict, gin.H{"error": "group already exists"})
+	case errors.Is(err, services.ErrInvalidGroup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// audit records a change to a group.
+func (h *GroupHandler) audit(c *gin.Context, action string, group *models.Group) {
+	user, _ := c.Get(middleware.UserContextKey)
+	u, ok := user.(*models.User)
+	if !ok {
+		return
+	}
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.AuditName(),
+		Action:       action,
+		ResourceType: "group",
+		ResourceID:   strconv.FormatInt(group.ID, 10),
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details:      map[string]interface{}{"group_name": group.Name, "user_ids": group.UserIDs},
+	})
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/middleware"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// NotificationHandler handles HTTP requests for app notification rules and their delivery log.
+type NotificationHandler struct {
+	appService          *services.AppService
+	notificationService *services.NotificationService
+	auditService        *services.AuditService
+}
+
+// NewNotificationHandler creates a new NotificationHandler instance.
+func NewNotificationHandler(appService *services.AppService, notificationService *services.NotificationService, auditService *services.AuditService) *NotificationHandler {
+	return &NotificationHandler{
+		appService:          appService,
+		notificationService: notificationService,
+		auditService:        auditService,
+	}
+}
+
+// requireApp writes a 404 response and returns false if the app does not exist.
+func (h *NotificationHandler) requireApp(c *gin.Context, appID string) bool {
+	if _, err := h.appService.GetAppByID(appID); err != nil {
+		if err == services.ErrAppNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "app not found"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// List returns the notification rules of an app. Webhook secrets are never included.
+func (h *NotificationHandler) List(c *gin.Context) {
+	appID := c.Param("id")
+	if !h.requireApp(c, appID) {
+		return
+	}
+
+	rules, err := h.notificationService.ListRules(appID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// Create adds a notification rule to an app.
+func (h *NotificationHandler) Create(c *gin.Context) {
+	appID := c.Param("id")
+
+	var req models.CreateNotificationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.requireApp(c, appID) {
+		return
+	}
+
+	rule, err := h.notificationService.CreateRule(appID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidNotificationRule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "create", rule)
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// Update changes a notification rule of an app.
+func (h *NotificationHandler) Update(c *gin.Context) {
+	appID := c.Param("id")
+	ruleID := c.Param("rule_id")
+
+	var req models.UpdateNotificationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.notificationService.UpdateRule(appID, ruleID, &req)
+	if err != nil {
+		switch {
+		case err == services.ErrNotificationRuleNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidNotificationRule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.audit(c, "update", rule)
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// Delete removes a notification rule from an app.
+func (h *NotificationHandler) Delete(c *gin.Context) {
+	appID := c.Param("id")
+	ruleID := c.Param("rule_id")
+
+	rule, err := h.notificationService.GetRule(appID, ruleID)
+	if err != nil {
+		if err == services.ErrNotificationRuleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.notificationService.DeleteRule(appID, ruleID); err != nil {
+		if err == services.ErrNotificationRuleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "delete", rule)
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification rule deleted"})
+}
+
+// ListDeliveries returns the delivery log of an app, newest first.
+// Query: execution_id (optional), limit (default 50, max 200)
+func (h *NotificationHandler) ListDeliveries(c *gin.Context) {
+	appID := c.Param("id")
+	if !h.requireApp(c, appID) {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	deliveries, err := h.notificationService.ListDeliveries(appID, c.Query("execution_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// audit records a change to a notification rule. Secrets are never logged.
+func (h *NotificationHandler) audit(c *gin.Context, action string, rule *models.NotificationRule) {
+	user, _ := c.Get(middleware.UserContextKey)
+	u, ok := user.(*models.User)
+	if !ok {
+		return
+	}
+
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.AuditName(),
+		Action:       action,
+		ResourceType: "notification_rule",
+		ResourceID:   rule.ID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"name":     rule.Name,
+			"app_id":   rule.AppID,
+			"channel":  rule.Channel,
+			"statuses": rule.Statuses,
+		},
+	})
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+	"github.com/pandeptwidyaop/http-remote/internal/validation"
+)
+
+// PolicyHandler handles command policy rule management endpoints.
+type PolicyHandler struct {
+	policyService *services.PolicyService
+	auditService  *services.AuditService
+}
+
+// NewPolicyHandler creates a new PolicyHandler instance.
+func NewPolicyHandler(policyService *services.PolicyService, auditService *services.AuditService) *PolicyHandler {
+	return &PolicyHandler{
+		policyService: policyService,
+		auditService:  auditService,
+	}
+}
+
+// List returns all policy rules.
+func (h *PolicyHandler) List(c *gin.Context) {
+	rules, err := h.policyService.ListRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// Create creates a policy rule.
+func (h *PolicyHandler) Create(c *gin.Context) {
+	var req models.CreatePolicyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidateName(req.Name, 100); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule name: " + err.Error()})
+		return
+	}
+	req.Name = validation.SanitizeString(req.Name)
+	req.Description = validation.SanitizeString(req.Description)
+
+	rule, err := h.policyService.CreateRule(&req)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	h.audit(c, "policy_create", rule)
+	c.JSON(http.StatusCreated, rule)
+}
+
+// Update updates a policy rule.
+func (h *PolicyHandler) Update(c *gin.Context) {
+	var req models.UpdatePolicyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		if err := validation.ValidateName(*req.Name, 100); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule name: " + err.Error()})
+			return
+		}
+		name := validation.SanitizeString(*req.Name)
+		req.Name = &name
+	}
+	if req.Description != nil {
+		description := validation.SanitizeString(*req.Description)
+		req.Description = &description
+	}
+
+	rule, err := h.policyService.UpdateRule(c.Param("id"), &req)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	h.audit(c, "policy_update", rule)
+	c.JSON(http.StatusOK, rule)
+}
+
+// Delete deletes a policy rule.
+func (h *PolicyHandler) Delete(c *gin.Context) {
+	rule, err := h.policyService.GetRule(c.Param("id"))
+	if err == nil {
+		err = h.policyService.DeleteRule(rule.ID)
+	}
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+
+	h.audit(c, "policy_delete", rule)
+	c.JSON(http.StatusOK, gin.H{"message": "policy rule deleted"})
+}
+
+// EvaluateRequest contains a command to evaluate against the policy rules of a role.
+type EvaluateRequest struct {
+	Command string          `json:"command" binding:"required"`
+	Role    models.UserRole `json:"role"`
+}
+
+// Evaluate returns the decision of the policy rules for a command, without enforcing it.
+// An empty role evaluates the command like an execution without a user.
+func (h *PolicyHandler) Evaluate(c *gin.Context) {
+	var req EvaluateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision, err := h.policyService.Evaluate(req.Command, req.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"allowed":           decision.Reason == "",
+		"rule":              decision.Rule,
+		"reason":            decision.Reason,
+		"requires_approval": decision.RequiresApproval,
+	})
+}
+
+// writeError writes the response for an error of the policy service.
+func (h *PolicyHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case err == services.ErrPolicyRuleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "policy rule not found"})
+	case err == services.ErrPolicyRuleExists:
+		c.JSON(http.StatusConflict, gin.H{"error": "policy rule already exists"})
+	case errors.Is(err, services.ErrInvalidPolicyRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// audit records a change to a policy rule.
+func (h *PolicyHandler) audit(c *gin.Context, action string, rule *models.PolicyRule) {
+	u, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.AuditName(),
+		Action:       action,
+		ResourceType: "policy",
+		ResourceID:   rule.ID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"rule_name":  rule.Name,
+			"action":     rule.Action,
+			"match_type": rule.MatchType,
+			"pattern":    rule.Pattern,
+			"roles":      rule.Roles,
+			"enabled":    rule.Enabled,
+		},
+	})
+}
+
+// enforcePolicy checks a command of the current user against the command policy, where a match
+// of an approval rule is not a violation. A rejected command is written to the audit log by the
+// policy service; enforcePolicy then writes the error response and returns false.
+func enforcePolicy(c *gin.Context, policyService *services.PolicyService, u *models.User, command, action, resourceType, resourceID string) bool {
+	if policyService == nil {
+		return true
+	}
+	_, err := policyService.Enforce(command, services.PolicyCheck{
+		User:         u,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Approvable:   true,
+	})
+	if err != nil {
+		writePolicyError(c, err)
+		return false
+	}
+	return true
+}
+
+// writePolicyError writes the response for an error evaluating the command policy.
+func writePolicyError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPolicyViolation) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
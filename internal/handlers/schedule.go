@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/middleware"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// ScheduleHandler handles HTTP requests for cron schedules of commands.
+type ScheduleHandler struct {
+	appService       *services.AppService
+	schedulerService *services.SchedulerService
+	auditService     *services.AuditService
+}
+
+// NewScheduleHandler creates a new ScheduleHandler instance.
+func NewScheduleHandler(appService *services.AppService, schedulerService *services.SchedulerService, auditService *services.AuditService) *ScheduleHandler {
+	return &ScheduleHandler{
+		appService:       appService,
+		schedulerService: schedulerService,
+		auditService:     auditService,
+	}
+}
+
+// List returns the schedules of a command.
+func (h *ScheduleHandler) List(c *gin.Context) {
+	commandID := c.Param("id")
+	if _, err := h.appService.GetCommandByID(commandID); err != nil {
+		if err == services.ErrCommandNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedules, err := h.schedulerService.ListSchedules(commandID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// Create adds a schedule to a command.
+func (h *ScheduleHandler) Create(c *gin.Context) {
+	var req models.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.schedulerService.CreateSchedule(c.Param("id"), &req)
+	if err != nil {
+		switch {
+		case err == services.ErrCommandNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
+		case errors.Is(err, services.ErrInvalidSchedule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.audit(c, "create", schedule)
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// Update changes a schedule. Setting enabled to false pauses it without losing its settings.
+func (h *ScheduleHandler) Update(c *gin.Context) {
+	var req models.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.schedulerService.UpdateSchedule(c.Param("id"), &req)
+	if err != nil {
+		switch {
+		case err == services.ErrScheduleNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidSchedule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.audit(c, "update", schedule)
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// Delete removes a schedule.
+func (h *ScheduleHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+
+	schedule, err := h.schedulerService.GetSchedule(id)
+	if err != nil {
+		if err == services.ErrScheduleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.schedulerService.DeleteSchedule(id); err != nil {
+		if err == services.ErrScheduleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.audit(c, "delete", schedule)
+
+	c.JSON(http.StatusOK, gin.H{"message": "schedule deleted"})
+}
+
+// Preview returns the next run times of a cron expression without saving it.
+// Query: cron (required), timezone (default UTC), count (default 5, max 50)
+func (h *ScheduleHandler) Preview(c *gin.Context) {
+	expr := c.Query("cron")
+	if expr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cron is required"})
+		return
+	}
+
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if count <= 0 || count > 50 {
+		count = 5
+	}
+
+	runs, err := h.schedulerService.Preview(expr, c.DefaultQuery("timezone", "UTC"), time.Now(), count)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"next_runs": runs})
+}
+
+// audit records a change to a schedule.
+func (h *ScheduleHandler) audit(c *gin.Context, action string, schedule *models.Schedule) {
+	user, _ := c.Get(middleware.UserContextKey)
+	u, ok := user.(*models.User)
+	if !ok {
+		return
+	}
+
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &u.ID,
+		Username:     u.AuditName(),
+		Action:       action,
+		ResourceType: "schedule",
+		ResourceID:   schedule.ID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"command_id":     schedule.CommandID,
+			"cron":           schedule.Cron,
+			"timezone":       schedule.Timezone,
+			"overlap_policy": schedule.OverlapPolicy,
+			"enabled":        schedule.Enabled,
+		},
+	})
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -24,12 +26,50 @@ func NewStreamHandler(executorService *services.ExecutorService) *StreamHandler
 	}
 }
 
+// replayPageSize is the number of stored output chunks read at a time when replaying a finished execution.
+const replayPageSize = 1000
+
 // Stream streams execution output using Server-Sent Events.
+// Every output event carries the line's sequence number as its id. With ?format=chunks, lines are sent as
+// "chunk" events whose data is the JSON chunk with its stream, timestamp and elapsed time; otherwise as
+// "output" events whose data is the plain line.
+// A client that reconnects with Last-Event-ID (or ?last_event_id) receives the lines after that id before
+// the live output. Lines that can no longer be replayed are reported with a "dropped" event.
 func (h *StreamHandler) Stream(c *gin.Context) {
 	id := c.Param("id")
 	h.streamExecution(c, id)
 }
 
+// writeChunk writes a line of output as an SSE event in the requested format.
+func writeChunk(w io.Writer, chunk models.OutputChunk, asJSON bool) {
+	if chunk.Seq > 0 {
+		_, _ = fmt.Fprintf(w, "id: %d\n", chunk.Seq)
+	}
+	if !asJSON {
+		_, _ = fmt.Fprintf(w, "event: output\ndata: %s\n\n", chunk.Line)
+		return
+	}
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "event: chunk\ndata: %s\n\n", data)
+}
+
+// lastEventID returns the sequence number of the last line a reconnecting client received, from the
+// Last-Event-ID header that EventSource sends or the last_event_id query parameter.
+func lastEventID(c *gin.Context) int64 {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seq < 0 {
+		return 0
+	}
+	return seq
+}
+
 func (h *StreamHandler) streamExecution(c *gin.Context, id string) {
 	execution, err := h.executorService.GetExecutionByID(id)
 	if err != nil {
@@ -41,36 +81,34 @@ func (h *StreamHandler) streamExecution(c *gin.Context, id string) {
 		return
 	}
 
-	if execution.Status == models.StatusSuccess || execution.Status == models.StatusFailed {
-		c.Header("Content-Type", "text/event-stream")
-		c.Header("Cache-Control", "no-cache")
-		c.Header("Connection", "keep-alive")
+	asJSON := c.Query("format") == "chunks"
+	lastSeq := lastEventID(c)
 
-		if execution.Output != "" {
-			lines := strings.Split(execution.Output, "\n")
-			for _, line := range lines {
-				if line != "" {
-					_, _ = fmt.Fprintf(c.Writer, "event: output\ndata: %s\n\n", line)
-				}
-			}
-		}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	var ch chan string
+	if !execution.Status.IsFinished() {
+		ch = h.executorService.Subscribe(id)
+		defer h.executorService.Unsubscribe(id, ch)
 
-		exitCode := 0
-		if execution.ExitCode != nil {
-			exitCode = *execution.ExitCode
+		// The execution may have finished before subscribing, in which case its complete message was missed
+		if current, err := h.executorService.GetExecutionByID(id); err == nil {
+			execution = current
 		}
-		_, _ = fmt.Fprintf(c.Writer, "event: complete\ndata: {\"status\": \"%s\", \"exit_code\": %d}\n\n", execution.Status, exitCode)
+	}
+
+	if execution.Status.IsFinished() {
+		h.replayFinished(c.Writer, execution, lastSeq, asJSON)
 		c.Writer.Flush()
 		return
 	}
 
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("X-Accel-Buffering", "no")
-
-	ch := h.executorService.Subscribe(id)
-	defer h.executorService.Unsubscribe(id, ch)
+	// Send what the client missed before switching to live output
+	lastSeq = h.catchUp(c.Writer, id, lastSeq, asJSON)
+	c.Writer.Flush()
 
 	c.Stream(func(w io.Writer) bool {
 		select {
@@ -80,10 +118,30 @@ func (h *StreamHandler) streamExecution(c *gin.Context, id string) {
 			}
 
 			if strings.HasPrefix(msg, "output:") {
-				line := strings.TrimPrefix(msg, "output:")
-				_, _ = fmt.Fprintf(w, "event: output\ndata: %s\n\n", line)
+				var chunk models.OutputChunk
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(msg, "output:")), &chunk); err != nil {
+					return true
+				}
+				switch {
+				case chunk.Seq == 0:
+					// Not recorded, such as errors before the execution started
+					writeChunk(w, chunk, asJSON)
+				case chunk.Seq <= lastSeq:
+					// Already sent while catching up
+				case chunk.Seq > lastSeq+1:
+					// Lines were dropped because the client is reading slower than the command writes
+					lastSeq = h.catchUp(w, id, lastSeq, asJSON)
+				default:
+					writeChunk(w, chunk, asJSON)
+					lastSeq = chunk.Seq
+				}
+			} else if strings.HasPrefix(msg, "step:") {
+				_, _ = fmt.Fprintf(w, "event: step\ndata: %s\n\n", strings.TrimPrefix(msg, "step:"))
 			} else if strings.HasPrefix(msg, "complete:") {
-				// Fetch final execution to get actual exit code and any remaining output
+				// Lines at the end may have been dropped as well
+				h.catchUp(w, id, lastSeq, asJSON)
+
+				// Fetch final execution to get actual exit code
 				finalExec, err := h.executorService.GetExecutionByID(id)
 				exitCode := 1
 				status := strings.TrimPrefix(msg, "complete:")
@@ -100,3 +158,50 @@ func (h *StreamHandler) streamExecution(c *gin.Context, id string) {
 		}
 	})
 }
+
+// catchUp writes the output of a running execution after lastSeq and returns the sequence number of the
+// last line written. Lines that are no longer available are reported with a "dropped" event.
+func (h *StreamHandler) catchUp(w io.Writer, id string, lastSeq int64, asJSON bool) int64 {
+	chunks, dropped, err := h.executorService.OutputSince(id, lastSeq)
+	if err != nil {
+		return lastSeq
+	}
+	if dropped > 0 {
+		_, _ = fmt.Fprintf(w, "event: dropped\ndata: {\"count\": %d, \"after\": %d}\n\n", dropped, lastSeq)
+	}
+	for _, chunk := range chunks {
+		writeChunk(w, chunk, asJSON)
+		lastSeq = chunk.Seq
+	}
+	return lastSeq
+}
+
+// replayFinished writes the stored output after lastSeq, the steps and the result of a finished execution.
+func (h *StreamHandler) replayFinished(w io.Writer, execution *models.Execution, lastSeq int64, asJSON bool) {
+	for after := lastSeq; ; {
+		chunks, more, err := h.executorService.GetOutput(execution.ID, after, replayPageSize)
+		if err != nil {
+			break
+		}
+		for _, chunk := range chunks {
+			writeChunk(w, chunk, asJSON)
+			after = chunk.Seq
+		}
+		if !more {
+			break
+		}
+	}
+
+	for _, step := range execution.Steps {
+		step.Output = ""
+		if data, err := json.Marshal(step); err == nil {
+			_, _ = fmt.Fprintf(w, "event: step\ndata: %s\n\n", data)
+		}
+	}
+
+	exitCode := 0
+	if execution.ExitCode != nil {
+		exitCode = *execution.ExitCode
+	}
+	_, _ = fmt.Fprintf(w, "event: complete\ndata: {\"status\": \"%s\", \"exit_code\": %d}\n\n", execution.Status, exitCode)
+}
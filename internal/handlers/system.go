@@ -104,7 +104,7 @@ func (h *SystemHandler) Upgrade(c *gin.Context) {
 	// Audit log the upgrade attempt
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &u.ID,
-		Username:     u.Username,
+		Username:     u.AuditName(),
 		Action:       "system_upgrade_started",
 		ResourceType: "system",
 		Details:      map[string]interface{}{"message": "User initiated system upgrade"},
@@ -122,7 +122,7 @@ func (h *SystemHandler) Upgrade(c *gin.Context) {
 		// Audit log the failure
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "system_upgrade_failed",
 			ResourceType: "system",
 			Details:      map[string]interface{}{"error": err.Error()},
@@ -140,7 +140,7 @@ func (h *SystemHandler) Upgrade(c *gin.Context) {
 	// Audit log success
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &u.ID,
-		Username:     u.Username,
+		Username:     u.AuditName(),
 		Action:       "system_upgrade_completed",
 		ResourceType: "system",
 		Details:      map[string]interface{}{"new_version": release.TagName},
@@ -195,7 +195,7 @@ func (h *SystemHandler) Restart(c *gin.Context) {
 	// Audit log the restart
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &u.ID,
-		Username:     u.Username,
+		Username:     u.AuditName(),
 		Action:       "system_restart",
 		ResourceType: "system",
 		Details:      map[string]interface{}{"message": "User initiated service restart"},
@@ -219,7 +219,7 @@ func (h *SystemHandler) Restart(c *gin.Context) {
 			// Log the error - we can't send it back since response is already sent
 			_ = h.auditService.Log(services.AuditLog{
 				UserID:       &u.ID,
-				Username:     u.Username,
+				Username:     u.AuditName(),
 				Action:       "system_restart_failed",
 				ResourceType: "system",
 				Details:      map[string]interface{}{"error": err.Error()},
@@ -298,7 +298,7 @@ func (h *SystemHandler) Rollback(c *gin.Context) {
 	// Audit log the rollback attempt
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &u.ID,
-		Username:     u.Username,
+		Username:     u.AuditName(),
 		Action:       "system_rollback_started",
 		ResourceType: "system",
 		Details:      map[string]interface{}{"target_version": backupVersion},
@@ -310,7 +310,7 @@ func (h *SystemHandler) Rollback(c *gin.Context) {
 	if err := upgrade.Rollback(req.BackupPath); err != nil {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &u.ID,
-			Username:     u.Username,
+			Username:     u.AuditName(),
 			Action:       "system_rollback_failed",
 			ResourceType: "system",
 			Details:      map[string]interface{}{"error": err.Error()},
@@ -325,7 +325,7 @@ func (h *SystemHandler) Rollback(c *gin.Context) {
 	// Audit log success
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &u.ID,
-		Username:     u.Username,
+		Username:     u.AuditName(),
 		Action:       "system_rollback_completed",
 		ResourceType: "system",
 		Details:      map[string]interface{}{"rolled_back_to": backupVersion},
@@ -2,18 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
 	"github.com/pandeptwidyaop/http-remote/internal/config"
-	"github.com/pandeptwidyaop/http-remote/internal/middleware"
 	"github.com/pandeptwidyaop/http-remote/internal/models"
 	"github.com/pandeptwidyaop/http-remote/internal/services"
 )
@@ -21,18 +24,26 @@ import (
 // TerminalHandler handles interactive terminal sessions over WebSocket.
 type TerminalHandler struct {
 	cfg            *config.TerminalConfig
+	authService    *services.AuthService
 	auditService   *services.AuditService
+	recordings     *services.TerminalRecordingService
+	policyService  *services.PolicyService
 	sessionManager *services.TerminalSessionManager
 	allowedOrigins []string
 	upgrader       websocket.Upgrader
 }
 
 // NewTerminalHandler creates a new TerminalHandler instance.
-func NewTerminalHandler(cfg *config.TerminalConfig, auditService *services.AuditService, allowedOrigins []string) *TerminalHandler {
+// Sessions are recorded if recordings is enabled; it may be nil. Lines entered into sessions are
+// checked against the command policy of policyService, which may be nil.
+func NewTerminalHandler(cfg *config.TerminalConfig, authService *services.AuthService, auditService *services.AuditService, recordings *services.TerminalRecordingService, policyService *services.PolicyService, allowedOrigins []string) *TerminalHandler {
 	h := &TerminalHandler{
 		cfg:            cfg,
+		authService:    authService,
 		auditService:   auditService,
-		sessionManager: services.NewTerminalSessionManager(cfg),
+		recordings:     recordings,
+		policyService:  policyService,
+		sessionManager: services.NewTerminalSessionManager(cfg, recordings),
 		allowedOrigins: allowedOrigins,
 	}
 
@@ -83,56 +94,67 @@ func (h *TerminalHandler) checkOrigin(r *http.Request) bool {
 	return false
 }
 
-// ListSessions returns all terminal sessions for the current user.
-func (h *TerminalHandler) ListSessions(c *gin.Context) {
+// terminalUser returns the current user if they may use the terminal, and writes an error response otherwise.
+func (h *TerminalHandler) terminalUser(c *gin.Context) (*models.User, bool) {
 	if !h.cfg.IsEnabled() {
 		c.JSON(http.StatusForbidden, gin.H{"error": "terminal is disabled"})
-		return
+		return nil, false
 	}
-
-	userObj, exists := c.Get(middleware.UserContextKey)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
+	user, ok := currentUser(c)
+	if !ok {
+		return nil, false
 	}
+	if h.cfg.AdminOnly && !user.HasPermission(models.RoleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "terminal is only available to admins"})
+		return nil, false
+	}
+	return user, true
+}
 
-	user, ok := userObj.(*models.User)
+// ListSessions returns the terminal sessions of the current user and the sessions they were invited into,
+// with the limits of the user and the shells they may choose from.
+func (h *TerminalHandler) ListSessions(c *gin.Context) {
+	user, ok := h.terminalUser(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user context"})
 		return
 	}
 
-	sessions := h.sessionManager.GetUserSessions(user.ID)
+	sessions := append(h.sessionManager.GetUserSessions(user.ID), h.sessionManager.GetSharedSessions(user.ID)...)
 	sessionInfos := make([]services.SessionInfo, 0, len(sessions))
 	for _, s := range sessions {
 		if !s.IsClosed() {
-			sessionInfos = append(sessionInfos, s.Info())
+			sessionInfos = append(sessionInfos, s.InfoFor(user.ID))
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sessions": sessionInfos})
+	c.JSON(http.StatusOK, gin.H{
+		"sessions":      sessionInfos,
+		"limits":        h.sessionManager.Limits(user.Role),
+		"shells":        h.cfg.Shells(),
+		"default_shell": h.cfg.Shell,
+	})
+}
+
+// CreateSessionRequest contains the data for creating a terminal session. Shell defaults to the configured shell.
+type CreateSessionRequest struct {
+	Shell string `json:"shell"`
 }
 
 // CreateSession creates a new persistent terminal session.
 func (h *TerminalHandler) CreateSession(c *gin.Context) {
-	if !h.cfg.IsEnabled() {
-		c.JSON(http.StatusForbidden, gin.H{"error": "terminal is disabled"})
-		return
-	}
-
-	userObj, exists := c.Get(middleware.UserContextKey)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	user, ok := h.terminalUser(c)
+	if !ok {
 		return
 	}
 
-	user, ok := userObj.(*models.User)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user context"})
+	// The body is optional
+	var req CreateSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	session, err := h.sessionManager.CreateSession(user.ID, user.Username)
+	session, err := h.sessionManager.CreateSession(user, req.Shell)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -142,37 +164,23 @@ func (h *TerminalHandler) CreateSession(c *gin.Context) {
 	if h.auditService != nil {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &user.ID,
-			Username:     user.Username,
+			Username:     user.AuditName(),
 			Action:       "terminal_session_create",
 			ResourceType: "terminal",
 			ResourceID:   session.ID,
 			IPAddress:    c.ClientIP(),
 			UserAgent:    c.GetHeader("User-Agent"),
-			Details: map[string]interface{}{
-				"shell": h.cfg.Shell,
-			},
+			Details:      sessionDetails(session),
 		})
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"session": session.Info()})
+	c.JSON(http.StatusCreated, gin.H{"session": session.InfoFor(user.ID)})
 }
 
 // CloseSession closes a persistent terminal session.
 func (h *TerminalHandler) CloseSession(c *gin.Context) {
-	if !h.cfg.IsEnabled() {
-		c.JSON(http.StatusForbidden, gin.H{"error": "terminal is disabled"})
-		return
-	}
-
-	userObj, exists := c.Get(middleware.UserContextKey)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-
-	user, ok := userObj.(*models.User)
+	user, ok := h.terminalUser(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user context"})
 		return
 	}
 
@@ -199,7 +207,7 @@ func (h *TerminalHandler) CloseSession(c *gin.Context) {
 	if h.auditService != nil {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &user.ID,
-			Username:     user.Username,
+			Username:     user.AuditName(),
 			Action:       "terminal_session_close",
 			ResourceType: "terminal",
 			ResourceID:   sessionID,
@@ -211,35 +219,313 @@ func (h *TerminalHandler) CloseSession(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "session closed"})
 }
 
+// InviteParticipantRequest contains the data for inviting a user into a terminal session.
+type InviteParticipantRequest struct {
+	Username string                   `json:"username" binding:"required"`
+	Role     services.ParticipantRole `json:"role" binding:"required"`
+}
+
+// participantSession returns the session of the session_id parameter if user may see its participants,
+// and writes an error response otherwise. With ownerOnly, only the owner may.
+func (h *TerminalHandler) participantSession(c *gin.Context, user *models.User, ownerOnly bool) (*services.TerminalSession, bool) {
+	session, ok := h.sessionManager.GetSession(c.Param("session_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return nil, false
+	}
+	role, ok := session.RoleOf(user.ID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return nil, false
+	}
+	if ownerOnly && role != services.ParticipantOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner of the session can manage participants"})
+		return nil, false
+	}
+	return session, true
+}
+
+// ListParticipants returns the users invited into a terminal session and the users connected to it.
+func (h *TerminalHandler) ListParticipants(c *gin.Context) {
+	user, ok := h.terminalUser(c)
+	if !ok {
+		return
+	}
+	session, ok := h.participantSession(c, user, false)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":      session.InfoFor(user.ID),
+		"participants": session.Participants(),
+		"presence":     session.Presence(),
+	})
+}
+
+// InviteParticipant lets another user join a terminal session of the current user as an observer or
+// driver, or changes the role of a participant.
+func (h *TerminalHandler) InviteParticipant(c *gin.Context) {
+	user, ok := h.terminalUser(c)
+	if !ok {
+		return
+	}
+	session, ok := h.participantSession(c, user, true)
+	if !ok {
+		return
+	}
+
+	var req InviteParticipantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitee, err := h.authService.GetUserByUsername(strings.TrimSpace(req.Username))
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Joining goes through the terminal WebSocket, which needs the operator role
+	if !invitee.HasPermission(models.RoleOperator) || (h.cfg.AdminOnly && !invitee.HasPermission(models.RoleAdmin)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: %s cannot use the terminal", services.ErrInvalidParticipant, invitee.Username)})
+		return
+	}
+
+	participant, err := session.Invite(invitee.ID, invitee.Username, req.Role)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidParticipant) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.logSession(c, user, "terminal_session_invite", session.ID, map[string]interface{}{
+		"participant": participant.Username,
+		"role":        participant.Role,
+	})
+
+	c.JSON(http.StatusOK, participant)
+}
+
+// KickParticipant removes a participant from a terminal session of the current user and disconnects them.
+func (h *TerminalHandler) KickParticipant(c *gin.Context) {
+	user, ok := h.terminalUser(c)
+	if !ok {
+		return
+	}
+	session, ok := h.participantSession(c, user, true)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var username string
+	for _, p := range session.Participants() {
+		if p.UserID == userID {
+			username = p.Username
+		}
+	}
+	if !session.Kick(userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+		return
+	}
+
+	h.logSession(c, user, "terminal_session_kick", session.ID, map[string]interface{}{
+		"participant": username,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "participant removed"})
+}
+
+// ListRecordings returns the terminal recordings of the current user, or of all users for admins.
+func (h *TerminalHandler) ListRecordings(c *gin.Context) {
+	user, ok := h.terminalUser(c)
+	if !ok {
+		return
+	}
+
+	var userID *int64
+	if !user.HasPermission(models.RoleAdmin) {
+		userID = &user.ID
+	}
+	recordings, err := h.recordings.ListRecordings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recordings": recordings, "enabled": h.recordings.Enabled()})
+}
+
+// GetRecording streams a terminal recording as an asciicast v2 file. Users other than admins may only
+// play back their own recordings.
+func (h *TerminalHandler) GetRecording(c *gin.Context) {
+	user, ok := h.terminalUser(c)
+	if !ok {
+		return
+	}
+
+	recording, err := h.recordings.GetRecording(c.Param("id"))
+	if err == nil && !user.HasPermission(models.RoleAdmin) && (recording.UserID == nil || *recording.UserID != user.ID) {
+		err = services.ErrRecordingNotFound
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrRecordingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.auditService != nil {
+		_ = h.auditService.Log(services.AuditLog{
+			UserID:       &user.ID,
+			Username:     user.AuditName(),
+			Action:       "terminal_recording_view",
+			ResourceType: "terminal",
+			ResourceID:   recording.SessionID,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.GetHeader("User-Agent"),
+			Details: map[string]interface{}{
+				"recording_id": recording.ID,
+			},
+		})
+	}
+
+	c.Header("Content-Type", "application/x-asciicast")
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s.cast", recording.ID))
+	c.File(recording.Path)
+}
+
 // WebSocketMessage represents a message from the WebSocket client.
 type WebSocketMessage struct {
 	Type      string `json:"type"`
 	SessionID string `json:"session_id,omitempty"`
 	Data      string `json:"data,omitempty"`
+	Cols      int    `json:"cols,omitempty"`
+	Rows      int    `json:"rows,omitempty"`
 }
 
-// HandleWebSocket handles WebSocket terminal connections with session support.
-func (h *TerminalHandler) HandleWebSocket(c *gin.Context) {
-	if !h.cfg.IsEnabled() {
-		c.JSON(403, gin.H{"error": "terminal is disabled"})
-		return
+// sessionDetails returns the audit log details of a new terminal session, which link to its recording.
+func sessionDetails(session *services.TerminalSession) map[string]interface{} {
+	details := map[string]interface{}{"shell": session.Shell}
+	if id := session.RecordingID(); id != "" {
+		details["recording_id"] = id
 	}
+	return details
+}
 
-	userObj, exists := c.Get(middleware.UserContextKey)
-	if !exists {
-		c.JSON(401, gin.H{"error": "unauthorized"})
-		return
+// handleMessage passes a message from the WebSocket client to the session. Text messages holding a
+// resize WebSocketMessage resize the terminal; all other messages are input, which is passed through
+// input if it is not nil.
+func handleMessage(session *services.TerminalSession, msgType int, msg []byte, input func([]byte) []byte) error {
+	if msgType == websocket.TextMessage && len(msg) > 0 && msg[0] == '{' {
+		var control WebSocketMessage
+		if json.Unmarshal(msg, &control) == nil && control.Type == "resize" {
+			if err := session.Resize(control.Cols, control.Rows); err != nil {
+				log.Printf("Session resize error: %v", err)
+			}
+			return nil
+		}
 	}
+	if input != nil {
+		msg = input(msg)
+	}
+	return session.Write(msg)
+}
+
+// terminalLine follows the line typed by a client of a terminal session, so that it can be checked
+// when it is entered. It cannot follow edits by the shell itself, such as history recall and
+// completion, so the check is best-effort.
+type terminalLine struct {
+	line []byte
+}
 
-	user, ok := userObj.(*models.User)
+// filter passes on input, replacing the Enter of each line that allowed rejects with Ctrl-U, which
+// discards the line in readline and in the line discipline of the PTY.
+func (t *terminalLine) filter(input []byte, allowed func(line string) bool) []byte {
+	out := make([]byte, 0, len(input))
+	for i := 0; i < len(input); i++ {
+		b := input[i]
+		switch {
+		case b == '\r' || b == '\n':
+			line := string(t.line)
+			t.line = t.line[:0]
+			if !allowed(line) {
+				out = append(out, 0x15)
+				continue
+			}
+		case b == 0x7f || b == 0x08:
+			if len(t.line) > 0 {
+				_, size := utf8.DecodeLastRune(t.line)
+				t.line = t.line[:len(t.line)-size]
+			}
+		case b == 0x03 || b == 0x04 || b == 0x15:
+			// Ctrl-C, Ctrl-D and Ctrl-U discard the line
+			t.line = t.line[:0]
+		case b == 0x1b:
+			// Escape sequences such as cursor keys and bracketed paste markers are passed on unread
+			end := escapeSequenceEnd(input, i)
+			out = append(out, input[i:end]...)
+			i = end - 1
+			continue
+		case b >= 0x20:
+			t.line = append(t.line, b)
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// escapeSequenceEnd returns the index after the escape sequence that starts at input[start].
+func escapeSequenceEnd(input []byte, start int) int {
+	i := start + 1
+	if i >= len(input) {
+		return i
+	}
+	switch input[i] {
+	case '[':
+		// CSI: parameters and intermediates up to a final byte in 0x40-0x7e
+		for i++; i < len(input); i++ {
+			if input[i] >= 0x40 && input[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return i
+	case 'O':
+		// SS3, as sent by cursor and function keys in application mode
+		return min(i+2, len(input))
+	default:
+		// Alt+key
+		return i + 1
+	}
+}
+
+// HandleWebSocket handles WebSocket terminal connections with session support.
+func (h *TerminalHandler) HandleWebSocket(c *gin.Context) {
+	user, ok := h.terminalUser(c)
 	if !ok {
-		c.JSON(500, gin.H{"error": "invalid user context"})
 		return
 	}
 
 	// Check for session_id query parameter (for persistent sessions)
 	sessionID := c.Query("session_id")
 	persistent := c.Query("persistent") == "true"
+	// Shell of a new session, one of the allowed shells
+	shell := c.Query("shell")
 
 	ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -251,28 +537,32 @@ func (h *TerminalHandler) HandleWebSocket(c *gin.Context) {
 	log.Printf("[Terminal] User %s connected (persistent: %v, session: %s)", user.Username, persistent, sessionID)
 
 	if persistent {
-		h.handlePersistentSession(ws, user, sessionID, c)
+		h.handlePersistentSession(ws, user, sessionID, shell, c)
 	} else {
-		h.handleEphemeralSession(ws, user, c)
+		h.handleEphemeralSession(ws, user, shell, c)
 	}
 }
 
-// handlePersistentSession handles a WebSocket connection to a persistent session.
-func (h *TerminalHandler) handlePersistentSession(ws *websocket.Conn, user *models.User, sessionID string, c *gin.Context) {
+// handlePersistentSession handles a WebSocket connection to a persistent session, which may be a session
+// of another user the user was invited into.
+func (h *TerminalHandler) handlePersistentSession(ws *websocket.Conn, user *models.User, sessionID, shell string, c *gin.Context) {
 	var session *services.TerminalSession
 	var ok bool
 
 	// If sessionID provided, try to attach to existing session
 	if sessionID != "" {
 		session, ok = h.sessionManager.GetSession(sessionID)
-		if !ok || session.UserID != user.ID {
+		if ok {
+			_, ok = session.RoleOf(user.ID)
+		}
+		if !ok {
 			_ = ws.WriteMessage(websocket.TextMessage, []byte("\r\n\x1b[1;31mSession not found or access denied\x1b[0m\r\n"))
 			return
 		}
 	} else {
 		// Create new persistent session
 		var err error
-		session, err = h.sessionManager.CreateSession(user.ID, user.Username)
+		session, err = h.sessionManager.CreateSession(user, shell)
 		if err != nil {
 			_ = ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("\r\n\x1b[1;31mFailed to create session: %s\x1b[0m\r\n", err.Error())))
 			return
@@ -282,12 +572,13 @@ func (h *TerminalHandler) handlePersistentSession(ws *websocket.Conn, user *mode
 		if h.auditService != nil {
 			_ = h.auditService.Log(services.AuditLog{
 				UserID:       &user.ID,
-				Username:     user.Username,
+				Username:     user.AuditName(),
 				Action:       "terminal_session_create",
 				ResourceType: "terminal",
 				ResourceID:   session.ID,
 				IPAddress:    c.ClientIP(),
 				UserAgent:    c.GetHeader("User-Agent"),
+				Details:      sessionDetails(session),
 			})
 		}
 	}
@@ -295,199 +586,244 @@ func (h *TerminalHandler) handlePersistentSession(ws *websocket.Conn, user *mode
 	// Send session info to client
 	sessionInfo, _ := json.Marshal(map[string]interface{}{
 		"type":    "session_info",
-		"session": session.Info(),
+		"session": session.InfoFor(user.ID),
 	})
 	_ = ws.WriteMessage(websocket.TextMessage, sessionInfo)
 
-	// Generate unique client ID
-	clientID := fmt.Sprintf("client-%d-%d", user.ID, time.Now().UnixNano())
+	// Participants other than the owner join and leave the session
+	role, _ := session.RoleOf(user.ID)
+	connectAction, disconnectAction := "terminal_connect", "terminal_disconnect"
+	var details map[string]interface{}
+	if role != services.ParticipantOwner {
+		connectAction, disconnectAction = "terminal_session_join", "terminal_session_leave"
+		details = map[string]interface{}{"owner": session.Username, "role": role}
+	}
+	h.logSession(c, user, connectAction, session.ID, details)
 
-	// Subscribe to session output
-	outputCh, history := session.Subscribe(clientID)
-	defer session.Unsubscribe(clientID)
+	sessionStart := time.Now()
+	h.serveClient(ws, session, user, fmt.Sprintf("client-%d-%d", user.ID, time.Now().UnixNano()), c)
 
-	// Send history (replay buffer)
-	if len(history) > 0 {
-		_ = ws.WriteMessage(websocket.BinaryMessage, history)
+	// Log disconnect
+	sessionDuration := time.Since(sessionStart)
+	if details == nil {
+		details = map[string]interface{}{}
 	}
+	details["duration_seconds"] = sessionDuration.Seconds()
+	details["session_active"] = !session.IsClosed()
+	h.logSession(c, user, disconnectAction, session.ID, details)
+
+	log.Printf("[Terminal] User %s disconnected from session %s (duration: %v, session still active: %v)",
+		user.Username, session.ID, sessionDuration, !session.IsClosed())
+}
+
+// handleEphemeralSession handles a traditional one-off terminal session.
+func (h *TerminalHandler) handleEphemeralSession(ws *websocket.Conn, user *models.User, shell string, c *gin.Context) {
+	log.Printf("[Terminal] User %s connected (ephemeral)", user.Username)
+
+	sessionStart := time.Now()
+
+	// Create ephemeral session using session manager
+	session, err := h.sessionManager.CreateEphemeralSession(user, shell)
+	if err != nil {
+		_ = ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("\r\n\x1b[1;31mFailed to start terminal: %s\x1b[0m\r\n", err.Error())))
+		return
+	}
+
+	// Mark for cleanup when WebSocket closes
+	defer func() { _ = h.sessionManager.CloseSession(session.ID) }()
 
-	// Log connection
 	if h.auditService != nil {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &user.ID,
-			Username:     user.Username,
+			Username:     user.AuditName(),
 			Action:       "terminal_connect",
 			ResourceType: "terminal",
-			ResourceID:   session.ID,
+			ResourceID:   "ephemeral",
 			IPAddress:    c.ClientIP(),
 			UserAgent:    c.GetHeader("User-Agent"),
+			Details:      sessionDetails(session),
 		})
 	}
 
-	sessionStart := time.Now()
-	done := make(chan struct{})
+	h.serveClient(ws, session, user, fmt.Sprintf("ephemeral-%d-%d", user.ID, time.Now().UnixNano()), c)
 
-	// Read from session and send to WebSocket
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case data, ok := <-outputCh:
-				if !ok {
-					close(done)
-					return
-				}
-				if err := ws.WriteMessage(websocket.BinaryMessage, data); err != nil {
-					log.Printf("WebSocket write error: %v", err)
-					return
-				}
-			}
-		}
-	}()
-
-	// Read from WebSocket and send to session
-	for {
-		select {
-		case <-done:
-			goto cleanup
-		default:
-			msgType, msg, err := ws.ReadMessage()
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("WebSocket read error: %v", err)
-				}
-				goto cleanup
-			}
-
-			if msgType == websocket.TextMessage || msgType == websocket.BinaryMessage {
-				if err := session.Write(msg); err != nil {
-					log.Printf("Session write error: %v", err)
-					goto cleanup
-				}
-			}
-		}
-	}
-
-cleanup:
-	close(done)
-
-	// Log disconnect
 	sessionDuration := time.Since(sessionStart)
 	if h.auditService != nil {
 		_ = h.auditService.Log(services.AuditLog{
 			UserID:       &user.ID,
-			Username:     user.Username,
+			Username:     user.AuditName(),
 			Action:       "terminal_disconnect",
 			ResourceType: "terminal",
-			ResourceID:   session.ID,
+			ResourceID:   "ephemeral",
 			IPAddress:    c.ClientIP(),
 			UserAgent:    c.GetHeader("User-Agent"),
 			Details: map[string]interface{}{
 				"duration_seconds": sessionDuration.Seconds(),
-				"session_active":   !session.IsClosed(),
 			},
 		})
 	}
 
-	log.Printf("[Terminal] User %s disconnected from session %s (duration: %v, session still active: %v)",
-		user.Username, session.ID, sessionDuration, !session.IsClosed())
+	log.Printf("[Terminal] User %s disconnected (ephemeral, duration: %v)", user.Username, sessionDuration)
 }
 
-// handleEphemeralSession handles a traditional one-off terminal session.
-func (h *TerminalHandler) handleEphemeralSession(ws *websocket.Conn, user *models.User, c *gin.Context) {
-	log.Printf("[Terminal] User %s connected (ephemeral)", user.Username)
+// serveClient relays between a WebSocket client of user and a session until either of them ends.
+// The client receives the output and presence changes of the session, and is disconnected when the
+// session closes, the user is removed from it, or the session has no activity for the idle timeout
+// of the user's own role, whatever the roles of the other clients. Input and resizes are only passed
+// on while the user may write to the session, and lines rejected by the command policy are discarded
+// instead of entered.
+func (h *TerminalHandler) serveClient(ws *websocket.Conn, session *services.TerminalSession, user *models.User, clientID string, c *gin.Context) {
+	outputCh, presenceCh, history := session.Subscribe(clientID, user.ID, user.Username)
+	defer session.Unsubscribe(clientID)
 
-	sessionStart := time.Now()
-	if h.auditService != nil {
-		_ = h.auditService.Log(services.AuditLog{
-			UserID:       &user.ID,
-			Username:     user.Username,
-			Action:       "terminal_connect",
+	// Violations are reported by the writer goroutine below
+	violations := make(chan string, 1)
+	var input func([]byte) []byte
+	if h.policyService != nil {
+		line := &terminalLine{}
+		// Lines may be passwords typed at a prompt, so they are kept out of the audit log
+		check := services.PolicyCheck{
+			User:         user,
+			Action:       "terminal_input",
 			ResourceType: "terminal",
-			ResourceID:   "ephemeral",
+			ResourceID:   session.ID,
 			IPAddress:    c.ClientIP(),
 			UserAgent:    c.GetHeader("User-Agent"),
-			Details: map[string]interface{}{
-				"shell": h.cfg.Shell,
-			},
-		})
+			Redact:       true,
+		}
+		input = func(msg []byte) []byte {
+			return line.filter(msg, func(command string) bool {
+				if strings.TrimSpace(command) == "" {
+					return true
+				}
+				_, err := h.policyService.Enforce(command, check)
+				if err == nil {
+					return true
+				}
+				if !errors.Is(err, services.ErrPolicyViolation) {
+					log.Printf("[Terminal] Failed to check input against the command policy: %v", err)
+				}
+				select {
+				case violations <- err.Error():
+				default:
+				}
+				return false
+			})
+		}
 	}
-
-	// Create ephemeral session using session manager
-	session, err := h.sessionManager.CreateSession(user.ID, user.Username)
-	if err != nil {
-		_ = ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("\r\n\x1b[1;31mFailed to start terminal: %s\x1b[0m\r\n", err.Error())))
-		return
+	// A nil channel never fires, so without an idle timeout clients stay connected. The idle time
+	// counts from the last activity of the session, or from the connection if that is later.
+	var idleTimer *time.Timer
+	var idleCh <-chan time.Time
+	connectedAt := time.Now()
+	idleTimeout := h.sessionManager.IdleTimeout(user.Role)
+	if idleTimeout > 0 {
+		idleTimer = time.NewTimer(idleTimeout)
+		defer idleTimer.Stop()
+		idleCh = idleTimer.C
+	}
+	disconnect := func(reason string) {
+		// Closing the connection ends the read loop below
+		_ = ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason), time.Now().Add(time.Second))
+		_ = ws.Close()
 	}
 
-	// Mark for cleanup when WebSocket closes
-	defer func() { _ = h.sessionManager.CloseSession(session.ID) }()
-
-	clientID := fmt.Sprintf("ephemeral-%d-%d", user.ID, time.Now().UnixNano())
-	outputCh, _ := session.Subscribe(clientID)
-	defer session.Unsubscribe(clientID)
+	// Send history (replay buffer)
+	if len(history) > 0 {
+		_ = ws.WriteMessage(websocket.BinaryMessage, history)
+	}
 
 	done := make(chan struct{})
+	defer close(done)
 
-	// Read from session and send to WebSocket
+	// Read from session and send to WebSocket; only this goroutine writes messages from here on
 	go func() {
 		for {
 			select {
 			case <-done:
 				return
+			case violation := <-violations:
+				message, _ := json.Marshal(map[string]interface{}{
+					"type":  "policy_violation",
+					"error": violation,
+				})
+				if err := ws.WriteMessage(websocket.TextMessage, message); err != nil {
+					return
+				}
+			case <-idleCh:
+				lastActivity := session.Info().LastActivity
+				if lastActivity.Before(connectedAt) {
+					lastActivity = connectedAt
+				}
+				idle := time.Since(lastActivity)
+				if idle >= idleTimeout {
+					disconnect("idle timeout")
+					return
+				}
+				idleTimer.Reset(idleTimeout - idle)
+			case <-presenceCh:
+				presence, _ := json.Marshal(map[string]interface{}{
+					"type":         "presence",
+					"participants": session.Presence(),
+				})
+				if err := ws.WriteMessage(websocket.TextMessage, presence); err != nil {
+					return
+				}
 			case data, ok := <-outputCh:
 				if !ok {
+					reason := "removed from session"
+					if session.IsClosed() {
+						reason = "session closed"
+					}
+					disconnect(reason)
 					return
 				}
 				if err := ws.WriteMessage(websocket.BinaryMessage, data); err != nil {
+					log.Printf("WebSocket write error: %v", err)
 					return
 				}
 			}
 		}
 	}()
 
-	// Read from WebSocket and write to session
+	// Read from WebSocket and send to session
 	for {
-		select {
-		case <-done:
-			goto cleanup
-		default:
-			msgType, msg, err := ws.ReadMessage()
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("WebSocket read error: %v", err)
-				}
-				goto cleanup
+		msgType, msg, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("WebSocket read error: %v", err)
 			}
+			return
+		}
+		if msgType != websocket.TextMessage && msgType != websocket.BinaryMessage {
+			continue
+		}
 
-			if msgType == websocket.TextMessage || msgType == websocket.BinaryMessage {
-				if err := session.Write(msg); err != nil {
-					log.Printf("Session write error: %v", err)
-					goto cleanup
-				}
-			}
+		// The role is checked for every message so that role changes apply at once
+		if role, ok := session.RoleOf(user.ID); !ok || !role.CanWrite() {
+			continue
+		}
+		if err := handleMessage(session, msgType, msg, input); err != nil {
+			log.Printf("Session write error: %v", err)
+			return
 		}
 	}
+}
 
-cleanup:
-	close(done)
-
-	sessionDuration := time.Since(sessionStart)
-	if h.auditService != nil {
-		_ = h.auditService.Log(services.AuditLog{
-			UserID:       &user.ID,
-			Username:     user.Username,
-			Action:       "terminal_disconnect",
-			ResourceType: "terminal",
-			ResourceID:   "ephemeral",
-			IPAddress:    c.ClientIP(),
-			UserAgent:    c.GetHeader("User-Agent"),
-			Details: map[string]interface{}{
-				"duration_seconds": sessionDuration.Seconds(),
-			},
-		})
+// logSession records an audit log entry for an action of user on a terminal session.
+func (h *TerminalHandler) logSession(c *gin.Context, user *models.User, action, sessionID string, details map[string]interface{}) {
+	if h.auditService == nil {
+		return
 	}
-
-	log.Printf("[Terminal] User %s disconnected (ephemeral, duration: %v)", user.Username, sessionDuration)
+	_ = h.auditService.Log(services.AuditLog{
+		UserID:       &user.ID,
+		Username:     user.AuditName(),
+		Action:       action,
+		ResourceType: "terminal",
+		ResourceID:   sessionID,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.GetHeader("User-Agent"),
+		Details:      details,
+	})
 }
@@ -1,10 +1,14 @@
 package handlers_test
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -46,7 +50,7 @@ func setupTerminalHandlerTest(t *testing.T) (*handlers.TerminalHandler, *service
 		Args:    []string{},
 	}
 	auditService := services.NewAuditService(db)
-	handler := handlers.NewTerminalHandler(cfg, auditService, []string{})
+	handler := handlers.NewTerminalHandler(cfg, nil, auditService, nil, nil, []string{})
 
 	router := gin.New()
 	router.Use(func(c *gin.Context) {
@@ -84,7 +88,7 @@ func TestTerminalHandler_DisabledTerminal(t *testing.T) {
 		Shell:   "/bin/sh",
 	}
 	auditService := services.NewAuditService(db)
-	handler := handlers.NewTerminalHandler(cfg, auditService, []string{})
+	handler := handlers.NewTerminalHandler(cfg, nil, auditService, nil, nil, []string{})
 
 	router := gin.New()
 	router.Use(func(c *gin.Context) {
@@ -129,7 +133,7 @@ func TestTerminalHandler_Unauthorized(t *testing.T) {
 		Shell:   "/bin/sh",
 	}
 	auditService := services.NewAuditService(db)
-	handler := handlers.NewTerminalHandler(cfg, auditService, []string{})
+	handler := handlers.NewTerminalHandler(cfg, nil, auditService, nil, nil, []string{})
 
 	// Router without user middleware
 	router := gin.New()
@@ -222,8 +226,486 @@ func TestTerminalHandler_NewTerminalHandler(t *testing.T) {
 	}
 	auditService := services.NewAuditService(db)
 
-	handler := handlers.NewTerminalHandler(cfg, auditService, []string{})
+	handler := handlers.NewTerminalHandler(cfg, nil, auditService, nil, nil, []string{})
 	if handler == nil {
 		t.Error("expected handler to be created")
 	}
 }
+
+func TestTerminalHandler_Recordings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	cfg := &config.TerminalConfig{Enabled: boolPtr(true), Shell: "/bin/sh"}
+	cfg.Recording = config.TerminalRecordingConfig{Enabled: true, Dir: t.TempDir()}
+	recordings := services.NewTerminalRecordingService(db, &cfg.Recording)
+	handler := handlers.NewTerminalHandler(cfg, nil, services.NewAuditService(db), recordings, nil, []string{})
+
+	own, _ := recordings.Start("term-1", 1, "alice", "/bin/sh", 80, 24)
+	own.Close()
+	other, _ := recordings.Start("term-2", 2, "bob", "/bin/sh", 80, 24)
+	other.Close()
+
+	request := func(user *models.User, path string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set(middleware.UserContextKey, user) })
+		router.GET("/api/terminal/recordings", handler.ListRecordings)
+		router.GET("/api/terminal/recordings/:id", handler.GetRecording)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	alice := &models.User{ID: 1, Username: "alice", Role: models.RoleOperator}
+	admin := &models.User{ID: 3, Username: "admin", Role: models.RoleAdmin}
+
+	if w := request(alice, "/api/terminal/recordings"); w.Code != http.StatusOK || strings.Count(w.Body.String(), `"session_id"`) != 1 {
+		t.Errorf("expected only the own recording to be listed, got %d %s", w.Code, w.Body.String())
+	}
+	if w := request(admin, "/api/terminal/recordings"); w.Code != http.StatusOK || strings.Count(w.Body.String(), `"session_id"`) != 2 {
+		t.Errorf("expected all recordings to be listed for admins, got %d %s", w.Code, w.Body.String())
+	}
+
+	w := request(alice, "/api/terminal/recordings/"+own.ID)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-asciicast" || !strings.HasPrefix(w.Body.String(), `{"version":2`) {
+		t.Errorf("expected the own recording to be streamed, got %d %q", w.Code, w.Body.String())
+	}
+	if w := request(alice, "/api/terminal/recordings/"+other.ID); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for the recording of another user, got %d", w.Code)
+	}
+	if w := request(admin, "/api/terminal/recordings/"+other.ID); w.Code != http.StatusOK {
+		t.Errorf("expected admins to play back any recording, got %d", w.Code)
+	}
+
+	// Recordings are closed like the rest of the terminal
+	cfg.AdminOnly = true
+	for _, path := range []string{"/api/terminal/recordings", "/api/terminal/recordings/" + own.ID} {
+		if w := request(alice, path); w.Code != http.StatusForbidden {
+			t.Errorf("expected %s to be denied to operators with admin_only, got %d", path, w.Code)
+		}
+	}
+	cfg.Enabled = boolPtr(false)
+	if w := request(admin, "/api/terminal/recordings/"+other.ID); w.Code != http.StatusForbidden {
+		t.Errorf("expected recordings to be denied with the terminal disabled, got %d", w.Code)
+	}
+}
+
+// readUntil reads messages from ws until one contains want, and returns it.
+func readUntil(t *testing.T, ws *websocket.Conn, want string) string {
+	t.Helper()
+
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("expected a message containing %q, got %v", want, err)
+		}
+		if strings.Contains(string(msg), want) {
+			return string(msg)
+		}
+	}
+}
+
+func TestTerminalHandler_SharedSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	users := map[string]*models.User{
+		"alice": {ID: 1, Username: "alice", Role: models.RoleOperator},
+		"bob":   {ID: 2, Username: "bob", Role: models.RoleOperator},
+		"carol": {ID: 3, Username: "carol", Role: models.RoleViewer},
+	}
+	for _, u := range users {
+		if _, err := db.Exec("INSERT INTO users (id, username, password_hash, role) VALUES (?, ?, 'x', ?)", u.ID, u.Username, u.Role); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	cfg := &config.TerminalConfig{Enabled: boolPtr(true), Shell: "/bin/sh"}
+	auditService := services.NewAuditService(db)
+	handler := handlers.NewTerminalHandler(cfg, services.NewAuthService(db, &config.Config{}, nil), auditService, nil, nil, []string{})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(middleware.UserContextKey, users[c.GetHeader("X-User")]) })
+	router.GET("/api/terminal/ws", handler.HandleWebSocket)
+	router.GET("/api/terminal/sessions", handler.ListSessions)
+	router.POST("/api/terminal/sessions", handler.CreateSession)
+	router.POST("/api/terminal/sessions/:session_id/participants", handler.InviteParticipant)
+	router.DELETE("/api/terminal/sessions/:session_id/participants/:user_id", handler.KickParticipant)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	request := func(user, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	connect := func(user, sessionID string) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/terminal/ws?persistent=true&session_id=" + sessionID
+		ws, _, err := websocket.DefaultDialer.Dial(url, http.Header{"X-User": []string{user}})
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		return ws
+	}
+
+	w := request("alice", http.MethodPost, "/api/terminal/sessions", "")
+	var created struct {
+		Session services.SessionInfo `json:"session"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("failed to create session: %d %s", w.Code, w.Body.String())
+	}
+	sessionID := created.Session.ID
+	participants := "/api/terminal/sessions/" + sessionID + "/participants"
+
+	// Uninvited users cannot join
+	ws := connect("bob", sessionID)
+	readUntil(t, ws, "access denied")
+	_ = ws.Close()
+
+	for _, tt := range []struct {
+		user, body string
+		want       int
+	}{
+		{"bob", `{"username": "alice", "role": "observer"}`, http.StatusNotFound},
+		{"alice", `{"username": "carol", "role": "observer"}`, http.StatusBadRequest},
+		{"alice", `{"username": "bob", "role": "owner"}`, http.StatusBadRequest},
+		{"alice", `{"username": "nobody", "role": "observer"}`, http.StatusNotFound},
+		{"alice", `{"username": "bob", "role": "observer"}`, http.StatusOK},
+	} {
+		if w := request(tt.user, http.MethodPost, participants, tt.body); w.Code != tt.want {
+			t.Errorf("expected %d for %s inviting %s, got %d %s", tt.want, tt.user, tt.body, w.Code, w.Body.String())
+		}
+	}
+	if w := request("bob", http.MethodGet, "/api/terminal/sessions", ""); !strings.Contains(w.Body.String(), `"role":"observer"`) {
+		t.Errorf("expected the shared session to be listed for bob, got %s", w.Body.String())
+	}
+
+	owner := connect("alice", sessionID)
+	defer func() { _ = owner.Close() }()
+	readUntil(t, owner, `"role":"owner"`)
+
+	observer := connect("bob", sessionID)
+	defer func() { _ = observer.Close() }()
+	readUntil(t, observer, `"role":"observer"`)
+	readUntil(t, owner, `"username":"bob"`)
+
+	// Input of observers is dropped
+	_ = observer.WriteMessage(websocket.TextMessage, []byte("echo observer-$((40+2))\n"))
+	_ = owner.WriteMessage(websocket.TextMessage, []byte("echo owner-$((40+2))\n"))
+	output := readUntil(t, observer, "owner-42")
+	if strings.Contains(output, "observer-42") {
+		t.Error("expected the input of the observer to be dropped")
+	}
+
+	if w := request("bob", http.MethodDelete, participants+"/2", ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected participants not to be able to kick, got %d", w.Code)
+	}
+	if w := request("alice", http.MethodDelete, participants+"/2", ""); w.Code != http.StatusOK {
+		t.Fatalf("failed to kick: %d %s", w.Code, w.Body.String())
+	}
+	_ = observer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := observer.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				t.Errorf("expected the observer to be disconnected, got %v", err)
+			}
+			break
+		}
+	}
+	if w := request("alice", http.MethodDelete, participants+"/2", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a removed participant, got %d", w.Code)
+	}
+
+	want := map[string]bool{"terminal_session_invite": false, "terminal_session_join": false, "terminal_session_kick": false, "terminal_session_leave": false}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		logs, _ := auditService.GetLogs(100, 0)
+		for _, log := range logs {
+			if _, ok := want[log.Action]; ok {
+				want[log.Action] = true
+			}
+		}
+		if want["terminal_session_leave"] {
+			break
+		}
+	}
+	for action, found := range want {
+		if !found {
+			t.Errorf("expected a %s audit log", action)
+		}
+	}
+}
+
+func TestTerminalHandler_Limits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	users := map[string]*models.User{
+		"root":  {ID: 1, Username: "root", Role: models.RoleAdmin},
+		"alice": {ID: 2, Username: "alice", Role: models.RoleOperator},
+	}
+	cfg := &config.TerminalConfig{
+		Enabled:       boolPtr(true),
+		Shell:         "/bin/sh",
+		AllowedShells: []string{"/bin/bash"},
+		Limits:        config.TerminalLimitsConfig{IdleTimeout: "1s"},
+		RoleLimits:    map[string]config.TerminalLimitsConfig{"admin": {MaxSessions: 2}},
+	}
+	handler := handlers.NewTerminalHandler(cfg, nil, nil, nil, nil, []string{})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(middleware.UserContextKey, users[c.GetHeader("X-User")]) })
+	router.GET("/api/terminal/ws", handler.HandleWebSocket)
+	router.GET("/api/terminal/sessions", handler.ListSessions)
+	router.POST("/api/terminal/sessions", handler.CreateSession)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	request := func(user, method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/terminal/sessions", strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("root", http.MethodPost, `{"shell": "/usr/bin/python3"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a shell that is not allowed to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+	if w := request("root", http.MethodPost, `{"shell": "/bin/bash"}`); w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"shell":"/bin/bash"`) {
+		t.Errorf("expected a bash session, got %d %s", w.Code, w.Body.String())
+	}
+
+	w := request("root", http.MethodGet, "")
+	var list struct {
+		Sessions []services.SessionInfo  `json:"sessions"`
+		Limits   services.TerminalLimits `json:"limits"`
+		Shells   []string                `json:"shells"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+	}
+	if len(list.Sessions) != 1 || list.Limits.MaxSessions != 2 || list.Limits.IdleTimeout != 1 || len(list.Shells) != 2 {
+		t.Errorf("unexpected sessions and limits %s", w.Body.String())
+	}
+
+	// Clients are disconnected once the session has been idle for the idle timeout
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/terminal/ws?persistent=true",
+		http.Header{"X-User": []string{"root"}})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer func() { _ = ws.Close() }()
+	_ = ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) || closeErr.Text != "idle timeout" {
+				t.Errorf("expected an idle timeout, got %v", err)
+			}
+			break
+		}
+	}
+
+	// With admin_only, only admins may use the terminal
+	cfg.AdminOnly = true
+	if w := request("alice", http.MethodGet, ""); w.Code != http.StatusForbidden {
+		t.Errorf("expected operators to be denied the terminal, got %d", w.Code)
+	}
+	if w := request("root", http.MethodGet, ""); w.Code != http.StatusOK {
+		t.Errorf("expected admins to use the terminal, got %d", w.Code)
+	}
+}
+
+func TestTerminalHandler_IdleTimeoutPerRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	users := map[string]*models.User{
+		"alice": {ID: 1, Username: "alice", Role: models.RoleOperator},
+		"root":  {ID: 2, Username: "root", Role: models.RoleAdmin},
+	}
+	for _, u := range users {
+		if _, err := db.Exec("INSERT INTO users (id, username, password_hash, role) VALUES (?, ?, 'x', ?)", u.ID, u.Username, u.Role); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+
+	// Only operators have an idle timeout, below a second to check that it is not rounded
+	cfg := &config.TerminalConfig{
+		Enabled:    boolPtr(true),
+		Shell:      "/bin/sh",
+		RoleLimits: map[string]config.TerminalLimitsConfig{"operator": {IdleTimeout: "300ms"}},
+	}
+	handler := handlers.NewTerminalHandler(cfg, services.NewAuthService(db, &config.Config{}, nil), nil, nil, nil, []string{})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(middleware.UserContextKey, users[c.GetHeader("X-User")]) })
+	router.GET("/api/terminal/ws", handler.HandleWebSocket)
+	router.POST("/api/terminal/sessions", handler.CreateSession)
+	router.POST("/api/terminal/sessions/:session_id/participants", handler.InviteParticipant)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	request := func(user, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	connect := func(user, sessionID string) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/terminal/ws?persistent=true&session_id=" + sessionID
+		ws, _, err := websocket.DefaultDialer.Dial(url, http.Header{"X-User": []string{user}})
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		return ws
+	}
+
+	w := request("alice", "/api/terminal/sessions", "")
+	var created struct {
+		Session services.SessionInfo `json:"session"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("failed to create session: %d %s", w.Code, w.Body.String())
+	}
+	sessionID := created.Session.ID
+	if w := request("alice", "/api/terminal/sessions/"+sessionID+"/participants", `{"username": "root", "role": "driver"}`); w.Code != http.StatusOK {
+		t.Fatalf("failed to invite: %d %s", w.Code, w.Body.String())
+	}
+
+	owner := connect("alice", sessionID)
+	defer func() { _ = owner.Close() }()
+	driver := connect("root", sessionID)
+	defer func() { _ = driver.Close() }()
+
+	// The owner is disconnected by the timeout of their role, the driver stays connected
+	_ = owner.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := owner.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) || closeErr.Text != "idle timeout" {
+				t.Errorf("expected an idle timeout of the owner, got %v", err)
+			}
+			break
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+	_ = driver.WriteMessage(websocket.TextMessage, []byte("echo still-$((40+2))\n"))
+	readUntil(t, driver, "still-42")
+}
+
+func TestTerminalHandler_Policy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, username, password_hash) VALUES (1, 'alice', 'hash')`); err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	auditService := services.NewAuditService(db)
+	policyService := services.NewPolicyService(db, auditService)
+	if _, err := policyService.CreateRule(&models.CreatePolicyRuleRequest{
+		Name: "no-touch", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "touch *",
+	}); err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+
+	cfg := &config.TerminalConfig{Enabled: boolPtr(true), Shell: "/bin/sh"}
+	handler := handlers.NewTerminalHandler(cfg, nil, auditService, nil, policyService, []string{})
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, &models.User{ID: 1, Username: "alice", Role: models.RoleOperator})
+	})
+	router.GET("/api/terminal/ws", handler.HandleWebSocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/terminal/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer func() { _ = ws.Close() }()
+	_ = ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	// A rejected line is discarded instead of entered, typed in two messages
+	denied := t.TempDir() + "/denied"
+	_ = ws.WriteMessage(websocket.TextMessage, []byte("touch "+denied))
+	_ = ws.WriteMessage(websocket.TextMessage, []byte("\r"))
+	for {
+		msgType, msg, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("expected a policy violation: %v", err)
+		}
+		if msgType == websocket.TextMessage && strings.Contains(string(msg), `"policy_violation"`) {
+			if !strings.Contains(string(msg), "no-touch") {
+				t.Errorf("expected the violation to name the rule, got %s", msg)
+			}
+			break
+		}
+	}
+
+	_ = ws.WriteMessage(websocket.TextMessage, []byte("echo done-$((1+1))\r"))
+	var output strings.Builder
+	for !strings.Contains(output.String(), "done-2") {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("expected the next line to run, got %q: %v", output.String(), err)
+		}
+		output.Write(msg)
+	}
+	if _, err := os.Stat(denied); !os.IsNotExist(err) {
+		t.Errorf("expected the rejected line not to run, got %v", err)
+	}
+
+	var details string
+	if err := db.QueryRow("SELECT details FROM audit_logs WHERE action = 'policy_violation' AND resource_type = 'terminal'").Scan(&details); err != nil {
+		t.Fatalf("expected an audit log entry: %v", err)
+	}
+	if !strings.Contains(details, `"action":"terminal_input"`) || !strings.Contains(details, `"rule":"no-touch"`) {
+		t.Errorf("unexpected audit details %s", details)
+	}
+	// The line itself may be a password typed at a prompt
+	if strings.Contains(details, denied) {
+		t.Errorf("expected the rejected line to be redacted, got %s", details)
+	}
+}
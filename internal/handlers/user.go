@@ -136,7 +136,7 @@ func (h *UserHandler) Create(c *gin.Context) {
 	// Log audit
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &currentUser.ID,
-		Username:     currentUser.Username,
+		Username:     currentUser.AuditName(),
 		Action:       "user_create",
 		ResourceType: "user",
 		ResourceID:   strconv.FormatInt(user.ID, 10),
@@ -223,7 +223,7 @@ func (h *UserHandler) Update(c *gin.Context) {
 	// Log audit
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &currentUser.ID,
-		Username:     currentUser.Username,
+		Username:     currentUser.AuditName(),
 		Action:       "user_update",
 		ResourceType: "user",
 		ResourceID:   strconv.FormatInt(id, 10),
@@ -285,7 +285,7 @@ func (h *UserHandler) UpdatePassword(c *gin.Context) {
 	// Log audit
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &currentUser.ID,
-		Username:     currentUser.Username,
+		Username:     currentUser.AuditName(),
 		Action:       "user_password_reset",
 		ResourceType: "user",
 		ResourceID:   strconv.FormatInt(id, 10),
@@ -362,7 +362,7 @@ func (h *UserHandler) Delete(c *gin.Context) {
 	// Log audit
 	_ = h.auditService.Log(services.AuditLog{
 		UserID:       &currentUser.ID,
-		Username:     currentUser.Username,
+		Username:     currentUser.AuditName(),
 		Action:       "user_delete",
 		ResourceType: "user",
 		ResourceID:   strconv.FormatInt(id, 10),
@@ -40,8 +40,8 @@ func (h *WebHandler) Dashboard(c *gin.Context) {
 		return
 	}
 
-	apps, _ := h.appService.GetAllApps()
-	executions, _ := h.executorService.GetExecutions(10, 0)
+	apps, _ := h.appService.GetAllApps(u)
+	executions, _ := h.executorService.ListExecutions(services.ExecutionFilter{Limit: 10, Member: u})
 
 	c.HTML(http.StatusOK, "dashboard.html", gin.H{
 		"PathPrefix": h.pathPrefix,
@@ -65,7 +65,7 @@ func (h *WebHandler) AppsPage(c *gin.Context) {
 		return
 	}
 
-	apps, _ := h.appService.GetAllApps()
+	apps, _ := h.appService.GetAllApps(u)
 
 	c.HTML(http.StatusOK, "apps.html", gin.H{
 		"PathPrefix": h.pathPrefix,
@@ -17,9 +17,23 @@ const (
 	UserContextKey = "user"
 )
 
-// AuthRequired is a middleware that requires authentication.
+// AuthRequired is a middleware that requires authentication, either with a session cookie
+// or with a personal API token in an "Authorization: Bearer" header.
 func AuthRequired(authService *services.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// A bearer token takes precedence over the session cookie, so that Authorize limits the request to its scopes
+		if token := bearerToken(c); token != "" {
+			user, err := authService.AuthenticateAPIToken(token, c.ClientIP())
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			c.Set(UserContextKey, user)
+			c.Next()
+			return
+		}
+
 		sessionID, err := c.Cookie(SessionCookieName)
 		if err != nil || sessionID == "" {
 			if isAPIRequest(c) {
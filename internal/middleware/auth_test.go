@@ -0,0 +1,100 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/middleware"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestAuthRequired_APIToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	authService := services.NewAuthService(db, &config.Config{Auth: config.AuthConfig{BcryptCost: 4}}, nil)
+	user, _ := authService.CreateUserWithRole("scripter", "password", models.RoleOperator)
+	scoped, _ := authService.CreateAPIToken(user.ID, &models.CreateAPITokenRequest{Name: "ci", Scopes: []models.APITokenScope{models.ScopeApps}})
+	full, _ := authService.CreateAPIToken(user.ID, &models.CreateAPITokenRequest{Name: "full"})
+
+	router := gin.New()
+	api := router.Group("/devops/api")
+	api.Use(middleware.CSRFProtection(middleware.NewCSRFStore(), "/devops", false))
+	protected := api.Group("")
+	protected.Use(middleware.AuthRequired(authService))
+	protected.Use(middleware.Authorize(protected.BasePath(), middleware.Permissions{
+		"GET /apps":                  {Role: models.RoleViewer, Scopes: []models.APITokenScope{models.ScopeRead, models.ScopeApps}},
+		"POST /apps":                 {Role: models.RoleOperator, Scopes: []models.APITokenScope{models.ScopeApps}},
+		"POST /commands/:id/execute": {Role: models.RoleOperator, Scopes: []models.APITokenScope{models.ScopeExecute}},
+		"GET /files":                 {Role: models.RoleViewer, Scopes: []models.APITokenScope{models.ScopeRead, models.ScopeFiles}},
+		"POST /files/save":           {Role: models.RoleOperator, Scopes: []models.APITokenScope{models.ScopeFiles}},
+		"GET /auth/tokens":           {Role: models.RoleViewer},
+	}, nil))
+	ok := func(c *gin.Context) {
+		u := c.MustGet(middleware.UserContextKey).(*models.User)
+		c.String(http.StatusOK, u.AuditName())
+	}
+	protected.GET("/apps", ok)
+	protected.POST("/apps", ok)
+	protected.POST("/commands/:id/execute", ok)
+	protected.GET("/files", ok)
+	protected.POST("/files/save", ok)
+	protected.GET("/auth/tokens", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"no credentials", "GET", "/devops/api/apps", "", http.StatusUnauthorized},
+		{"unknown token", "GET", "/devops/api/apps", "hrp_unknown", http.StatusUnauthorized},
+		{"read in scoped area", "GET", "/devops/api/apps", scoped.Token, http.StatusOK},
+		{"write in scoped area skips CSRF", "POST", "/devops/api/apps", scoped.Token, http.StatusOK},
+		{"execute needs its own scope", "POST", "/devops/api/commands/1/execute", scoped.Token, http.StatusForbidden},
+		{"read outside scopes", "GET", "/devops/api/files", scoped.Token, http.StatusForbidden},
+		{"token without scopes has the full role", "POST", "/devops/api/files/save", full.Token, http.StatusOK},
+		{"token management needs a session", "GET", "/devops/api/auth/tokens", full.Token, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusOK && w.Body.String() == "scripter" {
+				t.Errorf("expected the audit name to name the token")
+			}
+		})
+	}
+
+	// Without a bearer token, state-changing requests still need a CSRF token
+	req := httptest.NewRequest("POST", "/devops/api/apps", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected CSRF protection without a bearer token, got %d", w.Code)
+	}
+}
@@ -111,6 +111,13 @@ func CSRFProtection(store *CSRFStore, pathPrefix string, secureCookie bool) gin.
 			return
 		}
 
+		// Skip CSRF for requests with an API token: browsers never send it on their own,
+		// and AuthRequired then ignores the session cookie
+		if bearerToken(c) != "" {
+			c.Next()
+			return
+		}
+
 		// Skip CSRF for login endpoint (no session yet)
 		if contains(c.Request.URL.Path, "/auth/login") {
 			c.Next()
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// Permission is the access a route requires: a minimum role and, for requests authenticated with an
+// API token, one of Scopes. A route without Scopes cannot be used with an API token.
+// A route with a Resource acts on the app of the resource identified by its ":id" parameter, and for
+// users other than admins the role is required on that app instead of as the user's own role, so
+// that app members can have more or fewer rights on an app than elsewhere.
+type Permission struct {
+	Role     models.UserRole
+	Resource models.AppResource
+	Scopes   []models.APITokenScope
+}
+
+// On returns the permission for a route that acts on the app of resource.
+func (p Permission) On(resource models.AppResource) Permission {
+	p.Resource = resource
+	return p
+}
+
+// Permissions maps routes, as "METHOD /path" relative to the group of the routes, to their permission.
+type Permissions map[string]Permission
+
+// Authorize is a middleware that checks the permission of the matched route against the authenticated
+// user, API token and app membership. basePath is the path of the route group, which keys in permissions
+// are relative to. Routes without a permission are denied. It must run after AuthRequired.
+func Authorize(basePath string, permissions Permissions, appService *services.AppService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userObj, exists := c.Get(UserContextKey)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+		user, ok := userObj.(*models.User)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user context"})
+			c.Abort()
+			return
+		}
+
+		permission, ok := permissions[c.Request.Method+" "+strings.TrimPrefix(c.FullPath(), basePath)]
+		if !ok || (permission.Resource == "" && !user.HasPermission(permission.Role)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			c.Abort()
+			return
+		}
+
+		if user.APIToken != nil && !allowAPIToken(c, user.APIToken, permission.Scopes) {
+			return
+		}
+
+		if permission.Resource != "" && !allowApp(c, appService, user, permission) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allowApp reports whether user has the role of a permission on the app of its resource. Users other
+// than admins need the role as a member of the app, whatever their own role.
+// Users that are not members of the app get the same response as for a resource that does not exist.
+// On failure it writes the error response and aborts.
+func allowApp(c *gin.Context, appService *services.AppService, user *models.User, permission Permission) bool {
+	if user.HasPermission(models.RoleAdmin) {
+		return true
+	}
+
+	appID, err := appService.ResourceAppID(permission.Resource, c.Param("id"))
+	if err == services.ErrAppNotFound && user.HasPermission(permission.Role) {
+		// The handler reports the missing resource
+		return true
+	}
+	var role models.UserRole
+	if err == nil {
+		role, err = appService.AppRole(user, appID)
+	}
+
+	switch {
+	case err == services.ErrNotAppMember || err == services.ErrAppNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": string(permission.Resource) + " not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	case !role.Includes(permission.Role):
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+	default:
+		return true
+	}
+	c.Abort()
+	return false
+}
+
+// allowAPIToken reports whether an API token has one of the scopes of a route.
+// On failure it writes the error response and aborts.
+func allowAPIToken(c *gin.Context, token *models.APIToken, scopes []models.APITokenScope) bool {
+	if len(scopes) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint is not available with an API token"})
+		c.Abort()
+		return false
+	}
+	for _, scope := range scopes {
+		if token.Allows(scope) {
+			return true
+		}
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "token does not have the " + string(scopes[len(scopes)-1]) + " scope"})
+	c.Abort()
+	return false
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// APITokenScope is an area of the API that a personal API token may use.
+type APITokenScope string
+
+const (
+	// ScopeRead allows GET requests, except for interactive terminals.
+	ScopeRead APITokenScope = "read"
+	// ScopeApps allows managing apps, commands, environment variables, notifications and schedules.
+	ScopeApps APITokenScope = "apps"
+	// ScopeExecute allows running, re-running, cancelling and reviewing executions.
+	ScopeExecute APITokenScope = "execute"
+	// ScopeFiles allows changing files through the file browser.
+	ScopeFiles APITokenScope = "files"
+	// ScopeContainers allows container actions, including exec.
+	ScopeContainers APITokenScope = "containers"
+	// ScopeTerminal allows opening and managing terminal sessions.
+	ScopeTerminal APITokenScope = "terminal"
+	// ScopeAdmin allows user, backup, metrics and system administration.
+	ScopeAdmin APITokenScope = "admin"
+)
+
+// AllAPITokenScopes lists every API token scope.
+var AllAPITokenScopes = []APITokenScope{ScopeRead, ScopeApps, ScopeExecute, ScopeFiles, ScopeContainers, ScopeTerminal, ScopeAdmin}
+
+// IsValid reports whether s is a known API token scope.
+func (s APITokenScope) IsValid() bool {
+	for _, scope := range AllAPITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a personal access token that authenticates its owner on the API with a bearer header.
+// Requests made with it are limited by the owner's role and, if Scopes is not empty, by its scopes.
+// Only a hash of the token is stored; Token holds the plaintext only in the response that creates it.
+type APIToken struct {
+	CreatedAt  time.Time       `json:"created_at"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	LastUsedAt *time.Time      `json:"last_used_at,omitempty"`
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Prefix     string          `json:"prefix"`
+	Token      string          `json:"token,omitempty"`
+	LastUsedIP string          `json:"last_used_ip,omitempty"`
+	Scopes     []APITokenScope `json:"scopes"`
+	UserID     int64           `json:"user_id"`
+}
+
+// Allows reports whether the token may be used for scope. A token without scopes has its owner's full role.
+func (t *APIToken) Allows(scope APITokenScope) bool {
+	if len(t.Scopes) == 0 {
+		return true
+	}
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPITokenRequest contains the data for creating a personal API token.
+// Without Scopes the token has the owner's full role. ExpiresAt defaults to 90 days from now.
+type CreateAPITokenRequest struct {
+	ExpiresAt *time.Time      `json:"expires_at"`
+	Name      string          `json:"name" binding:"required"`
+	Scopes    []APITokenScope `json:"scopes"`
+}
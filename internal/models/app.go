@@ -3,28 +3,99 @@ package models
 
 import "time"
 
+// ConcurrencyPolicy controls what happens when an app is triggered while one of its executions is still active.
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyQueue runs executions one after another in the order they were triggered.
+	ConcurrencyQueue ConcurrencyPolicy = "queue"
+	// ConcurrencyReject refuses new executions while another one is active.
+	ConcurrencyReject ConcurrencyPolicy = "reject"
+	// ConcurrencyCancelPrevious cancels active executions in favour of the new one.
+	ConcurrencyCancelPrevious ConcurrencyPolicy = "cancel-previous"
+)
+
+// IsValid reports whether p is a known concurrency policy.
+func (p ConcurrencyPolicy) IsValid() bool {
+	return p == ConcurrencyQueue || p == ConcurrencyReject || p == ConcurrencyCancelPrevious
+}
+
+// ResourceLimits caps the resources available to the processes of an execution. Zero means unlimited.
+type ResourceLimits struct {
+	MaxMemoryMB   int `json:"max_memory_mb,omitempty"`
+	MaxCPUSeconds int `json:"max_cpu_seconds,omitempty"`
+	MaxOpenFiles  int `json:"max_open_files,omitempty"`
+	MaxProcesses  int `json:"max_processes,omitempty"`
+}
+
+// IsZero reports whether no limit is set.
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
+}
+
 // App represents an application with its configuration.
+// With RequiresApproval, executions of every command wait until another operator or admin approves them.
+// Token holds the plaintext of the app's default deploy token, only in the responses that create it.
 type App struct {
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	WorkingDir   string    `json:"working_dir"`
-	Token        string    `json:"token,omitempty"`
-	CommandCount int       `json:"command_count,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	Description       string            `json:"description"`
+	WorkingDir        string            `json:"working_dir"`
+	Token             string            `json:"token,omitempty"`
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy"`
+	RunAsUser         string            `json:"run_as_user,omitempty"`
+	RunAsGroup        string            `json:"run_as_group,omitempty"`
+	ResourceLimits    ResourceLimits    `json:"resource_limits"`
+	GitRepoURL        string            `json:"git_repo_url,omitempty"`
+	GitRef            string            `json:"git_ref,omitempty"`
+	HasDeployKey      bool              `json:"has_deploy_key"`
+	WebhookBranches   []string          `json:"webhook_branches,omitempty"`
+	WebhookTags       []string          `json:"webhook_tags,omitempty"`
+	HasWebhookSecret  bool              `json:"has_webhook_secret"`
+	RequiresApproval  bool              `json:"requires_approval"`
+	CommandCount      int               `json:"command_count,omitempty"`
+}
+
+// HasGitSource reports whether the working directory is synced from a git repository before each execution.
+func (a *App) HasGitSource() bool {
+	return a.GitRepoURL != ""
 }
 
 // CreateAppRequest contains the data for creating a new application.
 type CreateAppRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	WorkingDir  string `json:"working_dir" binding:"required"`
+	Name              string            `json:"name" binding:"required"`
+	Description       string            `json:"description"`
+	WorkingDir        string            `json:"working_dir" binding:"required"`
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy" binding:"omitempty,oneof=queue reject cancel-previous"`
+	RunAsUser         string            `json:"run_as_user"`
+	RunAsGroup        string            `json:"run_as_group"`
+	ResourceLimits    ResourceLimits    `json:"resource_limits"`
+	GitRepoURL        string            `json:"git_repo_url"`
+	GitRef            string            `json:"git_ref"`
+	GitDeployKey      string            `json:"git_deploy_key"`
+	WebhookSecret     string            `json:"webhook_secret"`
+	WebhookBranches   []string          `json:"webhook_branches"`
+	WebhookTags       []string          `json:"webhook_tags"`
+	RequiresApproval  bool              `json:"requires_approval"`
 }
 
 // UpdateAppRequest contains the data for updating an existing application.
+// A nil pointer or slice field keeps the current value; an empty string or slice clears it.
 type UpdateAppRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	WorkingDir  string `json:"working_dir"`
+	Name              string            `json:"name"`
+	Description       string            `json:"description"`
+	WorkingDir        string            `json:"working_dir"`
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy" binding:"omitempty,oneof=queue reject cancel-previous"`
+	RunAsUser         *string           `json:"run_as_user"`
+	RunAsGroup        *string           `json:"run_as_group"`
+	ResourceLimits    *ResourceLimits   `json:"resource_limits"`
+	GitRepoURL        *string           `json:"git_repo_url"`
+	GitRef            *string           `json:"git_ref"`
+	GitDeployKey      *string           `json:"git_deploy_key"`
+	WebhookSecret     *string           `json:"webhook_secret"`
+	WebhookBranches   []string          `json:"webhook_branches"`
+	WebhookTags       []string          `json:"webhook_tags"`
+	RequiresApproval  *bool             `json:"requires_approval"`
 }
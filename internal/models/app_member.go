@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Group is a named set of users that can be given access to apps together.
+type Group struct {
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	UserIDs   []int64   `json:"user_ids"`
+	ID        int64     `json:"id"`
+}
+
+// GroupRequest contains the data for creating or updating a group. UserIDs replaces the members of the group.
+type GroupRequest struct {
+	Name    string  `json:"name" binding:"required"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+// AppMember gives a user, or every user of a group, a role on one app. Users other than admins
+// only see and act on the apps they are members of, with the highest role of their memberships,
+// which may be above or below their own role. Outside of the app their own role applies.
+type AppMember struct {
+	CreatedAt time.Time `json:"created_at"`
+	UserID    *int64    `json:"user_id,omitempty"`
+	GroupID   *int64    `json:"group_id,omitempty"`
+	ID        string    `json:"id"`
+	AppID     string    `json:"app_id"`
+	Name      string    `json:"name"` // Username or group name
+	Role      UserRole  `json:"role"`
+}
+
+// AddAppMemberRequest contains the data for adding a user or a group to an app.
+// Adding an existing member changes its role.
+type AddAppMemberRequest struct {
+	UserID  *int64   `json:"user_id"`
+	GroupID *int64   `json:"group_id"`
+	Role    UserRole `json:"role" binding:"required"`
+}
+
+// AppResource is a kind of resource that belongs to an app, used to find the app of a request.
+type AppResource string
+
+// App resource constants.
+const (
+	ResourceApp       AppResource = "app"
+	ResourceCommand   AppResource = "command"
+	ResourceExecution AppResource = "execution"
+	ResourceSchedule  AppResource = "schedule"
+)
@@ -2,18 +2,47 @@ package models
 
 // AppBackup represents an app with its commands for backup/export
 type AppBackup struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	WorkingDir  string          `json:"working_dir"`
-	Commands    []CommandBackup `json:"commands"`
+	Name              string            `json:"name"`
+	Description       string            `json:"description"`
+	WorkingDir        string            `json:"working_dir"`
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrency_policy,omitempty"`
+	RunAsUser         string            `json:"run_as_user,omitempty"`
+	RunAsGroup        string            `json:"run_as_group,omitempty"`
+	ResourceLimits    ResourceLimits    `json:"resource_limits"`
+	GitRepoURL        string            `json:"git_repo_url,omitempty"`
+	GitRef            string            `json:"git_ref,omitempty"`
+	WebhookBranches   []string          `json:"webhook_branches,omitempty"`
+	WebhookTags       []string          `json:"webhook_tags,omitempty"`
+	RequiresApproval  bool              `json:"requires_approval,omitempty"`
+	Commands          []CommandBackup   `json:"commands"`
 }
 
 // CommandBackup represents a command for backup/export (without IDs)
 type CommandBackup struct {
-	Name           string `json:"name"`
-	Description    string `json:"description"`
-	Command        string `json:"command"`
-	TimeoutSeconds int    `json:"timeout_seconds"`
+	Name             string               `json:"name"`
+	Description      string               `json:"description"`
+	Command          string               `json:"command"`
+	Steps            []PipelineStepBackup `json:"steps,omitempty"`
+	Parameters       []CommandParameter   `json:"parameters,omitempty"`
+	TimeoutSeconds   int                  `json:"timeout_seconds"`
+	RerunOnInterrupt bool                 `json:"rerun_on_interrupt,omitempty"`
+	RequiresApproval bool                 `json:"requires_approval,omitempty"`
+	Schedules        []ScheduleBackup     `json:"schedules,omitempty"`
+}
+
+// PipelineStepBackup represents a pipeline step for backup/export, referencing the step command by name
+type PipelineStepBackup struct {
+	CommandName     string `json:"command_name"`
+	ContinueOnError bool   `json:"continue_on_error"`
+}
+
+// ScheduleBackup represents a command schedule for backup/export
+type ScheduleBackup struct {
+	Cron          string            `json:"cron"`
+	Timezone      string            `json:"timezone"`
+	OverlapPolicy OverlapPolicy     `json:"overlap_policy"`
+	Parameters    map[string]string `json:"parameters,omitempty"`
+	Enabled       bool              `json:"enabled"`
 }
 
 // BackupData represents the full backup structure
@@ -3,31 +3,105 @@ package models
 import "time"
 
 // Command represents a command that can be executed for an application.
+// A command with Steps is a pipeline: its steps run in order as one execution.
+// RerunOnInterrupt starts a new execution when one is interrupted by a server restart, and
+// RequiresApproval holds executions until another user approves them.
 type Command struct {
-	CreatedAt      time.Time `json:"created_at"`
-	ID             string    `json:"id"`
-	AppID          string    `json:"app_id"`
-	Name           string    `json:"name"`
-	Description    string    `json:"description"`
-	Command        string    `json:"command"`
-	TimeoutSeconds int       `json:"timeout_seconds"`
-	SortOrder      int       `json:"sort_order"`
+	CreatedAt        time.Time          `json:"created_at"`
+	ID               string             `json:"id"`
+	AppID            string             `json:"app_id"`
+	Name             string             `json:"name"`
+	Description      string             `json:"description"`
+	Command          string             `json:"command"`
+	Steps            []PipelineStep     `json:"steps,omitempty"`
+	Parameters       []CommandParameter `json:"parameters,omitempty"`
+	TimeoutSeconds   int                `json:"timeout_seconds"`
+	SortOrder        int                `json:"sort_order"`
+	RerunOnInterrupt bool               `json:"rerun_on_interrupt"`
+	RequiresApproval bool               `json:"requires_approval"`
+}
+
+// IsPipeline returns true if the command runs a list of steps instead of a shell command.
+func (c *Command) IsPipeline() bool {
+	return len(c.Steps) > 0
+}
+
+// ParameterType is the type of a command parameter.
+type ParameterType string
+
+const (
+	// ParameterString accepts any text, optionally restricted by a regex pattern.
+	ParameterString ParameterType = "string"
+	// ParameterEnum accepts one of a fixed list of options.
+	ParameterEnum ParameterType = "enum"
+	// ParameterBoolean accepts "true" or "false".
+	ParameterBoolean ParameterType = "boolean"
+)
+
+// CommandParameter declares a typed input supplied when a command is triggered.
+// Values reach the process as PARAM_<NAME> environment variables, never through the shell string.
+type CommandParameter struct {
+	Name        string        `json:"name"`
+	Type        ParameterType `json:"type"`
+	Description string        `json:"description,omitempty"`
+	Default     string        `json:"default,omitempty"`
+	Pattern     string        `json:"pattern,omitempty"`
+	Options     []string      `json:"options,omitempty"`
+	Required    bool          `json:"required,omitempty"`
+}
+
+// PipelineStep represents a single step of a pipeline command.
+type PipelineStep struct {
+	ID              string `json:"id"`
+	CommandID       string `json:"command_id"`
+	CommandName     string `json:"command_name"`
+	StepOrder       int    `json:"step_order"`
+	ContinueOnError bool   `json:"continue_on_error"`
+}
+
+// PipelineStepRequest contains the data for a pipeline step in create/update requests.
+type PipelineStepRequest struct {
+	CommandID       string `json:"command_id" binding:"required"`
+	ContinueOnError bool   `json:"continue_on_error"`
 }
 
 // CreateCommandRequest contains the data for creating a new command.
+// Either Command or Steps must be set.
 type CreateCommandRequest struct {
-	Name           string `json:"name" binding:"required"`
-	Description    string `json:"description"`
-	Command        string `json:"command" binding:"required"`
-	TimeoutSeconds int    `json:"timeout_seconds"`
+	Name             string                `json:"name" binding:"required"`
+	Description      string                `json:"description"`
+	Command          string                `json:"command"`
+	Steps            []PipelineStepRequest `json:"steps"`
+	Parameters       []CommandParameter    `json:"parameters"`
+	TimeoutSeconds   int                   `json:"timeout_seconds"`
+	RerunOnInterrupt bool                  `json:"rerun_on_interrupt"`
+	RequiresApproval bool                  `json:"requires_approval"`
 }
 
 // UpdateCommandRequest contains the data for updating an existing command.
+// A non-nil Steps or Parameters replaces the pipeline steps or parameters of the command.
 type UpdateCommandRequest struct {
-	Name           string `json:"name"`
-	Description    string `json:"description"`
-	Command        string `json:"command"`
-	TimeoutSeconds int    `json:"timeout_seconds"`
+	Name             string                `json:"name"`
+	Description      string                `json:"description"`
+	Command          string                `json:"command"`
+	Steps            []PipelineStepRequest `json:"steps"`
+	Parameters       []CommandParameter    `json:"parameters"`
+	TimeoutSeconds   int                   `json:"timeout_seconds"`
+	RerunOnInterrupt *bool                 `json:"rerun_on_interrupt"`
+	RequiresApproval *bool                 `json:"requires_approval"`
+}
+
+// ReviewExecutionRequest contains the optional comment for approving or rejecting an execution.
+type ReviewExecutionRequest struct {
+	Comment string `json:"comment"`
+}
+
+// ExecuteCommandRequest contains the optional parameter values for executing a command.
+// Ref and CommitSHA select what to check out for apps with a git source.
+type ExecuteCommandRequest struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Ref        string                 `json:"ref"`
+	CommitSHA  string                 `json:"commit_sha"`
 }
 
 // ReorderCommandsRequest contains the data for reordering commands.
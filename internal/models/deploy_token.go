@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// DeployTokenScope is an action that a deploy token may perform on its app.
+type DeployTokenScope string
+
+const (
+	// ScopeDeploy allows triggering deployments.
+	ScopeDeploy DeployTokenScope = "deploy"
+	// ScopeStatus allows reading the status and output of deployments.
+	ScopeStatus DeployTokenScope = "status"
+	// ScopeCancel allows cancelling deployments.
+	ScopeCancel DeployTokenScope = "cancel"
+)
+
+// AllDeployTokenScopes lists every deploy token scope.
+var AllDeployTokenScopes = []DeployTokenScope{ScopeDeploy, ScopeStatus, ScopeCancel}
+
+// IsValid reports whether s is a known deploy token scope.
+func (s DeployTokenScope) IsValid() bool {
+	return s == ScopeDeploy || s == ScopeStatus || s == ScopeCancel
+}
+
+// DeployToken authenticates API deployments of one app. Only a hash of the token is stored;
+// Token holds the plaintext only in the response that creates it, and Prefix identifies it afterwards.
+// CommandIDs restricts which commands the token may deploy; empty allows all commands of the app.
+type DeployToken struct {
+	CreatedAt  time.Time          `json:"created_at"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty"`
+	ID         string             `json:"id"`
+	AppID      string             `json:"app_id"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	Token      string             `json:"token,omitempty"`
+	LastUsedIP string             `json:"last_used_ip,omitempty"`
+	CreatedBy  string             `json:"created_by,omitempty"`
+	Scopes     []DeployTokenScope `json:"scopes"`
+	CommandIDs []string           `json:"command_ids,omitempty"`
+}
+
+// HasScope reports whether the token grants scope.
+func (t *DeployToken) HasScope(scope DeployTokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsCommand reports whether the token may deploy the command.
+func (t *DeployToken) AllowsCommand(commandID string) bool {
+	if len(t.CommandIDs) == 0 {
+		return true
+	}
+	for _, id := range t.CommandIDs {
+		if id == commandID {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateDeployTokenRequest contains the data for creating a deploy token.
+// Scopes default to all scopes. A token without ExpiresAt does not expire.
+type CreateDeployTokenRequest struct {
+	ExpiresAt  *time.Time         `json:"expires_at"`
+	Name       string             `json:"name" binding:"required"`
+	Scopes     []DeployTokenScope `json:"scopes"`
+	CommandIDs []string           `json:"command_ids"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// EnvVar is an environment variable injected into the executions of an app.
+// Variables without a CommandID apply to every command of the app; command variables override them.
+// The value of a secret is stored encrypted and never returned by the API.
+type EnvVar struct {
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ID        string    `json:"id"`
+	AppID     string    `json:"app_id"`
+	CommandID string    `json:"command_id,omitempty"`
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
+	IsSecret  bool      `json:"is_secret"`
+}
+
+// CreateEnvVarRequest contains the data for creating an environment variable.
+type CreateEnvVarRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Value     string `json:"value"`
+	CommandID string `json:"command_id"`
+	IsSecret  bool   `json:"is_secret"`
+}
+
+// UpdateEnvVarRequest contains the data for updating an environment variable.
+// A nil Value keeps the current value, so secrets can be updated without resending them.
+type UpdateEnvVarRequest struct {
+	Value    *string `json:"value"`
+	IsSecret *bool   `json:"is_secret"`
+}
@@ -14,19 +14,79 @@ const (
 	StatusSuccess ExecutionStatus = "success"
 	// StatusFailed indicates the execution failed.
 	StatusFailed ExecutionStatus = "failed"
+	// StatusSkipped indicates a pipeline step was not run because an earlier step failed.
+	StatusSkipped ExecutionStatus = "skipped"
+	// StatusCancelled indicates the execution was stopped by a user before it finished.
+	StatusCancelled ExecutionStatus = "cancelled"
+	// StatusInterrupted indicates the server stopped while the execution was pending or running.
+	StatusInterrupted ExecutionStatus = "interrupted"
+	// StatusAwaitingApproval indicates the execution waits for another user to approve it before it is queued.
+	StatusAwaitingApproval ExecutionStatus = "awaiting_approval"
+	// StatusRejected indicates the execution was rejected, or its approval expired, so it never ran.
+	StatusRejected ExecutionStatus = "rejected"
 )
 
+// IsFinished reports whether the status is final and the execution will not produce more output.
+func (s ExecutionStatus) IsFinished() bool {
+	return s == StatusSuccess || s == StatusFailed || s == StatusCancelled || s == StatusInterrupted || s == StatusRejected
+}
+
+// IsValid reports whether the status is a known execution status.
+func (s ExecutionStatus) IsValid() bool {
+	switch s {
+	case StatusPending, StatusRunning, StatusSuccess, StatusFailed, StatusSkipped, StatusCancelled,
+		StatusInterrupted, StatusAwaitingApproval, StatusRejected:
+		return true
+	}
+	return false
+}
+
+// OutputStream identifies where a line of execution output came from.
+type OutputStream string
+
+const (
+	// StreamStdout is output written by the command to standard output.
+	StreamStdout OutputStream = "stdout"
+	// StreamStderr is output written by the command to standard error.
+	StreamStderr OutputStream = "stderr"
+	// StreamSystem is output written by the server itself, such as git sync progress and pipeline step headers.
+	StreamSystem OutputStream = "system"
+)
+
+// OutputChunk is one line of execution output. Seq increases by one per line within an execution and
+// ElapsedMs is measured on the monotonic clock from the start of the execution.
+type OutputChunk struct {
+	Timestamp time.Time    `json:"timestamp"`
+	Stream    OutputStream `json:"stream"`
+	Line      string       `json:"line"`
+	Seq       int64        `json:"seq"`
+	ElapsedMs int64        `json:"elapsed_ms"`
+}
+
 // Execution represents a command execution instance.
+// ReviewedBy, ReviewedAt and ReviewComment record the approval or rejection of executions that required approval.
+// RerunOf is the ID of the execution that a re-run or rollback repeats.
 type Execution struct {
-	CreatedAt  time.Time       `json:"created_at"`
-	ExitCode   *int            `json:"exit_code"`
-	StartedAt  *time.Time      `json:"started_at"`
-	FinishedAt *time.Time      `json:"finished_at"`
-	ID         string          `json:"id"`
-	CommandID  string          `json:"command_id"`
-	Status     ExecutionStatus `json:"status"`
-	Output     string          `json:"output"`
-	UserID     int64           `json:"user_id"`
+	CreatedAt         time.Time         `json:"created_at"`
+	ExitCode          *int              `json:"exit_code"`
+	StartedAt         *time.Time        `json:"started_at"`
+	FinishedAt        *time.Time        `json:"finished_at"`
+	ReviewedAt        *time.Time        `json:"reviewed_at,omitempty"`
+	ApprovalExpiresAt *time.Time        `json:"approval_expires_at,omitempty"`
+	ID                string            `json:"id"`
+	CommandID         string            `json:"command_id"`
+	Status            ExecutionStatus   `json:"status"`
+	Output            string            `json:"output"`
+	CancelledBy       string            `json:"cancelled_by,omitempty"`
+	Parameters        map[string]string `json:"parameters,omitempty"`
+	GitRef            string            `json:"git_ref,omitempty"`
+	CommitSHA         string            `json:"commit_sha,omitempty"`
+	ReviewedBy        string            `json:"reviewed_by,omitempty"`
+	ReviewComment     string            `json:"review_comment,omitempty"`
+	RerunOf           string            `json:"rerun_of,omitempty"`
+	Steps             []ExecutionStep   `json:"steps,omitempty"`
+	UserID            int64             `json:"user_id"`
+	QueuePosition     int               `json:"queue_position,omitempty"`
 }
 
 // ExecutionWithDetails extends Execution with additional related information.
@@ -36,3 +96,19 @@ type ExecutionWithDetails struct {
 	AppName     string `json:"app_name"`
 	Username    string `json:"username"`
 }
+
+// ExecutionStep represents the result of a single pipeline step within an execution.
+type ExecutionStep struct {
+	ExitCode        *int            `json:"exit_code"`
+	StartedAt       *time.Time      `json:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at"`
+	ID              string          `json:"id"`
+	ExecutionID     string          `json:"execution_id"`
+	CommandID       string          `json:"command_id"`
+	Name            string          `json:"name"`
+	Status          ExecutionStatus `json:"status"`
+	Output          string          `json:"output"`
+	StepOrder       int             `json:"step_order"`
+	DurationMs      int64           `json:"duration_ms"`
+	ContinueOnError bool            `json:"continue_on_error"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// NotificationChannel is the kind of destination a notification is delivered to.
+type NotificationChannel string
+
+const (
+	// ChannelWebhook posts a JSON payload signed with HMAC-SHA256 to a URL.
+	ChannelWebhook NotificationChannel = "webhook"
+	// ChannelSlack posts a message to a Slack or Mattermost incoming webhook.
+	ChannelSlack NotificationChannel = "slack"
+	// ChannelEmail sends an email through the configured SMTP server.
+	ChannelEmail NotificationChannel = "email"
+)
+
+// IsValid reports whether c is a known notification channel.
+func (c NotificationChannel) IsValid() bool {
+	return c == ChannelWebhook || c == ChannelSlack || c == ChannelEmail
+}
+
+// NotificationRule sends a message to a channel when an execution of an app finishes with one of the given statuses.
+// Target is the URL for webhook and slack channels and a comma separated list of addresses for email.
+type NotificationRule struct {
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+	ID        string              `json:"id"`
+	AppID     string              `json:"app_id"`
+	Name      string              `json:"name"`
+	Channel   NotificationChannel `json:"channel"`
+	Target    string              `json:"target"`
+	Template  string              `json:"template,omitempty"`
+	Statuses  []ExecutionStatus   `json:"statuses"`
+	HasSecret bool                `json:"has_secret"`
+	Enabled   bool                `json:"enabled"`
+}
+
+// CreateNotificationRuleRequest contains the data for creating a notification rule.
+// Secret is only used by webhook channels to sign the payload.
+type CreateNotificationRuleRequest struct {
+	Name     string              `json:"name" binding:"required"`
+	Channel  NotificationChannel `json:"channel" binding:"required,oneof=webhook slack email"`
+	Target   string              `json:"target" binding:"required"`
+	Secret   string              `json:"secret"`
+	Template string              `json:"template"`
+	Statuses []ExecutionStatus   `json:"statuses" binding:"required"`
+	Enabled  *bool               `json:"enabled"`
+}
+
+// UpdateNotificationRuleRequest contains the data for updating a notification rule.
+// A nil field keeps the current value; an empty secret or template clears it.
+type UpdateNotificationRuleRequest struct {
+	Name     *string           `json:"name"`
+	Target   *string           `json:"target"`
+	Secret   *string           `json:"secret"`
+	Template *string           `json:"template"`
+	Statuses []ExecutionStatus `json:"statuses"`
+	Enabled  *bool             `json:"enabled"`
+}
+
+// DeliveryStatus represents the state of a notification delivery.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending indicates the notification has not been delivered yet and may be retried.
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySent indicates the notification was delivered.
+	DeliverySent DeliveryStatus = "sent"
+	// DeliveryFailed indicates every delivery attempt failed.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// NotificationDelivery records the delivery of a notification for one execution.
+type NotificationDelivery struct {
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	ID              string              `json:"id"`
+	RuleID          string              `json:"rule_id,omitempty"`
+	RuleName        string              `json:"rule_name"`
+	AppID           string              `json:"app_id"`
+	ExecutionID     string              `json:"execution_id"`
+	Channel         NotificationChannel `json:"channel"`
+	Status          DeliveryStatus      `json:"status"`
+	ExecutionStatus ExecutionStatus     `json:"execution_status"`
+	LastError       string              `json:"last_error,omitempty"`
+	Attempts        int                 `json:"attempts"`
+}
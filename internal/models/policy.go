@@ -0,0 +1,83 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// PolicyAction is what a command policy rule does with the commands it matches.
+type PolicyAction string
+
+const (
+	// PolicyDeny rejects the commands the rule matches.
+	PolicyDeny PolicyAction = "deny"
+	// PolicyAllow permits the commands the rule matches. Once a role has an allow rule, its commands
+	// must be permitted by one.
+	PolicyAllow PolicyAction = "allow"
+	// PolicyApproval holds executions of the commands the rule matches until another user approves them.
+	PolicyApproval PolicyAction = "approval"
+)
+
+// IsValid reports whether a is a known policy action.
+func (a PolicyAction) IsValid() bool {
+	return a == PolicyDeny || a == PolicyAllow || a == PolicyApproval
+}
+
+// PolicyMatchType is how the pattern of a command policy rule is matched.
+type PolicyMatchType string
+
+const (
+	// PolicyMatchRegex matches a regular expression anywhere in a command.
+	PolicyMatchRegex PolicyMatchType = "regex"
+	// PolicyMatchGlob matches a whole command, where * matches any text and ? any single character.
+	PolicyMatchGlob PolicyMatchType = "glob"
+)
+
+// IsValid reports whether t is a known match type.
+func (t PolicyMatchType) IsValid() bool {
+	return t == PolicyMatchRegex || t == PolicyMatchGlob
+}
+
+// PolicyRule is an admin-configured rule that denies, allows or requires approval of commands.
+// Rules apply to the commands of users with one of Roles, or to every command if Roles is empty.
+type PolicyRule struct {
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Action      PolicyAction    `json:"action"`
+	MatchType   PolicyMatchType `json:"match_type"`
+	Pattern     string          `json:"pattern"`
+	Roles       []UserRole      `json:"roles"`
+	Enabled     bool            `json:"enabled"`
+}
+
+// AppliesTo reports whether the rule applies to the commands of role. Commands without a user,
+// such as scheduled executions and deploys, have no role and only rules without roles apply to them.
+func (r *PolicyRule) AppliesTo(role UserRole) bool {
+	return len(r.Roles) == 0 || slices.Contains(r.Roles, role)
+}
+
+// CreatePolicyRuleRequest contains the data for creating a command policy rule.
+type CreatePolicyRuleRequest struct {
+	Name        string          `json:"name" binding:"required"`
+	Description string          `json:"description"`
+	Action      PolicyAction    `json:"action" binding:"required,oneof=deny allow approval"`
+	MatchType   PolicyMatchType `json:"match_type" binding:"required,oneof=regex glob"`
+	Pattern     string          `json:"pattern" binding:"required"`
+	Roles       []UserRole      `json:"roles"`
+	Enabled     *bool           `json:"enabled"`
+}
+
+// UpdatePolicyRuleRequest contains the data for updating a command policy rule.
+// A nil field keeps the current value.
+type UpdatePolicyRuleRequest struct {
+	Name        *string          `json:"name"`
+	Description *string          `json:"description"`
+	Action      *PolicyAction    `json:"action"`
+	MatchType   *PolicyMatchType `json:"match_type"`
+	Pattern     *string          `json:"pattern"`
+	Roles       []UserRole       `json:"roles"`
+	Enabled     *bool            `json:"enabled"`
+}
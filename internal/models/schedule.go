@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// OverlapPolicy controls what a schedule does when its previous run is still active at the next tick.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip skips the tick while the previous run of the schedule is pending or running.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue starts a new execution anyway and leaves ordering to the app's concurrency policy.
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapCancelPrevious cancels the previous run of the schedule before starting a new one.
+	OverlapCancelPrevious OverlapPolicy = "cancel-previous"
+)
+
+// IsValid reports whether p is a known overlap policy.
+func (p OverlapPolicy) IsValid() bool {
+	return p == OverlapSkip || p == OverlapQueue || p == OverlapCancelPrevious
+}
+
+// Schedule runs a command on a cron expression evaluated in a timezone.
+// NextRunAt is empty while the schedule is disabled.
+type Schedule struct {
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	NextRunAt       *time.Time        `json:"next_run_at"`
+	LastRunAt       *time.Time        `json:"last_run_at"`
+	ID              string            `json:"id"`
+	CommandID       string            `json:"command_id"`
+	AppID           string            `json:"app_id"`
+	Cron            string            `json:"cron"`
+	Timezone        string            `json:"timezone"`
+	OverlapPolicy   OverlapPolicy     `json:"overlap_policy"`
+	Parameters      map[string]string `json:"parameters,omitempty"`
+	LastExecutionID string            `json:"last_execution_id,omitempty"`
+	LastError       string            `json:"last_error,omitempty"`
+	Enabled         bool              `json:"enabled"`
+}
+
+// CreateScheduleRequest contains the data for creating a schedule.
+// Timezone defaults to UTC and OverlapPolicy to skip.
+type CreateScheduleRequest struct {
+	Cron          string            `json:"cron" binding:"required"`
+	Timezone      string            `json:"timezone"`
+	OverlapPolicy OverlapPolicy     `json:"overlap_policy" binding:"omitempty,oneof=skip queue cancel-previous"`
+	Parameters    map[string]string `json:"parameters"`
+	Enabled       *bool             `json:"enabled"`
+}
+
+// UpdateScheduleRequest contains the data for updating a schedule.
+// A nil field keeps the current value; a non-nil Parameters replaces the parameter values.
+type UpdateScheduleRequest struct {
+	Cron          *string           `json:"cron"`
+	Timezone      *string           `json:"timezone"`
+	OverlapPolicy OverlapPolicy     `json:"overlap_policy" binding:"omitempty,oneof=skip queue cancel-previous"`
+	Parameters    map[string]string `json:"parameters"`
+	Enabled       *bool             `json:"enabled"`
+}
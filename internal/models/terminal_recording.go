@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TerminalRecording is an asciicast v2 recording of the output, input and resizes of one terminal session.
+// EndedAt is nil while the session is still being recorded.
+type TerminalRecording struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	UserID    *int64     `json:"user_id,omitempty"`
+	ID        string     `json:"id"`
+	SessionID string     `json:"session_id"`
+	Username  string     `json:"username"`
+	Shell     string     `json:"shell"`
+	Path      string     `json:"-"`
+	Size      int64      `json:"size"`
+}
@@ -12,6 +12,14 @@ const (
 	RoleViewer   UserRole = "viewer"   // Read-only access
 )
 
+// roleLevels orders the roles from the least to the most privileged.
+var roleLevels = map[UserRole]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// Includes reports whether role r has at least the permissions of role other (admin > operator > viewer).
+func (r UserRole) Includes(other UserRole) bool {
+	return roleLevels[other] > 0 && roleLevels[r] >= roleLevels[other]
+}
+
 // User represents a user account.
 type User struct {
 	CreatedAt    time.Time `json:"created_at"`
@@ -24,6 +32,8 @@ type User struct {
 	ID           int64     `json:"id"`
 	TOTPEnabled  bool      `json:"totp_enabled"` // Whether 2FA is enabled
 	IsAdmin      bool      `json:"is_admin"`     // Deprecated: use Role instead
+	// APIToken is the personal API token that authenticated the current request, if any
+	APIToken *APIToken `json:"-"`
 }
 
 // IsRole checks if user has the specified role
@@ -50,6 +60,15 @@ func (u *User) HasPermission(requiredRole UserRole) bool {
 	}
 }
 
+// AuditName returns the name recorded in audit logs for actions of the user,
+// which names the API token when the request was authenticated with one.
+func (u *User) AuditName() string {
+	if u.APIToken != nil {
+		return u.Username + " via token " + u.APIToken.Name
+	}
+	return u.Username
+}
+
 // CanManageUsers returns true if user can manage other users
 func (u *User) CanManageUsers() bool {
 	return u.Role == RoleAdmin || u.IsAdmin
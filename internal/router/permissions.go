@@ -0,0 +1,179 @@
+package router
+
+import (
+	"github.com/pandeptwidyaop/http-remote/internal/middleware"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// viewer, operator and admin return the permission of a route for the minimum role and API token scopes.
+func viewer(scopes ...models.APITokenScope) middleware.Permission {
+	return middleware.Permission{Role: models.RoleViewer, Scopes: scopes}
+}
+
+func operator(scopes ...models.APITokenScope) middleware.Permission {
+	return middleware.Permission{Role: models.RoleOperator, Scopes: scopes}
+}
+
+func admin(scopes ...models.APITokenScope) middleware.Permission {
+	return middleware.Permission{Role: models.RoleAdmin, Scopes: scopes}
+}
+
+const (
+	read       = models.ScopeRead
+	apps       = models.ScopeApps
+	execute    = models.ScopeExecute
+	files      = models.ScopeFiles
+	containers = models.ScopeContainers
+	terminal   = models.ScopeTerminal
+	system     = models.ScopeAdmin
+)
+
+// apiPermissions lists the permission of every authenticated /api route. Viewers can read,
+// operators can also manage apps, run commands, change files and use terminals and containers,
+// and admins can also manage users, groups, app members, backups and the server. Routes on an app,
+// command, execution or schedule need the role as a member of its app instead of as the user's own
+// role, while host-wide routes such as terminals, files and containers always need the own role.
+// Reads with an API token need the read scope or the scope of the area; routes without scopes are
+// only available with a session.
+var apiPermissions = middleware.Permissions{
+	// Account
+	"GET /auth/me":                  viewer(read),
+	"GET /2fa/status":               viewer(),
+	"POST /2fa/generate-secret":     viewer(),
+	"GET /2fa/qrcode":               viewer(),
+	"POST /2fa/enable":              viewer(),
+	"POST /2fa/disable":             viewer(),
+	"POST /auth/change-password":    viewer(),
+	"GET /auth/tokens":              viewer(),
+	"POST /auth/tokens":             viewer(),
+	"DELETE /auth/tokens/:token_id": viewer(),
+
+	// Apps
+	"GET /apps":                               viewer(read, apps),
+	"POST /apps":                              operator(apps),
+	"GET /apps/:id":                           viewer(read, apps).On(models.ResourceApp),
+	"PUT /apps/:id":                           operator(apps).On(models.ResourceApp),
+	"DELETE /apps/:id":                        operator(apps).On(models.ResourceApp),
+	"POST /apps/:id/regenerate-token":         operator(apps).On(models.ResourceApp),
+	"GET /apps/:id/tokens":                    viewer(read, apps).On(models.ResourceApp),
+	"POST /apps/:id/tokens":                   operator(apps).On(models.ResourceApp),
+	"DELETE /apps/:id/tokens/:token_id":       operator(apps).On(models.ResourceApp),
+	"POST /apps/:id/rollback":                 operator(execute).On(models.ResourceApp),
+	"GET /apps/:id/commands":                  viewer(read, apps).On(models.ResourceApp),
+	"POST /apps/:id/commands":                 operator(apps).On(models.ResourceApp),
+	"POST /apps/:id/commands/reorder":         operator(apps).On(models.ResourceApp),
+	"GET /apps/:id/env":                       viewer(read, apps).On(models.ResourceApp),
+	"POST /apps/:id/env":                      operator(apps).On(models.ResourceApp),
+	"PUT /apps/:id/env/:env_id":               operator(apps).On(models.ResourceApp),
+	"DELETE /apps/:id/env/:env_id":            operator(apps).On(models.ResourceApp),
+	"GET /apps/:id/notifications":             viewer(read, apps).On(models.ResourceApp),
+	"POST /apps/:id/notifications":            operator(apps).On(models.ResourceApp),
+	"PUT /apps/:id/notifications/:rule_id":    operator(apps).On(models.ResourceApp),
+	"DELETE /apps/:id/notifications/:rule_id": operator(apps).On(models.ResourceApp),
+	"GET /apps/:id/members":                   admin(read, system).On(models.ResourceApp),
+	"POST /apps/:id/members":                  admin(system).On(models.ResourceApp),
+	"DELETE /apps/:id/members/:member_id":     admin(system).On(models.ResourceApp),
+	"GET /apps/:id/notifications/deliveries":  viewer(read, apps).On(models.ResourceApp),
+
+	// Commands and schedules
+	"GET /commands/:id":            viewer(read, apps).On(models.ResourceCommand),
+	"PUT /commands/:id":            operator(apps).On(models.ResourceCommand),
+	"DELETE /commands/:id":         operator(apps).On(models.ResourceCommand),
+	"POST /commands/:id/execute":   operator(execute).On(models.ResourceCommand),
+	"GET /commands/:id/schedules":  viewer(read, apps).On(models.ResourceCommand),
+	"POST /commands/:id/schedules": operator(apps).On(models.ResourceCommand),
+	"GET /schedules/preview":       viewer(read, apps),
+	"PUT /schedules/:id":           operator(apps).On(models.ResourceSchedule),
+	"DELETE /schedules/:id":        operator(apps).On(models.ResourceSchedule),
+
+	// Executions
+	"GET /executions":              viewer(read, execute),
+	"GET /executions/:id":          viewer(read, execute).On(models.ResourceExecution),
+	"GET /executions/:id/output":   viewer(read, execute).On(models.ResourceExecution),
+	"GET /executions/:id/stream":   viewer(read, execute).On(models.ResourceExecution),
+	"POST /executions/:id/cancel":  operator(execute).On(models.ResourceExecution),
+	"POST /executions/:id/rerun":   operator(execute).On(models.ResourceExecution),
+	"POST /executions/:id/approve": operator(execute).On(models.ResourceExecution),
+	"POST /executions/:id/reject":  operator(execute).On(models.ResourceExecution),
+
+	"GET /audit-logs":    viewer(read),
+	"GET /version/check": viewer(read),
+
+	// Backup
+	"GET /backup/export":   admin(read, system),
+	"POST /backup/import":  admin(system),
+	"GET /apps/:id/export": admin(read, system).On(models.ResourceApp),
+
+	// Terminal
+	"GET /terminal/ws":                                            operator(terminal),
+	"GET /terminal/sessions":                                      operator(read, terminal),
+	"POST /terminal/sessions":                                     operator(terminal),
+	"DELETE /terminal/sessions/:session_id":                       operator(terminal),
+	"GET /terminal/sessions/:session_id/participants":             operator(read, terminal),
+	"POST /terminal/sessions/:session_id/participants":            operator(terminal),
+	"DELETE /terminal/sessions/:session_id/participants/:user_id": operator(terminal),
+	"GET /terminal/recordings":                                    operator(read, terminal),
+	"GET /terminal/recordings/:id":                                operator(read, terminal),
+
+	// Files
+	"GET /files":              viewer(read, files),
+	"GET /files/default-path": viewer(read, files),
+	"GET /files/read":         viewer(read, files),
+	"GET /files/download":     viewer(read, files),
+	"POST /files/upload":      operator(files),
+	"POST /files/mkdir":       operator(files),
+	"POST /files/save":        operator(files),
+	"POST /files/rename":      operator(files),
+	"POST /files/copy":        operator(files),
+	"DELETE /files":           operator(files),
+
+	// Users
+	"GET /users":              admin(read, system),
+	"POST /users":             admin(system),
+	"GET /users/:id":          admin(read, system),
+	"PUT /users/:id":          admin(system),
+	"PUT /users/:id/password": admin(system),
+	"DELETE /users/:id":       admin(system),
+	"GET /groups":             admin(read, system),
+	"POST /groups":            admin(system),
+	"PUT /groups/:id":         admin(system),
+	"DELETE /groups/:id":      admin(system),
+
+	// Command policy
+	"GET /policies":           admin(read, system),
+	"POST /policies":          admin(system),
+	"POST /policies/evaluate": admin(system),
+	"PUT /policies/:id":       admin(system),
+	"DELETE /policies/:id":    admin(system),
+
+	// System
+	"GET /system/status":            viewer(read, system),
+	"POST /system/upgrade":          admin(system),
+	"POST /system/restart":          admin(system),
+	"GET /system/rollback-versions": admin(read, system),
+	"POST /system/rollback":         admin(system),
+
+	// Metrics
+	"GET /metrics/system":             viewer(read, system),
+	"GET /metrics/docker":             viewer(read, system),
+	"GET /metrics/docker/:id":         viewer(read, system),
+	"GET /metrics/docker/:id/history": viewer(read, system),
+	"GET /metrics/summary":            viewer(read, system),
+	"GET /metrics/history":            viewer(read, system),
+	"GET /metrics/storage":            viewer(read, system),
+	"GET /metrics/stream":             viewer(read, system),
+	"POST /metrics/prune":             admin(system),
+	"POST /metrics/vacuum":            admin(system),
+
+	// Containers
+	"GET /containers/status":       viewer(read, containers),
+	"GET /containers":              viewer(read, containers),
+	"GET /containers/:id":          viewer(read, containers),
+	"POST /containers/:id/start":   operator(containers),
+	"POST /containers/:id/stop":    operator(containers),
+	"POST /containers/:id/restart": operator(containers),
+	"DELETE /containers/:id":       operator(containers),
+	"GET /containers/:id/logs":     viewer(read, containers),
+	"POST /containers/:id/exec":    operator(containers),
+	"GET /containers/:id/terminal": operator(containers),
+}
@@ -17,7 +17,7 @@ import (
 )
 
 // New creates and configures a new Gin router with all routes and middleware.
-func New(cfg *config.Config, authService *services.AuthService, appService *services.AppService, executorService *services.ExecutorService, auditService *services.AuditService, metricsCollector ...*services.MetricsCollector) *gin.Engine {
+func New(cfg *config.Config, authService *services.AuthService, appService *services.AppService, envService *services.EnvService, gitService *services.GitService, executorService *services.ExecutorService, notificationService *services.NotificationService, schedulerService *services.SchedulerService, auditService *services.AuditService, terminalRecordings *services.TerminalRecordingService, policyService *services.PolicyService, metricsCollector ...*services.MetricsCollector) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
@@ -74,16 +74,21 @@ func New(cfg *config.Config, authService *services.AuthService, appService *serv
 
 	authHandler := handlers.NewAuthHandler(authService, auditService, cfg.Server.PathPrefix, cfg.Server.SecureCookie)
 	twoFAHandler := handlers.NewTwoFAHandler(authService, auditService)
-	appHandler := handlers.NewAppHandler(appService, auditService, cfg.Server.PathPrefix)
-	commandHandler := handlers.NewCommandHandler(appService, executorService, auditService, cfg.Server.PathPrefix)
+	appHandler := handlers.NewAppHandler(appService, gitService, auditService, policyService, cfg.Server.PathPrefix)
+	envHandler := handlers.NewEnvHandler(appService, envService, auditService)
+	notificationHandler := handlers.NewNotificationHandler(appService, notificationService, auditService)
+	scheduleHandler := handlers.NewScheduleHandler(appService, schedulerService, auditService)
+	commandHandler := handlers.NewCommandHandler(appService, executorService, auditService, policyService, cfg.Server.PathPrefix)
 	streamHandler := handlers.NewStreamHandler(executorService)
-	deployHandler := handlers.NewDeployHandler(appService, executorService, cfg.Server.PathPrefix)
+	deployHandler := handlers.NewDeployHandler(appService, gitService, executorService, auditService, cfg.Server.PathPrefix)
 	auditHandler := handlers.NewAuditHandler(auditService, cfg.Server.PathPrefix)
 	versionHandler := handlers.NewVersionHandler()
-	terminalHandler := handlers.NewTerminalHandler(&cfg.Terminal, auditService, cfg.Server.AllowedOrigins)
-	backupHandler := handlers.NewBackupHandler(appService, auditService)
+	terminalHandler := handlers.NewTerminalHandler(&cfg.Terminal, authService, auditService, terminalRecordings, policyService, cfg.Server.AllowedOrigins)
+	backupHandler := handlers.NewBackupHandler(appService, schedulerService, auditService)
 	fileHandler := handlers.NewFileHandler(cfg, auditService)
 	userHandler := handlers.NewUserHandler(authService, auditService, cfg)
+	groupHandler := handlers.NewGroupHandler(authService, auditService)
+	policyHandler := handlers.NewPolicyHandler(policyService, auditService)
 	systemHandler := handlers.NewSystemHandler(auditService)
 
 	// Initialize metrics handler (optional metrics collector)
@@ -95,7 +100,7 @@ func New(cfg *config.Config, authService *services.AuthService, appService *serv
 
 	// Initialize container handler
 	containerService := services.NewContainerService(auditService)
-	containerHandler := handlers.NewContainerHandler(containerService)
+	containerHandler := handlers.NewContainerHandler(containerService, policyService)
 
 	// Rate limiters
 	loginLimiter := middleware.NewRateLimiter(5, time.Minute)   // 5 req/min for login
@@ -107,6 +112,12 @@ func New(cfg *config.Config, authService *services.AuthService, appService *serv
 	prefix.POST("/deploy/:app_id", deployLimiter.Middleware(), deployHandler.Deploy)
 	prefix.GET("/deploy/:app_id/status/:execution_id", apiLimiter.Middleware(), deployHandler.DeployStatus)
 	prefix.GET("/deploy/:app_id/stream/:execution_id", apiLimiter.Middleware(), deployHandler.DeployStream)
+	prefix.POST("/deploy/:app_id/cancel/:execution_id", deployLimiter.Middleware(), deployHandler.DeployCancel)
+
+	// Git provider push webhooks (webhook secret auth)
+	prefix.POST("/deploy/:app_id/github", deployLimiter.Middleware(), deployHandler.Webhook(services.WebhookGitHub))
+	prefix.POST("/deploy/:app_id/gitlab", deployLimiter.Middleware(), deployHandler.Webhook(services.WebhookGitLab))
+	prefix.POST("/deploy/:app_id/gitea", deployLimiter.Middleware(), deployHandler.Webhook(services.WebhookGitea))
 
 	api := prefix.Group("/api")
 	// Apply CSRF protection to all API routes
@@ -122,6 +133,7 @@ func New(cfg *config.Config, authService *services.AuthService, appService *serv
 
 		protected := api.Group("")
 		protected.Use(middleware.AuthRequired(authService))
+		protected.Use(middleware.Authorize(protected.BasePath(), apiPermissions, appService))
 		{
 			protected.GET("/auth/me", authHandler.Me)
 
@@ -135,24 +147,56 @@ func New(cfg *config.Config, authService *services.AuthService, appService *serv
 			// Password management
 			protected.POST("/auth/change-password", authHandler.ChangePassword)
 
+			// Personal API tokens
+			protected.GET("/auth/tokens", authHandler.ListTokens)
+			protected.POST("/auth/tokens", authHandler.CreateToken)
+			protected.DELETE("/auth/tokens/:token_id", authHandler.RevokeToken)
+
 			protected.GET("/apps", appHandler.List)
 			protected.POST("/apps", appHandler.Create)
 			protected.GET("/apps/:id", appHandler.Get)
 			protected.PUT("/apps/:id", appHandler.Update)
 			protected.DELETE("/apps/:id", appHandler.Delete)
 			protected.POST("/apps/:id/regenerate-token", appHandler.RegenerateToken)
+			protected.GET("/apps/:id/tokens", appHandler.ListTokens)
+			protected.POST("/apps/:id/tokens", appHandler.CreateToken)
+			protected.DELETE("/apps/:id/tokens/:token_id", appHandler.RevokeToken)
+			protected.POST("/apps/:id/rollback", commandHandler.Rollback)
 			protected.GET("/apps/:id/commands", appHandler.ListCommands)
 			protected.POST("/apps/:id/commands", appHandler.CreateCommand)
 			protected.POST("/apps/:id/commands/reorder", appHandler.ReorderCommands)
+			protected.GET("/apps/:id/env", envHandler.List)
+			protected.POST("/apps/:id/env", envHandler.Create)
+			protected.PUT("/apps/:id/env/:env_id", envHandler.Update)
+			protected.DELETE("/apps/:id/env/:env_id", envHandler.Delete)
+			protected.GET("/apps/:id/notifications", notificationHandler.List)
+			protected.POST("/apps/:id/notifications", notificationHandler.Create)
+			protected.PUT("/apps/:id/notifications/:rule_id", notificationHandler.Update)
+			protected.DELETE("/apps/:id/notifications/:rule_id", notificationHandler.Delete)
+			protected.GET("/apps/:id/notifications/deliveries", notificationHandler.ListDeliveries)
+			protected.GET("/apps/:id/members", appHandler.ListMembers)
+			protected.POST("/apps/:id/members", appHandler.AddMember)
+			protected.DELETE("/apps/:id/members/:member_id", appHandler.RemoveMember)
 
 			protected.GET("/commands/:id", commandHandler.Get)
 			protected.PUT("/commands/:id", commandHandler.Update)
 			protected.DELETE("/commands/:id", commandHandler.Delete)
 			protected.POST("/commands/:id/execute", commandHandler.Execute)
+			protected.GET("/commands/:id/schedules", scheduleHandler.List)
+			protected.POST("/commands/:id/schedules", scheduleHandler.Create)
+
+			protected.GET("/schedules/preview", scheduleHandler.Preview)
+			protected.PUT("/schedules/:id", scheduleHandler.Update)
+			protected.DELETE("/schedules/:id", scheduleHandler.Delete)
 
 			protected.GET("/executions", commandHandler.ListExecutions)
 			protected.GET("/executions/:id", commandHandler.GetExecution)
+			protected.GET("/executions/:id/output", commandHandler.GetExecutionOutput)
 			protected.GET("/executions/:id/stream", streamHandler.Stream)
+			protected.POST("/executions/:id/cancel", commandHandler.CancelExecution)
+			protected.POST("/executions/:id/rerun", commandHandler.RerunExecution)
+			protected.POST("/executions/:id/approve", commandHandler.ApproveExecution)
+			protected.POST("/executions/:id/reject", commandHandler.RejectExecution)
 
 			protected.GET("/audit-logs", auditHandler.List)
 			protected.GET("/version/check", versionHandler.CheckUpdate)
@@ -167,6 +211,11 @@ func New(cfg *config.Config, authService *services.AuthService, appService *serv
 			protected.GET("/terminal/sessions", terminalHandler.ListSessions)
 			protected.POST("/terminal/sessions", terminalHandler.CreateSession)
 			protected.DELETE("/terminal/sessions/:session_id", terminalHandler.CloseSession)
+			protected.GET("/terminal/sessions/:session_id/participants", terminalHandler.ListParticipants)
+			protected.POST("/terminal/sessions/:session_id/participants", terminalHandler.InviteParticipant)
+			protected.DELETE("/terminal/sessions/:session_id/participants/:user_id", terminalHandler.KickParticipant)
+			protected.GET("/terminal/recordings", terminalHandler.ListRecordings)
+			protected.GET("/terminal/recordings/:id", terminalHandler.GetRecording)
 
 			// File management endpoints
 			protected.GET("/files", fileHandler.ListFiles)
@@ -180,13 +229,24 @@ func New(cfg *config.Config, authService *services.AuthService, appService *serv
 			protected.POST("/files/copy", fileHandler.CopyFile)
 			protected.DELETE("/files", fileHandler.DeleteFile)
 
-			// User management endpoints (admin only)
+			// User management endpoints
 			protected.GET("/users", userHandler.List)
 			protected.POST("/users", userHandler.Create)
 			protected.GET("/users/:id", userHandler.Get)
 			protected.PUT("/users/:id", userHandler.Update)
 			protected.PUT("/users/:id/password", userHandler.UpdatePassword)
 			protected.DELETE("/users/:id", userHandler.Delete)
+			protected.GET("/groups", groupHandler.List)
+			protected.POST("/groups", groupHandler.Create)
+			protected.PUT("/groups/:id", groupHandler.Update)
+			protected.DELETE("/groups/:id", groupHandler.Delete)
+
+			// Command policy endpoints
+			protected.GET("/policies", policyHandler.List)
+			protected.POST("/policies", policyHandler.Create)
+			protected.POST("/policies/evaluate", policyHandler.Evaluate)
+			protected.PUT("/policies/:id", policyHandler.Update)
+			protected.DELETE("/policies/:id", policyHandler.Delete)
 
 			// System management endpoints
 			protected.GET("/system/status", systemHandler.Status)
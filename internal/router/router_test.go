@@ -0,0 +1,305 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/middleware"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+const testPrefix = "/devops"
+
+// publicRoutes are the /api routes that do not require authentication.
+var publicRoutes = map[string]bool{
+	"GET /api/version":      true,
+	"POST /api/auth/login":  true,
+	"POST /api/auth/logout": true,
+}
+
+// operatorRoutes and adminRoutes list the routes that need more than the viewer role.
+// They are kept separately from apiPermissions so that a change in the table has to be made here too.
+var operatorRoutes = []string{
+	"POST /apps", "PUT /apps/:id", "DELETE /apps/:id", "POST /apps/:id/regenerate-token",
+	"POST /apps/:id/tokens", "DELETE /apps/:id/tokens/:token_id", "POST /apps/:id/rollback",
+	"POST /apps/:id/commands", "POST /apps/:id/commands/reorder",
+	"POST /apps/:id/env", "PUT /apps/:id/env/:env_id", "DELETE /apps/:id/env/:env_id",
+	"POST /apps/:id/notifications", "PUT /apps/:id/notifications/:rule_id", "DELETE /apps/:id/notifications/:rule_id",
+	"PUT /commands/:id", "DELETE /commands/:id", "POST /commands/:id/execute", "POST /commands/:id/schedules",
+	"PUT /schedules/:id", "DELETE /schedules/:id",
+	"POST /executions/:id/cancel", "POST /executions/:id/rerun", "POST /executions/:id/approve", "POST /executions/:id/reject",
+	"GET /terminal/ws", "GET /terminal/sessions", "POST /terminal/sessions", "DELETE /terminal/sessions/:session_id",
+	"GET /terminal/recordings", "GET /terminal/recordings/:id",
+	"GET /terminal/sessions/:session_id/participants", "POST /terminal/sessions/:session_id/participants",
+	"DELETE /terminal/sessions/:session_id/participants/:user_id",
+	"POST /files/upload", "POST /files/mkdir", "POST /files/save", "POST /files/rename", "POST /files/copy", "DELETE /files",
+	"POST /containers/:id/start", "POST /containers/:id/stop", "POST /containers/:id/restart", "DELETE /containers/:id",
+	"POST /containers/:id/exec", "GET /containers/:id/terminal",
+}
+
+var adminRoutes = []string{
+	"GET /backup/export", "POST /backup/import", "GET /apps/:id/export",
+	"GET /apps/:id/members", "POST /apps/:id/members", "DELETE /apps/:id/members/:member_id",
+	"GET /groups", "POST /groups", "PUT /groups/:id", "DELETE /groups/:id",
+	"GET /policies", "POST /policies", "POST /policies/evaluate", "PUT /policies/:id", "DELETE /policies/:id",
+	"GET /users", "POST /users", "GET /users/:id", "PUT /users/:id", "PUT /users/:id/password", "DELETE /users/:id",
+	"POST /system/upgrade", "POST /system/restart", "GET /system/rollback-versions", "POST /system/rollback",
+	"POST /metrics/prune", "POST /metrics/vacuum",
+}
+
+// appResources maps the route prefixes whose ":id" identifies a resource of an app to the resource.
+var appResources = map[string]models.AppResource{
+	"/apps/:id":       models.ResourceApp,
+	"/commands/:id":   models.ResourceCommand,
+	"/executions/:id": models.ResourceExecution,
+	"/schedules/:id":  models.ResourceSchedule,
+}
+
+// testUsers are users with each role, with the IDs they have in the database of setupPermissionsDB.
+var testUsers = []*models.User{
+	{ID: 1, Username: "viewer", Role: models.RoleViewer},
+	{ID: 2, Username: "operator", Role: models.RoleOperator},
+	{ID: 3, Username: "admin", Role: models.RoleAdmin},
+}
+
+// setupPermissionsDB creates an app with a command, execution and schedule that all have the ID "1",
+// which samplePath fills in, and makes the users members of the app with memberRole. An empty
+// memberRole makes them members with their own role.
+func setupPermissionsDB(t *testing.T, memberRole models.UserRole) *services.AppService {
+	t.Helper()
+
+	db, err := database.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	statements := []string{
+		"INSERT INTO apps (id, name, working_dir, token) VALUES ('1', 'app', '/tmp', 'x')",
+		"INSERT INTO commands (id, app_id, name, command) VALUES ('1', '1', 'deploy', 'true')",
+		"INSERT INTO executions (id, command_id, status) VALUES ('1', '1', 'success')",
+		"INSERT INTO command_schedules (id, command_id, cron) VALUES ('1', '1', '* * * * *')",
+	}
+	for _, user := range testUsers {
+		role := memberRole
+		if role == "" {
+			role = user.Role
+		}
+		statements = append(statements,
+			fmt.Sprintf("INSERT INTO users (id, username, password_hash, role) VALUES (%d, '%s', 'x', '%s')", user.ID, user.Username, user.Role),
+			fmt.Sprintf("INSERT INTO app_members (id, app_id, user_id, role) VALUES ('m%d', '1', %d, '%s')", user.ID, user.ID, role),
+		)
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("failed to insert test data: %v", err)
+		}
+	}
+	return services.NewAppService(db)
+}
+
+// authorize runs a request for route as user through Authorize and returns the response code.
+// The route's handler responds with 204.
+func authorize(user *models.User, route string, appService *services.AppService) int {
+	method, path, _ := strings.Cut(route, " ")
+	engine := gin.New()
+	api := engine.Group(testPrefix + "/api")
+	api.Use(func(c *gin.Context) { c.Set(middleware.UserContextKey, user) })
+	api.Use(middleware.Authorize(api.BasePath(), apiPermissions, appService))
+	api.Handle(method, path, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(method, testPrefix+"/api"+samplePath(path), nil))
+	return w.Code
+}
+
+// protectedRoutes returns the authenticated /api routes of the router, relative to /api.
+func protectedRoutes(t *testing.T) []string {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Server.PathPrefix = testPrefix
+	engine := New(cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	var routes []string
+	for _, route := range engine.Routes() {
+		path := strings.TrimPrefix(route.Path, testPrefix)
+		if !strings.HasPrefix(path, "/api/") || publicRoutes[route.Method+" "+path] {
+			continue
+		}
+		routes = append(routes, route.Method+" "+strings.TrimPrefix(path, "/api"))
+	}
+	if len(routes) == 0 {
+		t.Fatal("expected protected routes")
+	}
+	return routes
+}
+
+func TestPermissions_CoverEveryRoute(t *testing.T) {
+	routes := protectedRoutes(t)
+
+	registered := map[string]bool{}
+	for _, route := range routes {
+		registered[route] = true
+		permission, ok := apiPermissions[route]
+		if !ok {
+			t.Errorf("route %s has no permission", route)
+			continue
+		}
+
+		// Routes on a resource of an app must check the membership of that app
+		_, path, _ := strings.Cut(route, " ")
+		var want models.AppResource
+		for prefix, resource := range appResources {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				want = resource
+			}
+		}
+		if permission.Resource != want {
+			t.Errorf("route %s: expected resource %q, got %q", route, want, permission.Resource)
+		}
+	}
+	for route := range apiPermissions {
+		if !registered[route] {
+			t.Errorf("permission for unknown route %s", route)
+		}
+	}
+}
+
+func TestPermissions_Roles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appService := setupPermissionsDB(t, "")
+
+	required := map[string]models.UserRole{}
+	for _, route := range operatorRoutes {
+		required[route] = models.RoleOperator
+	}
+	for _, route := range adminRoutes {
+		required[route] = models.RoleAdmin
+	}
+
+	for _, route := range protectedRoutes(t) {
+		want, ok := required[route]
+		if !ok {
+			want = models.RoleViewer
+		}
+
+		for _, user := range testUsers {
+			expected := http.StatusForbidden
+			if user.Role.Includes(want) {
+				expected = http.StatusNoContent
+			}
+			if code := authorize(user, route, appService); code != expected {
+				t.Errorf("%s as %s: expected %d, got %d", route, user.Role, expected, code)
+			}
+		}
+	}
+}
+
+func TestPermissions_AppMembership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viewers := setupPermissionsDB(t, models.RoleViewer)
+	operator, admin := testUsers[1], testUsers[2]
+	outsider := &models.User{ID: 4, Username: "contractor", Role: models.RoleOperator}
+
+	for _, route := range protectedRoutes(t) {
+		permission := apiPermissions[route]
+		if permission.Resource == "" || permission.Role == models.RoleAdmin {
+			continue
+		}
+
+		// Operators that are viewers of the app can only read it
+		expected := http.StatusNoContent
+		if permission.Role == models.RoleOperator {
+			expected = http.StatusForbidden
+		}
+		if code := authorize(operator, route, viewers); code != expected {
+			t.Errorf("%s as viewer member: expected %d, got %d", route, expected, code)
+		}
+
+		// Users that are not members do not see the app
+		if code := authorize(outsider, route, viewers); code != http.StatusNotFound {
+			t.Errorf("%s as non-member: expected 404, got %d", route, code)
+		}
+
+		// Admins have access to every app
+		if code := authorize(admin, route, viewers); code != http.StatusNoContent {
+			t.Errorf("%s as admin: expected 204, got %d", route, code)
+		}
+	}
+}
+
+func TestPermissions_AppOperator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	operators := setupPermissionsDB(t, models.RoleOperator)
+	contractor := testUsers[0]
+
+	// A viewer that is an operator of the app can operate it, but keeps the viewer role elsewhere
+	for _, route := range protectedRoutes(t) {
+		permission := apiPermissions[route]
+		expected := http.StatusForbidden
+		if permission.Role == models.RoleViewer || (permission.Resource != "" && permission.Role == models.RoleOperator) {
+			expected = http.StatusNoContent
+		}
+		if code := authorize(contractor, route, operators); code != expected {
+			t.Errorf("%s as viewer with operator membership: expected %d, got %d", route, expected, code)
+		}
+	}
+
+	for _, route := range []string{"GET /terminal/ws", "POST /files/upload", "POST /containers/:id/exec", "POST /apps"} {
+		if code := authorize(contractor, route, operators); code != http.StatusForbidden {
+			t.Errorf("%s as viewer with operator membership: expected 403, got %d", route, code)
+		}
+	}
+}
+
+func TestPermissions_APITokenScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appService := setupPermissionsDB(t, "")
+
+	// A token of an admin with only the read scope may use reads and nothing else
+	user := &models.User{ID: 3, Username: "admin", Role: models.RoleAdmin, APIToken: &models.APIToken{
+		Name: "reader", Scopes: []models.APITokenScope{models.ScopeRead},
+	}}
+
+	for _, route := range protectedRoutes(t) {
+		readable := false
+		for _, scope := range apiPermissions[route].Scopes {
+			readable = readable || scope == models.ScopeRead
+		}
+		if readable && !strings.HasPrefix(route, http.MethodGet+" ") {
+			t.Errorf("%s changes state but allows the read scope", route)
+		}
+
+		expected := http.StatusForbidden
+		if readable {
+			expected = http.StatusNoContent
+		}
+		if code := authorize(user, route, appService); code != expected {
+			t.Errorf("%s with a read token: expected %d, got %d", route, expected, code)
+		}
+	}
+}
+
+// samplePath fills the parameters of a route path.
+func samplePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "1"
+		}
+	}
+	return strings.Join(parts, "/")
+}
@@ -47,6 +47,9 @@ WorkingDirectory={{.WorkingDir}}
 ExecStart={{.ExecPath}} -config {{.ConfigPath}}
 Restart=always
 RestartSec=5
+# Signal only the server on stop so running commands can finish within execution.drain_timeout
+KillMode=mixed
+TimeoutStopSec=120
 StandardOutput=journal
 StandardError=journal
 
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrAPITokenNotFound indicates the requested API token was not found.
+	ErrAPITokenNotFound = errors.New("API token not found")
+	// ErrAPITokenExists indicates the user already has an API token with the same name.
+	ErrAPITokenExists = errors.New("API token already exists")
+	// ErrInvalidAPIToken indicates the name, scopes or expiry of a new API token are invalid.
+	ErrInvalidAPIToken = errors.New("invalid API token")
+)
+
+// apiTokenPrefix marks generated personal API tokens, as deployTokenPrefix does for deploy tokens.
+const apiTokenPrefix = "hrp_"
+
+// defaultAPITokenLifetime is the lifetime of API tokens created without an expiry.
+const defaultAPITokenLifetime = 90 * 24 * time.Hour
+
+// apiTokenColumns lists the api_tokens columns read by scanAPIToken, in order.
+const apiTokenColumns = `id, user_id, name, token_prefix, scopes, expires_at, last_used_at, COALESCE(last_used_ip, ''), created_at`
+
+// CreateAPIToken creates a personal API token for a user.
+// The returned token is the only one that contains the plaintext.
+func (s *AuthService) CreateAPIToken(userID int64, req *models.CreateAPITokenRequest) (*models.APIToken, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAPIToken)
+	}
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []models.APITokenScope{}
+	}
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIToken, scope)
+		}
+	}
+	expiresAt := time.Now().Add(defaultAPITokenLifetime)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIToken)
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := apiTokenPrefix + hex.EncodeToString(secret)
+
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	_, err = s.db.Exec(
+		"INSERT INTO api_tokens (id, user_id, name, token_hash, token_prefix, scopes, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		id, userID, name, hashToken(token), token[:len(apiTokenPrefix)+8], string(scopesJSON), expiresAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrAPITokenExists
+		}
+		return nil, err
+	}
+
+	created, err := s.getAPIToken(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	created.Token = token
+	return created, nil
+}
+
+// ListAPITokens returns the API tokens of a user, without their plaintext.
+func (s *AuthService) ListAPITokens(userID int64) ([]models.APIToken, error) {
+	rows, err := s.db.Query("SELECT "+apiTokenColumns+" FROM api_tokens WHERE user_id = ? ORDER BY created_at, name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// getAPIToken returns an API token of a user, without its plaintext.
+func (s *AuthService) getAPIToken(userID int64, id string) (*models.APIToken, error) {
+	token, err := scanAPIToken(s.db.QueryRow("SELECT "+apiTokenColumns+" FROM api_tokens WHERE user_id = ? AND id = ?", userID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAPITokenNotFound
+	}
+	return token, err
+}
+
+// RevokeAPIToken deletes an API token of a user and returns it.
+func (s *AuthService) RevokeAPIToken(userID int64, id string) (*models.APIToken, error) {
+	token, err := s.getAPIToken(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// AuthenticateAPIToken returns the owner of an unexpired API token, with APIToken set, and records its use from ip.
+func (s *AuthService) AuthenticateAPIToken(token, ip string) (*models.User, error) {
+	apiToken, err := scanAPIToken(s.db.QueryRow("SELECT "+apiTokenColumns+" FROM api_tokens WHERE token_hash = ?", hashToken(token)))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !apiToken.ExpiresAt.After(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+
+	user, err := s.GetUserByID(apiToken.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	_, _ = s.db.Exec("UPDATE api_tokens SET last_used_at = ?, last_used_ip = ? WHERE id = ?", now, ip, apiToken.ID)
+	apiToken.LastUsedAt = &now
+	apiToken.LastUsedIP = ip
+
+	user.APIToken = apiToken
+	return user, nil
+}
+
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &scopes, &token.ExpiresAt, &lastUsedAt,
+		&token.LastUsedIP, &token.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &token.Scopes); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
@@ -0,0 +1,84 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestAuthService_APITokens(t *testing.T) {
+	db, sqlDB, cfg := setupAuthTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	authSvc := services.NewAuthService(db, cfg, nil)
+	user, _ := authSvc.CreateUserWithRole("scripter", "password123", models.RoleOperator)
+
+	token, err := authSvc.CreateAPIToken(user.ID, &models.CreateAPITokenRequest{
+		Name:   "ci",
+		Scopes: []models.APITokenScope{models.ScopeRead, models.ScopeExecute},
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if token.Token == "" || token.Token[:len(token.Prefix)] != token.Prefix {
+		t.Errorf("expected the plaintext with its prefix, got %+v", token)
+	}
+	if until := time.Until(token.ExpiresAt); until < 89*24*time.Hour || until > 91*24*time.Hour {
+		t.Errorf("expected the token to expire in 90 days, got %s", token.ExpiresAt)
+	}
+
+	// Invalid requests
+	past := time.Now().Add(-time.Hour)
+	for name, req := range map[string]*models.CreateAPITokenRequest{
+		"duplicate name":     {Name: "ci"},
+		"unknown scope":      {Name: "x", Scopes: []models.APITokenScope{"root"}},
+		"expiry in the past": {Name: "x", ExpiresAt: &past},
+	} {
+		if _, err := authSvc.CreateAPIToken(user.ID, req); err != services.ErrAPITokenExists && !errors.Is(err, services.ErrInvalidAPIToken) {
+			t.Errorf("%s: expected an error, got %v", name, err)
+		}
+	}
+
+	// Authentication returns the owner with the token and records its use
+	authenticated, err := authSvc.AuthenticateAPIToken(token.Token, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if authenticated.ID != user.ID || authenticated.Role != models.RoleOperator || authenticated.APIToken == nil {
+		t.Fatalf("unexpected user: %+v", authenticated)
+	}
+	if !authenticated.APIToken.Allows(models.ScopeExecute) || authenticated.APIToken.Allows(models.ScopeFiles) {
+		t.Errorf("unexpected scopes: %v", authenticated.APIToken.Scopes)
+	}
+	if name := authenticated.AuditName(); name != "scripter via token ci" {
+		t.Errorf("unexpected audit name %q", name)
+	}
+	tokens, _ := authSvc.ListAPITokens(user.ID)
+	if len(tokens) != 1 || tokens[0].Token != "" || tokens[0].LastUsedAt == nil || tokens[0].LastUsedIP != "10.0.0.1" {
+		t.Errorf("expected the last use without the plaintext, got %+v", tokens)
+	}
+
+	if _, err := authSvc.AuthenticateAPIToken("hrp_unknown", "10.0.0.1"); err != services.ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+	if _, err := sqlDB.Exec("UPDATE api_tokens SET expires_at = ?", past); err != nil {
+		t.Fatalf("failed to expire token: %v", err)
+	}
+	if _, err := authSvc.AuthenticateAPIToken(token.Token, "10.0.0.1"); err != services.ErrTokenExpired {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+
+	// Tokens can only be revoked by their owner
+	if _, err := authSvc.RevokeAPIToken(user.ID+1, token.ID); err != services.ErrAPITokenNotFound {
+		t.Errorf("expected ErrAPITokenNotFound for another user, got %v", err)
+	}
+	if _, err := authSvc.RevokeAPIToken(user.ID, token.ID); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if tokens, _ := authSvc.ListAPITokens(user.ID); len(tokens) != 0 {
+		t.Errorf("expected no tokens, got %d", len(tokens))
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrNotAppMember indicates the user is not a member of the app, directly or through a group.
+	ErrNotAppMember = errors.New("not a member of this app")
+	// ErrAppMemberNotFound indicates the requested app member was not found.
+	ErrAppMemberNotFound = errors.New("app member not found")
+	// ErrInvalidAppMember indicates the user, group or role of a new app member is invalid.
+	ErrInvalidAppMember = errors.New("invalid app member")
+)
+
+// memberAppIDs selects the IDs of the apps that a user is a member of, directly or through a group.
+// It takes the user ID twice.
+const memberAppIDs = `SELECT app_id FROM app_members
+	WHERE user_id = ? OR group_id IN (SELECT group_id FROM user_group_members WHERE user_id = ?)`
+
+// appMemberColumns lists the columns read by scanAppMember, in order, from app_members m joined with users u and user_groups g.
+const appMemberColumns = `m.id, m.app_id, m.user_id, m.group_id, m.role, m.created_at, COALESCE(u.username, g.name, '')`
+
+const appMemberTables = `app_members m
+	LEFT JOIN users u ON m.user_id = u.id
+	LEFT JOIN user_groups g ON m.group_id = g.id`
+
+// memberCondition returns an SQL condition that restricts appIDColumn to the apps user is a member of,
+// with its arguments. Admins and a nil user are not restricted.
+func memberCondition(user *models.User, appIDColumn string) (string, []interface{}) {
+	if user == nil || user.HasPermission(models.RoleAdmin) {
+		return "1 = 1", nil
+	}
+	return appIDColumn + " IN (" + memberAppIDs + ")", []interface{}{user.ID, user.ID}
+}
+
+// AppRole returns the role of user on an app: the highest role of its memberships, which may be above
+// or below the user's own role. Admins have the admin role on every app. It returns ErrNotAppMember if
+// the user is not a member.
+func (s *AppService) AppRole(user *models.User, appID string) (models.UserRole, error) {
+	if user.HasPermission(models.RoleAdmin) {
+		return models.RoleAdmin, nil
+	}
+
+	rows, err := s.db.Query(
+		"SELECT role FROM app_members WHERE app_id = ? AND (user_id = ? OR group_id IN (SELECT group_id FROM user_group_members WHERE user_id = ?))",
+		appID, user.ID, user.ID,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var role models.UserRole
+	for rows.Next() {
+		var memberRole models.UserRole
+		if err := rows.Scan(&memberRole); err != nil {
+			return "", err
+		}
+		if role == "" || memberRole.Includes(role) {
+			role = memberRole
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if role == "" {
+		return "", ErrNotAppMember
+	}
+	return role, nil
+}
+
+// ResourceAppID returns the ID of the app that a resource belongs to.
+// It returns ErrAppNotFound if the resource does not exist.
+func (s *AppService) ResourceAppID(resource models.AppResource, id string) (string, error) {
+	var query string
+	switch resource {
+	case models.ResourceApp:
+		query = "SELECT id FROM apps WHERE id = ?"
+	case models.ResourceCommand:
+		query = "SELECT app_id FROM commands WHERE id = ?"
+	case models.ResourceExecution:
+		query = "SELECT c.app_id FROM executions e JOIN commands c ON e.command_id = c.id WHERE e.id = ?"
+	case models.ResourceSchedule:
+		query = "SELECT c.app_id FROM command_schedules cs JOIN commands c ON cs.command_id = c.id WHERE cs.id = ?"
+	default:
+		return "", fmt.Errorf("unknown app resource %q", resource)
+	}
+
+	var appID string
+	err := s.db.QueryRow(query, id).Scan(&appID)
+	if err == sql.ErrNoRows {
+		return "", ErrAppNotFound
+	}
+	return appID, err
+}
+
+// ListAppMembers returns the users and groups that are members of an app.
+func (s *AppService) ListAppMembers(appID string) ([]models.AppMember, error) {
+	rows, err := s.db.Query("SELECT "+appMemberColumns+" FROM "+appMemberTables+" WHERE m.app_id = ? ORDER BY m.group_id IS NOT NULL, COALESCE(u.username, g.name)", appID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	members := []models.AppMember{}
+	for rows.Next() {
+		member, err := scanAppMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, *member)
+	}
+	return members, rows.Err()
+}
+
+// GetAppMember returns a member of an app.
+func (s *AppService) GetAppMember(appID, id string) (*models.AppMember, error) {
+	member, err := scanAppMember(s.db.QueryRow("SELECT "+appMemberColumns+" FROM "+appMemberTables+" WHERE m.app_id = ? AND m.id = ?", appID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAppMemberNotFound
+	}
+	return member, err
+}
+
+// AddAppMember makes a user or a group a member of an app with a role, or changes the role of an existing member.
+// Members can be viewers or operators; admins have access to every app.
+func (s *AppService) AddAppMember(appID string, req *models.AddAppMemberRequest) (*models.AppMember, error) {
+	if _, err := s.GetAppByID(appID); err != nil {
+		return nil, err
+	}
+	if (req.UserID == nil) == (req.GroupID == nil) {
+		return nil, fmt.Errorf("%w: either user_id or group_id is required", ErrInvalidAppMember)
+	}
+	if req.Role != models.RoleViewer && req.Role != models.RoleOperator {
+		return nil, fmt.Errorf("%w: role must be viewer or operator", ErrInvalidAppMember)
+	}
+
+	column, table, memberID := "user_id", "users", req.UserID
+	if req.GroupID != nil {
+		column, table, memberID = "group_id", "user_groups", req.GroupID
+	}
+
+	var exists int
+	// #nosec G202 - column and table are constants
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ?", *memberID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("%w: %s %d does not exist", ErrInvalidAppMember, column, *memberID)
+	}
+
+	var id string
+	// #nosec G202 - column is a constant
+	err := s.db.QueryRow("SELECT id FROM app_members WHERE app_id = ? AND "+column+" = ?", appID, *memberID).Scan(&id)
+	switch {
+	case err == sql.ErrNoRows:
+		id = uuid.New().String()
+		// #nosec G202 - column is a constant
+		_, err = s.db.Exec("INSERT INTO app_members (id, app_id, "+column+", role) VALUES (?, ?, ?, ?)", id, appID, *memberID, req.Role)
+	case err == nil:
+		_, err = s.db.Exec("UPDATE app_members SET role = ? WHERE id = ?", req.Role, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetAppMember(appID, id)
+}
+
+// RemoveAppMember removes a user or a group from an app.
+func (s *AppService) RemoveAppMember(appID, id string) error {
+	result, err := s.db.Exec("DELETE FROM app_members WHERE app_id = ? AND id = ?", appID, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrAppMemberNotFound
+	}
+	return nil
+}
+
+func scanAppMember(row rowScanner) (*models.AppMember, error) {
+	var member models.AppMember
+	var userID, groupID sql.NullInt64
+	if err := row.Scan(&member.ID, &member.AppID, &userID, &groupID, &member.Role, &member.CreatedAt, &member.Name); err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		member.UserID = &userID.Int64
+	}
+	if groupID.Valid {
+		member.GroupID = &groupID.Int64
+	}
+	return &member, nil
+}
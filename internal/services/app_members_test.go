@@ -0,0 +1,222 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestAppService_AppMembers(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	if _, err := sqlDB.Exec(`
+		INSERT INTO users (id, username, password_hash) VALUES (1, 'contractor', 'x'), (2, 'viewer', 'x'), (3, 'ops', 'x');
+		INSERT INTO user_groups (id, name) VALUES (1, 'ops-team');
+		INSERT INTO user_group_members (group_id, user_id) VALUES (1, 3);
+	`); err != nil {
+		t.Fatalf("failed to insert users: %v", err)
+	}
+	contractor := &models.User{ID: 1, Username: "contractor", Role: models.RoleOperator}
+	viewer := &models.User{ID: 2, Username: "viewer", Role: models.RoleViewer}
+	ops := &models.User{ID: 3, Username: "ops", Role: models.RoleOperator}
+	admin := &models.User{ID: 4, Username: "admin", Role: models.RoleAdmin}
+
+	web, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "web", WorkingDir: t.TempDir()})
+	worker, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "worker", WorkingDir: t.TempDir()})
+	deploy, _ := appSvc.CreateCommand(web.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true"})
+	migrate, _ := appSvc.CreateCommand(worker.ID, &models.CreateCommandRequest{Name: "migrate", Command: "true"})
+	webExecution, _ := execSvc.CreateExecution(deploy.ID, 1)
+	_, _ = execSvc.CreateExecution(migrate.ID, 3)
+
+	userID := func(id int64) *int64 { return &id }
+
+	// Invalid members are rejected
+	for name, req := range map[string]*models.AddAppMemberRequest{
+		"no member":    {Role: models.RoleViewer},
+		"both":         {UserID: userID(1), GroupID: userID(1), Role: models.RoleViewer},
+		"admin role":   {UserID: userID(1), Role: models.RoleAdmin},
+		"unknown user": {UserID: userID(99), Role: models.RoleViewer},
+	} {
+		if _, err := appSvc.AddAppMember(web.ID, req); !errors.Is(err, services.ErrInvalidAppMember) {
+			t.Errorf("%s: expected ErrInvalidAppMember, got %v", name, err)
+		}
+	}
+
+	// Adding an existing member changes its role
+	if _, err := appSvc.AddAppMember(web.ID, &models.AddAppMemberRequest{UserID: userID(1), Role: models.RoleViewer}); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	member, err := appSvc.AddAppMember(web.ID, &models.AddAppMemberRequest{UserID: userID(1), Role: models.RoleOperator})
+	if err != nil {
+		t.Fatalf("failed to update member: %v", err)
+	}
+	if member.Name != "contractor" || member.Role != models.RoleOperator {
+		t.Errorf("expected contractor as operator, got %s as %s", member.Name, member.Role)
+	}
+	_, _ = appSvc.AddAppMember(web.ID, &models.AddAppMemberRequest{UserID: userID(2), Role: models.RoleOperator})
+	_, _ = appSvc.AddAppMember(worker.ID, &models.AddAppMemberRequest{GroupID: userID(1), Role: models.RoleOperator})
+
+	members, err := appSvc.ListAppMembers(web.ID)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("expected 2 members, got %d (err=%v)", len(members), err)
+	}
+
+	// Roles come from direct and group memberships, whatever the user's own role
+	roles := []struct {
+		user  *models.User
+		app   string
+		want  models.UserRole
+		err   error
+		label string
+	}{
+		{contractor, web.ID, models.RoleOperator, nil, "direct member"},
+		{contractor, worker.ID, "", services.ErrNotAppMember, "non-member"},
+		{viewer, web.ID, models.RoleOperator, nil, "member role above own role"},
+		{ops, worker.ID, models.RoleOperator, nil, "group member"},
+		{admin, worker.ID, models.RoleAdmin, nil, "admin"},
+	}
+	for _, tt := range roles {
+		role, err := appSvc.AppRole(tt.user, tt.app)
+		if role != tt.want || err != tt.err {
+			t.Errorf("%s: expected %q (err=%v), got %q (err=%v)", tt.label, tt.want, tt.err, role, err)
+		}
+	}
+
+	// Lists only contain the apps of the user's memberships
+	for _, tt := range []struct {
+		user *models.User
+		want int
+	}{{contractor, 1}, {ops, 1}, {admin, 2}, {nil, 2}} {
+		apps, err := appSvc.GetAllApps(tt.user)
+		if err != nil || len(apps) != tt.want {
+			t.Errorf("expected %d apps, got %d (err=%v)", tt.want, len(apps), err)
+		}
+		executions, err := execSvc.ListExecutions(services.ExecutionFilter{Member: tt.user})
+		if err != nil || len(executions) != tt.want {
+			t.Errorf("expected %d executions, got %d (err=%v)", tt.want, len(executions), err)
+		}
+	}
+
+	// Resources resolve to their app
+	if appID, err := appSvc.ResourceAppID(models.ResourceExecution, webExecution.ID); err != nil || appID != web.ID {
+		t.Errorf("expected execution to belong to %s, got %s (err=%v)", web.ID, appID, err)
+	}
+	if _, err := appSvc.ResourceAppID(models.ResourceCommand, "missing"); err != services.ErrAppNotFound {
+		t.Errorf("expected ErrAppNotFound, got %v", err)
+	}
+
+	// Removing a member revokes access
+	if err := appSvc.RemoveAppMember(web.ID, member.ID); err != nil {
+		t.Fatalf("failed to remove member: %v", err)
+	}
+	if _, err := appSvc.AppRole(contractor, web.ID); err != services.ErrNotAppMember {
+		t.Errorf("expected ErrNotAppMember after removal, got %v", err)
+	}
+	if err := appSvc.RemoveAppMember(web.ID, member.ID); err != services.ErrAppMemberNotFound {
+		t.Errorf("expected ErrAppMemberNotFound, got %v", err)
+	}
+}
+
+func TestAuthService_Groups(t *testing.T) {
+	db, sqlDB, cfg := setupAuthTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	authSvc := services.NewAuthService(db, cfg, nil)
+	alice, _ := authSvc.CreateUserWithRole("alice", "password123", models.RoleOperator)
+	bob, _ := authSvc.CreateUserWithRole("bob", "password123", models.RoleViewer)
+
+	group, err := authSvc.CreateGroup(&models.GroupRequest{Name: "contractors", UserIDs: []int64{alice.ID}})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	if len(group.UserIDs) != 1 || group.UserIDs[0] != alice.ID {
+		t.Errorf("expected alice as the only member, got %v", group.UserIDs)
+	}
+
+	if _, err := authSvc.CreateGroup(&models.GroupRequest{Name: "contractors"}); err != services.ErrGroupExists {
+		t.Errorf("expected ErrGroupExists, got %v", err)
+	}
+	if _, err := authSvc.CreateGroup(&models.GroupRequest{Name: "ghosts", UserIDs: []int64{99}}); !errors.Is(err, services.ErrInvalidGroup) {
+		t.Errorf("expected ErrInvalidGroup, got %v", err)
+	}
+
+	group, err = authSvc.UpdateGroup(group.ID, &models.GroupRequest{Name: "vendors", UserIDs: []int64{bob.ID}})
+	if err != nil {
+		t.Fatalf("failed to update group: %v", err)
+	}
+	if group.Name != "vendors" || len(group.UserIDs) != 1 || group.UserIDs[0] != bob.ID {
+		t.Errorf("expected vendors with bob, got %s with %v", group.Name, group.UserIDs)
+	}
+
+	if err := authSvc.DeleteGroup(group.ID); err != nil {
+		t.Fatalf("failed to delete group: %v", err)
+	}
+	if _, err := authSvc.GetGroup(group.ID); err != services.ErrGroupNotFound {
+		t.Errorf("expected ErrGroupNotFound, got %v", err)
+	}
+	if groups, _ := authSvc.ListGroups(); len(groups) != 0 {
+		t.Errorf("expected no groups, got %d", len(groups))
+	}
+}
+
+func TestAuditService_MemberLogs(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+	auditSvc := services.NewAuditService(db)
+
+	if _, err := sqlDB.Exec("INSERT INTO users (id, username, password_hash) VALUES (1, 'contractor', 'x'), (2, 'ops', 'x')"); err != nil {
+		t.Fatalf("failed to insert users: %v", err)
+	}
+	contractor := &models.User{ID: 1, Username: "contractor", Role: models.RoleViewer}
+	admin := &models.User{ID: 3, Username: "admin", Role: models.RoleAdmin}
+
+	web, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "web", WorkingDir: t.TempDir()})
+	worker, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "worker", WorkingDir: t.TempDir()})
+	deploy, _ := appSvc.CreateCommand(web.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true"})
+	migrate, _ := appSvc.CreateCommand(worker.ID, &models.CreateCommandRequest{Name: "migrate", Command: "true"})
+	webExecution, _ := execSvc.CreateExecution(deploy.ID, 2)
+	workerExecution, _ := execSvc.CreateExecution(migrate.ID, 2)
+	contractorID := int64(1)
+	_, _ = appSvc.AddAppMember(web.ID, &models.AddAppMemberRequest{UserID: &contractorID, Role: models.RoleOperator})
+
+	opsID := int64(2)
+	for _, entry := range []services.AuditLog{
+		{UserID: &opsID, Username: "ops", Action: "update", ResourceType: "app", ResourceID: web.ID},
+		{UserID: &opsID, Username: "ops", Action: "update", ResourceType: "app", ResourceID: worker.ID},
+		{UserID: &opsID, Username: "ops", Action: "execute", ResourceType: "command", ResourceID: migrate.ID},
+		{Username: "webhook:github", Action: "execute", ResourceType: "execution", ResourceID: webExecution.ID},
+		{Username: "webhook:github", Action: "execute", ResourceType: "execution", ResourceID: workerExecution.ID},
+		{UserID: &opsID, Username: "ops", Action: "terminal_connect", ResourceType: "terminal", ResourceID: "s1"},
+		{UserID: &contractorID, Username: "contractor", Action: "login_success", ResourceType: "auth"},
+	} {
+		if err := auditSvc.Log(entry); err != nil {
+			t.Fatalf("failed to log: %v", err)
+		}
+	}
+
+	// Members only see the entries of their apps and their own entries
+	logs, err := auditSvc.GetMemberLogs(contractor, 50, 0)
+	if err != nil {
+		t.Fatalf("failed to get logs: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, log := range logs {
+		seen[log.ResourceType+":"+log.ResourceID] = true
+	}
+	if len(logs) != 3 || !seen["app:"+web.ID] || !seen["execution:"+webExecution.ID] || !seen["auth:"] {
+		t.Errorf("expected the web app, its execution and the own login, got %v", seen)
+	}
+
+	if logs, _ := auditSvc.GetMemberLogs(admin, 50, 0); len(logs) != 7 {
+		t.Errorf("expected admins to see 7 entries, got %d", len(logs))
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// ErrNotAwaitingApproval is returned when approving or rejecting an execution that is not awaiting approval.
+var ErrNotAwaitingApproval = errors.New("execution is not awaiting approval")
+
+// ErrSelfApproval is returned when a user reviews an execution they started.
+var ErrSelfApproval = errors.New("execution must be reviewed by a different user")
+
+// ErrApprovalExpired is returned when approving an execution whose approval has expired.
+var ErrApprovalExpired = errors.New("approval has expired")
+
+// approvalExpiredComment is recorded as the review comment of executions whose approval expired.
+const approvalExpiredComment = "approval expired"
+
+// Approve queues an execution that awaits approval on behalf of reviewer, who must not be the user
+// who started it. The caller runs the approved execution with Execute.
+func (s *ExecutorService) Approve(executionID string, reviewer *models.User, comment string) (*models.Execution, error) {
+	execution, err := s.reviewable(executionID, reviewer)
+	if err != nil {
+		return nil, err
+	}
+
+	command, err := s.appService.GetCommandByID(execution.CommandID)
+	if err != nil {
+		return nil, err
+	}
+	app, err := s.appService.GetAppByID(command.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.admit(app, executionID, func() error {
+		return s.review(executionID, models.StatusPending, reviewer.Username, comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Executor] Execution %s approved by %s", executionID, reviewer.Username)
+	return s.GetExecutionByID(executionID)
+}
+
+// Reject finishes an execution that awaits approval without running it, on behalf of reviewer,
+// who must not be the user who started it.
+func (s *ExecutorService) Reject(executionID string, reviewer *models.User, comment string) (*models.Execution, error) {
+	if _, err := s.reviewable(executionID, reviewer); err != nil {
+		return nil, err
+	}
+
+	if err := s.review(executionID, models.StatusRejected, reviewer.Username, comment); err != nil {
+		return nil, err
+	}
+	s.finishRejected(executionID)
+
+	log.Printf("[Executor] Execution %s rejected by %s", executionID, reviewer.Username)
+	return s.GetExecutionByID(executionID)
+}
+
+// ExpireApprovals rejects executions whose approval expired before now and returns how many were rejected.
+func (s *ExecutorService) ExpireApprovals(now time.Time) int {
+	rows, err := s.db.Query(
+		"SELECT id FROM executions WHERE status = ? AND approval_expires_at IS NOT NULL AND approval_expires_at <= ?",
+		models.StatusAwaitingApproval, now,
+	)
+	if err != nil {
+		log.Printf("[Executor] Failed to list expired approvals: %v", err)
+		return 0
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	_ = rows.Close()
+
+	expired := 0
+	for _, id := range ids {
+		if s.expire(id) == nil {
+			expired++
+		}
+	}
+	return expired
+}
+
+// reviewable returns an execution that reviewer may approve or reject.
+// An execution whose approval expired is rejected on the way.
+func (s *ExecutorService) reviewable(executionID string, reviewer *models.User) (*models.Execution, error) {
+	execution, err := s.GetExecutionByID(executionID)
+	if err != nil {
+		return nil, err
+	}
+	if execution.Status != models.StatusAwaitingApproval {
+		return nil, ErrNotAwaitingApproval
+	}
+	if execution.UserID == reviewer.ID {
+		return nil, ErrSelfApproval
+	}
+	if execution.ApprovalExpiresAt != nil && !execution.ApprovalExpiresAt.After(time.Now()) {
+		if err := s.expire(executionID); err != nil && err != ErrNotAwaitingApproval {
+			return nil, err
+		}
+		return nil, ErrApprovalExpired
+	}
+	return execution, nil
+}
+
+// expire rejects an execution whose approval expired.
+func (s *ExecutorService) expire(executionID string) error {
+	if err := s.review(executionID, models.StatusRejected, "", approvalExpiredComment); err != nil {
+		return err
+	}
+	s.finishRejected(executionID)
+	log.Printf("[Executor] Approval of execution %s expired", executionID)
+	return nil
+}
+
+// review records the decision on an execution that is still awaiting approval.
+func (s *ExecutorService) review(executionID string, status models.ExecutionStatus, reviewedBy, comment string) error {
+	result, err := s.db.Exec(
+		"UPDATE executions SET status = ?, reviewed_by = ?, reviewed_at = ?, review_comment = ? WHERE id = ? AND status = ?",
+		status, nullString(reviewedBy), time.Now(), nullString(comment), executionID, models.StatusAwaitingApproval,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotAwaitingApproval
+	}
+	return nil
+}
+
+// finishRejected completes a rejected execution for notifications and stream subscribers.
+func (s *ExecutorService) finishRejected(executionID string) {
+	s.finishExecution(executionID, models.StatusRejected, "", -1)
+	s.broadcastComplete(executionID, -1, models.StatusRejected)
+}
@@ -0,0 +1,155 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestExecutorService_Approval(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Gated", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:             "deploy",
+		Command:          "echo deployed",
+		RequiresApproval: true,
+	})
+
+	execution, err := execSvc.CreateExecution(cmd.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	if execution.Status != models.StatusAwaitingApproval || execution.QueuePosition != 0 {
+		t.Fatalf("expected the execution to await approval outside the queue, got %s at %d", execution.Status, execution.QueuePosition)
+	}
+
+	// Running it without approval does nothing
+	if err := execSvc.Execute(execution.ID); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if waiting, _ := execSvc.GetExecutionByID(execution.ID); waiting.Status != models.StatusAwaitingApproval {
+		t.Errorf("expected the execution to keep awaiting approval, got %s", waiting.Status)
+	}
+
+	// The user who started it cannot approve it
+	if _, err := execSvc.Approve(execution.ID, &models.User{ID: 1, Username: "author"}, ""); err != services.ErrSelfApproval {
+		t.Errorf("expected ErrSelfApproval, got %v", err)
+	}
+
+	approved, err := execSvc.Approve(execution.ID, &models.User{ID: 2, Username: "reviewer"}, "looks good")
+	if err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+	if approved.Status != models.StatusPending || approved.ReviewedBy != "reviewer" || approved.ReviewComment != "looks good" || approved.ReviewedAt == nil {
+		t.Errorf("unexpected approved execution: %+v", approved)
+	}
+	if _, err := execSvc.Reject(execution.ID, &models.User{ID: 3, Username: "late"}, ""); err != services.ErrNotAwaitingApproval {
+		t.Errorf("expected ErrNotAwaitingApproval after the approval, got %v", err)
+	}
+
+	if err := execSvc.Execute(execution.ID); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	finished, _ := execSvc.GetExecutionByID(execution.ID)
+	if finished.Status != models.StatusSuccess || !strings.Contains(finished.Output, "deployed") {
+		t.Errorf("expected the approved execution to run, got %s: %q", finished.Status, finished.Output)
+	}
+
+	// Skipping approval, as re-runs of interrupted executions do
+	skipped, err := execSvc.CreateExecutionWithOptions(cmd.ID, 1, services.ExecutionOptions{SkipApproval: true})
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	if skipped.Status != models.StatusPending {
+		t.Errorf("expected the execution to skip approval, got %s", skipped.Status)
+	}
+	_ = execSvc.Execute(skipped.ID)
+}
+
+func TestExecutorService_RejectApproval(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	// Approval required by the app applies to all of its commands
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Gated app", WorkingDir: t.TempDir(), RequiresApproval: true})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "deploy", Command: "echo deployed"})
+
+	execution, _ := execSvc.CreateExecution(cmd.ID, 1)
+	ch := execSvc.Subscribe(execution.ID)
+	defer execSvc.Unsubscribe(execution.ID, ch)
+
+	rejected, err := execSvc.Reject(execution.ID, &models.User{ID: 2, Username: "reviewer"}, "not today")
+	if err != nil {
+		t.Fatalf("failed to reject: %v", err)
+	}
+	if rejected.Status != models.StatusRejected || rejected.FinishedAt == nil || rejected.ReviewComment != "not today" {
+		t.Errorf("unexpected rejected execution: %+v", rejected)
+	}
+
+	select {
+	case msg := <-ch:
+		if msg != "complete:"+string(models.StatusRejected) {
+			t.Errorf("expected subscribers to be told about the rejection, got %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected subscribers to be told about the rejection")
+	}
+
+	if _, err := execSvc.Approve(execution.ID, &models.User{ID: 2, Username: "reviewer"}, ""); err != services.ErrNotAwaitingApproval {
+		t.Errorf("expected ErrNotAwaitingApproval after the rejection, got %v", err)
+	}
+}
+
+func TestExecutorService_ApprovalExpiry(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.ApprovalTimeout = "1h"
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Expiring", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true", RequiresApproval: true})
+
+	first, _ := execSvc.CreateExecution(cmd.ID, 1)
+	second, _ := execSvc.CreateExecution(cmd.ID, 1)
+	if first.ApprovalExpiresAt == nil || first.ApprovalExpiresAt.Sub(time.Now()) < 59*time.Minute {
+		t.Fatalf("expected the approval to expire in an hour, got %v", first.ApprovalExpiresAt)
+	}
+
+	// Nothing has expired yet
+	if n := execSvc.ExpireApprovals(time.Now()); n != 0 {
+		t.Errorf("expected no expired approvals, got %d", n)
+	}
+
+	// Reviewing an expired execution rejects it
+	if _, err := sqlDB.Exec("UPDATE executions SET approval_expires_at = ? WHERE id = ?", time.Now().Add(-time.Minute), first.ID); err != nil {
+		t.Fatalf("failed to backdate approval: %v", err)
+	}
+	if _, err := execSvc.Approve(first.ID, &models.User{ID: 2, Username: "reviewer"}, ""); err != services.ErrApprovalExpired {
+		t.Errorf("expected ErrApprovalExpired, got %v", err)
+	}
+	if expired, _ := execSvc.GetExecutionByID(first.ID); expired.Status != models.StatusRejected {
+		t.Errorf("expected the expired execution to be rejected, got %s", expired.Status)
+	}
+
+	// The scheduler loop rejects the others
+	if n := execSvc.ExpireApprovals(time.Now().Add(2 * time.Hour)); n != 1 {
+		t.Errorf("expected 1 expired approval, got %d", n)
+	}
+	if expired, _ := execSvc.GetExecutionByID(second.ID); expired.Status != models.StatusRejected || expired.ReviewComment != "approval expired" {
+		t.Errorf("expected the second execution to expire, got %s (%q)", expired.Status, expired.ReviewComment)
+	}
+}
@@ -3,6 +3,7 @@ package services
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -21,6 +22,18 @@ var (
 	ErrCommandNotFound = errors.New("command not found")
 	// ErrInvalidToken indicates the provided authentication token is invalid.
 	ErrInvalidToken = errors.New("invalid token")
+	// ErrInvalidPipelineStep indicates a pipeline step references a command that cannot be used as a step.
+	ErrInvalidPipelineStep = errors.New("pipeline steps must reference non-pipeline commands of the same app")
+	// ErrInvalidCommand indicates a command has neither or both a shell command and pipeline steps.
+	ErrInvalidCommand = errors.New("command must have either a shell command or pipeline steps")
+	// ErrInvalidRunAs indicates the run_as user or group of an app does not exist on this host.
+	ErrInvalidRunAs = errors.New("invalid run_as user or group")
+	// ErrInvalidResourceLimits indicates a resource limit is negative.
+	ErrInvalidResourceLimits = errors.New("resource limits must not be negative")
+	// ErrInvalidGitSource indicates the git repository URL or default ref of an app is invalid.
+	ErrInvalidGitSource = errors.New("invalid git source")
+	// ErrInvalidWebhookFilter indicates a webhook branch or tag pattern is malformed.
+	ErrInvalidWebhookFilter = errors.New("invalid webhook filter")
 )
 
 // AppService manages applications and their commands.
@@ -33,81 +46,115 @@ func NewAppService(db *database.DB) *AppService {
 	return &AppService{db: db}
 }
 
-// CreateApp creates a new application with a generated token.
+// appColumns lists the apps columns read by scanApp, in order.
+const appColumns = "id, name, description, working_dir, concurrency_policy, run_as_user, run_as_group, resource_limits, git_repo_url, git_ref, COALESCE(git_deploy_key, '') != '', webhook_branches, webhook_tags, COALESCE(webhook_secret, '') != '', requires_approval, created_at, updated_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanApp scans a row selected with appColumns, followed by any extra columns.
+func scanApp(row rowScanner, extra ...interface{}) (*models.App, error) {
+	var app models.App
+	var limits, branches, tags sql.NullString
+	dest := []interface{}{&app.ID, &app.Name, &app.Description, &app.WorkingDir, &app.ConcurrencyPolicy, &app.RunAsUser, &app.RunAsGroup, &limits, &app.GitRepoURL, &app.GitRef, &app.HasDeployKey, &branches, &tags, &app.HasWebhookSecret, &app.RequiresApproval, &app.CreatedAt, &app.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if limits.Valid && limits.String != "" {
+		if err := json.Unmarshal([]byte(limits.String), &app.ResourceLimits); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []struct {
+		column sql.NullString
+		dest   *[]string
+	}{{branches, &app.WebhookBranches}, {tags, &app.WebhookTags}} {
+		if f.column.Valid && f.column.String != "" {
+			if err := json.Unmarshal([]byte(f.column.String), f.dest); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &app, nil
+}
+
+// CreateApp creates a new application with a default deploy token.
+// The returned app holds the plaintext of the token in Token.
 func (s *AppService) CreateApp(req *models.CreateAppRequest) (*models.App, error) {
 	id := uuid.New().String()
-	token := uuid.New().String()
 
-	_, err := s.db.Exec(
-		"INSERT INTO apps (id, name, description, working_dir, token) VALUES (?, ?, ?, ?, ?)",
-		id, req.Name, req.Description, req.WorkingDir, token,
+	policy := req.ConcurrencyPolicy
+	if policy == "" {
+		policy = models.ConcurrencyQueue
+	}
+
+	if err := validateRunAs(req.RunAsUser, req.RunAsGroup); err != nil {
+		return nil, err
+	}
+	limits, err := marshalResourceLimits(req.ResourceLimits)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateGitSource(req.GitRepoURL, req.GitRef); err != nil {
+		return nil, err
+	}
+	branches, err := marshalWebhookFilter(req.WebhookBranches)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := marshalWebhookFilter(req.WebhookTags)
+	if err != nil {
+		return nil, err
+	}
+
+	// apps.token is unique, it holds the app ID until RegenerateToken stores the hash of the default deploy token
+	_, err = s.db.Exec(
+		"INSERT INTO apps (id, name, description, working_dir, token, concurrency_policy, run_as_user, run_as_group, resource_limits, git_repo_url, git_ref, webhook_branches, webhook_tags, requires_approval) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, req.Name, req.Description, req.WorkingDir, id, policy, req.RunAsUser, req.RunAsGroup, limits, req.GitRepoURL, req.GitRef, branches, tags, req.RequiresApproval,
 	)
 	if err != nil {
 		return nil, ErrAppExists
 	}
 
-	return s.GetAppByID(id)
+	return s.RegenerateToken(id)
 }
 
 // GetAppByID retrieves an application by its ID.
 func (s *AppService) GetAppByID(id string) (*models.App, error) {
-	var app models.App
-	err := s.db.QueryRow(
-		"SELECT id, name, description, working_dir, token, created_at, updated_at FROM apps WHERE id = ?",
-		id,
-	).Scan(&app.ID, &app.Name, &app.Description, &app.WorkingDir, &app.Token, &app.CreatedAt, &app.UpdatedAt)
-
+	app, err := scanApp(s.db.QueryRow("SELECT "+appColumns+" FROM apps WHERE id = ?", id))
 	if err == sql.ErrNoRows {
 		return nil, ErrAppNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &app, nil
-}
-
-// GetAppByToken retrieves an application by its authentication token.
-func (s *AppService) GetAppByToken(token string) (*models.App, error) {
-	var app models.App
-	err := s.db.QueryRow(
-		"SELECT id, name, description, working_dir, token, created_at, updated_at FROM apps WHERE token = ?",
-		token,
-	).Scan(&app.ID, &app.Name, &app.Description, &app.WorkingDir, &app.Token, &app.CreatedAt, &app.UpdatedAt)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrInvalidToken
-	}
-	if err != nil {
-		return nil, err
-	}
-	return &app, nil
+	return app, nil
 }
 
 // GetAppByName retrieves an application by its name.
 func (s *AppService) GetAppByName(name string) (*models.App, error) {
-	var app models.App
-	err := s.db.QueryRow(
-		"SELECT id, name, description, working_dir, token, created_at, updated_at FROM apps WHERE name = ?",
-		name,
-	).Scan(&app.ID, &app.Name, &app.Description, &app.WorkingDir, &app.Token, &app.CreatedAt, &app.UpdatedAt)
-
+	app, err := scanApp(s.db.QueryRow("SELECT "+appColumns+" FROM apps WHERE name = ?", name))
 	if err == sql.ErrNoRows {
 		return nil, ErrAppNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &app, nil
+	return app, nil
 }
 
-// GetAllApps retrieves all applications ordered by name with command counts.
-func (s *AppService) GetAllApps() ([]models.App, error) {
+// GetAllApps retrieves the applications that user is a member of ordered by name with command counts.
+// Admins, and a nil user, get all applications.
+func (s *AppService) GetAllApps(user *models.User) ([]models.App, error) {
+	condition, args := memberCondition(user, "a.id")
 	rows, err := s.db.Query(`
-		SELECT a.id, a.name, a.description, a.working_dir, a.token, a.created_at, a.updated_at,
+		SELECT `+appColumns+`,
 		       (SELECT COUNT(*) FROM commands c WHERE c.app_id = a.id) as command_count
 		FROM apps a
+		WHERE `+condition+`
 		ORDER BY a.name
-	`)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -115,11 +162,13 @@ func (s *AppService) GetAllApps() ([]models.App, error) {
 
 	var apps []models.App
 	for rows.Next() {
-		var app models.App
-		if err := rows.Scan(&app.ID, &app.Name, &app.Description, &app.WorkingDir, &app.Token, &app.CreatedAt, &app.UpdatedAt, &app.CommandCount); err != nil {
+		var commandCount int
+		app, err := scanApp(rows, &commandCount)
+		if err != nil {
 			return nil, err
 		}
-		apps = append(apps, app)
+		app.CommandCount = commandCount
+		apps = append(apps, *app)
 	}
 	return apps, nil
 }
@@ -140,29 +189,56 @@ func (s *AppService) UpdateApp(id string, req *models.UpdateAppRequest) (*models
 	if req.WorkingDir != "" {
 		app.WorkingDir = req.WorkingDir
 	}
+	if req.ConcurrencyPolicy != "" {
+		app.ConcurrencyPolicy = req.ConcurrencyPolicy
+	}
+	if req.RunAsUser != nil {
+		app.RunAsUser = *req.RunAsUser
+	}
+	if req.RunAsGroup != nil {
+		app.RunAsGroup = *req.RunAsGroup
+	}
+	if req.ResourceLimits != nil {
+		app.ResourceLimits = *req.ResourceLimits
+	}
+	if req.GitRepoURL != nil {
+		app.GitRepoURL = *req.GitRepoURL
+	}
+	if req.GitRef != nil {
+		app.GitRef = *req.GitRef
+	}
+	if req.WebhookBranches != nil {
+		app.WebhookBranches = req.WebhookBranches
+	}
+	if req.WebhookTags != nil {
+		app.WebhookTags = req.WebhookTags
+	}
+	if req.RequiresApproval != nil {
+		app.RequiresApproval = *req.RequiresApproval
+	}
 
-	_, err = s.db.Exec(
-		"UPDATE apps SET name = ?, description = ?, working_dir = ?, updated_at = ? WHERE id = ?",
-		app.Name, app.Description, app.WorkingDir, time.Now(), id,
-	)
+	if err := validateRunAs(app.RunAsUser, app.RunAsGroup); err != nil {
+		return nil, err
+	}
+	limits, err := marshalResourceLimits(app.ResourceLimits)
 	if err != nil {
 		return nil, err
 	}
-
-	return s.GetAppByID(id)
-}
-
-// RegenerateToken generates a new authentication token for an application.
-func (s *AppService) RegenerateToken(id string) (*models.App, error) {
-	_, err := s.GetAppByID(id)
+	if err := validateGitSource(app.GitRepoURL, app.GitRef); err != nil {
+		return nil, err
+	}
+	branches, err := marshalWebhookFilter(app.WebhookBranches)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := marshalWebhookFilter(app.WebhookTags)
 	if err != nil {
 		return nil, err
 	}
 
-	newToken := uuid.New().String()
 	_, err = s.db.Exec(
-		"UPDATE apps SET token = ?, updated_at = ? WHERE id = ?",
-		newToken, time.Now(), id,
+		"UPDATE apps SET name = ?, description = ?, working_dir = ?, concurrency_policy = ?, run_as_user = ?, run_as_group = ?, resource_limits = ?, git_repo_url = ?, git_ref = ?, webhook_branches = ?, webhook_tags = ?, requires_approval = ?, updated_at = ? WHERE id = ?",
+		app.Name, app.Description, app.WorkingDir, app.ConcurrencyPolicy, app.RunAsUser, app.RunAsGroup, limits, app.GitRepoURL, app.GitRef, branches, tags, app.RequiresApproval, time.Now(), id,
 	)
 	if err != nil {
 		return nil, err
@@ -179,6 +255,16 @@ func (s *AppService) DeleteApp(id string) error {
 		return err
 	}
 
+	_, err = s.db.Exec("DELETE FROM deploy_tokens WHERE app_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM app_members WHERE app_id = ?", id)
+	if err != nil {
+		return err
+	}
+
 	// Then delete the app (commands will be deleted by CASCADE)
 	result, err := s.db.Exec("DELETE FROM apps WHERE id = ?", id)
 	if err != nil {
@@ -218,38 +304,107 @@ func (s *AppService) CreateCommand(appID string, req *models.CreateCommandReques
 	}
 	sortOrder := maxOrder + 1
 
-	_, err = s.db.Exec(
-		"INSERT INTO commands (id, app_id, name, description, command, timeout_seconds, sort_order) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		id, appID, req.Name, req.Description, req.Command, timeout, sortOrder,
+	if err := s.validatePipelineSteps(appID, id, req.Steps); err != nil {
+		return nil, err
+	}
+	if err := validateParameterDefinitions(req.Parameters); err != nil {
+		return nil, err
+	}
+	parameters, err := marshalParameters(req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		"INSERT INTO commands (id, app_id, name, description, command, timeout_seconds, sort_order, parameters, rerun_on_interrupt, requires_approval) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, appID, req.Name, req.Description, req.Command, timeout, sortOrder, parameters, req.RerunOnInterrupt, req.RequiresApproval,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := insertPipelineSteps(tx, id, req.Steps); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return s.GetCommandByID(id)
 }
 
-// GetCommandByID retrieves a command by its ID.
-func (s *AppService) GetCommandByID(id string) (*models.Command, error) {
+// commandColumns lists the commands columns read by scanCommand, in order.
+const commandColumns = "id, app_id, name, description, command, timeout_seconds, created_at, COALESCE(sort_order, 0), parameters, rerun_on_interrupt, requires_approval"
+
+// scanCommand scans a row selected with commandColumns.
+func scanCommand(row rowScanner) (*models.Command, error) {
 	var cmd models.Command
-	err := s.db.QueryRow(
-		"SELECT id, app_id, name, description, command, timeout_seconds, created_at, COALESCE(sort_order, 0) FROM commands WHERE id = ?",
-		id,
-	).Scan(&cmd.ID, &cmd.AppID, &cmd.Name, &cmd.Description, &cmd.Command, &cmd.TimeoutSeconds, &cmd.CreatedAt, &cmd.SortOrder)
+	var parameters sql.NullString
+	if err := row.Scan(&cmd.ID, &cmd.AppID, &cmd.Name, &cmd.Description, &cmd.Command, &cmd.TimeoutSeconds, &cmd.CreatedAt, &cmd.SortOrder, &parameters, &cmd.RerunOnInterrupt, &cmd.RequiresApproval); err != nil {
+		return nil, err
+	}
+	if parameters.String != "" {
+		if err := json.Unmarshal([]byte(parameters.String), &cmd.Parameters); err != nil {
+			return nil, err
+		}
+	}
+	return &cmd, nil
+}
 
+// marshalParameters encodes parameter definitions for storage, using NULL for none.
+func marshalParameters(params []models.CommandParameter) (interface{}, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// marshalResourceLimits encodes resource limits for storage, using NULL for none.
+func marshalResourceLimits(limits models.ResourceLimits) (interface{}, error) {
+	if limits.MaxMemoryMB < 0 || limits.MaxCPUSeconds < 0 || limits.MaxOpenFiles < 0 || limits.MaxProcesses < 0 {
+		return nil, ErrInvalidResourceLimits
+	}
+	if limits.IsZero() {
+		return nil, nil
+	}
+	data, err := json.Marshal(limits)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// GetCommandByID retrieves a command by its ID.
+func (s *AppService) GetCommandByID(id string) (*models.Command, error) {
+	cmd, err := scanCommand(s.db.QueryRow("SELECT "+commandColumns+" FROM commands WHERE id = ?", id))
 	if err == sql.ErrNoRows {
 		return nil, ErrCommandNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &cmd, nil
+
+	if cmd.Steps, err = s.getPipelineSteps(cmd.ID); err != nil {
+		return nil, err
+	}
+	return cmd, nil
 }
 
 // GetCommandsByAppID retrieves all commands for a specific application ordered by sort_order.
 func (s *AppService) GetCommandsByAppID(appID string) ([]models.Command, error) {
 	rows, err := s.db.Query(
-		"SELECT id, app_id, name, description, command, timeout_seconds, created_at, COALESCE(sort_order, 0) FROM commands WHERE app_id = ? ORDER BY sort_order, created_at",
+		"SELECT "+commandColumns+" FROM commands WHERE app_id = ? ORDER BY sort_order, created_at",
 		appID,
 	)
 	if err != nil {
@@ -259,30 +414,35 @@ func (s *AppService) GetCommandsByAppID(appID string) ([]models.Command, error)
 
 	var commands []models.Command
 	for rows.Next() {
-		var cmd models.Command
-		if err := rows.Scan(&cmd.ID, &cmd.AppID, &cmd.Name, &cmd.Description, &cmd.Command, &cmd.TimeoutSeconds, &cmd.CreatedAt, &cmd.SortOrder); err != nil {
+		cmd, err := scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, *cmd)
+	}
+	_ = rows.Close()
+
+	for i := range commands {
+		if commands[i].Steps, err = s.getPipelineSteps(commands[i].ID); err != nil {
 			return nil, err
 		}
-		commands = append(commands, cmd)
 	}
 	return commands, nil
 }
 
 // GetDefaultCommandByAppID retrieves the default (first by sort_order) command for an application.
 func (s *AppService) GetDefaultCommandByAppID(appID string) (*models.Command, error) {
-	var cmd models.Command
-	err := s.db.QueryRow(
-		"SELECT id, app_id, name, description, command, timeout_seconds, created_at, COALESCE(sort_order, 0) FROM commands WHERE app_id = ? ORDER BY sort_order, created_at LIMIT 1",
+	cmd, err := scanCommand(s.db.QueryRow(
+		"SELECT "+commandColumns+" FROM commands WHERE app_id = ? ORDER BY sort_order, created_at LIMIT 1",
 		appID,
-	).Scan(&cmd.ID, &cmd.AppID, &cmd.Name, &cmd.Description, &cmd.Command, &cmd.TimeoutSeconds, &cmd.CreatedAt, &cmd.SortOrder)
-
+	))
 	if err == sql.ErrNoRows {
 		return nil, ErrCommandNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &cmd, nil
+	return cmd, nil
 }
 
 // UpdateCommand updates an existing command.
@@ -304,18 +464,147 @@ func (s *AppService) UpdateCommand(id string, req *models.UpdateCommandRequest)
 	if req.TimeoutSeconds > 0 {
 		cmd.TimeoutSeconds = req.TimeoutSeconds
 	}
+	if req.RerunOnInterrupt != nil {
+		cmd.RerunOnInterrupt = *req.RerunOnInterrupt
+	}
+	if req.RequiresApproval != nil {
+		cmd.RequiresApproval = *req.RequiresApproval
+	}
 
-	_, err = s.db.Exec(
-		"UPDATE commands SET name = ?, description = ?, command = ?, timeout_seconds = ? WHERE id = ?",
-		cmd.Name, cmd.Description, cmd.Command, cmd.TimeoutSeconds, id,
+	stepCount := len(cmd.Steps)
+	if req.Steps != nil {
+		if err := s.validatePipelineSteps(cmd.AppID, id, req.Steps); err != nil {
+			return nil, err
+		}
+		stepCount = len(req.Steps)
+		if stepCount > 0 {
+			cmd.Command = ""
+		}
+	}
+	if (cmd.Command == "") == (stepCount == 0) {
+		return nil, ErrInvalidCommand
+	}
+
+	if req.Parameters != nil {
+		if err := validateParameterDefinitions(req.Parameters); err != nil {
+			return nil, err
+		}
+		cmd.Parameters = req.Parameters
+	}
+	parameters, err := marshalParameters(cmd.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(
+		"UPDATE commands SET name = ?, description = ?, command = ?, timeout_seconds = ?, parameters = ?, rerun_on_interrupt = ?, requires_approval = ? WHERE id = ?",
+		cmd.Name, cmd.Description, cmd.Command, cmd.TimeoutSeconds, parameters, cmd.RerunOnInterrupt, cmd.RequiresApproval, id,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if req.Steps != nil {
+		if _, err := tx.Exec("DELETE FROM pipeline_steps WHERE pipeline_id = ?", id); err != nil {
+			return nil, err
+		}
+		if err := insertPipelineSteps(tx, id, req.Steps); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return s.GetCommandByID(id)
 }
 
+// getPipelineSteps retrieves the ordered steps of a pipeline command.
+func (s *AppService) getPipelineSteps(pipelineID string) ([]models.PipelineStep, error) {
+	rows, err := s.db.Query(`
+		SELECT ps.id, ps.command_id, c.name, ps.step_order, ps.continue_on_error
+		FROM pipeline_steps ps
+		JOIN commands c ON ps.command_id = c.id
+		WHERE ps.pipeline_id = ?
+		ORDER BY ps.step_order
+	`, pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var steps []models.PipelineStep
+	for rows.Next() {
+		var step models.PipelineStep
+		if err := rows.Scan(&step.ID, &step.CommandID, &step.CommandName, &step.StepOrder, &step.ContinueOnError); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// validatePipelineSteps verifies that every step references a plain command of the same app.
+// Nested pipelines are not supported.
+func (s *AppService) validatePipelineSteps(appID, pipelineID string, steps []models.PipelineStepRequest) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	// A command that is already a step of another pipeline cannot become a pipeline itself
+	var usedAsStep int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM pipeline_steps WHERE command_id = ?", pipelineID).Scan(&usedAsStep); err != nil {
+		return err
+	}
+	if usedAsStep > 0 {
+		return ErrInvalidPipelineStep
+	}
+
+	for _, step := range steps {
+		if step.CommandID == pipelineID {
+			return ErrInvalidPipelineStep
+		}
+
+		var stepAppID string
+		var nestedSteps int
+		err := s.db.QueryRow(
+			"SELECT app_id, (SELECT COUNT(*) FROM pipeline_steps WHERE pipeline_id = commands.id) FROM commands WHERE id = ?",
+			step.CommandID,
+		).Scan(&stepAppID, &nestedSteps)
+		if err == sql.ErrNoRows {
+			return ErrInvalidPipelineStep
+		}
+		if err != nil {
+			return err
+		}
+		if stepAppID != appID || nestedSteps > 0 {
+			return ErrInvalidPipelineStep
+		}
+	}
+	return nil
+}
+
+// insertPipelineSteps stores the steps of a pipeline command in order.
+func insertPipelineSteps(tx *sql.Tx, pipelineID string, steps []models.PipelineStepRequest) error {
+	for i, step := range steps {
+		_, err := tx.Exec(
+			"INSERT INTO pipeline_steps (id, pipeline_id, command_id, step_order, continue_on_error) VALUES (?, ?, ?, ?, ?)",
+			uuid.New().String(), pipelineID, step.CommandID, i, step.ContinueOnError,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DeleteCommand deletes a command and all related executions.
 func (s *AppService) DeleteCommand(id string) error {
 	// First delete related executions
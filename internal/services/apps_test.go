@@ -2,6 +2,7 @@ package services_test
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -27,10 +28,49 @@ func setupAppTestDB(t *testing.T) (*database.DB, *sql.DB) {
 			description TEXT,
 			working_dir TEXT NOT NULL,
 			token TEXT UNIQUE NOT NULL,
+			concurrency_policy TEXT NOT NULL DEFAULT 'queue',
+			run_as_user TEXT NOT NULL DEFAULT '',
+			run_as_group TEXT NOT NULL DEFAULT '',
+			resource_limits TEXT,
+			git_repo_url TEXT NOT NULL DEFAULT '',
+			git_ref TEXT NOT NULL DEFAULT '',
+			git_deploy_key TEXT,
+			webhook_secret TEXT,
+			webhook_branches TEXT,
+			webhook_tags TEXT,
+			requires_approval BOOLEAN NOT NULL DEFAULT FALSE,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 
+		CREATE TABLE deploy_tokens (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			token_prefix TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			command_ids TEXT,
+			expires_at DATETIME,
+			last_used_at DATETIME,
+			last_used_ip TEXT,
+			created_by TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (app_id, name),
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE app_members (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			user_id INTEGER,
+			group_id INTEGER,
+			role TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (app_id, user_id),
+			UNIQUE (app_id, group_id)
+		);
+
 		CREATE TABLE commands (
 			id TEXT PRIMARY KEY,
 			app_id TEXT NOT NULL,
@@ -39,6 +79,9 @@ func setupAppTestDB(t *testing.T) (*database.DB, *sql.DB) {
 			command TEXT NOT NULL,
 			timeout_seconds INTEGER DEFAULT 300,
 			sort_order INTEGER DEFAULT 0,
+			parameters TEXT,
+			rerun_on_interrupt BOOLEAN NOT NULL DEFAULT FALSE,
+			requires_approval BOOLEAN NOT NULL DEFAULT FALSE,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
 		);
@@ -54,9 +97,43 @@ func setupAppTestDB(t *testing.T) (*database.DB, *sql.DB) {
 			exit_code INTEGER,
 			started_at DATETIME,
 			finished_at DATETIME,
+			cancelled_by TEXT,
+			parameters TEXT,
+			git_ref TEXT,
+			commit_sha TEXT,
+			reviewed_by TEXT,
+			reviewed_at DATETIME,
+			review_comment TEXT,
+			approval_expires_at DATETIME,
+			rerun_of TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
 		);
+
+		CREATE TABLE pipeline_steps (
+			id TEXT PRIMARY KEY,
+			pipeline_id TEXT NOT NULL,
+			command_id TEXT NOT NULL,
+			step_order INTEGER NOT NULL DEFAULT 0,
+			continue_on_error BOOLEAN NOT NULL DEFAULT FALSE,
+			FOREIGN KEY (pipeline_id) REFERENCES commands(id) ON DELETE CASCADE,
+			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE execution_steps (
+			id TEXT PRIMARY KEY,
+			execution_id TEXT NOT NULL,
+			command_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			step_order INTEGER NOT NULL DEFAULT 0,
+			continue_on_error BOOLEAN NOT NULL DEFAULT FALSE,
+			status TEXT NOT NULL DEFAULT 'pending',
+			output TEXT,
+			exit_code INTEGER,
+			started_at DATETIME,
+			finished_at DATETIME,
+			FOREIGN KEY (execution_id) REFERENCES executions(id) ON DELETE CASCADE
+		);
 	`)
 	if err != nil {
 		t.Fatalf("failed to create tables: %v", err)
@@ -168,7 +245,7 @@ func TestAppService_GetAllApps(t *testing.T) {
 	_, _ = appSvc.CreateApp(&models.CreateAppRequest{Name: "App 2", WorkingDir: "/tmp/2"})
 	_, _ = appSvc.CreateApp(&models.CreateAppRequest{Name: "App 3", WorkingDir: "/tmp/3"})
 
-	apps, err := appSvc.GetAllApps()
+	apps, err := appSvc.GetAllApps(nil)
 	if err != nil {
 		t.Fatalf("failed to get all apps: %v", err)
 	}
@@ -213,6 +290,71 @@ func TestAppService_UpdateApp(t *testing.T) {
 	}
 }
 
+func TestAppService_ConcurrencyPolicy(t *testing.T) {
+	db, sqlDB := setupAppTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Test App", WorkingDir: "/tmp/test"})
+	if app.ConcurrencyPolicy != models.ConcurrencyQueue {
+		t.Errorf("expected default policy 'queue', got %q", app.ConcurrencyPolicy)
+	}
+
+	updated, err := appSvc.UpdateApp(app.ID, &models.UpdateAppRequest{ConcurrencyPolicy: models.ConcurrencyReject})
+	if err != nil {
+		t.Fatalf("failed to update app: %v", err)
+	}
+	if updated.ConcurrencyPolicy != models.ConcurrencyReject {
+		t.Errorf("expected policy 'reject', got %q", updated.ConcurrencyPolicy)
+	}
+
+	// Other updates keep the policy
+	updated, _ = appSvc.UpdateApp(app.ID, &models.UpdateAppRequest{Name: "Renamed"})
+	if updated.ConcurrencyPolicy != models.ConcurrencyReject {
+		t.Errorf("expected policy to be kept, got %q", updated.ConcurrencyPolicy)
+	}
+}
+
+func TestAppService_RunAsAndLimits(t *testing.T) {
+	db, sqlDB := setupAppTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+
+	_, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Bad User", WorkingDir: "/tmp", RunAsUser: "no-such-user-http-remote"})
+	if !errors.Is(err, services.ErrInvalidRunAs) {
+		t.Errorf("expected ErrInvalidRunAs, got %v", err)
+	}
+	_, err = appSvc.CreateApp(&models.CreateAppRequest{Name: "Bad Limits", WorkingDir: "/tmp", ResourceLimits: models.ResourceLimits{MaxOpenFiles: -1}})
+	if err != services.ErrInvalidResourceLimits {
+		t.Errorf("expected ErrInvalidResourceLimits, got %v", err)
+	}
+
+	limits := models.ResourceLimits{MaxMemoryMB: 512, MaxCPUSeconds: 60, MaxOpenFiles: 256, MaxProcesses: 64}
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Limited", WorkingDir: "/tmp", RunAsUser: "root", ResourceLimits: limits})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	if app.RunAsUser != "root" || app.ResourceLimits != limits {
+		t.Errorf("expected run_as and limits to be stored, got %q %+v", app.RunAsUser, app.ResourceLimits)
+	}
+
+	// Other updates keep run_as and limits, an empty run_as clears it
+	updated, _ := appSvc.UpdateApp(app.ID, &models.UpdateAppRequest{Name: "Renamed"})
+	if updated.RunAsUser != "root" || updated.ResourceLimits != limits {
+		t.Errorf("expected run_as and limits to be kept, got %q %+v", updated.RunAsUser, updated.ResourceLimits)
+	}
+	empty := ""
+	updated, err = appSvc.UpdateApp(app.ID, &models.UpdateAppRequest{RunAsUser: &empty, ResourceLimits: &models.ResourceLimits{}})
+	if err != nil {
+		t.Fatalf("failed to update app: %v", err)
+	}
+	if updated.RunAsUser != "" || !updated.ResourceLimits.IsZero() {
+		t.Errorf("expected run_as and limits to be cleared, got %q %+v", updated.RunAsUser, updated.ResourceLimits)
+	}
+}
+
 func TestAppService_DeleteApp(t *testing.T) {
 	db, sqlDB := setupAppTestDB(t)
 	defer func() { _ = sqlDB.Close() }()
@@ -268,10 +410,16 @@ func TestAppService_RegenerateToken(t *testing.T) {
 		t.Error("expected new token to be different from original")
 	}
 
-	// Verify token in database
+	// Tokens are only stored hashed: the new token authenticates and the old one no longer does
 	retrieved, _ := appSvc.GetAppByID(app.ID)
-	if retrieved.Token != updatedApp.Token {
-		t.Errorf("expected token %q, got %q", updatedApp.Token, retrieved.Token)
+	if retrieved.Token != "" {
+		t.Errorf("expected the token not to be readable, got %q", retrieved.Token)
+	}
+	if _, err := appSvc.AuthenticateDeployToken(app.ID, updatedApp.Token, models.ScopeDeploy, "127.0.0.1"); err != nil {
+		t.Errorf("expected the new token to authenticate, got %v", err)
+	}
+	if _, err := appSvc.AuthenticateDeployToken(app.ID, originalToken, models.ScopeDeploy, "127.0.0.1"); err != services.ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for the old token, got %v", err)
 	}
 }
 
@@ -580,3 +728,104 @@ func TestAppService_GetDefaultCommand_UsesSortOrder(t *testing.T) {
 		t.Errorf("expected default to be cmd2 after reorder")
 	}
 }
+
+func TestAppService_CreatePipelineCommand(t *testing.T) {
+	db, sqlDB := setupAppTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Test App", WorkingDir: "/tmp/test"})
+	pull, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "pull", Command: "git pull"})
+	build, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "build", Command: "make build"})
+
+	pipeline, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name: "deploy",
+		Steps: []models.PipelineStepRequest{
+			{CommandID: pull.ID},
+			{CommandID: build.ID, ContinueOnError: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create pipeline: %v", err)
+	}
+
+	if !pipeline.IsPipeline() {
+		t.Fatal("expected command to be a pipeline")
+	}
+	if len(pipeline.Steps) != 2 {
+		t.Fatalf("expected 2 steps, got %d", len(pipeline.Steps))
+	}
+	if pipeline.Steps[0].CommandID != pull.ID || pipeline.Steps[0].CommandName != "pull" {
+		t.Errorf("expected first step to be 'pull', got %q", pipeline.Steps[0].CommandName)
+	}
+	if !pipeline.Steps[1].ContinueOnError {
+		t.Error("expected second step to continue on error")
+	}
+
+	// Replacing the steps keeps the pipeline in sync
+	updated, err := appSvc.UpdateCommand(pipeline.ID, &models.UpdateCommandRequest{
+		Steps: []models.PipelineStepRequest{{CommandID: build.ID}},
+	})
+	if err != nil {
+		t.Fatalf("failed to update pipeline: %v", err)
+	}
+	if len(updated.Steps) != 1 || updated.Steps[0].CommandID != build.ID {
+		t.Errorf("expected single 'build' step after update, got %+v", updated.Steps)
+	}
+
+	// Deleting a step command removes it from the pipeline
+	if err := appSvc.DeleteCommand(build.ID); err != nil {
+		t.Fatalf("failed to delete command: %v", err)
+	}
+	reloaded, _ := appSvc.GetCommandByID(pipeline.ID)
+	if len(reloaded.Steps) != 0 {
+		t.Errorf("expected no steps after deleting step command, got %d", len(reloaded.Steps))
+	}
+}
+
+func TestAppService_CreatePipelineCommand_InvalidSteps(t *testing.T) {
+	db, sqlDB := setupAppTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "App 1", WorkingDir: "/tmp/1"})
+	other, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "App 2", WorkingDir: "/tmp/2"})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "pull", Command: "git pull"})
+	otherCmd, _ := appSvc.CreateCommand(other.ID, &models.CreateCommandRequest{Name: "pull", Command: "git pull"})
+
+	pipeline, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:  "deploy",
+		Steps: []models.PipelineStepRequest{{CommandID: cmd.ID}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create pipeline: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		steps []models.PipelineStepRequest
+	}{
+		{"command of another app", []models.PipelineStepRequest{{CommandID: otherCmd.ID}}},
+		{"nested pipeline", []models.PipelineStepRequest{{CommandID: pipeline.ID}}},
+		{"unknown command", []models.PipelineStepRequest{{CommandID: "nonexistent"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "invalid", Steps: tt.steps})
+			if err != services.ErrInvalidPipelineStep {
+				t.Errorf("expected ErrInvalidPipelineStep, got %v", err)
+			}
+		})
+	}
+
+	// A step command cannot be turned into a pipeline itself
+	_, err = appSvc.UpdateCommand(cmd.ID, &models.UpdateCommandRequest{
+		Steps: []models.PipelineStepRequest{{CommandID: cmd.ID}},
+	})
+	if err != services.ErrInvalidPipelineStep {
+		t.Errorf("expected ErrInvalidPipelineStep, got %v", err)
+	}
+}
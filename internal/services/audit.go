@@ -86,7 +86,7 @@ func (s *AuditService) LogLogout(user *models.User, ip, userAgent string) {
 func (s *AuditService) LogCommandCreate(user *models.User, commandID, commandName string, ip, userAgent string) {
 	_ = s.Log(AuditLog{
 		UserID:       &user.ID,
-		Username:     user.Username,
+		Username:     user.AuditName(),
 		Action:       "create",
 		ResourceType: "command",
 		ResourceID:   commandID,
@@ -102,7 +102,7 @@ func (s *AuditService) LogCommandCreate(user *models.User, commandID, commandNam
 func (s *AuditService) LogCommandUpdate(user *models.User, commandID, commandName string, ip, userAgent string) {
 	_ = s.Log(AuditLog{
 		UserID:       &user.ID,
-		Username:     user.Username,
+		Username:     user.AuditName(),
 		Action:       "update",
 		ResourceType: "command",
 		ResourceID:   commandID,
@@ -118,7 +118,7 @@ func (s *AuditService) LogCommandUpdate(user *models.User, commandID, commandNam
 func (s *AuditService) LogCommandDelete(user *models.User, commandID, commandName string, ip, userAgent string) {
 	_ = s.Log(AuditLog{
 		UserID:       &user.ID,
-		Username:     user.Username,
+		Username:     user.AuditName(),
 		Action:       "delete",
 		ResourceType: "command",
 		ResourceID:   commandID,
@@ -147,6 +147,113 @@ func (s *AuditService) LogCommandExecute(username string, userID *int64, command
 	})
 }
 
+// LogWebhookExecute logs an execution triggered by a git provider webhook.
+func (s *AuditService) LogWebhookExecute(provider, executionID, commandName, appName, ref, commitSHA, pusher string, ip, userAgent string) {
+	_ = s.Log(AuditLog{
+		Username:     "webhook:" + provider,
+		Action:       "execute",
+		ResourceType: "execution",
+		ResourceID:   executionID,
+		IPAddress:    ip,
+		UserAgent:    userAgent,
+		Details: map[string]interface{}{
+			"command_name": commandName,
+			"app_name":     appName,
+			"ref":          ref,
+			"commit_sha":   commitSHA,
+			"pusher":       pusher,
+		},
+	})
+}
+
+// LogDeployExecute logs an execution triggered through the deploy API with a deploy token.
+func (s *AuditService) LogDeployExecute(token *models.DeployToken, executionID, commandName, appName string, ip, userAgent string) {
+	_ = s.Log(AuditLog{
+		Username:     DeployTokenActor(token),
+		Action:       "execute",
+		ResourceType: "execution",
+		ResourceID:   executionID,
+		IPAddress:    ip,
+		UserAgent:    userAgent,
+		Details: map[string]interface{}{
+			"command_name": commandName,
+			"app_name":     appName,
+			"token_id":     token.ID,
+		},
+	})
+}
+
+// LogScheduledExecute logs an execution started by a command schedule.
+func (s *AuditService) LogScheduledExecute(scheduleID, executionID, commandName, appName, cron string) {
+	_ = s.Log(AuditLog{
+		Username:     SchedulerActor,
+		Action:       "execute",
+		ResourceType: "execution",
+		ResourceID:   executionID,
+		Details: map[string]interface{}{
+			"command_name": commandName,
+			"app_name":     appName,
+			"schedule_id":  scheduleID,
+			"cron":         cron,
+		},
+	})
+}
+
+// LogExecutionRerun logs a re-run ("rerun") or rollback ("rollback") of an earlier execution.
+func (s *AuditService) LogExecutionRerun(username string, userID *int64, action string, execution *models.Execution, commandName, appName string, ip, userAgent string) {
+	_ = s.Log(AuditLog{
+		UserID:       userID,
+		Username:     username,
+		Action:       action,
+		ResourceType: "execution",
+		ResourceID:   execution.ID,
+		IPAddress:    ip,
+		UserAgent:    userAgent,
+		Details: map[string]interface{}{
+			"rerun_of":     execution.RerunOf,
+			"command_name": commandName,
+			"app_name":     appName,
+			"git_ref":      execution.GitRef,
+			"commit_sha":   execution.CommitSHA,
+		},
+	})
+}
+
+// LogExecutionReview logs the approval ("approve") or rejection ("reject") of an execution that required approval.
+func (s *AuditService) LogExecutionReview(username string, userID *int64, action, executionID, commandName, appName, comment string, ip, userAgent string) {
+	_ = s.Log(AuditLog{
+		UserID:       userID,
+		Username:     username,
+		Action:       action,
+		ResourceType: "execution",
+		ResourceID:   executionID,
+		IPAddress:    ip,
+		UserAgent:    userAgent,
+		Details: map[string]interface{}{
+			"command_name": commandName,
+			"app_name":     appName,
+			"comment":      comment,
+		},
+	})
+}
+
+// LogExecutionCancel logs the cancellation of a running or pending execution.
+func (s *AuditService) LogExecutionCancel(username string, userID *int64, executionID, commandName, appName string, ip, userAgent string) {
+	_ = s.Log(AuditLog{
+		UserID:       userID,
+		Username:     username,
+		Action:       "cancel",
+		ResourceType: "execution",
+		ResourceID:   executionID,
+		IPAddress:    ip,
+		UserAgent:    userAgent,
+		Details: map[string]interface{}{
+			"command_name": commandName,
+			"app_name":     appName,
+		},
+	})
+}
+
 // AuditLogEntry represents an audit log record from the database.
 type AuditLogEntry struct {
 	UserID       *int64 `json:"user_id"`
@@ -161,18 +268,42 @@ type AuditLogEntry struct {
 	ID           int64  `json:"id"`
 }
 
+// auditLogAppID is an SQL expression for the ID of the app that the resource of an audit log entry
+// belongs to. It is NULL for entries outside of apps and for resources that no longer exist.
+const auditLogAppID = `CASE audit_logs.resource_type
+	WHEN 'app' THEN audit_logs.resource_id
+	WHEN 'commands' THEN audit_logs.resource_id
+	WHEN 'command' THEN (SELECT app_id FROM commands WHERE id = audit_logs.resource_id)
+	WHEN 'execution' THEN (SELECT c.app_id FROM executions e JOIN commands c ON e.command_id = c.id WHERE e.id = audit_logs.resource_id)
+	WHEN 'schedule' THEN (SELECT c.app_id FROM command_schedules cs JOIN commands c ON cs.command_id = c.id WHERE cs.id = audit_logs.resource_id)
+	WHEN 'env_var' THEN (SELECT app_id FROM env_vars WHERE id = audit_logs.resource_id)
+	WHEN 'notification_rule' THEN (SELECT app_id FROM notification_rules WHERE id = audit_logs.resource_id)
+END`
+
 // GetLogs retrieves audit logs with pagination.
 func (s *AuditService) GetLogs(limit, offset int) ([]AuditLogEntry, error) {
+	return s.GetMemberLogs(nil, limit, offset)
+}
+
+// GetMemberLogs retrieves the audit logs that member may see with pagination: the entries of the
+// apps member is a member of and member's own entries. Admins and a nil member see every entry.
+func (s *AuditService) GetMemberLogs(member *models.User, limit, offset int) ([]AuditLogEntry, error) {
 	if limit == 0 {
 		limit = 50
 	}
 
-	rows, err := s.db.Query(`
+	query := `
 		SELECT id, user_id, username, action, resource_type, resource_id, ip_address, user_agent, details, created_at
-		FROM audit_logs
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-	`, limit, offset)
+		FROM audit_logs`
+	var args []interface{}
+	if condition, conditionArgs := memberCondition(member, auditLogAppID); conditionArgs != nil {
+		query += " WHERE (user_id = ? OR " + condition + ")"
+		args = append(append(args, member.ID), conditionArgs...)
+	}
+	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -800,6 +800,21 @@ func (s *AuthService) DeleteUser(userID int64) error {
 		return err
 	}
 
+	_, err = s.db.Exec("DELETE FROM api_tokens WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM app_members WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM user_group_members WHERE user_id = ?", userID)
+	if err != nil {
+		return err
+	}
+
 	// Then delete the user
 	result, err := s.db.Exec("DELETE FROM users WHERE id = ?", userID)
 	if err != nil {
@@ -0,0 +1,95 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * FOO *",
+		"@every 5m",
+	} {
+		if _, err := services.ParseCron(expr); !errors.Is(err, services.ErrInvalidCron) {
+			t.Errorf("%q: expected ErrInvalidCron, got %v", expr, err)
+		}
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		expr  string
+		after string
+		want  string
+	}{
+		{"* * * * *", "2026-01-01 10:00", "2026-01-01 10:01"},
+		{"*/15 * * * *", "2026-01-01 10:07", "2026-01-01 10:15"},
+		{"30 2 * * *", "2026-01-01 03:00", "2026-01-02 02:30"},
+		{"0 9-17/4 * * *", "2026-01-01 09:00", "2026-01-01 13:00"},
+		{"5/20 * * * *", "2026-01-01 10:30", "2026-01-01 10:45"},
+		{"0 0 * * MON-FRI", "2026-01-02 12:00", "2026-01-05 00:00"}, // Friday -> Monday
+		{"0 0 * * 7", "2026-01-01 00:00", "2026-01-04 00:00"},       // 7 is Sunday
+		{"0 0 1,15 * *", "2026-01-02 00:00", "2026-01-15 00:00"},
+		{"0 0 13 * FRI", "2026-01-01 00:00", "2026-01-02 00:00"}, // Either day field matches
+		{"0 0 29 FEB *", "2026-01-01 00:00", "2028-02-29 00:00"},
+		{"@monthly", "2026-01-15 00:00", "2026-02-01 00:00"},
+		{"@hourly", "2026-12-31 23:30", "2027-01-01 00:00"},
+	}
+
+	for _, tt := range tests {
+		cron, err := services.ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got := cron.Next(utc(tt.after)); !got.Equal(utc(tt.want)) {
+			t.Errorf("%q after %s: expected %s, got %s", tt.expr, tt.after, tt.want, got.Format("2006-01-02 15:04"))
+		}
+	}
+
+	never, _ := services.ParseCron("0 0 30 2 *")
+	if got := never.Next(utc("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("expected no run for February 30th, got %s", got)
+	}
+}
+
+func TestCronSchedule_NextInTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	cron, _ := services.ParseCron("30 2 * * *")
+	// 2:30 does not exist on the day clocks spring forward, so that day is skipped
+	got := cron.Next(time.Date(2026, 3, 7, 12, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	cron, _ = services.ParseCron("0 9 * * *")
+	got = cron.Next(time.Date(2026, 11, 1, 0, 0, 0, 0, loc))
+	if want := time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected 9:00 EST (%s), got %s", want, got.UTC())
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrDeployTokenNotFound indicates the requested deploy token was not found.
+	ErrDeployTokenNotFound = errors.New("deploy token not found")
+	// ErrDeployTokenExists indicates the app already has a deploy token with the same name.
+	ErrDeployTokenExists = errors.New("deploy token already exists")
+	// ErrInvalidDeployToken indicates the name, scopes, commands or expiry of a new deploy token are invalid.
+	ErrInvalidDeployToken = errors.New("invalid deploy token")
+	// ErrTokenExpired indicates the deploy token has expired.
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenScope indicates the deploy token does not grant the requested action.
+	ErrTokenScope = errors.New("token does not have the required scope")
+)
+
+// defaultDeployTokenName is the name of the deploy token created with each app and replaced by RegenerateToken.
+const defaultDeployTokenName = "default"
+
+// deployTokenPrefix marks generated deploy tokens so that they are recognisable, for example by secret scanners.
+const deployTokenPrefix = "hrd_"
+
+// deployTokenColumns lists the deploy_tokens columns read by scanDeployToken, in order.
+const deployTokenColumns = `id, app_id, name, token_prefix, scopes, command_ids, expires_at, last_used_at,
+	COALESCE(last_used_ip, ''), COALESCE(created_by, ''), created_at`
+
+// DeployTokenActor returns the name recorded in audit logs and cancellations for actions taken with a deploy token.
+func DeployTokenActor(token *models.DeployToken) string {
+	return "token:" + token.Name
+}
+
+// hashToken returns the stored form of a token. Tokens are random, so a fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateDeployToken creates a deploy token for an app on behalf of createdBy.
+// The returned token is the only one that contains the plaintext.
+func (s *AppService) CreateDeployToken(appID string, req *models.CreateDeployTokenRequest, createdBy string) (*models.DeployToken, error) {
+	if _, err := s.GetAppByID(appID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidDeployToken)
+	}
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = models.AllDeployTokenScopes
+	}
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidDeployToken, scope)
+		}
+	}
+	for _, commandID := range req.CommandIDs {
+		command, err := s.GetCommandByID(commandID)
+		if err != nil || command.AppID != appID {
+			return nil, fmt.Errorf("%w: command %s does not belong to this app", ErrInvalidDeployToken, commandID)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidDeployToken)
+	}
+
+	return s.insertDeployToken(appID, name, scopes, req.CommandIDs, req.ExpiresAt, createdBy)
+}
+
+// insertDeployToken generates and stores a deploy token.
+func (s *AppService) insertDeployToken(appID, name string, scopes []models.DeployTokenScope, commandIDs []string, expiresAt *time.Time, createdBy string) (*models.DeployToken, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := deployTokenPrefix + hex.EncodeToString(secret)
+
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, err
+	}
+	var commandsJSON interface{}
+	if len(commandIDs) > 0 {
+		data, err := json.Marshal(commandIDs)
+		if err != nil {
+			return nil, err
+		}
+		commandsJSON = string(data)
+	}
+
+	id := uuid.New().String()
+	_, err = s.db.Exec(
+		"INSERT INTO deploy_tokens (id, app_id, name, token_hash, token_prefix, scopes, command_ids, expires_at, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, appID, name, hashToken(token), token[:len(deployTokenPrefix)+8], string(scopesJSON), commandsJSON, expiresAt, nullString(createdBy),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrDeployTokenExists
+		}
+		return nil, err
+	}
+
+	created, err := s.GetDeployToken(appID, id)
+	if err != nil {
+		return nil, err
+	}
+	created.Token = token
+	return created, nil
+}
+
+// ListDeployTokens returns the deploy tokens of an app, without their plaintext.
+func (s *AppService) ListDeployTokens(appID string) ([]models.DeployToken, error) {
+	rows, err := s.db.Query("SELECT "+deployTokenColumns+" FROM deploy_tokens WHERE app_id = ? ORDER BY created_at, name", appID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tokens := []models.DeployToken{}
+	for rows.Next() {
+		token, err := scanDeployToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetDeployToken returns a deploy token of an app, without its plaintext.
+func (s *AppService) GetDeployToken(appID, id string) (*models.DeployToken, error) {
+	token, err := scanDeployToken(s.db.QueryRow("SELECT "+deployTokenColumns+" FROM deploy_tokens WHERE app_id = ? AND id = ?", appID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeployTokenNotFound
+	}
+	return token, err
+}
+
+// RevokeDeployToken deletes a deploy token of an app. Other tokens of the app keep working.
+func (s *AppService) RevokeDeployToken(appID, id string) error {
+	result, err := s.db.Exec("DELETE FROM deploy_tokens WHERE app_id = ? AND id = ?", appID, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrDeployTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateDeployToken returns the deploy token of an app matching token if it has not expired and grants scope,
+// and records its use from ip.
+func (s *AppService) AuthenticateDeployToken(appID, token string, scope models.DeployTokenScope, ip string) (*models.DeployToken, error) {
+	deployToken, err := scanDeployToken(s.db.QueryRow(
+		"SELECT "+deployTokenColumns+" FROM deploy_tokens WHERE app_id = ? AND token_hash = ?",
+		appID, hashToken(token),
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if deployToken.ExpiresAt != nil && !deployToken.ExpiresAt.After(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+	if !deployToken.HasScope(scope) {
+		return nil, fmt.Errorf("%w: %s", ErrTokenScope, scope)
+	}
+
+	now := time.Now()
+	_, _ = s.db.Exec("UPDATE deploy_tokens SET last_used_at = ?, last_used_ip = ? WHERE id = ?", now, ip, deployToken.ID)
+	deployToken.LastUsedAt = &now
+	deployToken.LastUsedIP = ip
+	return deployToken, nil
+}
+
+// RegenerateToken replaces the default deploy token of an application with a new one with all scopes.
+// The returned app holds the new token in Token. Other deploy tokens are not affected.
+func (s *AppService) RegenerateToken(id string) (*models.App, error) {
+	app, err := s.GetAppByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.Exec("DELETE FROM deploy_tokens WHERE app_id = ? AND name = ?", id, defaultDeployTokenName); err != nil {
+		return nil, err
+	}
+	token, err := s.insertDeployToken(id, defaultDeployTokenName, models.AllDeployTokenScopes, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// apps.token is no longer used for authentication and only keeps the hash of the default token
+	_, err = s.db.Exec("UPDATE apps SET token = ?, updated_at = ? WHERE id = ?", hashToken(token.Token), time.Now(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Token = token.Token
+	return app, nil
+}
+
+func scanDeployToken(row rowScanner) (*models.DeployToken, error) {
+	var token models.DeployToken
+	var scopes string
+	var commandIDs sql.NullString
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&token.ID, &token.AppID, &token.Name, &token.Prefix, &scopes, &commandIDs, &expiresAt, &lastUsedAt,
+		&token.LastUsedIP, &token.CreatedBy, &token.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &token.Scopes); err != nil {
+		return nil, err
+	}
+	if commandIDs.Valid && commandIDs.String != "" {
+		if err := json.Unmarshal([]byte(commandIDs.String), &token.CommandIDs); err != nil {
+			return nil, err
+		}
+	}
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
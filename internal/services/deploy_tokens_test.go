@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestAppService_DeployTokens(t *testing.T) {
+	db, sqlDB := setupAppTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Tokens", WorkingDir: "/tmp/test"})
+	other, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Other", WorkingDir: "/tmp/test"})
+	deploy, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true"})
+	foreign, _ := appSvc.CreateCommand(other.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true"})
+
+	ci, err := appSvc.CreateDeployToken(app.ID, &models.CreateDeployTokenRequest{
+		Name:       "ci",
+		Scopes:     []models.DeployTokenScope{models.ScopeDeploy},
+		CommandIDs: []string{deploy.ID},
+	}, "admin")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if ci.Token == "" || ci.Prefix == "" || ci.Token[:len(ci.Prefix)] != ci.Prefix || ci.CreatedBy != "admin" {
+		t.Errorf("unexpected token: %+v", ci)
+	}
+
+	future := time.Now().Add(time.Hour)
+	monitor, err := appSvc.CreateDeployToken(app.ID, &models.CreateDeployTokenRequest{
+		Name:      "monitor",
+		Scopes:    []models.DeployTokenScope{models.ScopeStatus},
+		ExpiresAt: &future,
+	}, "admin")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	// Invalid requests
+	past := time.Now().Add(-time.Hour)
+	for name, req := range map[string]*models.CreateDeployTokenRequest{
+		"duplicate name":     {Name: "ci"},
+		"unknown scope":      {Name: "x", Scopes: []models.DeployTokenScope{"admin"}},
+		"foreign command":    {Name: "x", CommandIDs: []string{foreign.ID}},
+		"expiry in the past": {Name: "x", ExpiresAt: &past},
+	} {
+		if _, err := appSvc.CreateDeployToken(app.ID, req, "admin"); err != services.ErrDeployTokenExists && !errors.Is(err, services.ErrInvalidDeployToken) {
+			t.Errorf("%s: expected an error, got %v", name, err)
+		}
+	}
+
+	// Listing never returns the plaintext
+	tokens, err := appSvc.ListDeployTokens(app.ID)
+	if err != nil {
+		t.Fatalf("failed to list tokens: %v", err)
+	}
+	if len(tokens) != 3 {
+		t.Fatalf("expected the default, ci and monitor tokens, got %d", len(tokens))
+	}
+	for _, token := range tokens {
+		if token.Token != "" {
+			t.Errorf("expected no plaintext for %s", token.Name)
+		}
+	}
+
+	// Scopes, apps and last use
+	authenticated, err := appSvc.AuthenticateDeployToken(app.ID, ci.Token, models.ScopeDeploy, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if !authenticated.AllowsCommand(deploy.ID) || authenticated.AllowsCommand("another") {
+		t.Errorf("expected the token to be limited to %s, got %v", deploy.ID, authenticated.CommandIDs)
+	}
+	if _, err := appSvc.AuthenticateDeployToken(app.ID, ci.Token, models.ScopeCancel, "10.0.0.1"); !errors.Is(err, services.ErrTokenScope) {
+		t.Errorf("expected ErrTokenScope, got %v", err)
+	}
+	if _, err := appSvc.AuthenticateDeployToken(other.ID, ci.Token, models.ScopeDeploy, "10.0.0.1"); err != services.ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for another app, got %v", err)
+	}
+	used, _ := appSvc.GetDeployToken(app.ID, ci.ID)
+	if used.LastUsedAt == nil || used.LastUsedIP != "10.0.0.1" {
+		t.Errorf("expected the last use to be recorded, got %v from %q", used.LastUsedAt, used.LastUsedIP)
+	}
+
+	// Expired tokens are rejected
+	if _, err := appSvc.AuthenticateDeployToken(app.ID, monitor.Token, models.ScopeStatus, "10.0.0.1"); err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if _, err := sqlDB.Exec("UPDATE deploy_tokens SET expires_at = ? WHERE id = ?", past, monitor.ID); err != nil {
+		t.Fatalf("failed to expire token: %v", err)
+	}
+	if _, err := appSvc.AuthenticateDeployToken(app.ID, monitor.Token, models.ScopeStatus, "10.0.0.1"); err != services.ErrTokenExpired {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+
+	// Revoking one token leaves the others working
+	if err := appSvc.RevokeDeployToken(app.ID, ci.ID); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if _, err := appSvc.AuthenticateDeployToken(app.ID, ci.Token, models.ScopeDeploy, "10.0.0.1"); err != services.ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for the revoked token, got %v", err)
+	}
+	if _, err := appSvc.AuthenticateDeployToken(app.ID, app.Token, models.ScopeDeploy, "10.0.0.1"); err != nil {
+		t.Errorf("expected the default token to keep working, got %v", err)
+	}
+	if err := appSvc.RevokeDeployToken(other.ID, monitor.ID); err != services.ErrDeployTokenNotFound {
+		t.Errorf("expected ErrDeployTokenNotFound for another app, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrEnvVarNotFound indicates the requested environment variable was not found.
+	ErrEnvVarNotFound = errors.New("environment variable not found")
+	// ErrEnvVarExists indicates a variable with the same name already exists in the same scope.
+	ErrEnvVarExists = errors.New("environment variable already exists")
+	// ErrInvalidEnvVar indicates an environment variable name or update is invalid.
+	ErrInvalidEnvVar = errors.New("invalid environment variable")
+)
+
+// secretMask replaces secret values in execution output.
+const secretMask = "***"
+
+// EnvService manages the environment variables and secrets of apps.
+type EnvService struct {
+	db     *database.DB
+	crypto *CryptoService
+}
+
+// NewEnvService creates a new EnvService instance.
+// Secret values are encrypted with the given crypto service.
+func NewEnvService(db *database.DB, crypto *CryptoService) *EnvService {
+	return &EnvService{db: db, crypto: crypto}
+}
+
+const envVarColumns = "id, app_id, COALESCE(command_id, ''), name, value, is_secret, created_at, updated_at"
+
+func scanEnvVar(row rowScanner) (*models.EnvVar, error) {
+	var v models.EnvVar
+	if err := row.Scan(&v.ID, &v.AppID, &v.CommandID, &v.Name, &v.Value, &v.IsSecret, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// redact clears the value of a secret before it leaves the service.
+func redact(v *models.EnvVar) *models.EnvVar {
+	if v.IsSecret {
+		v.Value = ""
+	}
+	return v
+}
+
+// ListEnvVars returns all environment variables of an app, app-wide variables first.
+// Secret values are omitted.
+func (s *EnvService) ListEnvVars(appID string) ([]models.EnvVar, error) {
+	rows, err := s.db.Query(
+		"SELECT "+envVarColumns+" FROM env_vars WHERE app_id = ? ORDER BY command_id IS NOT NULL, command_id, name",
+		appID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	vars := []models.EnvVar{}
+	for rows.Next() {
+		v, err := scanEnvVar(rows)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, *redact(v))
+	}
+	return vars, rows.Err()
+}
+
+// GetEnvVar retrieves an environment variable of an app. Secret values are omitted.
+func (s *EnvService) GetEnvVar(appID, id string) (*models.EnvVar, error) {
+	v, err := s.getEnvVar(appID, id)
+	if err != nil {
+		return nil, err
+	}
+	return redact(v), nil
+}
+
+func (s *EnvService) getEnvVar(appID, id string) (*models.EnvVar, error) {
+	v, err := scanEnvVar(s.db.QueryRow("SELECT "+envVarColumns+" FROM env_vars WHERE id = ? AND app_id = ?", id, appID))
+	if err == sql.ErrNoRows {
+		return nil, ErrEnvVarNotFound
+	}
+	return v, err
+}
+
+// CreateEnvVar creates an environment variable for an app, or for one of its commands if CommandID is set.
+func (s *EnvService) CreateEnvVar(appID string, req *models.CreateEnvVarRequest) (*models.EnvVar, error) {
+	if !parameterNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name %q must contain only letters, digits and underscores", ErrInvalidEnvVar, req.Name)
+	}
+
+	var commandID interface{}
+	if req.CommandID != "" {
+		var count int
+		err := s.db.QueryRow("SELECT COUNT(*) FROM commands WHERE id = ? AND app_id = ?", req.CommandID, appID).Scan(&count)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrCommandNotFound
+		}
+		commandID = req.CommandID
+	}
+
+	value, err := s.storedValue(req.Value, req.IsSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	_, err = s.db.Exec(
+		"INSERT INTO env_vars (id, app_id, command_id, name, value, is_secret) VALUES (?, ?, ?, ?, ?, ?)",
+		id, appID, commandID, req.Name, value, req.IsSecret,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrEnvVarExists
+		}
+		return nil, err
+	}
+
+	return s.GetEnvVar(appID, id)
+}
+
+// UpdateEnvVar updates the value or secret flag of an environment variable.
+// Turning a secret into a plain variable requires a new value, the secret is never revealed.
+func (s *EnvService) UpdateEnvVar(appID, id string, req *models.UpdateEnvVarRequest) (*models.EnvVar, error) {
+	current, err := s.getEnvVar(appID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	isSecret := current.IsSecret
+	if req.IsSecret != nil {
+		isSecret = *req.IsSecret
+	}
+
+	value := current.Value
+	switch {
+	case req.Value != nil:
+		value, err = s.storedValue(*req.Value, isSecret)
+		if err != nil {
+			return nil, err
+		}
+	case current.IsSecret && !isSecret:
+		return nil, fmt.Errorf("%w: a new value is required to turn a secret into a plain variable", ErrInvalidEnvVar)
+	case !current.IsSecret && isSecret:
+		value, err = s.storedValue(current.Value, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE env_vars SET value = ?, is_secret = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND app_id = ?",
+		value, isSecret, id, appID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEnvVar(appID, id)
+}
+
+// DeleteEnvVar deletes an environment variable of an app.
+func (s *EnvService) DeleteEnvVar(appID, id string) error {
+	result, err := s.db.Exec("DELETE FROM env_vars WHERE id = ? AND app_id = ?", id, appID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEnvVarNotFound
+	}
+	return nil
+}
+
+// storedValue returns the value as it is stored in the database, encrypting secrets.
+func (s *EnvService) storedValue(value string, isSecret bool) (string, error) {
+	if !isSecret {
+		return value, nil
+	}
+	if s.crypto == nil {
+		return "", errors.New("encryption is not configured")
+	}
+	return s.crypto.Encrypt(value)
+}
+
+// ExecutionEnv returns the environment of a command as KEY=value pairs together with the
+// plaintext values of its secrets. Command variables override app-wide variables of the same name.
+func (s *EnvService) ExecutionEnv(appID, commandID string) ([]string, []string, error) {
+	rows, err := s.db.Query(
+		"SELECT "+envVarColumns+" FROM env_vars WHERE app_id = ? AND (command_id IS NULL OR command_id = ?) ORDER BY command_id IS NOT NULL",
+		appID, commandID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	values := make(map[string]string)
+	secret := make(map[string]bool)
+	for rows.Next() {
+		v, err := scanEnvVar(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := v.Value
+		if v.IsSecret {
+			if s.crypto == nil {
+				return nil, nil, errors.New("encryption is not configured")
+			}
+			value, err = s.crypto.Decrypt(v.Value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decrypt secret %s: %w", v.Name, err)
+			}
+		}
+		values[v.Name] = value
+		secret[v.Name] = v.IsSecret
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	env := make([]string, 0, len(values))
+	var secrets []string
+	for name, value := range values {
+		env = append(env, name+"="+value)
+		if secret[name] && value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	sort.Strings(env)
+	return env, secrets, nil
+}
+
+// newSecretMasker returns a replacer that masks the given secret values, or nil if there are none.
+// Each line of a multi-line secret is masked on its own because output is processed line by line.
+func newSecretMasker(secrets []string) *strings.Replacer {
+	var values []string
+	for _, secret := range secrets {
+		values = append(values, secret)
+		if strings.Contains(secret, "\n") {
+			for _, line := range strings.Split(secret, "\n") {
+				if strings.TrimSpace(line) != "" {
+					values = append(values, line)
+				}
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	// Longer values first so a secret containing another one is masked as a whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		pairs = append(pairs, v, secretMask)
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,274 @@
+package services_test
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func newTestCryptoService(t *testing.T) *services.CryptoService {
+	t.Helper()
+	crypto, err := services.NewCryptoService([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("failed to create crypto service: %v", err)
+	}
+	return crypto
+}
+
+func TestEnvService_Secrets(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	envSvc := services.NewEnvService(db, newTestCryptoService(t))
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Env App", WorkingDir: "/tmp"})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "Deploy", Command: "true"})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+
+	plain, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "MODE", Value: "production"})
+	if err != nil {
+		t.Fatalf("failed to create variable: %v", err)
+	}
+	if plain.Value != "production" {
+		t.Errorf("expected plain value to be returned, got %q", plain.Value)
+	}
+
+	secret, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "API_KEY", Value: "s3cr3t-value", IsSecret: true})
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	if secret.Value != "" {
+		t.Errorf("expected secret value to be redacted, got %q", secret.Value)
+	}
+
+	var stored string
+	if err := sqlDB.QueryRow("SELECT value FROM env_vars WHERE id = ?", secret.ID).Scan(&stored); err != nil {
+		t.Fatalf("failed to read stored value: %v", err)
+	}
+	if stored == "" || strings.Contains(stored, "s3cr3t-value") {
+		t.Errorf("expected secret to be stored encrypted, got %q", stored)
+	}
+
+	if _, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "MODE", Value: "again"}); err != services.ErrEnvVarExists {
+		t.Errorf("expected ErrEnvVarExists, got %v", err)
+	}
+	if _, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "BAD-NAME"}); !errors.Is(err, services.ErrInvalidEnvVar) {
+		t.Errorf("expected ErrInvalidEnvVar, got %v", err)
+	}
+
+	// Command variables override app variables of the same name
+	if _, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "MODE", Value: "staging", CommandID: cmd.ID}); err != nil {
+		t.Fatalf("failed to create command variable: %v", err)
+	}
+
+	vars, err := envSvc.ListEnvVars(app.ID)
+	if err != nil {
+		t.Fatalf("failed to list variables: %v", err)
+	}
+	if len(vars) != 3 {
+		t.Fatalf("expected 3 variables, got %d", len(vars))
+	}
+	for _, v := range vars {
+		if v.IsSecret && v.Value != "" {
+			t.Errorf("expected listed secret %s to be redacted", v.Name)
+		}
+	}
+
+	env, secrets, err := envSvc.ExecutionEnv(app.ID, cmd.ID)
+	if err != nil {
+		t.Fatalf("failed to resolve env: %v", err)
+	}
+	want := []string{"API_KEY=s3cr3t-value", "MODE=staging"}
+	if strings.Join(env, ",") != strings.Join(want, ",") {
+		t.Errorf("expected env %v, got %v", want, env)
+	}
+	if len(secrets) != 1 || secrets[0] != "s3cr3t-value" {
+		t.Errorf("expected decrypted secret, got %v", secrets)
+	}
+
+	// Updating without a value keeps the secret
+	notSecret := false
+	if _, err := envSvc.UpdateEnvVar(app.ID, secret.ID, &models.UpdateEnvVarRequest{IsSecret: &notSecret}); !errors.Is(err, services.ErrInvalidEnvVar) {
+		t.Errorf("expected ErrInvalidEnvVar when revealing a secret, got %v", err)
+	}
+	newValue := "rotated"
+	if _, err := envSvc.UpdateEnvVar(app.ID, secret.ID, &models.UpdateEnvVarRequest{Value: &newValue}); err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+	_, secrets, err = envSvc.ExecutionEnv(app.ID, "")
+	if err != nil {
+		t.Fatalf("failed to resolve env: %v", err)
+	}
+	if len(secrets) != 1 || secrets[0] != "rotated" {
+		t.Errorf("expected rotated secret, got %v", secrets)
+	}
+
+	if err := envSvc.DeleteEnvVar(app.ID, secret.ID); err != nil {
+		t.Fatalf("failed to delete variable: %v", err)
+	}
+	if err := envSvc.DeleteEnvVar(app.ID, secret.ID); err != services.ErrEnvVarNotFound {
+		t.Errorf("expected ErrEnvVarNotFound, got %v", err)
+	}
+}
+
+func TestExecutorService_ExecuteWithSecrets(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	sqlDB.SetMaxOpenConns(1)
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	envSvc := services.NewEnvService(db, newTestCryptoService(t))
+	execSvc := services.NewExecutorService(db, cfg, appSvc, envSvc, nil)
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Secret App", WorkingDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:    "Print",
+		Command: `echo "mode=$MODE"; echo "token=$TOKEN"; echo "$TOKEN" >&2`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+
+	if _, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "MODE", Value: "production"}); err != nil {
+		t.Fatalf("failed to create variable: %v", err)
+	}
+	if _, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "TOKEN", Value: "hunter2", IsSecret: true, CommandID: cmd.ID}); err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	execution, err := execSvc.CreateExecution(cmd.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+
+	ch := execSvc.Subscribe(execution.ID)
+	defer execSvc.Unsubscribe(execution.ID, ch)
+
+	if err := execSvc.Execute(execution.ID); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	result := waitForStatus(t, execSvc, execution.ID, models.ExecutionStatus.IsFinished)
+	if result.Status != models.StatusSuccess {
+		t.Fatalf("expected success, got %s: %s", result.Status, result.Output)
+	}
+	if !strings.Contains(result.Output, "mode=production") {
+		t.Errorf("expected plain variable in output, got %q", result.Output)
+	}
+	if strings.Contains(result.Output, "hunter2") {
+		t.Errorf("expected secret to be masked in stored output, got %q", result.Output)
+	}
+	if !strings.Contains(result.Output, "token=***") {
+		t.Errorf("expected masked secret in output, got %q", result.Output)
+	}
+
+	for {
+		select {
+		case msg := <-ch:
+			if strings.Contains(msg, "hunter2") {
+				t.Errorf("expected secret to be masked in stream, got %q", msg)
+			}
+			if strings.HasPrefix(msg, "complete:") {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestExecutorService_MasksSystemOutput(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	crypto := newTestCryptoService(t)
+	envSvc := services.NewEnvService(db, crypto)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, envSvc, services.NewGitService(db, crypto))
+
+	// The failing clone reports the repository path, which contains the secret
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{
+		Name:       "Secret Repo",
+		WorkingDir: filepath.Join(t.TempDir(), "checkout"),
+		GitRepoURL: filepath.Join(t.TempDir(), "hunter2"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "Deploy", Command: "true"})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+	if _, err := envSvc.CreateEnvVar(app.ID, &models.CreateEnvVarRequest{Name: "TOKEN", Value: "hunter2", IsSecret: true}); err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	execution, err := execSvc.CreateExecution(cmd.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	_ = execSvc.Execute(execution.ID)
+
+	result, _ := execSvc.GetExecutionByID(execution.ID)
+	if result.Status != models.StatusFailed {
+		t.Fatalf("expected the clone to fail, got %s: %s", result.Status, result.Output)
+	}
+	if strings.Contains(result.Output, "hunter2") || !strings.Contains(result.Output, "***") {
+		t.Errorf("expected secret to be masked in stored output, got %q", result.Output)
+	}
+
+	chunks, _, err := execSvc.GetOutput(execution.ID, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to get output: %v", err)
+	}
+	for _, chunk := range chunks {
+		if strings.Contains(chunk.Line, "hunter2") {
+			t.Errorf("expected secret to be masked in recorded %s line, got %q", chunk.Stream, chunk.Line)
+		}
+	}
+	// Errors that fail the execution before it runs are stored as its output
+	missing, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Missing Dir", WorkingDir: filepath.Join(t.TempDir(), "hunter2")})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	missingCmd, err := appSvc.CreateCommand(missing.ID, &models.CreateCommandRequest{Name: "Deploy", Command: "true"})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+	if _, err := envSvc.CreateEnvVar(missing.ID, &models.CreateEnvVarRequest{Name: "TOKEN", Value: "hunter2", IsSecret: true}); err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	execution, err = execSvc.CreateExecution(missingCmd.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	_ = execSvc.Execute(execution.ID)
+
+	result, _ = execSvc.GetExecutionByID(execution.ID)
+	if result.Status != models.StatusFailed || !strings.Contains(result.Output, "does not exist") {
+		t.Fatalf("expected the missing working directory to fail the execution, got %s: %s", result.Status, result.Output)
+	}
+	if strings.Contains(result.Output, "hunter2") {
+		t.Errorf("expected secret to be masked in the error, got %q", result.Output)
+	}
+}
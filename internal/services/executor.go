@@ -4,11 +4,14 @@ import (
 	"bufio"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,33 +25,147 @@ import (
 // ErrExecutionNotFound is returned when a requested execution does not exist.
 var ErrExecutionNotFound = errors.New("execution not found")
 
+// ErrExecutionNotRunning is returned when cancelling an execution that has already finished.
+var ErrExecutionNotRunning = errors.New("execution is not running")
+
+// ErrAppBusy is returned when an app with the reject concurrency policy already has an active execution.
+var ErrAppBusy = errors.New("app already has an active execution")
+
+// SystemUserID is the user ID recorded for executions that are not started by a user,
+// such as token deployments, webhooks and schedules.
+const SystemUserID int64 = 0
+
 // ExecutorService handles command execution and streaming.
 type ExecutorService struct {
-	db         *database.DB
-	cfg        *config.Config
-	appService *AppService
-	streams    map[string][]chan string
-	streamsMu  sync.RWMutex
+	db          *database.DB
+	cfg         *config.Config
+	appService  *AppService
+	envService  *EnvService
+	gitService  *GitService
+	notifier    *NotificationService
+	policy      *PolicyService
+	streams     map[string][]chan string
+	running     map[string]context.CancelFunc
+	recorders   map[string]*outputRecorder
+	queues      map[string][]string // app ID -> active execution IDs in start order; only the head may run
+	queueCond   *sync.Cond
+	streamsMu   sync.RWMutex
+	runningMu   sync.Mutex
+	queueMu     sync.Mutex
+	recordersMu sync.Mutex
+	search      bool // whether the executions_fts index exists
 }
 
 // NewExecutorService creates a new ExecutorService instance.
-func NewExecutorService(db *database.DB, cfg *config.Config, appService *AppService) *ExecutorService {
-	return &ExecutorService{
+// envService may be nil, in which case executions only receive their parameters as environment.
+// gitService may be nil if no app has a git source.
+func NewExecutorService(db *database.DB, cfg *config.Config, appService *AppService, envService *EnvService, gitService *GitService) *ExecutorService {
+	s := &ExecutorService{
 		db:         db,
 		cfg:        cfg,
 		appService: appService,
+		envService: envService,
+		gitService: gitService,
 		streams:    make(map[string][]chan string),
+		running:    make(map[string]context.CancelFunc),
+		recorders:  make(map[string]*outputRecorder),
+		queues:     make(map[string][]string),
+		search:     db.HasExecutionSearch(),
 	}
+	s.queueCond = sync.NewCond(&s.queueMu)
+	return s
 }
 
-// CreateExecution creates a new execution record for the given command and user.
+// ExecutionOptions contains optional inputs for a new execution.
+// Ref and CommitSHA select what to check out for apps with a git source; CommitSHA takes precedence.
+// RecordRef accepts them for apps without a git source too, where they are only recorded on the
+// execution, as for pushes reported by a webhook.
+// RerunOf links the execution to the execution it repeats.
+// SkipApproval creates the execution as pending even if its app or command requires approval,
+// for executions that repeat one that was already approved.
+type ExecutionOptions struct {
+	Parameters   map[string]string
+	Ref          string
+	CommitSHA    string
+	RerunOf      string
+	RecordRef    bool
+	SkipApproval bool
+}
+
+// CreateExecution creates a new execution record for the given command and user,
+// applying the concurrency policy of the command's app.
 func (s *ExecutorService) CreateExecution(commandID string, userID int64) (*models.Execution, error) {
+	return s.CreateExecutionWithOptions(commandID, userID, ExecutionOptions{})
+}
+
+// CreateExecutionWithOptions creates a new execution like CreateExecution.
+// Parameter values are validated against the command's parameters and stored with defaults applied.
+// The ref recorded for apps with a git source defaults to the app's ref.
+// If the app, the command or the command policy requires approval, the execution awaits approval
+// instead of being queued, and Execute does not run it until it is approved. A command rejected by
+// the policy returns ErrPolicyViolation.
+func (s *ExecutorService) CreateExecutionWithOptions(commandID string, userID int64, opts ExecutionOptions) (*models.Execution, error) {
+	command, err := s.appService.GetCommandByID(commandID)
+	if err != nil {
+		return nil, err
+	}
+	app, err := s.appService.GetAppByID(command.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := ResolveParameters(command.Parameters, opts.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	requiresApproval := app.RequiresApproval || command.RequiresApproval
+	if s.policy != nil {
+		approval, err := s.enforcePolicy(command, userID)
+		if err != nil {
+			return nil, err
+		}
+		requiresApproval = requiresApproval || approval
+	}
+	if err := validateGitTarget(app, opts.Ref, opts.CommitSHA, opts.RecordRef); err != nil {
+		return nil, err
+	}
+	ref := opts.Ref
+	if ref == "" && opts.CommitSHA == "" {
+		ref = app.GitRef
+	}
+	var paramsJSON interface{}
+	if len(params) > 0 {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		paramsJSON = string(data)
+	}
+
 	id := uuid.New().String()
 
-	_, err := s.db.Exec(
-		"INSERT INTO executions (id, command_id, user_id, status) VALUES (?, ?, ?, ?)",
-		id, commandID, userID, models.StatusPending,
-	)
+	if requiresApproval && !opts.SkipApproval {
+		var expiresAt interface{}
+		if timeout := s.cfg.Execution.GetApprovalTimeout(); timeout > 0 {
+			expiresAt = time.Now().Add(timeout)
+		}
+		_, err = s.db.Exec(
+			"INSERT INTO executions (id, command_id, user_id, status, parameters, git_ref, commit_sha, rerun_of, approval_expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			id, commandID, userID, models.StatusAwaitingApproval, paramsJSON, nullString(ref), nullString(opts.CommitSHA), nullString(opts.RerunOf), expiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return s.GetExecutionByID(id)
+	}
+
+	err = s.admit(app, id, func() error {
+		_, err := s.db.Exec(
+			"INSERT INTO executions (id, command_id, user_id, status, parameters, git_ref, commit_sha, rerun_of) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			id, commandID, userID, models.StatusPending, paramsJSON, nullString(ref), nullString(opts.CommitSHA), nullString(opts.RerunOf),
+		)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -56,22 +173,180 @@ func (s *ExecutorService) CreateExecution(commandID string, userID int64) (*mode
 	return s.GetExecutionByID(id)
 }
 
-// GetExecutionByID retrieves a single execution by its ID.
-func (s *ExecutorService) GetExecutionByID(id string) (*models.Execution, error) {
-	var exec models.Execution
-	var output sql.NullString
-	var exitCode sql.NullInt64
-	var startedAt, finishedAt sql.NullTime
+// enforcePolicy checks the command, or the commands of the steps of a pipeline, against the command
+// policy for the user an execution runs for, and reports whether the policy requires approval.
+func (s *ExecutorService) enforcePolicy(command *models.Command, userID int64) (bool, error) {
+	commands := []string{command.Command}
+	if command.IsPipeline() {
+		commands = commands[:0]
+		for _, step := range command.Steps {
+			stepCommand, err := s.appService.GetCommandByID(step.CommandID)
+			if err != nil {
+				return false, err
+			}
+			commands = append(commands, stepCommand.Command)
+		}
+	}
 
-	err := s.db.QueryRow(
-		"SELECT id, command_id, user_id, status, output, exit_code, started_at, finished_at, created_at FROM executions WHERE id = ?",
-		id,
-	).Scan(&exec.ID, &exec.CommandID, &exec.UserID, &exec.Status, &output, &exitCode, &startedAt, &finishedAt, &exec.CreatedAt)
+	check := PolicyCheck{
+		User:         s.executionUser(userID),
+		Action:       "command_execute",
+		ResourceType: "command",
+		ResourceID:   command.ID,
+		Approvable:   true,
+	}
+	requiresApproval := false
+	for _, text := range commands {
+		approval, err := s.policy.Enforce(text, check)
+		if err != nil {
+			return false, err
+		}
+		requiresApproval = requiresApproval || approval
+	}
+	return requiresApproval, nil
+}
 
-	if err == sql.ErrNoRows {
-		return nil, ErrExecutionNotFound
+// executionUser returns the user an execution runs for, or nil for executions without a user
+// and users that no longer exist.
+func (s *ExecutorService) executionUser(userID int64) *models.User {
+	if userID == SystemUserID {
+		return nil
 	}
+	var user models.User
+	err := s.db.QueryRow("SELECT id, username, COALESCE(role, 'operator') FROM users WHERE id = ?", userID).Scan(&user.ID, &user.Username, &user.Role)
 	if err != nil {
+		return nil
+	}
+	return &user
+}
+
+// admit applies the app's concurrency policy to a pending execution and adds it to the app's queue.
+// store saves the execution as pending; it runs while the queue is locked so that executions
+// triggered at the same time are admitted one by one.
+func (s *ExecutorService) admit(app *models.App, id string, store func() error) error {
+	s.queueMu.Lock()
+	previous := append([]string(nil), s.queues[app.ID]...)
+	if len(previous) > 0 && app.ConcurrencyPolicy == models.ConcurrencyReject {
+		s.queueMu.Unlock()
+		return ErrAppBusy
+	}
+
+	if err := store(); err != nil {
+		s.queueMu.Unlock()
+		return err
+	}
+	s.queues[app.ID] = append(s.queues[app.ID], id)
+	s.queueMu.Unlock()
+
+	if app.ConcurrencyPolicy == models.ConcurrencyCancelPrevious {
+		for _, previousID := range previous {
+			if err := s.Cancel(previousID, "system"); err != nil && err != ErrExecutionNotRunning {
+				log.Printf("[Executor] Failed to cancel execution %s superseded by %s: %v", previousID, id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// nullString returns nil for an empty string so that it is stored as NULL.
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// ActiveExecutionID returns the execution currently holding the app's run slot, if any.
+func (s *ExecutorService) ActiveExecutionID(appID string) string {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	if queue := s.queues[appID]; len(queue) > 0 {
+		return queue[0]
+	}
+	return ""
+}
+
+// waitForTurn blocks until the execution is at the head of its app's queue.
+// It returns false if the execution was cancelled while waiting.
+func (s *ExecutorService) waitForTurn(ctx context.Context, appID, executionID string) bool {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	queued := false
+	for _, id := range s.queues[appID] {
+		if id == executionID {
+			queued = true
+			break
+		}
+	}
+	if !queued {
+		// Created by another process or before a restart
+		s.queues[appID] = append(s.queues[appID], executionID)
+	}
+
+	for s.queues[appID][0] != executionID {
+		if ctx.Err() != nil {
+			return false
+		}
+		s.queueCond.Wait()
+	}
+	return ctx.Err() == nil
+}
+
+// dequeue removes an execution from its app's queue and wakes up waiting executions.
+func (s *ExecutorService) dequeue(executionID string) {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	for appID, queue := range s.queues {
+		for i, id := range queue {
+			if id != executionID {
+				continue
+			}
+			s.queues[appID] = append(queue[:i:i], queue[i+1:]...)
+			if len(s.queues[appID]) == 0 {
+				delete(s.queues, appID)
+			}
+			s.queueCond.Broadcast()
+			return
+		}
+	}
+}
+
+// queuePosition returns the number of executions that have to finish before the given one can start.
+func (s *ExecutorService) queuePosition(executionID string) int {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	for _, queue := range s.queues {
+		for i, id := range queue {
+			if id == executionID {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// executionColumns lists the executions columns read by scanExecution, in order.
+const executionColumns = `e.id, e.command_id, e.user_id, e.status, e.output, e.exit_code, e.started_at, e.finished_at,
+	COALESCE(e.cancelled_by, ''), e.parameters, COALESCE(e.git_ref, ''), COALESCE(e.commit_sha, ''),
+	COALESCE(e.reviewed_by, ''), e.reviewed_at, COALESCE(e.review_comment, ''), e.approval_expires_at, COALESCE(e.rerun_of, ''), e.created_at`
+
+// scanExecution scans a row selected with executionColumns, followed by any extra columns.
+func (s *ExecutorService) scanExecution(row rowScanner, extra ...interface{}) (*models.Execution, error) {
+	var exec models.Execution
+	var output, parameters sql.NullString
+	var exitCode sql.NullInt64
+	var startedAt, finishedAt, reviewedAt, expiresAt sql.NullTime
+
+	dest := []interface{}{
+		&exec.ID, &exec.CommandID, &exec.UserID, &exec.Status, &output, &exitCode, &startedAt, &finishedAt,
+		&exec.CancelledBy, &parameters, &exec.GitRef, &exec.CommitSHA,
+		&exec.ReviewedBy, &reviewedAt, &exec.ReviewComment, &expiresAt, &exec.RerunOf, &exec.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
 		return nil, err
 	}
 
@@ -88,100 +363,109 @@ func (s *ExecutorService) GetExecutionByID(id string) (*models.Execution, error)
 	if finishedAt.Valid {
 		exec.FinishedAt = &finishedAt.Time
 	}
-
+	if reviewedAt.Valid {
+		exec.ReviewedAt = &reviewedAt.Time
+	}
+	if expiresAt.Valid {
+		exec.ApprovalExpiresAt = &expiresAt.Time
+	}
+	if parameters.String != "" {
+		if err := json.Unmarshal([]byte(parameters.String), &exec.Parameters); err != nil {
+			return nil, err
+		}
+	}
+	if exec.Status == models.StatusPending {
+		exec.QueuePosition = s.queuePosition(exec.ID)
+	}
 	return &exec, nil
 }
 
-// GetExecutions retrieves a paginated list of executions with command and app details.
-func (s *ExecutorService) GetExecutions(limit, offset int) ([]models.ExecutionWithDetails, error) {
-	if limit == 0 {
-		limit = 50
+// GetExecutionByID retrieves a single execution by its ID.
+func (s *ExecutorService) GetExecutionByID(id string) (*models.Execution, error) {
+	exec, err := s.scanExecution(s.db.QueryRow("SELECT "+executionColumns+" FROM executions e WHERE e.id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrExecutionNotFound
 	}
-
-	rows, err := s.db.Query(`
-		SELECT e.id, e.command_id, e.user_id, e.status, e.output, e.exit_code,
-		       e.started_at, e.finished_at, e.created_at,
-		       c.name as command_name, a.name as app_name, COALESCE(u.username, 'API') as username
-		FROM executions e
-		JOIN commands c ON e.command_id = c.id
-		JOIN apps a ON c.app_id = a.id
-		LEFT JOIN users u ON e.user_id = u.id
-		ORDER BY e.created_at DESC
-		LIMIT ? OFFSET ?
-	`, limit, offset)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = rows.Close() }()
 
-	var executions []models.ExecutionWithDetails
-	for rows.Next() {
-		var exec models.ExecutionWithDetails
-		var output sql.NullString
-		var exitCode sql.NullInt64
-		var startedAt, finishedAt sql.NullTime
-
-		if err := rows.Scan(
-			&exec.ID, &exec.CommandID, &exec.UserID, &exec.Status, &output, &exitCode,
-			&startedAt, &finishedAt, &exec.CreatedAt,
-			&exec.CommandName, &exec.AppName, &exec.Username,
-		); err != nil {
-			return nil, err
-		}
+	if exec.Steps, err = s.getExecutionSteps(id); err != nil {
+		return nil, err
+	}
 
-		if output.Valid {
-			exec.Output = output.String
-		}
-		if exitCode.Valid {
-			code := int(exitCode.Int64)
-			exec.ExitCode = &code
-		}
-		if startedAt.Valid {
-			exec.StartedAt = &startedAt.Time
-		}
-		if finishedAt.Valid {
-			exec.FinishedAt = &finishedAt.Time
-		}
+	return exec, nil
+}
 
-		executions = append(executions, exec)
-	}
-	return executions, nil
+// GetExecutions retrieves a paginated list of executions with command and app details.
+func (s *ExecutorService) GetExecutions(limit, offset int) ([]models.ExecutionWithDetails, error) {
+	return s.ListExecutions(ExecutionFilter{Limit: limit, Offset: offset})
 }
 
 // Execute runs a command execution asynchronously and streams output to subscribers.
 func (s *ExecutorService) Execute(executionID string) error {
 	log.Printf("[Executor] Starting execution %s", executionID)
 
+	// Register before reading the status so a concurrent Cancel either sees the
+	// execution as running or has already marked it cancelled
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+	s.trackRunning(executionID, cancelRun)
+	defer s.untrackRunning(executionID)
+	defer s.dequeue(executionID)
+
 	execution, err := s.GetExecutionByID(executionID)
 	if err != nil {
 		log.Printf("[Executor] Error getting execution %s: %v", executionID, err)
-		s.finishExecution(executionID, models.StatusFailed, err.Error(), -1)
-		s.broadcastComplete(executionID, -1, models.StatusFailed)
+		s.failExecution(executionID, err.Error(), nil)
 		return err
 	}
 
+	switch execution.Status {
+	case models.StatusCancelled:
+		log.Printf("[Executor] Execution %s was cancelled before it started", executionID)
+		return nil
+	case models.StatusAwaitingApproval, models.StatusRejected:
+		log.Printf("[Executor] Execution %s is not approved, not starting it", executionID)
+		return nil
+	}
+
 	command, err := s.appService.GetCommandByID(execution.CommandID)
 	if err != nil {
 		log.Printf("[Executor] Error getting command for execution %s: %v", executionID, err)
-		s.finishExecution(executionID, models.StatusFailed, err.Error(), -1)
-		s.broadcastComplete(executionID, -1, models.StatusFailed)
+		s.failExecution(executionID, err.Error(), nil)
 		return err
 	}
 
 	app, err := s.appService.GetAppByID(command.AppID)
 	if err != nil {
 		log.Printf("[Executor] Error getting app for execution %s: %v", executionID, err)
-		s.finishExecution(executionID, models.StatusFailed, err.Error(), -1)
-		s.broadcastComplete(executionID, -1, models.StatusFailed)
+		masker, _ := s.executionMasker(command.AppID, command)
+		s.failExecution(executionID, err.Error(), masker)
+		return err
+	}
+
+	// Wait for earlier executions of the same app, they share the working directory
+	if !s.waitForTurn(runCtx, app.ID, executionID) {
+		log.Printf("[Executor] Execution %s was cancelled before it started", executionID)
+		s.finishExecution(executionID, models.StatusCancelled, "", -1)
+		s.broadcastComplete(executionID, -1, models.StatusCancelled)
+		return nil
+	}
+
+	// Every line of the output is masked, including system lines, git output and errors
+	masker, err := s.executionMasker(app.ID, command)
+	if err != nil {
+		log.Printf("[Executor] Error loading secrets for execution %s: %v", executionID, err)
+		s.failExecution(executionID, err.Error(), nil)
 		return err
 	}
 
-	// Check if working directory exists
-	if _, err := os.Stat(app.WorkingDir); os.IsNotExist(err) {
+	// Check if working directory exists, apps with a git source clone it on the first sync
+	if _, err := os.Stat(app.WorkingDir); os.IsNotExist(err) && !app.HasGitSource() {
 		errMsg := "Working directory does not exist: " + app.WorkingDir
 		log.Printf("[Executor] %s (execution %s)", errMsg, executionID)
-		s.finishExecution(executionID, models.StatusFailed, errMsg, -1)
-		s.broadcastComplete(executionID, -1, models.StatusFailed)
+		s.failExecution(executionID, errMsg, masker)
 		return errors.New(errMsg)
 	}
 
@@ -192,128 +476,550 @@ func (s *ExecutorService) Execute(executionID string) error {
 		"UPDATE executions SET status = ?, started_at = ? WHERE id = ?",
 		models.StatusRunning, now, executionID,
 	)
+	s.startRecording(executionID, now, masker)
+
+	ctx, cancel := context.WithTimeout(runCtx, s.timeoutFor(command))
+	defer cancel()
+
+	output := newOutputBuffer(s.cfg.Execution.MaxOutputSize, masker)
+
+	if app.HasGitSource() {
+		if err := s.syncSource(ctx, executionID, app, execution, output); err != nil {
+			status := models.StatusFailed
+			if runCtx.Err() != nil {
+				status = models.StatusCancelled
+			}
+			finalOutput, _ := output.result()
+			s.finishExecution(executionID, status, finalOutput, -1)
+			s.broadcastComplete(executionID, -1, status)
+			return err
+		}
+	}
+
+	var exitCode int
+	if command.IsPipeline() {
+		exitCode = s.runPipeline(ctx, executionID, app, command, execution.Parameters, output)
+	} else {
+		var spec processSpec
+		spec, err = s.processSpecFor(app, command, execution.Parameters)
+		if err == nil {
+			exitCode, err = s.runProcess(ctx, executionID, spec, output)
+		}
+		if err != nil {
+			log.Printf("[Executor] Error starting command: %v (execution %s)", err, executionID)
+			s.failExecution(executionID, err.Error(), masker)
+			return err
+		}
+	}
+
+	status := models.StatusSuccess
+	if exitCode != 0 {
+		status = models.StatusFailed
+	}
+	if runCtx.Err() != nil {
+		status = models.StatusCancelled
+	}
+
+	finalOutput, truncated := output.result()
+
+	s.finishExecution(executionID, status, finalOutput, exitCode)
+	s.broadcastComplete(executionID, exitCode, status)
+
+	if truncated {
+		log.Printf("[Executor] Finished execution %s with status=%s, exit_code=%d (output truncated at %d bytes)", executionID, status, exitCode, s.cfg.Execution.MaxOutputSize)
+	} else {
+		log.Printf("[Executor] Finished execution %s with status=%s, exit_code=%d", executionID, status, exitCode)
+	}
+
+	return nil
+}
 
+// Cancel stops an execution on behalf of cancelledBy. A running execution has its process group
+// terminated; a pending execution or one awaiting approval is marked cancelled so that it never starts.
+func (s *ExecutorService) Cancel(executionID, cancelledBy string) error {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	execution, err := s.GetExecutionByID(executionID)
+	if err != nil {
+		return err
+	}
+
+	cancel, running := s.running[executionID]
+	waiting := execution.Status == models.StatusPending || execution.Status == models.StatusAwaitingApproval
+	active := waiting || (running && execution.Status == models.StatusRunning)
+	if !active {
+		return ErrExecutionNotRunning
+	}
+	if execution.CancelledBy != "" {
+		// Already being cancelled, keep the first requester
+		return nil
+	}
+
+	if _, err := s.db.Exec("UPDATE executions SET cancelled_by = ? WHERE id = ?", cancelledBy, executionID); err != nil {
+		return err
+	}
+
+	if running {
+		log.Printf("[Executor] Cancelling execution %s (requested by %s)", executionID, cancelledBy)
+		cancel()
+		// Wake the execution up if it is waiting in the queue
+		s.queueMu.Lock()
+		s.queueCond.Broadcast()
+		s.queueMu.Unlock()
+		return nil
+	}
+
+	log.Printf("[Executor] Cancelled pending execution %s (requested by %s)", executionID, cancelledBy)
+	s.dequeue(executionID)
+	s.finishExecution(executionID, models.StatusCancelled, "", -1)
+	s.broadcastComplete(executionID, -1, models.StatusCancelled)
+	return nil
+}
+
+func (s *ExecutorService) trackRunning(executionID string, cancel context.CancelFunc) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	s.running[executionID] = cancel
+}
+
+func (s *ExecutorService) untrackRunning(executionID string) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	delete(s.running, executionID)
+}
+
+// timeoutFor returns the effective timeout of a command, capped by the configured maximum.
+func (s *ExecutorService) timeoutFor(command *models.Command) time.Duration {
 	timeout := command.TimeoutSeconds
 	if timeout > s.cfg.Execution.MaxTimeout {
 		timeout = s.cfg.Execution.MaxTimeout
 	}
+	return time.Duration(timeout) * time.Second
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+// syncSource checks out the requested commit of an app's git source and records its SHA on the execution.
+func (s *ExecutorService) syncSource(ctx context.Context, executionID string, app *models.App, execution *models.Execution, output *outputBuffer) error {
+	logf := func(line string) {
+		output.appendLine(line)
+		s.emit(executionID, models.StreamSystem, line)
+	}
 
-	// #nosec G204 - command execution with user input is expected behavior for this service
-	cmd := exec.CommandContext(ctx, "sh", "-c", command.Command)
-	cmd.Dir = app.WorkingDir
+	if s.gitService == nil {
+		err := errors.New("git sources are not configured")
+		logf("Git sync failed: " + err.Error())
+		return err
+	}
 
-	stdout, err := cmd.StdoutPipe()
+	sha, err := s.gitService.Sync(ctx, app, execution.GitRef, execution.CommitSHA, logf)
 	if err != nil {
-		log.Printf("[Executor] Error creating stdout pipe: %v (execution %s)", err, executionID)
-		s.finishExecution(executionID, models.StatusFailed, err.Error(), -1)
-		s.broadcastComplete(executionID, -1, models.StatusFailed)
+		log.Printf("[Executor] Git sync failed for execution %s: %v", executionID, err)
+		logf("Git sync failed: " + err.Error())
 		return err
 	}
 
-	stderr, err := cmd.StderrPipe()
+	_, _ = s.db.Exec("UPDATE executions SET commit_sha = ? WHERE id = ?", sha, executionID)
+	return nil
+}
+
+// processSpec describes a shell script to run for an execution.
+type processSpec struct {
+	dir    string
+	script string
+	env    []string
+	runAs  *runAsIdentity // nil to run as the server's own user
+	limits models.ResourceLimits
+}
+
+// executionMasker returns the masker for the secrets of an execution of command of the app with appID,
+// or nil if it has none. The secrets of all steps of a pipeline are masked throughout its output.
+func (s *ExecutorService) executionMasker(appID string, command *models.Command) (*strings.Replacer, error) {
+	if s.envService == nil {
+		return nil, nil
+	}
+
+	commandIDs := []string{command.ID}
+	for _, step := range command.Steps {
+		commandIDs = append(commandIDs, step.CommandID)
+	}
+	var secrets []string
+	for _, commandID := range commandIDs {
+		_, commandSecrets, err := s.envService.ExecutionEnv(appID, commandID)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, commandSecrets...)
+	}
+	return newSecretMasker(secrets), nil
+}
+
+// processSpecFor builds the process for a shell command of an app. The environment contains the
+// run_as user's HOME/USER, the app and command variables, and the parameter values, in that order.
+func (s *ExecutorService) processSpecFor(app *models.App, command *models.Command, params map[string]string) (processSpec, error) {
+	spec := processSpec{dir: app.WorkingDir, script: command.Command, limits: app.ResourceLimits}
+
+	runAs, err := resolveRunAs(app.RunAsUser, app.RunAsGroup)
 	if err != nil {
-		log.Printf("[Executor] Error creating stderr pipe: %v (execution %s)", err, executionID)
-		s.finishExecution(executionID, models.StatusFailed, err.Error(), -1)
-		s.broadcastComplete(executionID, -1, models.StatusFailed)
-		return err
+		return spec, err
+	}
+	if runAs != nil {
+		spec.runAs = runAs
+		spec.env = append(spec.env, runAs.env...)
 	}
 
-	if err := cmd.Start(); err != nil {
-		log.Printf("[Executor] Error starting command: %v (execution %s)", err, executionID)
-		s.finishExecution(executionID, models.StatusFailed, err.Error(), -1)
-		s.broadcastComplete(executionID, -1, models.StatusFailed)
-		return err
+	if s.envService != nil {
+		env, _, err := s.envService.ExecutionEnv(app.ID, command.ID)
+		if err != nil {
+			return spec, err
+		}
+		spec.env = append(spec.env, env...)
+	}
+	spec.env = append(spec.env, parameterEnv(params)...)
+	return spec, nil
+}
+
+// runProcess runs a shell script and streams its output to subscribers and the given buffers.
+// It returns the exit code of the process; err is only set if the process could not be started.
+func (s *ExecutorService) runProcess(ctx context.Context, executionID string, spec processSpec, outputs ...*outputBuffer) (int, error) {
+	limiter, err := newResourceLimiter(spec.limits)
+	if err != nil {
+		return -1, err
 	}
+	defer limiter.release()
 
-	var output string
-	var outputMu sync.Mutex
-	var outputLimitExceeded bool
+	// #nosec G204 - command execution with user input is expected behavior for this service
+	cmd := exec.CommandContext(ctx, "sh", "-c", limiter.wrap(spec.script))
+	cmd.Dir = spec.dir
+	if len(spec.env) > 0 {
+		cmd.Env = append(os.Environ(), spec.env...)
+	}
+	configureProcessGroup(cmd, s.cfg.Execution.GetKillGracePeriod())
+	applyRunAs(cmd, spec.runAs)
+	limiter.prepare(cmd)
+
+	// Output is copied through in-process pipes so that Wait returns only after all of it was read
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+	if err := limiter.started(cmd.Process.Pid); err != nil {
+		// The shell exits without running the script
+		_ = cmd.Wait()
+		return -1, err
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
 		defer wg.Done()
-		s.streamOutput(executionID, stdout, &output, &outputMu, &outputLimitExceeded)
+		s.streamOutput(executionID, stdout, models.StreamStdout, outputs...)
 	}()
 	go func() {
 		defer wg.Done()
-		s.streamOutput(executionID, stderr, &output, &outputMu, &outputLimitExceeded)
+		s.streamOutput(executionID, stderr, models.StreamStderr, outputs...)
 	}()
 
 	err = cmd.Wait()
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
 	wg.Wait()
 
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		if errors.Is(err, exec.ErrWaitDelay) {
+			// The command succeeded but a background process kept its output open
+			return 0, nil
+		}
+		return -1, nil
+	}
+	return 0, nil
+}
+
+// runPipeline runs the steps of a pipeline command in order and returns the exit code of the pipeline.
+// The pipeline stops at the first failing step unless that step is marked continue_on_error.
+func (s *ExecutorService) runPipeline(ctx context.Context, executionID string, app *models.App, pipeline *models.Command, params map[string]string, output *outputBuffer) int {
+	steps, err := s.createExecutionSteps(executionID, pipeline.Steps)
+	if err != nil {
+		msg := "Failed to prepare pipeline steps: " + err.Error()
+		output.appendLine(msg)
+		s.emit(executionID, models.StreamSystem, msg)
+		return -1
+	}
+
 	exitCode := 0
-	status := models.StatusSuccess
+	for i := range steps {
+		step := &steps[i]
+
+		if exitCode != 0 || ctx.Err() != nil {
+			step.Status = models.StatusSkipped
+			s.updateExecutionStep(step)
+			s.broadcastStep(executionID, step)
+			continue
+		}
+
+		header := fmt.Sprintf("==> [%d/%d] %s", i+1, len(steps), step.Name)
+		output.appendLine(header)
+		s.emit(executionID, models.StreamSystem, header)
+
+		startedAt := time.Now()
+		step.StartedAt = &startedAt
+		step.Status = models.StatusRunning
+		s.updateExecutionStep(step)
+		s.broadcastStep(executionID, step)
+
+		stepOutput := newOutputBuffer(s.cfg.Execution.MaxOutputSize, output.masker)
+		code := s.runStep(ctx, executionID, app, step, params, output, stepOutput)
+
+		finishedAt := time.Now()
+		step.FinishedAt = &finishedAt
+		step.DurationMs = finishedAt.Sub(startedAt).Milliseconds()
+		step.ExitCode = &code
+		step.Output, _ = stepOutput.result()
+		step.Status = models.StatusSuccess
+		if code != 0 {
+			step.Status = models.StatusFailed
+			if errors.Is(ctx.Err(), context.Canceled) {
+				step.Status = models.StatusCancelled
+				exitCode = code
+			} else if !step.ContinueOnError {
+				exitCode = code
+			}
+		}
+		s.updateExecutionStep(step)
+		s.broadcastStep(executionID, step)
+	}
 
+	return exitCode
+}
+
+// runStep runs a single pipeline step with its own command timeout.
+// The step receives the pipeline's parameters plus the defaults of its own parameters.
+func (s *ExecutorService) runStep(ctx context.Context, executionID string, app *models.App, step *models.ExecutionStep, params map[string]string, outputs ...*outputBuffer) int {
+	command, err := s.appService.GetCommandByID(step.CommandID)
 	if err != nil {
-		status = models.StatusFailed
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
-			exitCode = -1
+		for _, o := range outputs {
+			o.appendLine(err.Error())
 		}
+		s.emit(executionID, models.StreamSystem, err.Error())
+		return -1
 	}
 
-	outputMu.Lock()
-	finalOutput := output
-	outputMu.Unlock()
+	stepCtx, cancel := context.WithTimeout(ctx, s.timeoutFor(command))
+	defer cancel()
 
-	s.finishExecution(executionID, status, finalOutput, exitCode)
-	s.broadcastComplete(executionID, exitCode, status)
+	stepParams := make(map[string]string, len(params)+len(command.Parameters))
+	for _, p := range command.Parameters {
+		stepParams[p.Name] = p.Default
+	}
+	for name, value := range params {
+		stepParams[name] = value
+	}
 
-	if outputLimitExceeded {
-		log.Printf("[Executor] Finished execution %s with status=%s, exit_code=%d (output truncated at %d bytes)", executionID, status, exitCode, s.cfg.Execution.MaxOutputSize)
-	} else {
-		log.Printf("[Executor] Finished execution %s with status=%s, exit_code=%d", executionID, status, exitCode)
+	var code int
+	spec, err := s.processSpecFor(app, command, stepParams)
+	if err == nil {
+		code, err = s.runProcess(stepCtx, executionID, spec, outputs...)
+	}
+	if err != nil {
+		for _, o := range outputs {
+			o.appendLine(err.Error())
+		}
+		s.emit(executionID, models.StreamSystem, err.Error())
+		return -1
 	}
+	return code
+}
 
-	return nil
+// createExecutionSteps records all pipeline steps as pending for an execution.
+func (s *ExecutorService) createExecutionSteps(executionID string, pipelineSteps []models.PipelineStep) ([]models.ExecutionStep, error) {
+	steps := make([]models.ExecutionStep, 0, len(pipelineSteps))
+	for _, ps := range pipelineSteps {
+		step := models.ExecutionStep{
+			ID:              uuid.New().String(),
+			ExecutionID:     executionID,
+			CommandID:       ps.CommandID,
+			Name:            ps.CommandName,
+			StepOrder:       ps.StepOrder,
+			ContinueOnError: ps.ContinueOnError,
+			Status:          models.StatusPending,
+		}
+		_, err := s.db.Exec(
+			"INSERT INTO execution_steps (id, execution_id, command_id, name, step_order, continue_on_error, status) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			step.ID, step.ExecutionID, step.CommandID, step.Name, step.StepOrder, step.ContinueOnError, step.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (s *ExecutorService) updateExecutionStep(step *models.ExecutionStep) {
+	_, _ = s.db.Exec(
+		"UPDATE execution_steps SET status = ?, output = ?, exit_code = ?, started_at = ?, finished_at = ? WHERE id = ?",
+		step.Status, step.Output, step.ExitCode, step.StartedAt, step.FinishedAt, step.ID,
+	)
+}
+
+// getExecutionSteps retrieves the pipeline step results of an execution.
+func (s *ExecutorService) getExecutionSteps(executionID string) ([]models.ExecutionStep, error) {
+	rows, err := s.db.Query(`
+		SELECT id, execution_id, command_id, name, step_order, continue_on_error, status, output, exit_code, started_at, finished_at
+		FROM execution_steps
+		WHERE execution_id = ?
+		ORDER BY step_order
+	`, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var steps []models.ExecutionStep
+	for rows.Next() {
+		var step models.ExecutionStep
+		var output sql.NullString
+		var exitCode sql.NullInt64
+		var startedAt, finishedAt sql.NullTime
+
+		if err := rows.Scan(
+			&step.ID, &step.ExecutionID, &step.CommandID, &step.Name, &step.StepOrder, &step.ContinueOnError,
+			&step.Status, &output, &exitCode, &startedAt, &finishedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		step.Output = output.String
+		if exitCode.Valid {
+			code := int(exitCode.Int64)
+			step.ExitCode = &code
+		}
+		if startedAt.Valid {
+			step.StartedAt = &startedAt.Time
+		}
+		if finishedAt.Valid {
+			step.FinishedAt = &finishedAt.Time
+		}
+		if step.StartedAt != nil && step.FinishedAt != nil {
+			step.DurationMs = step.FinishedAt.Sub(*step.StartedAt).Milliseconds()
+		}
+
+		steps = append(steps, step)
+	}
+	return steps, nil
 }
 
-func (s *ExecutorService) streamOutput(executionID string, r io.Reader, output *string, mu *sync.Mutex, outputLimitExceeded *bool) {
+// outputBuffer accumulates execution output up to a maximum size, masking secret values.
+type outputBuffer struct {
+	mu        sync.Mutex
+	data      strings.Builder
+	masker    *strings.Replacer // nil if the execution has no secrets
+	maxSize   int
+	truncated bool
+}
+
+func newOutputBuffer(maxSize int, masker *strings.Replacer) *outputBuffer {
+	return &outputBuffer{maxSize: maxSize, masker: masker}
+}
+
+// appendLine adds a line to the buffer, truncating once max_output_size is exceeded.
+func (b *outputBuffer) appendLine(line string) {
+	if b.masker != nil {
+		line = b.masker.Replace(line)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	currentLen := b.data.Len()
+	if currentLen >= b.maxSize {
+		return
+	}
+
+	// Check if adding this line would exceed the limit
+	newLine := line + "\n"
+	if currentLen+len(newLine) > b.maxSize {
+		// Truncate the line to fit exactly
+		b.data.WriteString(newLine[:b.maxSize-currentLen])
+		b.data.WriteString("\n... [OUTPUT TRUNCATED - exceeded max_output_size limit]\n")
+		b.truncated = true
+		return
+	}
+	b.data.WriteString(newLine)
+}
+
+// result returns the buffered output and whether it was truncated.
+func (b *outputBuffer) result() (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data.String(), b.truncated
+}
+
+// streamOutput copies the lines read from r to the buffers and records them as the given stream.
+// The buffers and the recorder of the execution mask secrets.
+func (s *ExecutorService) streamOutput(executionID string, r io.Reader, stream models.OutputStream, outputs ...*outputBuffer) {
 	scanner := bufio.NewScanner(r)
-	maxSize := s.cfg.Execution.MaxOutputSize
 
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		mu.Lock()
-		currentLen := len(*output)
-		if currentLen < maxSize {
-			// Check if adding this line would exceed the limit
-			newLine := line + "\n"
-			if currentLen+len(newLine) > maxSize {
-				// Truncate the line to fit exactly
-				remaining := maxSize - currentLen
-				if remaining > 0 {
-					*output += newLine[:remaining]
-				}
-				*output += "\n... [OUTPUT TRUNCATED - exceeded max_output_size limit]\n"
-				*outputLimitExceeded = true
-			} else {
-				*output += newLine
-			}
+		for _, o := range outputs {
+			o.appendLine(line)
 		}
-		mu.Unlock()
 
 		// Always broadcast to live subscribers even if output is truncated
-		s.broadcastLine(executionID, line)
+		s.emit(executionID, stream, line)
 	}
+
+	// Keep draining after a scan error (e.g. an overlong line) so the process never blocks on writes
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// failExecution finishes an execution that could not run with message as its output, masking the
+// secrets of the execution first. masker is nil if they are not known.
+func (s *ExecutorService) failExecution(id, message string, masker *strings.Replacer) {
+	if masker != nil {
+		message = masker.Replace(message)
+	}
+	s.finishExecution(id, models.StatusFailed, message, -1)
+	s.broadcastComplete(id, -1, models.StatusFailed)
 }
 
 func (s *ExecutorService) finishExecution(id string, status models.ExecutionStatus, output string, exitCode int) {
+	s.stopRecording(id)
+
 	now := time.Now()
 	_, _ = s.db.Exec(
 		"UPDATE executions SET status = ?, output = ?, exit_code = ?, finished_at = ? WHERE id = ?",
 		status, output, exitCode, now, id,
 	)
+	s.indexOutput(id, output)
+	if s.notifier != nil {
+		s.notifier.Notify(id)
+	}
+}
+
+// SetNotificationService sends notifications through n whenever an execution finishes.
+func (s *ExecutorService) SetNotificationService(n *NotificationService) {
+	s.notifier = n
+}
+
+// SetPolicyService checks the commands of new executions against the command policy of p.
+func (s *ExecutorService) SetPolicyService(p *PolicyService) {
+	s.policy = p
 }
 
 // Subscribe creates a new channel to receive execution output for the given execution ID.
+// Messages are "output:<chunk JSON>", "step:<step JSON>" and finally "complete:<status>".
+// Output and step messages are dropped while the channel is full; subscribers detect missing
+// output from gaps in the sequence numbers and recover it with OutputSince. The complete
+// message is always delivered.
 func (s *ExecutorService) Subscribe(executionID string) chan string {
 	ch := make(chan string, 100)
 
@@ -343,13 +1049,19 @@ func (s *ExecutorService) Unsubscribe(executionID string, ch chan string) {
 	}
 }
 
-func (s *ExecutorService) broadcastLine(executionID string, line string) {
+// broadcastChunk sends a line of output to subscribers as JSON.
+func (s *ExecutorService) broadcastChunk(executionID string, chunk models.OutputChunk) {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return
+	}
+
 	s.streamsMu.RLock()
 	defer s.streamsMu.RUnlock()
 
 	for _, ch := range s.streams[executionID] {
 		select {
-		case ch <- "output:" + line:
+		case ch <- "output:" + string(data):
 		default:
 		}
 	}
@@ -359,9 +1071,44 @@ func (s *ExecutorService) broadcastComplete(executionID string, _ int, status mo
 	s.streamsMu.RLock()
 	defer s.streamsMu.RUnlock()
 
+	msg := "complete:" + string(status)
+	for _, ch := range s.streams[executionID] {
+		// Make room by discarding the oldest message of a slow subscriber
+		for {
+			select {
+			case ch <- msg:
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+func (s *ExecutorService) broadcastStep(executionID string, step *models.ExecutionStep) {
+	// Step output is available from the execution API; only stream the step status
+	data, err := json.Marshal(struct {
+		ID         string                 `json:"id"`
+		Name       string                 `json:"name"`
+		Status     models.ExecutionStatus `json:"status"`
+		ExitCode   *int                   `json:"exit_code"`
+		StepOrder  int                    `json:"step_order"`
+		DurationMs int64                  `json:"duration_ms"`
+	}{step.ID, step.Name, step.Status, step.ExitCode, step.StepOrder, step.DurationMs})
+	if err != nil {
+		return
+	}
+
+	s.streamsMu.RLock()
+	defer s.streamsMu.RUnlock()
+
 	for _, ch := range s.streams[executionID] {
 		select {
-		case ch <- "complete:" + string(status):
+		case ch <- "step:" + string(data):
 		default:
 		}
 	}
@@ -2,7 +2,14 @@ package services_test
 
 import (
 	"database/sql"
+	"errors"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
@@ -18,6 +25,8 @@ func setupTestDB(t *testing.T) (*database.DB, *sql.DB, *config.Config) {
 		t.Fatalf("failed to open test database: %v", err)
 	}
 
+	// Every connection to :memory: opens a new empty database, so background writers must share one
+	sqlDB.SetMaxOpenConns(1)
 	db := &database.DB{DB: sqlDB}
 
 	// Create tables
@@ -27,6 +36,7 @@ func setupTestDB(t *testing.T) (*database.DB, *sql.DB, *config.Config) {
 			username TEXT NOT NULL UNIQUE,
 			password_hash TEXT NOT NULL,
 			is_admin BOOLEAN DEFAULT 0,
+			role TEXT DEFAULT 'operator',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 
@@ -36,10 +46,61 @@ func setupTestDB(t *testing.T) (*database.DB, *sql.DB, *config.Config) {
 			description TEXT,
 			working_dir TEXT,
 			token TEXT NOT NULL UNIQUE,
+			concurrency_policy TEXT NOT NULL DEFAULT 'queue',
+			run_as_user TEXT NOT NULL DEFAULT '',
+			run_as_group TEXT NOT NULL DEFAULT '',
+			resource_limits TEXT,
+			git_repo_url TEXT NOT NULL DEFAULT '',
+			git_ref TEXT NOT NULL DEFAULT '',
+			git_deploy_key TEXT,
+			webhook_secret TEXT,
+			webhook_branches TEXT,
+			webhook_tags TEXT,
+			requires_approval BOOLEAN NOT NULL DEFAULT FALSE,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
 
+		CREATE TABLE deploy_tokens (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			token_prefix TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			command_ids TEXT,
+			expires_at DATETIME,
+			last_used_at DATETIME,
+			last_used_ip TEXT,
+			created_by TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (app_id, name),
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE app_members (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			user_id INTEGER,
+			group_id INTEGER,
+			role TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (app_id, user_id),
+			UNIQUE (app_id, group_id)
+		);
+
+		CREATE TABLE user_groups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE user_group_members (
+			group_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			PRIMARY KEY (group_id, user_id)
+		);
+
 		CREATE TABLE commands (
 			id TEXT PRIMARY KEY,
 			app_id TEXT NOT NULL,
@@ -48,6 +109,9 @@ func setupTestDB(t *testing.T) (*database.DB, *sql.DB, *config.Config) {
 			command TEXT NOT NULL,
 			timeout_seconds INTEGER DEFAULT 300,
 			sort_order INTEGER DEFAULT 0,
+			parameters TEXT,
+			rerun_on_interrupt BOOLEAN NOT NULL DEFAULT FALSE,
+			requires_approval BOOLEAN NOT NULL DEFAULT FALSE,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (app_id) REFERENCES apps(id)
 		);
@@ -61,9 +125,154 @@ func setupTestDB(t *testing.T) (*database.DB, *sql.DB, *config.Config) {
 			exit_code INTEGER,
 			started_at DATETIME,
 			finished_at DATETIME,
+			cancelled_by TEXT,
+			parameters TEXT,
+			git_ref TEXT,
+			commit_sha TEXT,
+			reviewed_by TEXT,
+			reviewed_at DATETIME,
+			review_comment TEXT,
+			approval_expires_at DATETIME,
+			rerun_of TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (command_id) REFERENCES commands(id)
 		);
+
+		CREATE TABLE pipeline_steps (
+			id TEXT PRIMARY KEY,
+			pipeline_id TEXT NOT NULL,
+			command_id TEXT NOT NULL,
+			step_order INTEGER NOT NULL DEFAULT 0,
+			continue_on_error BOOLEAN NOT NULL DEFAULT FALSE,
+			FOREIGN KEY (pipeline_id) REFERENCES commands(id) ON DELETE CASCADE,
+			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE execution_steps (
+			id TEXT PRIMARY KEY,
+			execution_id TEXT NOT NULL,
+			command_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			step_order INTEGER NOT NULL DEFAULT 0,
+			continue_on_error BOOLEAN NOT NULL DEFAULT FALSE,
+			status TEXT NOT NULL DEFAULT 'pending',
+			output TEXT,
+			exit_code INTEGER,
+			started_at DATETIME,
+			finished_at DATETIME,
+			FOREIGN KEY (execution_id) REFERENCES executions(id)
+		);
+
+		CREATE TABLE env_vars (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			command_id TEXT,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL DEFAULT '',
+			is_secret BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE,
+			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
+		);
+		CREATE UNIQUE INDEX idx_env_vars_scope_name ON env_vars(app_id, COALESCE(command_id, ''), name);
+
+		CREATE TABLE notification_rules (
+			id TEXT PRIMARY KEY,
+			app_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			target TEXT NOT NULL,
+			secret TEXT,
+			template TEXT NOT NULL DEFAULT '',
+			statuses TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE notification_deliveries (
+			id TEXT PRIMARY KEY,
+			rule_id TEXT,
+			rule_name TEXT NOT NULL,
+			app_id TEXT NOT NULL,
+			execution_id TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			status TEXT NOT NULL,
+			execution_status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (rule_id) REFERENCES notification_rules(id) ON DELETE SET NULL,
+			FOREIGN KEY (app_id) REFERENCES apps(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE command_schedules (
+			id TEXT PRIMARY KEY,
+			command_id TEXT NOT NULL,
+			cron TEXT NOT NULL,
+			timezone TEXT NOT NULL DEFAULT 'UTC',
+			overlap_policy TEXT NOT NULL DEFAULT 'skip',
+			parameters TEXT,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			next_run_at DATETIME,
+			last_run_at DATETIME,
+			last_execution_id TEXT,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (command_id) REFERENCES commands(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE execution_output (
+			execution_id TEXT NOT NULL,
+			seq INTEGER NOT NULL,
+			stream TEXT NOT NULL,
+			line TEXT NOT NULL,
+			elapsed_ms INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (execution_id, seq)
+		);
+
+		CREATE TABLE terminal_recordings (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			user_id INTEGER,
+			username TEXT NOT NULL,
+			shell TEXT NOT NULL,
+			path TEXT NOT NULL,
+			size INTEGER NOT NULL DEFAULT 0,
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME
+		);
+
+		CREATE TABLE command_policies (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			description TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			match_type TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			roles TEXT NOT NULL DEFAULT '[]',
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE audit_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			username TEXT,
+			action TEXT NOT NULL,
+			resource_type TEXT NOT NULL,
+			resource_id TEXT,
+			ip_address TEXT,
+			user_agent TEXT,
+			details TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
 	`)
 	if err != nil {
 		t.Fatalf("failed to create tables: %v", err)
@@ -84,7 +293,7 @@ func TestExecutorService_CreateExecution(t *testing.T) {
 	defer func() { _ = sqlDB.Close() }()
 
 	appSvc := services.NewAppService(db)
-	execSvc := services.NewExecutorService(db, cfg, appSvc)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
 
 	// Create test app and command
 	app, err := appSvc.CreateApp(&models.CreateAppRequest{
@@ -134,7 +343,7 @@ func TestExecutorService_GetExecutionByID(t *testing.T) {
 	defer func() { _ = sqlDB.Close() }()
 
 	appSvc := services.NewAppService(db)
-	execSvc := services.NewExecutorService(db, cfg, appSvc)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
 
 	// Create test app and command
 	app, _ := appSvc.CreateApp(&models.CreateAppRequest{
@@ -177,7 +386,7 @@ func TestExecutorService_GetExecutions(t *testing.T) {
 	defer func() { _ = sqlDB.Close() }()
 
 	appSvc := services.NewAppService(db)
-	execSvc := services.NewExecutorService(db, cfg, appSvc)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
 
 	// Create test app and command
 	app, _ := appSvc.CreateApp(&models.CreateAppRequest{
@@ -238,7 +447,7 @@ func TestExecutorService_GetExecutions_Pagination(t *testing.T) {
 	defer func() { _ = sqlDB.Close() }()
 
 	appSvc := services.NewAppService(db)
-	execSvc := services.NewExecutorService(db, cfg, appSvc)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
 
 	// Create test app and command
 	app, _ := appSvc.CreateApp(&models.CreateAppRequest{
@@ -284,3 +493,453 @@ func TestExecutorService_GetExecutions_Pagination(t *testing.T) {
 		}
 	}
 }
+
+func TestExecutorService_ExecutePipeline(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Test App", WorkingDir: t.TempDir()})
+	ok, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "ok", Command: "exit 0", TimeoutSeconds: 30})
+	warn, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "warn", Command: "exit 2", TimeoutSeconds: 30})
+	fail, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "fail", Command: "exit 3", TimeoutSeconds: 30})
+
+	t.Run("continue on error", func(t *testing.T) {
+		pipeline, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+			Name:           "tolerant",
+			TimeoutSeconds: 30,
+			Steps: []models.PipelineStepRequest{
+				{CommandID: warn.ID, ContinueOnError: true},
+				{CommandID: ok.ID},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create pipeline: %v", err)
+		}
+
+		exec, _ := execSvc.CreateExecution(pipeline.ID, 0)
+		if err := execSvc.Execute(exec.ID); err != nil {
+			t.Fatalf("failed to execute pipeline: %v", err)
+		}
+
+		result, _ := execSvc.GetExecutionByID(exec.ID)
+		if result.Status != models.StatusSuccess {
+			t.Errorf("expected pipeline status success, got %q", result.Status)
+		}
+		if len(result.Steps) != 2 {
+			t.Fatalf("expected 2 steps, got %d", len(result.Steps))
+		}
+		if result.Steps[0].Status != models.StatusFailed || *result.Steps[0].ExitCode != 2 {
+			t.Errorf("expected first step failed with exit code 2, got %q", result.Steps[0].Status)
+		}
+		if result.Steps[1].Status != models.StatusSuccess {
+			t.Errorf("expected second step success, got %q", result.Steps[1].Status)
+		}
+	})
+
+	t.Run("stop on first failure", func(t *testing.T) {
+		pipeline, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+			Name:           "strict",
+			TimeoutSeconds: 30,
+			Steps: []models.PipelineStepRequest{
+				{CommandID: ok.ID},
+				{CommandID: fail.ID},
+				{CommandID: ok.ID},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to create pipeline: %v", err)
+		}
+
+		exec, _ := execSvc.CreateExecution(pipeline.ID, 0)
+		_ = execSvc.Execute(exec.ID)
+
+		result, _ := execSvc.GetExecutionByID(exec.ID)
+		if result.Status != models.StatusFailed {
+			t.Errorf("expected pipeline status failed, got %q", result.Status)
+		}
+		if result.ExitCode == nil || *result.ExitCode != 3 {
+			t.Errorf("expected pipeline exit code 3, got %v", result.ExitCode)
+		}
+
+		expected := []models.ExecutionStatus{models.StatusSuccess, models.StatusFailed, models.StatusSkipped}
+		for i, status := range expected {
+			if result.Steps[i].Status != status {
+				t.Errorf("step %d: expected status %q, got %q", i, status, result.Steps[i].Status)
+			}
+		}
+		if result.Steps[2].StartedAt != nil {
+			t.Error("expected skipped step to have no start time")
+		}
+	})
+}
+
+func waitForStatus(t *testing.T, execSvc *services.ExecutorService, id string, match func(models.ExecutionStatus) bool) *models.Execution {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		exec, err := execSvc.GetExecutionByID(id)
+		if err != nil {
+			t.Fatalf("failed to get execution: %v", err)
+		}
+		if match(exec.Status) {
+			return exec
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for execution %s", id)
+	return nil
+}
+
+func TestExecutorService_Cancel(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	// Share the in-memory database between the executor goroutine and the test
+	sqlDB.SetMaxOpenConns(1)
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+	cfg.Execution.KillGracePeriod = "200ms"
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Test App", WorkingDir: t.TempDir()})
+
+	t.Run("running execution", func(t *testing.T) {
+		// The trap makes the shell and its children ignore SIGTERM, so SIGKILL must reach the group
+		cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+			Name:           "stubborn",
+			Command:        "trap '' TERM; sleep 30 & sleep 30; wait",
+			TimeoutSeconds: 60,
+		})
+		exec, _ := execSvc.CreateExecution(cmd.ID, 1)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = execSvc.Execute(exec.ID)
+		}()
+
+		waitForStatus(t, execSvc, exec.ID, func(s models.ExecutionStatus) bool { return s == models.StatusRunning })
+
+		ch := execSvc.Subscribe(exec.ID)
+		defer execSvc.Unsubscribe(exec.ID, ch)
+
+		start := time.Now()
+		if err := execSvc.Cancel(exec.ID, "admin"); err != nil {
+			t.Fatalf("failed to cancel execution: %v", err)
+		}
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("execution did not stop after cancel")
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected execution to stop within the grace period, took %v", elapsed)
+		}
+
+		result, _ := execSvc.GetExecutionByID(exec.ID)
+		if result.Status != models.StatusCancelled {
+			t.Errorf("expected status cancelled, got %q", result.Status)
+		}
+		if result.CancelledBy != "admin" {
+			t.Errorf("expected cancelled_by 'admin', got %q", result.CancelledBy)
+		}
+
+		var complete string
+		for msg := range ch {
+			if strings.HasPrefix(msg, "complete:") {
+				complete = msg
+				break
+			}
+		}
+		if complete != "complete:cancelled" {
+			t.Errorf("expected complete:cancelled broadcast, got %q", complete)
+		}
+
+		if err := execSvc.Cancel(exec.ID, "admin"); err != services.ErrExecutionNotRunning {
+			t.Errorf("expected ErrExecutionNotRunning for finished execution, got %v", err)
+		}
+	})
+
+	t.Run("pending execution", func(t *testing.T) {
+		cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "echo", Command: "echo hello", TimeoutSeconds: 60})
+		exec, _ := execSvc.CreateExecution(cmd.ID, 1)
+
+		if err := execSvc.Cancel(exec.ID, "operator"); err != nil {
+			t.Fatalf("failed to cancel execution: %v", err)
+		}
+
+		// A cancelled execution never starts
+		if err := execSvc.Execute(exec.ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, _ := execSvc.GetExecutionByID(exec.ID)
+		if result.Status != models.StatusCancelled {
+			t.Errorf("expected status cancelled, got %q", result.Status)
+		}
+		if result.StartedAt != nil || result.Output != "" {
+			t.Error("expected cancelled pending execution to never run")
+		}
+	})
+
+	t.Run("unknown execution", func(t *testing.T) {
+		if err := execSvc.Cancel("nonexistent", "admin"); err != services.ErrExecutionNotFound {
+			t.Errorf("expected ErrExecutionNotFound, got %v", err)
+		}
+	})
+}
+
+func TestExecutorService_ConcurrencyPolicy(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	sqlDB.SetMaxOpenConns(1)
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+	cfg.Execution.KillGracePeriod = "200ms"
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	// startLong starts a long running execution of app and waits until it is running
+	startLong := func(t *testing.T, app *models.App) (*models.Execution, chan struct{}) {
+		cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "long", Command: "sleep 30", TimeoutSeconds: 60})
+		exec, err := execSvc.CreateExecution(cmd.ID, 1)
+		if err != nil {
+			t.Fatalf("failed to create execution: %v", err)
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = execSvc.Execute(exec.ID)
+		}()
+		waitForStatus(t, execSvc, exec.ID, func(s models.ExecutionStatus) bool { return s == models.StatusRunning })
+		return exec, done
+	}
+
+	t.Run("queue", func(t *testing.T) {
+		app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Queued", WorkingDir: t.TempDir()})
+		first, firstDone := startLong(t, app)
+
+		cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "quick", Command: "exit 0", TimeoutSeconds: 60})
+		second, err := execSvc.CreateExecution(cmd.ID, 1)
+		if err != nil {
+			t.Fatalf("failed to create execution: %v", err)
+		}
+		if second.QueuePosition != 1 {
+			t.Errorf("expected queue position 1, got %d", second.QueuePosition)
+		}
+
+		secondDone := make(chan struct{})
+		go func() {
+			defer close(secondDone)
+			_ = execSvc.Execute(second.ID)
+		}()
+
+		// The second execution must wait for the first one
+		time.Sleep(200 * time.Millisecond)
+		if exec, _ := execSvc.GetExecutionByID(second.ID); exec.Status != models.StatusPending {
+			t.Errorf("expected queued execution to stay pending, got %q", exec.Status)
+		}
+
+		_ = execSvc.Cancel(first.ID, "admin")
+		<-firstDone
+		<-secondDone
+
+		result, _ := execSvc.GetExecutionByID(second.ID)
+		if result.Status != models.StatusSuccess {
+			t.Errorf("expected queued execution to succeed, got %q", result.Status)
+		}
+		firstResult, _ := execSvc.GetExecutionByID(first.ID)
+		if result.StartedAt.Before(*firstResult.FinishedAt) {
+			t.Error("expected queued execution to start after the previous one finished")
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		app, _ := appSvc.CreateApp(&models.CreateAppRequest{
+			Name:              "Rejecting",
+			WorkingDir:        t.TempDir(),
+			ConcurrencyPolicy: models.ConcurrencyReject,
+		})
+		first, firstDone := startLong(t, app)
+
+		cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "quick", Command: "exit 0", TimeoutSeconds: 60})
+		if _, err := execSvc.CreateExecution(cmd.ID, 1); err != services.ErrAppBusy {
+			t.Errorf("expected ErrAppBusy, got %v", err)
+		}
+		if active := execSvc.ActiveExecutionID(app.ID); active != first.ID {
+			t.Errorf("expected active execution %s, got %s", first.ID, active)
+		}
+
+		_ = execSvc.Cancel(first.ID, "admin")
+		<-firstDone
+
+		if _, err := execSvc.CreateExecution(cmd.ID, 1); err != nil {
+			t.Errorf("expected execution to be accepted once the app is idle, got %v", err)
+		}
+	})
+
+	t.Run("cancel previous", func(t *testing.T) {
+		app, _ := appSvc.CreateApp(&models.CreateAppRequest{
+			Name:              "Superseding",
+			WorkingDir:        t.TempDir(),
+			ConcurrencyPolicy: models.ConcurrencyCancelPrevious,
+		})
+		first, firstDone := startLong(t, app)
+
+		cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "quick", Command: "exit 0", TimeoutSeconds: 60})
+		second, err := execSvc.CreateExecution(cmd.ID, 1)
+		if err != nil {
+			t.Fatalf("failed to create execution: %v", err)
+		}
+
+		select {
+		case <-firstDone:
+		case <-time.After(10 * time.Second):
+			t.Fatal("previous execution was not cancelled")
+		}
+
+		firstResult, _ := execSvc.GetExecutionByID(first.ID)
+		if firstResult.Status != models.StatusCancelled || firstResult.CancelledBy != "system" {
+			t.Errorf("expected previous execution cancelled by system, got %q by %q", firstResult.Status, firstResult.CancelledBy)
+		}
+
+		if err := execSvc.Execute(second.ID); err != nil {
+			t.Fatalf("failed to execute: %v", err)
+		}
+		if result, _ := execSvc.GetExecutionByID(second.ID); result.Status != models.StatusSuccess {
+			t.Errorf("expected new execution to succeed, got %q", result.Status)
+		}
+	})
+}
+
+func TestExecutorService_ExecuteWithParameters(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Test App", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:    "deploy",
+		Command: `printf '%s|%s\n' "$PARAM_MESSAGE" "$PARAM_ENV"`,
+		Parameters: []models.CommandParameter{
+			{Name: "message", Type: models.ParameterString},
+			{Name: "env", Type: models.ParameterEnum, Options: []string{"staging", "production"}, Default: "staging"},
+		},
+		TimeoutSeconds: 30,
+	})
+
+	// Values are passed through the environment, so shell syntax is not interpreted
+	exec, err := execSvc.CreateExecutionWithOptions(cmd.ID, 1, services.ExecutionOptions{
+		Parameters: map[string]string{"message": "$(echo injected); echo pwned"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	if exec.Parameters["env"] != "staging" {
+		t.Errorf("expected default env recorded on execution, got %q", exec.Parameters["env"])
+	}
+
+	if err := execSvc.Execute(exec.ID); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	result, _ := execSvc.GetExecutionByID(exec.ID)
+	if result.Output != "$(echo injected); echo pwned|staging\n" {
+		t.Errorf("unexpected output %q", result.Output)
+	}
+
+	_, err = execSvc.CreateExecutionWithOptions(cmd.ID, 1, services.ExecutionOptions{
+		Parameters: map[string]string{"env": "dev"},
+	})
+	if !errors.Is(err, services.ErrInvalidParameter) {
+		t.Errorf("expected ErrInvalidParameter, got %v", err)
+	}
+}
+
+func TestExecutorService_RunAsAndLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on Linux")
+	}
+
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	// The run_as user must be able to enter the working directory
+	workDir, err := os.MkdirTemp("", "run-as-test")
+	if err != nil {
+		t.Fatalf("failed to create working dir: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+	// #nosec G302 - the directory must be readable by the run_as user
+	if err := os.Chmod(workDir, 0o755); err != nil {
+		t.Fatalf("failed to chmod working dir: %v", err)
+	}
+
+	run := func(t *testing.T, req *models.CreateAppRequest) string {
+		t.Helper()
+		req.WorkingDir = workDir
+		app, err := appSvc.CreateApp(req)
+		if err != nil {
+			t.Fatalf("failed to create app: %v", err)
+		}
+		cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+			Name:    "Identity",
+			Command: `echo "$(id -u) $(ulimit -n) $(ulimit -t)"`,
+		})
+		if err != nil {
+			t.Fatalf("failed to create command: %v", err)
+		}
+
+		exec, err := execSvc.CreateExecution(cmd.ID, 1)
+		if err != nil {
+			t.Fatalf("failed to create execution: %v", err)
+		}
+		if err := execSvc.Execute(exec.ID); err != nil {
+			t.Fatalf("failed to execute: %v", err)
+		}
+
+		result, _ := execSvc.GetExecutionByID(exec.ID)
+		if result.Status != models.StatusSuccess {
+			t.Fatalf("expected success, got %s: %s", result.Status, result.Output)
+		}
+		return result.Output
+	}
+
+	t.Run("limits", func(t *testing.T) {
+		output := run(t, &models.CreateAppRequest{
+			Name:           "Limited App",
+			ResourceLimits: models.ResourceLimits{MaxCPUSeconds: 30, MaxOpenFiles: 64},
+		})
+		if want := strconv.Itoa(os.Getuid()) + " 64 30\n"; output != want {
+			t.Errorf("expected output %q, got %q", want, output)
+		}
+	})
+
+	t.Run("run_as", func(t *testing.T) {
+		if os.Getuid() != 0 {
+			t.Skip("switching users requires root")
+		}
+		nobody, err := user.Lookup("nobody")
+		if err != nil {
+			t.Skip("user nobody does not exist")
+		}
+
+		output := run(t, &models.CreateAppRequest{Name: "Run As App", RunAsUser: "nobody"})
+		if !strings.HasPrefix(output, nobody.Uid+" ") {
+			t.Errorf("expected command to run as uid %s, got %q", nobody.Uid, output)
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrInvalidGitRef indicates a requested ref or commit SHA is malformed or does not exist.
+	ErrInvalidGitRef = errors.New("invalid git ref")
+	// ErrNoGitSource indicates a ref or commit was requested for an app without a git source.
+	ErrNoGitSource = errors.New("app has no git source")
+)
+
+var (
+	gitRefPattern    = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._/-]*$`)
+	commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+)
+
+// validateGitSource checks the repository URL and default ref of an app.
+// URLs starting with "-" are rejected so they can never be read as git options.
+func validateGitSource(repoURL, ref string) error {
+	if repoURL == "" {
+		if ref != "" {
+			return fmt.Errorf("%w: a ref requires a repository URL", ErrInvalidGitSource)
+		}
+		return nil
+	}
+	if strings.HasPrefix(repoURL, "-") || strings.ContainsAny(repoURL, " \t\r\n") {
+		return fmt.Errorf("%w: malformed repository URL", ErrInvalidGitSource)
+	}
+	if ref != "" && !validGitRef(ref) {
+		return fmt.Errorf("%w: malformed ref %q", ErrInvalidGitSource, ref)
+	}
+	return nil
+}
+
+func validGitRef(ref string) bool {
+	return gitRefPattern.MatchString(ref) && !strings.Contains(ref, "..") && !strings.HasSuffix(ref, ".lock")
+}
+
+// validateGitTarget checks the ref and commit SHA requested for an execution of app. Unless
+// recordOnly is set, they are only accepted for apps with a git source.
+func validateGitTarget(app *models.App, ref, commitSHA string, recordOnly bool) error {
+	if ref == "" && commitSHA == "" {
+		return nil
+	}
+	if !app.HasGitSource() && !recordOnly {
+		return ErrNoGitSource
+	}
+	if ref != "" && !validGitRef(ref) {
+		return fmt.Errorf("%w: malformed ref %q", ErrInvalidGitRef, ref)
+	}
+	if commitSHA != "" && !commitSHAPattern.MatchString(commitSHA) {
+		return fmt.Errorf("%w: commit_sha must be 7 to 40 hexadecimal characters", ErrInvalidGitRef)
+	}
+	return nil
+}
+
+// redactURL hides the credentials of a repository URL.
+func redactURL(repoURL string) string {
+	if u, err := url.Parse(repoURL); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return repoURL
+}
+
+// GitService syncs the working directory of apps with a git source.
+type GitService struct {
+	db     *database.DB
+	crypto *CryptoService
+}
+
+// NewGitService creates a new GitService instance.
+// Deploy keys are encrypted with the given crypto service.
+func NewGitService(db *database.DB, crypto *CryptoService) *GitService {
+	return &GitService{db: db, crypto: crypto}
+}
+
+// SetDeployKey stores the SSH deploy key of an app encrypted. An empty key removes it.
+func (s *GitService) SetDeployKey(appID, key string) error {
+	return s.setEncrypted(appID, "git_deploy_key", key)
+}
+
+func (s *GitService) deployKey(appID string) (string, error) {
+	return s.decrypted(appID, "git_deploy_key")
+}
+
+// setEncrypted stores value encrypted in a column of the app, or NULL if it is empty.
+// column is always a constant name chosen by the caller.
+func (s *GitService) setEncrypted(appID, column, value string) error {
+	var stored interface{}
+	if value != "" {
+		if s.crypto == nil {
+			return errors.New("encryption is not configured")
+		}
+		encrypted, err := s.crypto.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		stored = encrypted
+	}
+
+	// #nosec G202 - column is not user input
+	result, err := s.db.Exec("UPDATE apps SET "+column+" = ? WHERE id = ?", stored, appID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrAppNotFound
+	}
+	return nil
+}
+
+// decrypted reads and decrypts a column stored with setEncrypted, returning "" if it is not set.
+func (s *GitService) decrypted(appID, column string) (string, error) {
+	var encrypted string
+	// #nosec G202 - column is not user input
+	if err := s.db.QueryRow("SELECT COALESCE("+column+", '') FROM apps WHERE id = ?", appID).Scan(&encrypted); err != nil {
+		return "", err
+	}
+	if encrypted == "" {
+		return "", nil
+	}
+	if s.crypto == nil {
+		return "", errors.New("encryption is not configured")
+	}
+	return s.crypto.Decrypt(encrypted)
+}
+
+// Sync brings the working directory of an app to the requested commit and returns its full SHA.
+// The directory is cloned on first use and fetched afterwards. commitSHA takes precedence over ref;
+// without either the app's default ref, or else the remote's default branch, is checked out.
+// Progress lines are passed to logf.
+func (s *GitService) Sync(ctx context.Context, app *models.App, ref, commitSHA string, logf func(string)) (string, error) {
+	runAs, err := resolveRunAs(app.RunAsUser, app.RunAsGroup)
+	if err != nil {
+		return "", err
+	}
+
+	g := &gitRunner{ctx: ctx, dir: app.WorkingDir, repoURL: app.GitRepoURL, runAs: runAs}
+	g.env = append([]string{"GIT_TERMINAL_PROMPT=0"}, runAsEnv(runAs)...)
+
+	key, err := s.deployKey(app.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read deploy key: %w", err)
+	}
+	if key != "" {
+		keyFile, err := writeDeployKey(key, runAs)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = os.Remove(keyFile) }()
+		g.env = append(g.env, "GIT_SSH_COMMAND=ssh -i "+keyFile+" -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new")
+	}
+
+	if _, err := os.Stat(filepath.Join(app.WorkingDir, ".git")); os.IsNotExist(err) {
+		logf("==> git clone " + redactURL(app.GitRepoURL))
+		if _, err := g.run("", "clone", "--no-checkout", "--", app.GitRepoURL, app.WorkingDir); err != nil {
+			return "", err
+		}
+	} else if _, err := g.run(app.WorkingDir, "remote", "set-url", "origin", app.GitRepoURL); err != nil {
+		return "", err
+	}
+
+	logf("==> git fetch origin")
+	if _, err := g.run(app.WorkingDir, "fetch", "--prune", "--tags", "--force", "origin"); err != nil {
+		return "", err
+	}
+
+	if ref == "" {
+		ref = app.GitRef
+	}
+	sha, branch, err := g.resolve(ref, commitSHA)
+	if err != nil {
+		return "", err
+	}
+
+	if branch != "" {
+		logf(fmt.Sprintf("==> git checkout %s (%s)", branch, sha))
+		_, err = g.run(app.WorkingDir, "checkout", "--force", "-B", branch, sha)
+	} else {
+		logf("==> git checkout " + sha)
+		_, err = g.run(app.WorkingDir, "checkout", "--force", "--detach", sha)
+	}
+	if err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// runAsEnv returns the environment of the run_as user, if any.
+func runAsEnv(id *runAsIdentity) []string {
+	if id == nil {
+		return nil
+	}
+	return id.env
+}
+
+// writeDeployKey writes a deploy key to a private temporary file readable by the run_as user.
+func writeDeployKey(key string, runAs *runAsIdentity) (string, error) {
+	f, err := os.CreateTemp("", "http-remote-deploy-key-*")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+
+	// ssh rejects keys without a trailing newline
+	if !strings.HasSuffix(key, "\n") {
+		key += "\n"
+	}
+	_, err = f.WriteString(key)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && runAs != nil {
+		err = os.Chown(name, int(runAs.credential.Uid), int(runAs.credential.Gid))
+	}
+	if err != nil {
+		_ = os.Remove(name)
+		return "", fmt.Errorf("failed to write deploy key: %w", err)
+	}
+	return name, nil
+}
+
+// gitRunner runs git commands for one sync.
+type gitRunner struct {
+	ctx     context.Context
+	runAs   *runAsIdentity
+	dir     string
+	repoURL string
+	env     []string
+}
+
+// run runs git in dir and returns its trimmed output. The working directory is always trusted,
+// it may be owned by the run_as user rather than the server's user.
+func (g *gitRunner) run(dir string, args ...string) (string, error) {
+	args = append([]string{"-c", "safe.directory=" + g.dir}, args...)
+	// #nosec G204 - arguments are validated refs and the configured repository URL
+	cmd := exec.CommandContext(g.ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), g.env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	applyRunAs(cmd, g.runAs)
+
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(strings.ReplaceAll(string(out), g.repoURL, redactURL(g.repoURL)))
+	if err != nil {
+		if g.ctx.Err() != nil {
+			return "", g.ctx.Err()
+		}
+		if output == "" {
+			output = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[2], output)
+	}
+	return output, nil
+}
+
+// revParse resolves rev to a commit SHA, or returns "" if it does not exist.
+func (g *gitRunner) revParse(rev string) string {
+	sha, err := g.run(g.dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	return sha
+}
+
+// resolve finds the commit to check out. branch is set if ref names a remote branch,
+// so that the checkout keeps a local branch of the same name.
+func (g *gitRunner) resolve(ref, commitSHA string) (sha, branch string, err error) {
+	if commitSHA != "" {
+		if sha := g.revParse(commitSHA); sha != "" {
+			return sha, "", nil
+		}
+		// Commits that are not reachable from a fetched ref have to be fetched directly
+		if _, err := g.run(g.dir, "fetch", "origin", commitSHA); err == nil {
+			if sha := g.revParse(commitSHA); sha != "" {
+				return sha, "", nil
+			}
+		}
+		return "", "", fmt.Errorf("%w: commit %s not found", ErrInvalidGitRef, commitSHA)
+	}
+
+	if ref == "" {
+		if g.revParse("refs/remotes/origin/HEAD") == "" {
+			_, _ = g.run(g.dir, "remote", "set-head", "origin", "--auto")
+		}
+		head, err := g.run(g.dir, "symbolic-ref", "--short", "refs/remotes/origin/HEAD")
+		if err != nil {
+			return "", "", fmt.Errorf("%w: cannot determine the default branch of the repository", ErrInvalidGitRef)
+		}
+		ref = strings.TrimPrefix(head, "origin/")
+	}
+
+	if !strings.HasPrefix(ref, "refs/tags/") {
+		branchName := strings.TrimPrefix(ref, "refs/heads/")
+		if sha := g.revParse("refs/remotes/origin/" + branchName); sha != "" {
+			return sha, branchName, nil
+		}
+	}
+	if sha := g.revParse("refs/tags/" + strings.TrimPrefix(ref, "refs/tags/")); sha != "" {
+		return sha, "", nil
+	}
+	if commitSHAPattern.MatchString(ref) {
+		if sha := g.revParse(ref); sha != "" {
+			return sha, "", nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: ref %q not found", ErrInvalidGitRef, ref)
+}
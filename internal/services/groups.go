@@ -0,0 +1,197 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrGroupNotFound indicates the requested group was not found.
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrGroupExists indicates a group with the same name already exists.
+	ErrGroupExists = errors.New("group already exists")
+	// ErrInvalidGroup indicates the name or members of a group are invalid.
+	ErrInvalidGroup = errors.New("invalid group")
+)
+
+// ListGroups returns all groups with their members, ordered by name.
+func (s *AuthService) ListGroups() ([]models.Group, error) {
+	rows, err := s.db.Query("SELECT id, name, created_at FROM user_groups ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	groups := []models.Group{}
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.Name, &group.CreatedAt); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		if groups[i].UserIDs, err = s.groupUserIDs(groups[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// GetGroup returns a group with its members.
+func (s *AuthService) GetGroup(id int64) (*models.Group, error) {
+	var group models.Group
+	err := s.db.QueryRow("SELECT id, name, created_at FROM user_groups WHERE id = ?", id).Scan(&group.ID, &group.Name, &group.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if group.UserIDs, err = s.groupUserIDs(id); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// CreateGroup creates a group with the given members.
+func (s *AuthService) CreateGroup(req *models.GroupRequest) (*models.Group, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidGroup)
+	}
+	if err := s.checkUsersExist(req.UserIDs); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec("INSERT INTO user_groups (name) VALUES (?)", name)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrGroupExists
+		}
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if err := setGroupMembers(tx, id, req.UserIDs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(id)
+}
+
+// UpdateGroup renames a group and replaces its members.
+func (s *AuthService) UpdateGroup(id int64, req *models.GroupRequest) (*models.Group, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidGroup)
+	}
+	if _, err := s.GetGroup(id); err != nil {
+		return nil, err
+	}
+	if err := s.checkUsersExist(req.UserIDs); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec("UPDATE user_groups SET name = ? WHERE id = ?", name, id); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrGroupExists
+		}
+		return nil, err
+	}
+	if err := setGroupMembers(tx, id, req.UserIDs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(id)
+}
+
+// DeleteGroup deletes a group and its app memberships.
+func (s *AuthService) DeleteGroup(id int64) error {
+	if _, err := s.db.Exec("DELETE FROM app_members WHERE group_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("DELETE FROM user_group_members WHERE group_id = ?", id); err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec("DELETE FROM user_groups WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+// groupUserIDs returns the IDs of the members of a group.
+func (s *AuthService) groupUserIDs(groupID int64) ([]int64, error) {
+	rows, err := s.db.Query("SELECT user_id FROM user_group_members WHERE group_id = ? ORDER BY user_id", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// checkUsersExist returns ErrInvalidGroup unless every user exists.
+func (s *AuthService) checkUsersExist(userIDs []int64) error {
+	for _, id := range userIDs {
+		if _, err := s.GetUserByID(id); err != nil {
+			if err == ErrUserNotFound {
+				return fmt.Errorf("%w: user %d does not exist", ErrInvalidGroup, id)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// setGroupMembers replaces the members of a group.
+func setGroupMembers(tx *sql.Tx, groupID int64, userIDs []int64) error {
+	if _, err := tx.Exec("DELETE FROM user_group_members WHERE group_id = ?", groupID); err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO user_group_members (group_id, user_id) VALUES (?, ?)", groupID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
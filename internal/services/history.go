@@ -0,0 +1,135 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// ExecutionFilter selects executions for ListExecutions. Empty fields do not filter.
+// From and To bound the creation time, including From and excluding To. Query matches executions whose output
+// contains every whitespace-separated term, using the FTS5 index if available and LIKE otherwise.
+// Member restricts executions to the apps that user is a member of; admins are not restricted.
+type ExecutionFilter struct {
+	From      time.Time
+	To        time.Time
+	Member    *models.User
+	UserID    *int64
+	AppID     string
+	CommandID string
+	Query     string
+	Statuses  []models.ExecutionStatus
+	Limit     int
+	Offset    int
+}
+
+// ListExecutions returns the executions matching filter with command and app details, newest first.
+func (s *ExecutorService) ListExecutions(filter ExecutionFilter) ([]models.ExecutionWithDetails, error) {
+	if filter.Limit == 0 {
+		filter.Limit = 50
+	}
+
+	query := `
+		SELECT ` + executionColumns + `,
+		       c.name as command_name, a.name as app_name, COALESCE(u.username, 'API') as username
+		FROM executions e
+		JOIN commands c ON e.command_id = c.id
+		JOIN apps a ON c.app_id = a.id
+		LEFT JOIN users u ON e.user_id = u.id
+		WHERE 1 = 1`
+	var args []interface{}
+
+	if condition, conditionArgs := memberCondition(filter.Member, "c.app_id"); conditionArgs != nil {
+		query += " AND " + condition
+		args = append(args, conditionArgs...)
+	}
+
+	if filter.AppID != "" {
+		query += " AND c.app_id = ?"
+		args = append(args, filter.AppID)
+	}
+	if filter.CommandID != "" {
+		query += " AND e.command_id = ?"
+		args = append(args, filter.CommandID)
+	}
+	if filter.UserID != nil {
+		query += " AND e.user_id = ?"
+		args = append(args, *filter.UserID)
+	}
+	if len(filter.Statuses) > 0 {
+		query += " AND e.status IN (?" + strings.Repeat(", ?", len(filter.Statuses)-1) + ")"
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	// created_at is written by SQLite as UTC text, so bounds are compared in the same format
+	if !filter.From.IsZero() {
+		query += " AND e.created_at >= ?"
+		args = append(args, sqliteTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		query += " AND e.created_at < ?"
+		args = append(args, sqliteTime(filter.To))
+	}
+	if terms := strings.Fields(filter.Query); len(terms) > 0 {
+		if s.search {
+			query += " AND e.id IN (SELECT execution_id FROM executions_fts WHERE executions_fts MATCH ?)"
+			args = append(args, ftsQuery(terms))
+		} else {
+			for _, term := range terms {
+				query += ` AND e.output LIKE ? ESCAPE '\'`
+				args = append(args, "%"+likeEscaper.Replace(term)+"%")
+			}
+		}
+	}
+
+	query += " ORDER BY e.created_at DESC, e.rowid DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var executions []models.ExecutionWithDetails
+	for rows.Next() {
+		var details models.ExecutionWithDetails
+		exec, err := s.scanExecution(rows, &details.CommandName, &details.AppName, &details.Username)
+		if err != nil {
+			return nil, err
+		}
+		details.Execution = *exec
+		executions = append(executions, details)
+	}
+	return executions, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards of a search term.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ftsQuery quotes search terms so that FTS5 matches them literally instead of parsing its query syntax.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// sqliteTime formats t like CURRENT_TIMESTAMP.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// indexOutput adds the final output of an execution to the search index, if there is one.
+func (s *ExecutorService) indexOutput(executionID, output string) {
+	if !s.search || output == "" {
+		return
+	}
+	if _, err := s.db.Exec("INSERT INTO executions_fts (execution_id, output) VALUES (?, ?)", executionID, output); err != nil {
+		log.Printf("[Executor] Failed to index output of execution %s: %v", executionID, err)
+	}
+}
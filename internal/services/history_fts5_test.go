@@ -0,0 +1,64 @@
+//go:build sqlite_fts5
+
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestExecutorService_SearchIndex(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	if _, err := sqlDB.Exec(`CREATE VIRTUAL TABLE executions_fts USING fts5(
+		execution_id UNINDEXED, output, content = '', contentless_delete = 1, contentless_unindexed = 1
+	)`); err != nil {
+		t.Fatalf("failed to create search index: %v", err)
+	}
+	if !db.HasExecutionSearch() {
+		t.Fatal("expected the search index to be detected")
+	}
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Indexed", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "deploy", Command: `echo "Restarting nginx workers"`})
+
+	execution, _ := execSvc.CreateExecution(cmd.ID, 1)
+	if err := execSvc.Execute(execution.ID); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	// Finished output is indexed by word, and terms with query syntax are matched literally
+	for query, want := range map[string]int{"nginx": 1, "restarting WORKERS": 1, "nginx apache": 0, `"nginx" OR`: 0} {
+		executions, err := execSvc.ListExecutions(services.ExecutionFilter{Query: query})
+		if err != nil {
+			t.Fatalf("failed to search %q: %v", query, err)
+		}
+		if len(executions) != want {
+			t.Errorf("expected %d results for %q, got %d", want, query, len(executions))
+		}
+	}
+
+	// Pruning removes the index entries
+	if n, err := execSvc.PruneExecutions(0, 0, time.Now()); err != nil || n != 0 {
+		t.Fatalf("expected nothing to be pruned, got %d (err=%v)", n, err)
+	}
+	if _, err := sqlDB.Exec("UPDATE executions SET created_at = '2000-01-01 00:00:00'"); err != nil {
+		t.Fatalf("failed to backdate execution: %v", err)
+	}
+	if n, err := execSvc.PruneExecutions(1, 0, time.Now()); err != nil || n != 1 {
+		t.Fatalf("expected 1 pruned execution, got %d (err=%v)", n, err)
+	}
+	var indexed int
+	_ = sqlDB.QueryRow("SELECT COUNT(*) FROM executions_fts WHERE executions_fts MATCH 'nginx'").Scan(&indexed)
+	if indexed != 0 {
+		t.Errorf("expected the index entry to be deleted, %d left", indexed)
+	}
+}
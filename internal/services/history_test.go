@@ -0,0 +1,141 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// insertHistory creates two apps with executions at fixed times for the history tests.
+func insertHistory(t *testing.T, appSvc *services.AppService, exec func(string, ...interface{})) (web, worker *models.App, deploy, migrate *models.Command) {
+	t.Helper()
+
+	web, _ = appSvc.CreateApp(&models.CreateAppRequest{Name: "web", WorkingDir: t.TempDir()})
+	worker, _ = appSvc.CreateApp(&models.CreateAppRequest{Name: "worker", WorkingDir: t.TempDir()})
+	deploy, _ = appSvc.CreateCommand(web.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true"})
+	migrate, _ = appSvc.CreateCommand(worker.ID, &models.CreateCommandRequest{Name: "migrate", Command: "true"})
+
+	exec(`
+		INSERT INTO executions (id, command_id, user_id, status, output, created_at) VALUES
+			('d1', ?, 1, 'success', 'build ok\ndeployed v1\n', '2026-01-01 10:00:00'),
+			('d2', ?, 2, 'failed', 'ERROR: disk 100% full\n', '2026-01-02 10:00:00'),
+			('d3', ?, 1, 'success', 'deployed v3\n', '2026-01-03 10:00:00'),
+			('m1', ?, 2, 'success', 'migrated 3 tables\n', '2026-01-02 12:00:00'),
+			('m2', ?, 2, 'running', '', '2026-01-04 12:00:00')
+	`, deploy.ID, deploy.ID, deploy.ID, migrate.ID, migrate.ID)
+	return web, worker, deploy, migrate
+}
+
+func TestExecutorService_ListExecutions(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	web, _, deploy, _ := insertHistory(t, appSvc, func(query string, args ...interface{}) {
+		if _, err := sqlDB.Exec(query, args...); err != nil {
+			t.Fatalf("failed to insert executions: %v", err)
+		}
+	})
+	operator := int64(2)
+
+	tests := []struct {
+		name   string
+		filter services.ExecutionFilter
+		want   []string
+	}{
+		{"all, newest first", services.ExecutionFilter{}, []string{"m2", "d3", "m1", "d2", "d1"}},
+		{"app", services.ExecutionFilter{AppID: web.ID}, []string{"d3", "d2", "d1"}},
+		{"command and user", services.ExecutionFilter{CommandID: deploy.ID, UserID: &operator}, []string{"d2"}},
+		{"statuses", services.ExecutionFilter{Statuses: []models.ExecutionStatus{models.StatusFailed, models.StatusRunning}}, []string{"m2", "d2"}},
+		{"date range", services.ExecutionFilter{
+			From: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC),
+		}, []string{"m1", "d2"}},
+		{"search terms", services.ExecutionFilter{Query: "deployed v3"}, []string{"d3"}},
+		{"search is literal", services.ExecutionFilter{Query: "100%"}, []string{"d2"}},
+		{"search without match", services.ExecutionFilter{Query: "10_%"}, nil},
+		{"page", services.ExecutionFilter{Limit: 2, Offset: 1}, []string{"d3", "m1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executions, err := execSvc.ListExecutions(tt.filter)
+			if err != nil {
+				t.Fatalf("failed to list executions: %v", err)
+			}
+			var got []string
+			for _, e := range executions {
+				got = append(got, e.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestExecutorService_PruneExecutions(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	insertHistory(t, appSvc, func(query string, args ...interface{}) {
+		if _, err := sqlDB.Exec(query, args...); err != nil {
+			t.Fatalf("failed to insert executions: %v", err)
+		}
+	})
+	if _, err := sqlDB.Exec(`
+		INSERT INTO execution_output (execution_id, seq, stream, line, created_at) VALUES ('d1', 1, 'stdout', 'build ok', CURRENT_TIMESTAMP);
+		INSERT INTO execution_steps (id, execution_id, command_id, name, step_order, status) VALUES ('s1', 'd1', 'x', 'build', 0, 'success');
+	`); err != nil {
+		t.Fatalf("failed to insert output: %v", err)
+	}
+
+	remaining := func() map[string]bool {
+		ids := map[string]bool{}
+		executions, _ := execSvc.ListExecutions(services.ExecutionFilter{})
+		for _, e := range executions {
+			ids[e.ID] = true
+		}
+		return ids
+	}
+
+	// Disabled limits delete nothing
+	if n, err := execSvc.PruneExecutions(0, 0, time.Now()); err != nil || n != 0 {
+		t.Fatalf("expected nothing to be pruned, got %d (err=%v)", n, err)
+	}
+
+	// Keep the last 2 finished executions per command; the running one is not counted
+	n, err := execSvc.PruneExecutions(0, 2, time.Now())
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if ids := remaining(); n != 1 || ids["d1"] || !ids["d2"] || !ids["d3"] || !ids["m1"] || !ids["m2"] {
+		t.Errorf("expected only d1 to be pruned, got %d pruned and %v left", n, ids)
+	}
+	var children int
+	_ = sqlDB.QueryRow("SELECT (SELECT COUNT(*) FROM execution_output) + (SELECT COUNT(*) FROM execution_steps)").Scan(&children)
+	if children != 0 {
+		t.Errorf("expected the output and steps of d1 to be deleted, %d rows left", children)
+	}
+
+	// Keep 2 days before Jan 4 13:00: everything finished before Jan 2 13:00 goes, running executions stay
+	n, err = execSvc.PruneExecutions(2, 0, time.Date(2026, 1, 4, 13, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if ids := remaining(); n != 2 || len(ids) != 2 || !ids["d3"] || !ids["m2"] {
+		t.Errorf("expected d2 and m1 to be pruned, got %d pruned and %v left", n, ids)
+	}
+}
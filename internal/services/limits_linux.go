@@ -0,0 +1,209 @@
+//go:build linux
+
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"golang.org/x/sys/unix"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// cgroupRoot is the cgroup v2 directory under which each limited process gets its own cgroup.
+const cgroupRoot = "/sys/fs/cgroup/http-remote"
+
+var (
+	cgroupOnce      sync.Once
+	cgroupAvailable bool
+)
+
+// cgroupsAvailable reports whether cgroup v2 with the memory and pids controllers can be used,
+// preparing cgroupRoot on first use.
+func cgroupsAvailable() bool {
+	cgroupOnce.Do(func() {
+		controllers, err := os.ReadFile("/sys/fs/cgroup/cgroup.controllers")
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(string(controllers))
+		if !containsString(fields, "memory") || !containsString(fields, "pids") {
+			return
+		}
+
+		// #nosec G301 - cgroup directories must be traversable
+		if err := os.MkdirAll(cgroupRoot, 0o755); err != nil {
+			log.Printf("[Executor] cgroup v2 unavailable, using rlimits: %v", err)
+			return
+		}
+		_ = os.WriteFile("/sys/fs/cgroup/cgroup.subtree_control", []byte("+memory +pids"), 0o600)
+		if err := os.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte("+memory +pids"), 0o600); err != nil {
+			log.Printf("[Executor] cgroup v2 unavailable, using rlimits: %v", err)
+			return
+		}
+		cgroupAvailable = true
+	})
+	return cgroupAvailable
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceLimiter applies resource limits to a single process. Memory and process limits use a
+// cgroup when cgroup v2 is available and rlimits otherwise; CPU time and open files always use rlimits.
+//
+// rlimits can only be set once the process exists, so the shell first waits on a pipe that is
+// released after they were applied. A nil limiter does nothing.
+type resourceLimiter struct {
+	rlimits  map[int]uint64
+	cgroup   string
+	cgroupFD *os.File
+	gateR    *os.File
+	gateW    *os.File
+}
+
+// newResourceLimiter prepares the limits for one process. It returns nil if no limit is set.
+func newResourceLimiter(limits models.ResourceLimits) (*resourceLimiter, error) {
+	if limits.IsZero() {
+		return nil, nil
+	}
+
+	l := &resourceLimiter{rlimits: make(map[int]uint64)}
+	if limits.MaxCPUSeconds > 0 {
+		l.rlimits[unix.RLIMIT_CPU] = uint64(limits.MaxCPUSeconds)
+	}
+	if limits.MaxOpenFiles > 0 {
+		l.rlimits[unix.RLIMIT_NOFILE] = uint64(limits.MaxOpenFiles)
+	}
+
+	if (limits.MaxMemoryMB > 0 || limits.MaxProcesses > 0) && cgroupsAvailable() {
+		if err := l.createCgroup(limits); err != nil {
+			l.release()
+			return nil, err
+		}
+	} else {
+		if limits.MaxMemoryMB > 0 {
+			l.rlimits[unix.RLIMIT_AS] = uint64(limits.MaxMemoryMB) * 1024 * 1024
+		}
+		if limits.MaxProcesses > 0 {
+			l.rlimits[unix.RLIMIT_NPROC] = uint64(limits.MaxProcesses)
+		}
+	}
+
+	if len(l.rlimits) > 0 {
+		r, w, err := os.Pipe()
+		if err != nil {
+			l.release()
+			return nil, err
+		}
+		l.gateR, l.gateW = r, w
+	}
+	return l, nil
+}
+
+func (l *resourceLimiter) createCgroup(limits models.ResourceLimits) error {
+	dir := filepath.Join(cgroupRoot, uuid.New().String())
+	// #nosec G301 - cgroup directories must be traversable
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	l.cgroup = dir
+
+	if limits.MaxMemoryMB > 0 {
+		memory := strconv.FormatInt(int64(limits.MaxMemoryMB)*1024*1024, 10)
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(memory), 0o600); err != nil {
+			return fmt.Errorf("failed to set memory limit: %w", err)
+		}
+		// Keep the process from swapping instead of hitting the limit
+		_ = os.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0o600)
+	}
+	if limits.MaxProcesses > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "pids.max"), []byte(strconv.Itoa(limits.MaxProcesses)), 0o600); err != nil {
+			return fmt.Errorf("failed to set process limit: %w", err)
+		}
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	l.cgroupFD = fd
+	return nil
+}
+
+// wrap makes the script wait until the rlimits were applied. The gate is passed as fd 3.
+func (l *resourceLimiter) wrap(script string) string {
+	if l == nil || l.gateR == nil {
+		return script
+	}
+	return "read -r _ <&3 || exit 126; exec 3<&-\n" + script
+}
+
+// prepare configures cmd to start inside the cgroup and to receive the gate.
+// cmd.SysProcAttr must already be set and cmd.ExtraFiles must be empty.
+func (l *resourceLimiter) prepare(cmd *exec.Cmd) {
+	if l == nil {
+		return
+	}
+	if l.cgroupFD != nil {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(l.cgroupFD.Fd())
+	}
+	if l.gateR != nil {
+		cmd.ExtraFiles = []*os.File{l.gateR}
+	}
+}
+
+// started applies the rlimits to the started process and lets the script run.
+// On error the gate is closed without releasing it, so the shell exits before running the script.
+func (l *resourceLimiter) started(pid int) error {
+	if l == nil || l.gateW == nil {
+		return nil
+	}
+	_ = l.gateR.Close()
+	l.gateR = nil
+	defer func() {
+		_ = l.gateW.Close()
+		l.gateW = nil
+	}()
+
+	for resource, value := range l.rlimits {
+		limit := unix.Rlimit{Cur: value, Max: value}
+		if err := unix.Prlimit(pid, resource, &limit, nil); err != nil {
+			return fmt.Errorf("failed to apply resource limits: %w", err)
+		}
+	}
+	_, err := l.gateW.Write([]byte("\n"))
+	return err
+}
+
+// release frees the files and removes the cgroup once the process has exited.
+func (l *resourceLimiter) release() {
+	if l == nil {
+		return
+	}
+	for _, f := range []*os.File{l.cgroupFD, l.gateR, l.gateW} {
+		if f != nil {
+			_ = f.Close()
+		}
+	}
+	if l.cgroup != "" {
+		if err := os.Remove(l.cgroup); err != nil {
+			log.Printf("[Executor] Failed to remove cgroup %s: %v", l.cgroup, err)
+		}
+	}
+}
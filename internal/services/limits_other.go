@@ -0,0 +1,29 @@
+//go:build !linux
+
+package services
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// resourceLimiter is not supported on this platform. A nil limiter does nothing.
+type resourceLimiter struct{}
+
+// newResourceLimiter fails if any limit is set, resource limits are only supported on Linux.
+func newResourceLimiter(limits models.ResourceLimits) (*resourceLimiter, error) {
+	if limits.IsZero() {
+		return nil, nil
+	}
+	return nil, errors.New("resource limits are only supported on Linux")
+}
+
+func (l *resourceLimiter) wrap(script string) string { return script }
+
+func (l *resourceLimiter) prepare(_ *exec.Cmd) {}
+
+func (l *resourceLimiter) started(_ int) error { return nil }
+
+func (l *resourceLimiter) release() {}
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrNotificationRuleNotFound indicates the requested notification rule was not found.
+	ErrNotificationRuleNotFound = errors.New("notification rule not found")
+	// ErrInvalidNotificationRule indicates a notification rule has an invalid target, status list or template.
+	ErrInvalidNotificationRule = errors.New("invalid notification rule")
+)
+
+// defaultNotificationTemplate renders the message of rules without a template.
+const defaultNotificationTemplate = `[{{.App}}] {{.Command}} {{.Status}}{{if ne .ExitCode 0}} (exit code {{.ExitCode}}){{end}}
+{{if .CommitSHA}}Commit: {{.CommitSHA}}{{if .GitRef}} ({{.GitRef}}){{end}}
+{{end}}Execution: {{.ExecutionID}}
+Duration: {{.Duration}}`
+
+// notificationOutputLines is the number of output lines available to templates as OutputTail.
+const notificationOutputLines = 20
+
+// NotificationData is the data available to notification templates.
+type NotificationData struct {
+	StartedAt   *time.Time             `json:"started_at"`
+	FinishedAt  *time.Time             `json:"finished_at"`
+	AppID       string                 `json:"app_id"`
+	App         string                 `json:"app"`
+	CommandID   string                 `json:"command_id"`
+	Command     string                 `json:"command"`
+	ExecutionID string                 `json:"execution_id"`
+	Status      models.ExecutionStatus `json:"status"`
+	GitRef      string                 `json:"git_ref,omitempty"`
+	CommitSHA   string                 `json:"commit_sha,omitempty"`
+	OutputTail  string                 `json:"-"`
+	Duration    time.Duration          `json:"-"`
+	ExitCode    int                    `json:"exit_code"`
+}
+
+// NotificationService delivers notifications about finished executions according to the rules of each app.
+type NotificationService struct {
+	db     *database.DB
+	cfg    *config.NotificationsConfig
+	crypto *CryptoService
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+// NewNotificationService creates a new NotificationService instance.
+// Webhook secrets are encrypted with the given crypto service.
+func NewNotificationService(db *database.DB, cfg *config.NotificationsConfig, crypto *CryptoService) *NotificationService {
+	return &NotificationService{
+		db:     db,
+		cfg:    cfg,
+		crypto: crypto,
+		client: &http.Client{Timeout: cfg.GetTimeout()},
+	}
+}
+
+const notificationRuleColumns = "id, app_id, name, channel, target, COALESCE(secret, '') != '', template, statuses, enabled, created_at, updated_at"
+
+func scanNotificationRule(row rowScanner) (*models.NotificationRule, error) {
+	var r models.NotificationRule
+	var statuses string
+	if err := row.Scan(&r.ID, &r.AppID, &r.Name, &r.Channel, &r.Target, &r.HasSecret, &r.Template, &statuses, &r.Enabled, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(statuses), &r.Statuses); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListRules returns the notification rules of an app.
+func (s *NotificationService) ListRules(appID string) ([]models.NotificationRule, error) {
+	rows, err := s.db.Query("SELECT "+notificationRuleColumns+" FROM notification_rules WHERE app_id = ? ORDER BY name", appID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	rules := []models.NotificationRule{}
+	for rows.Next() {
+		r, err := scanNotificationRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule retrieves a notification rule of an app.
+func (s *NotificationService) GetRule(appID, id string) (*models.NotificationRule, error) {
+	r, err := scanNotificationRule(s.db.QueryRow("SELECT "+notificationRuleColumns+" FROM notification_rules WHERE id = ? AND app_id = ?", id, appID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotificationRuleNotFound
+	}
+	return r, err
+}
+
+// CreateRule creates a notification rule for an app.
+func (s *NotificationService) CreateRule(appID string, req *models.CreateNotificationRuleRequest) (*models.NotificationRule, error) {
+	rule := &models.NotificationRule{
+		Name:     req.Name,
+		Channel:  req.Channel,
+		Target:   req.Target,
+		Template: req.Template,
+		Statuses: req.Statuses,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.validateRule(rule, req.Secret); err != nil {
+		return nil, err
+	}
+	statuses, err := json.Marshal(rule.Statuses)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.encryptSecret(req.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	_, err = s.db.Exec(
+		"INSERT INTO notification_rules (id, app_id, name, channel, target, secret, template, statuses, enabled) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, appID, rule.Name, rule.Channel, rule.Target, secret, rule.Template, string(statuses), rule.Enabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetRule(appID, id)
+}
+
+// UpdateRule updates a notification rule of an app. The channel of a rule cannot be changed.
+func (s *NotificationService) UpdateRule(appID, id string, req *models.UpdateNotificationRuleRequest) (*models.NotificationRule, error) {
+	rule, err := s.GetRule(appID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.Target != nil {
+		rule.Target = *req.Target
+	}
+	if req.Template != nil {
+		rule.Template = *req.Template
+	}
+	if req.Statuses != nil {
+		rule.Statuses = req.Statuses
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	secret := ""
+	if req.Secret != nil {
+		secret = *req.Secret
+	}
+	if err := s.validateRule(rule, secret); err != nil {
+		return nil, err
+	}
+	statuses, err := json.Marshal(rule.Statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE notification_rules SET name = ?, target = ?, template = ?, statuses = ?, enabled = ?, updated_at = ? WHERE id = ?",
+		rule.Name, rule.Target, rule.Template, string(statuses), rule.Enabled, time.Now(), id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if req.Secret != nil {
+		stored, err := s.encryptSecret(*req.Secret)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.db.Exec("UPDATE notification_rules SET secret = ? WHERE id = ?", stored, id); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetRule(appID, id)
+}
+
+// DeleteRule deletes a notification rule of an app. Its deliveries stay in the log.
+func (s *NotificationService) DeleteRule(appID, id string) error {
+	result, err := s.db.Exec("DELETE FROM notification_rules WHERE id = ? AND app_id = ?", id, appID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotificationRuleNotFound
+	}
+	return nil
+}
+
+// validateRule checks the name, target, statuses and template of a rule.
+func (s *NotificationService) validateRule(rule *models.NotificationRule, secret string) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidNotificationRule)
+	}
+	if !rule.Channel.IsValid() {
+		return fmt.Errorf("%w: unknown channel %q", ErrInvalidNotificationRule, rule.Channel)
+	}
+	if len(rule.Statuses) == 0 {
+		return fmt.Errorf("%w: at least one status is required", ErrInvalidNotificationRule)
+	}
+	for _, status := range rule.Statuses {
+		if !status.IsFinished() {
+			return fmt.Errorf("%w: status %q is not a final execution status", ErrInvalidNotificationRule, status)
+		}
+	}
+	if _, err := template.New(rule.Name).Parse(rule.Template); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNotificationRule, err)
+	}
+	if secret != "" && rule.Channel != models.ChannelWebhook {
+		return fmt.Errorf("%w: only webhook channels are signed with a secret", ErrInvalidNotificationRule)
+	}
+
+	if rule.Channel == models.ChannelEmail {
+		if s.cfg.SMTP.Host == "" {
+			return fmt.Errorf("%w: SMTP is not configured", ErrInvalidNotificationRule)
+		}
+		if _, err := mail.ParseAddressList(rule.Target); err != nil {
+			return fmt.Errorf("%w: invalid recipients: %v", ErrInvalidNotificationRule, err)
+		}
+		return nil
+	}
+	u, err := url.Parse(rule.Target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: target must be an http or https URL", ErrInvalidNotificationRule)
+	}
+	return nil
+}
+
+func (s *NotificationService) encryptSecret(secret string) (interface{}, error) {
+	if secret == "" {
+		return nil, nil
+	}
+	if s.crypto == nil {
+		return nil, errors.New("encryption is not configured")
+	}
+	return s.crypto.Encrypt(secret)
+}
+
+func (s *NotificationService) ruleSecret(id string) (string, error) {
+	var encrypted string
+	if err := s.db.QueryRow("SELECT COALESCE(secret, '') FROM notification_rules WHERE id = ?", id).Scan(&encrypted); err != nil {
+		return "", err
+	}
+	if encrypted == "" {
+		return "", nil
+	}
+	if s.crypto == nil {
+		return "", errors.New("encryption is not configured")
+	}
+	return s.crypto.Decrypt(encrypted)
+}
+
+const notificationDeliveryColumns = "id, COALESCE(rule_id, ''), rule_name, app_id, execution_id, channel, status, execution_status, attempts, last_error, created_at, updated_at"
+
+// ListDeliveries returns the most recent notification deliveries of an app, optionally only for one execution.
+func (s *NotificationService) ListDeliveries(appID, executionID string, limit int) ([]models.NotificationDelivery, error) {
+	query := "SELECT " + notificationDeliveryColumns + " FROM notification_deliveries WHERE app_id = ?"
+	args := []interface{}{appID}
+	if executionID != "" {
+		query += " AND execution_id = ?"
+		args = append(args, executionID)
+	}
+	query += " ORDER BY created_at DESC, rowid DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	deliveries := []models.NotificationDelivery{}
+	for rows.Next() {
+		var d models.NotificationDelivery
+		if err := rows.Scan(&d.ID, &d.RuleID, &d.RuleName, &d.AppID, &d.ExecutionID, &d.Channel, &d.Status, &d.ExecutionStatus, &d.Attempts, &d.LastError, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Notify delivers the notifications for a finished execution in the background.
+func (s *NotificationService) Notify(executionID string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.notify(executionID); err != nil {
+			log.Printf("[Notifications] Failed to notify about execution %s: %v", executionID, err)
+		}
+	}()
+}
+
+// Wait blocks until all pending deliveries, including their retries, are done.
+func (s *NotificationService) Wait() {
+	s.wg.Wait()
+}
+
+func (s *NotificationService) notify(executionID string) error {
+	var data NotificationData
+	var exitCode sql.NullInt64
+	var gitRef, commitSHA sql.NullString
+	var output string
+	err := s.db.QueryRow(`
+		SELECT a.id, a.name, c.id, c.name, e.id, e.status, e.exit_code, e.git_ref, e.commit_sha, e.output, e.started_at, e.finished_at
+		FROM executions e
+		JOIN commands c ON e.command_id = c.id
+		JOIN apps a ON c.app_id = a.id
+		WHERE e.id = ?
+	`, executionID).Scan(&data.AppID, &data.App, &data.CommandID, &data.Command, &data.ExecutionID, &data.Status, &exitCode, &gitRef, &commitSHA, &output, &data.StartedAt, &data.FinishedAt)
+	if err != nil {
+		return err
+	}
+	data.ExitCode = int(exitCode.Int64)
+	data.GitRef = gitRef.String
+	data.CommitSHA = commitSHA.String
+	data.OutputTail = tailLines(output, notificationOutputLines)
+	if data.StartedAt != nil && data.FinishedAt != nil {
+		data.Duration = data.FinishedAt.Sub(*data.StartedAt).Round(time.Second)
+	}
+
+	rules, err := s.ListRules(data.AppID)
+	if err != nil {
+		return err
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled || !containsStatus(rule.Statuses, data.Status) {
+			continue
+		}
+
+		deliveryID := uuid.New().String()
+		_, err := s.db.Exec(
+			"INSERT INTO notification_deliveries (id, rule_id, rule_name, app_id, execution_id, channel, status, execution_status) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			deliveryID, rule.ID, rule.Name, data.AppID, executionID, rule.Channel, models.DeliveryPending, data.Status,
+		)
+		if err != nil {
+			return err
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.deliver(deliveryID, rule, &data)
+		}()
+	}
+	return nil
+}
+
+func containsStatus(statuses []models.ExecutionStatus, status models.ExecutionStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// tailLines returns the last n lines of output.
+func tailLines(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// deliver sends a notification, retrying with an exponential backoff, and records each attempt.
+func (s *NotificationService) deliver(deliveryID string, rule *models.NotificationRule, data *NotificationData) {
+	maxAttempts := s.cfg.GetMaxAttempts()
+	delay := s.cfg.GetRetryDelay()
+
+	for attempt := 1; ; attempt++ {
+		err := s.send(deliveryID, rule, data)
+
+		status, lastError := models.DeliverySent, ""
+		if err != nil {
+			status, lastError = models.DeliveryPending, err.Error()
+			if attempt >= maxAttempts {
+				status = models.DeliveryFailed
+			}
+			log.Printf("[Notifications] Delivery %s (%s) attempt %d failed: %v", deliveryID, rule.Name, attempt, err)
+		}
+		_, _ = s.db.Exec(
+			"UPDATE notification_deliveries SET status = ?, attempts = ?, last_error = ?, updated_at = ? WHERE id = ?",
+			status, attempt, lastError, time.Now(), deliveryID,
+		)
+		if status != models.DeliveryPending {
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// send makes a single delivery attempt.
+func (s *NotificationService) send(deliveryID string, rule *models.NotificationRule, data *NotificationData) error {
+	text := rule.Template
+	if text == "" {
+		text = defaultNotificationTemplate
+	}
+	tmpl, err := template.New(rule.Name).Parse(text)
+	if err != nil {
+		return err
+	}
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, data); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	switch rule.Channel {
+	case models.ChannelSlack:
+		body, err := json.Marshal(map[string]string{"text": message.String()})
+		if err != nil {
+			return err
+		}
+		return s.post(rule.Target, body, nil)
+	case models.ChannelWebhook:
+		body, err := json.Marshal(struct {
+			Event   string `json:"event"`
+			Message string `json:"message"`
+			*NotificationData
+		}{"execution.finished", message.String(), data})
+		if err != nil {
+			return err
+		}
+		header := http.Header{"X-Http-Remote-Delivery": {deliveryID}}
+		secret, err := s.ruleSecret(rule.ID)
+		if err != nil {
+			return fmt.Errorf("failed to read secret: %w", err)
+		}
+		if secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		return s.post(rule.Target, body, header)
+	case models.ChannelEmail:
+		return s.sendMail(rule.Target, message.String())
+	default:
+		return fmt.Errorf("unknown channel %q", rule.Channel)
+	}
+}
+
+// post sends a JSON body and expects a 2xx response.
+func (s *NotificationService) post(target string, body []byte, header http.Header) error {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "http-remote")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return nil
+}
+
+// sendMail sends message to the comma separated recipients. The first line of the message is the subject.
+// Port 465 uses implicit TLS; other ports upgrade with STARTTLS when the server supports it.
+func (s *NotificationService) sendMail(recipients, message string) error {
+	smtpCfg := s.cfg.SMTP
+	addresses, err := mail.ParseAddressList(recipients)
+	if err != nil {
+		return err
+	}
+	from := smtpCfg.From
+	if from == "" {
+		from = smtpCfg.Username
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	subject, _, _ := strings.Cut(message, "\n")
+	to := make([]string, len(addresses))
+	for i, a := range addresses {
+		to[i] = a.Address
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + sender.String() + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(message, "\n", "\r\n") + "\r\n")
+
+	addr := net.JoinHostPort(smtpCfg.Host, strconv.Itoa(smtpCfg.Port))
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.GetTimeout())
+	defer cancel()
+
+	var conn net.Conn
+	if smtpCfg.Port == 465 {
+		conn, err = (&tls.Dialer{Config: &tls.Config{ServerName: smtpCfg.Host, MinVersion: tls.VersionTLS12}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, smtpCfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && smtpCfg.Port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: smtpCfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if smtpCfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,259 @@
+package services_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// startSMTPServer runs a minimal SMTP server that accepts every message and sends its data to the returned channel.
+func startSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	messages := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				r := bufio.NewReader(conn)
+				reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+				reply("220 localhost ESMTP")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+						reply("250 localhost")
+					case cmd == "DATA":
+						reply("354 end data with <CR><LF>.<CR><LF>")
+						var data strings.Builder
+						for {
+							line, err := r.ReadString('\n')
+							if err != nil || line == ".\r\n" {
+								break
+							}
+							data.WriteString(line)
+						}
+						messages <- data.String()
+						reply("250 OK")
+					case cmd == "QUIT":
+						reply("221 bye")
+						return
+					default:
+						reply("250 OK")
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestNotificationService_Rules(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	notifySvc := services.NewNotificationService(db, &config.NotificationsConfig{}, newTestCryptoService(t))
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Notify App", WorkingDir: "/tmp"})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+
+	invalid := []models.CreateNotificationRuleRequest{
+		{Name: "running", Channel: models.ChannelSlack, Target: "https://hooks.slack.com/x", Statuses: []models.ExecutionStatus{models.StatusRunning}},
+		{Name: "no statuses", Channel: models.ChannelSlack, Target: "https://hooks.slack.com/x"},
+		{Name: "bad url", Channel: models.ChannelWebhook, Target: "ftp://example.com", Statuses: []models.ExecutionStatus{models.StatusFailed}},
+		{Name: "no smtp", Channel: models.ChannelEmail, Target: "ops@example.com", Statuses: []models.ExecutionStatus{models.StatusFailed}},
+		{Name: "slack secret", Channel: models.ChannelSlack, Target: "https://hooks.slack.com/x", Secret: "s", Statuses: []models.ExecutionStatus{models.StatusFailed}},
+		{Name: "bad template", Channel: models.ChannelSlack, Target: "https://hooks.slack.com/x", Template: "{{.App", Statuses: []models.ExecutionStatus{models.StatusFailed}},
+	}
+	for _, req := range invalid {
+		if _, err := notifySvc.CreateRule(app.ID, &req); !errors.Is(err, services.ErrInvalidNotificationRule) {
+			t.Errorf("%s: expected ErrInvalidNotificationRule, got %v", req.Name, err)
+		}
+	}
+
+	rule, err := notifySvc.CreateRule(app.ID, &models.CreateNotificationRuleRequest{
+		Name:     "hook",
+		Channel:  models.ChannelWebhook,
+		Target:   "https://example.com/hook",
+		Secret:   "sign-me",
+		Statuses: []models.ExecutionStatus{models.StatusFailed},
+	})
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if !rule.HasSecret || !rule.Enabled {
+		t.Errorf("expected enabled rule with a secret, got %+v", rule)
+	}
+
+	disabled, empty := false, ""
+	rule, err = notifySvc.UpdateRule(app.ID, rule.ID, &models.UpdateNotificationRuleRequest{Enabled: &disabled, Secret: &empty})
+	if err != nil {
+		t.Fatalf("failed to update rule: %v", err)
+	}
+	if rule.HasSecret || rule.Enabled {
+		t.Errorf("expected disabled rule without a secret, got %+v", rule)
+	}
+
+	if err := notifySvc.DeleteRule(app.ID, rule.ID); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	if _, err := notifySvc.GetRule(app.ID, rule.ID); err != services.ErrNotificationRuleNotFound {
+		t.Errorf("expected ErrNotificationRuleNotFound, got %v", err)
+	}
+}
+
+func TestNotificationService_Deliveries(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	sqlDB.SetMaxOpenConns(1)
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	var mu sync.Mutex
+	received := map[string][]*http.Request{}
+	bodies := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.URL.Path] = append(received[r.URL.Path], r)
+		bodies[r.URL.Path] = append(bodies[r.URL.Path], string(body))
+		// The flaky endpoint only succeeds on the second attempt
+		if r.URL.Path == "/flaky" && len(received[r.URL.Path]) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	smtpAddr, mails := startSMTPServer(t)
+	host, port, _ := net.SplitHostPort(smtpAddr)
+	smtpPort, _ := strconv.Atoi(port)
+	notifyCfg := &config.NotificationsConfig{
+		SMTP:        config.SMTPConfig{Host: host, Port: smtpPort, From: "HTTP Remote <noreply@example.com>"},
+		MaxAttempts: 2,
+		RetryDelay:  "10ms",
+	}
+
+	appSvc := services.NewAppService(db)
+	notifySvc := services.NewNotificationService(db, notifyCfg, newTestCryptoService(t))
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+	execSvc.SetNotificationService(notifySvc)
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Notified", WorkingDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "Deploy", Command: "echo deployed"})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+
+	finished := []models.ExecutionStatus{models.StatusSuccess, models.StatusFailed}
+	rules := []models.CreateNotificationRuleRequest{
+		{Name: "webhook", Channel: models.ChannelWebhook, Target: server.URL + "/hook", Secret: "sign-me", Statuses: finished},
+		{Name: "slack", Channel: models.ChannelSlack, Target: server.URL + "/slack", Template: "{{.App}}: {{.Status}}\n{{.OutputTail}}", Statuses: finished},
+		{Name: "flaky", Channel: models.ChannelSlack, Target: server.URL + "/flaky", Statuses: finished},
+		{Name: "email", Channel: models.ChannelEmail, Target: "ops@example.com, Dev <dev@example.com>", Statuses: finished},
+		{Name: "failures only", Channel: models.ChannelSlack, Target: server.URL + "/failures", Statuses: []models.ExecutionStatus{models.StatusFailed}},
+	}
+	for _, req := range rules {
+		if _, err := notifySvc.CreateRule(app.ID, &req); err != nil {
+			t.Fatalf("failed to create rule %s: %v", req.Name, err)
+		}
+	}
+
+	execution, err := execSvc.CreateExecution(cmd.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	if err := execSvc.Execute(execution.ID); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	notifySvc.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received["/hook"]) != 1 {
+		t.Fatalf("expected one webhook request, got %d", len(received["/hook"]))
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(bodies["/hook"][0]), &payload); err != nil {
+		t.Fatalf("invalid webhook payload: %v", err)
+	}
+	if payload["event"] != "execution.finished" || payload["status"] != "success" || payload["execution_id"] != execution.ID {
+		t.Errorf("unexpected webhook payload: %v", payload)
+	}
+	if got, want := received["/hook"][0].Header.Get("X-Signature-256"), "sha256="+sign("sign-me", []byte(bodies["/hook"][0])); got != want {
+		t.Errorf("expected signature %s, got %s", want, got)
+	}
+
+	var slack map[string]string
+	if err := json.Unmarshal([]byte(bodies["/slack"][0]), &slack); err != nil {
+		t.Fatalf("invalid slack payload: %v", err)
+	}
+	if slack["text"] != "Notified: success\ndeployed" {
+		t.Errorf("unexpected slack message %q", slack["text"])
+	}
+
+	if len(received["/flaky"]) != 2 {
+		t.Errorf("expected the flaky endpoint to be retried once, got %d requests", len(received["/flaky"]))
+	}
+	if len(received["/failures"]) != 0 {
+		t.Errorf("expected no notification for a rule without the success status")
+	}
+
+	select {
+	case msg := <-mails:
+		if !strings.Contains(msg, "Subject: [Notified] Deploy success") || !strings.Contains(msg, "To: ops@example.com, dev@example.com") {
+			t.Errorf("unexpected email:\n%s", msg)
+		}
+	default:
+		t.Error("expected an email to be sent")
+	}
+
+	deliveries, err := notifySvc.ListDeliveries(app.ID, execution.ID, 50)
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 4 {
+		t.Fatalf("expected 4 deliveries, got %d", len(deliveries))
+	}
+	for _, d := range deliveries {
+		if d.Status != models.DeliverySent {
+			t.Errorf("expected delivery %s to be sent, got %s: %s", d.RuleName, d.Status, d.LastError)
+		}
+		if wantAttempts := map[string]int{"flaky": 2}[d.RuleName]; wantAttempts != 0 && d.Attempts != wantAttempts {
+			t.Errorf("expected %d attempts for %s, got %d", wantAttempts, d.RuleName, d.Attempts)
+		}
+		if d.ExecutionStatus != models.StatusSuccess {
+			t.Errorf("expected execution status success, got %s", d.ExecutionStatus)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// outputFlushInterval is how long recorded output may wait before it is written to the database.
+const outputFlushInterval = 250 * time.Millisecond
+
+// outputFlushSize is the number of pending chunks that triggers an immediate write.
+const outputFlushSize = 200
+
+// outputReplayLines is the number of recent lines kept in memory per running execution so that
+// reconnecting and slow stream clients can catch up, including lines beyond max_output_size.
+const outputReplayLines = 5000
+
+// outputTruncatedMessage is recorded once when an execution exceeds max_output_size.
+const outputTruncatedMessage = "... [OUTPUT TRUNCATED - exceeded max_output_size limit]"
+
+// outputRecorder numbers the output lines of one execution, publishes them to subscribers and
+// writes them to the execution_output table in batches, masking the secrets of the execution first.
+// Lines beyond max_output_size are still published and buffered but no longer stored.
+type outputRecorder struct {
+	db          *database.DB
+	publish     func(models.OutputChunk)
+	masker      *strings.Replacer // nil if the execution has no secrets
+	timer       *time.Timer
+	start       time.Time
+	executionID string
+	pending     []models.OutputChunk
+	flushing    []models.OutputChunk // chunks being written by flush
+	recent      []models.OutputChunk // the last outputReplayLines chunks
+	seq         int64
+	size        int
+	maxSize     int
+	truncated   bool
+	mu          sync.Mutex
+	flushMu     sync.Mutex
+}
+
+func newOutputRecorder(db *database.DB, executionID string, start time.Time, maxSize int, masker *strings.Replacer, publish func(models.OutputChunk)) *outputRecorder {
+	return &outputRecorder{
+		db:          db,
+		publish:     publish,
+		masker:      masker,
+		start:       start,
+		executionID: executionID,
+		maxSize:     maxSize,
+	}
+}
+
+// record numbers a line, publishes it and queues it for storage.
+func (r *outputRecorder) record(stream models.OutputStream, line string) {
+	if r.masker != nil {
+		line = r.masker.Replace(line)
+	}
+
+	r.mu.Lock()
+	chunk := r.next(stream, line)
+	r.push(chunk)
+
+	if !r.truncated {
+		if r.size+len(line)+1 <= r.maxSize {
+			r.size += len(line) + 1
+			r.pending = append(r.pending, chunk)
+		} else {
+			r.truncated = true
+			marker := r.next(models.StreamSystem, outputTruncatedMessage)
+			r.push(marker)
+			r.pending = append(r.pending, marker)
+		}
+	}
+
+	full := len(r.pending) >= outputFlushSize
+	if !full && len(r.pending) > 0 && r.timer == nil {
+		r.timer = time.AfterFunc(outputFlushInterval, r.flush)
+	}
+	r.mu.Unlock()
+
+	if full {
+		r.flush()
+	}
+}
+
+// push buffers a chunk and publishes it. The caller must hold r.mu, so that subscribers
+// receive lines in sequence order.
+func (r *outputRecorder) push(chunk models.OutputChunk) {
+	r.recent = append(r.recent, chunk)
+	if len(r.recent) >= 2*outputReplayLines {
+		r.recent = append([]models.OutputChunk(nil), r.recent[len(r.recent)-outputReplayLines:]...)
+	}
+	r.publish(chunk)
+}
+
+// since returns the chunks after afterSeq that are held in memory, which are the recent chunks and
+// those not yet written to the database, and the oldest sequence number held in memory.
+func (r *outputRecorder) since(afterSeq int64) ([]models.OutputChunk, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recent := r.recent
+	if len(recent) > outputReplayLines {
+		recent = recent[len(recent)-outputReplayLines:]
+	}
+	oldest := r.seq + 1
+	if len(recent) > 0 {
+		oldest = recent[0].Seq
+	}
+
+	var chunks []models.OutputChunk
+	unstored := append(append([]models.OutputChunk(nil), r.flushing...), r.pending...)
+	for _, c := range unstored {
+		if c.Seq < oldest {
+			if c.Seq > afterSeq {
+				chunks = append(chunks, c)
+			}
+		}
+	}
+	if len(unstored) > 0 && unstored[0].Seq < oldest {
+		oldest = unstored[0].Seq
+	}
+	for _, c := range recent {
+		if c.Seq > afterSeq {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, oldest
+}
+
+// next returns the chunk for the next sequence number. The caller must hold r.mu.
+func (r *outputRecorder) next(stream models.OutputStream, line string) models.OutputChunk {
+	r.seq++
+	return models.OutputChunk{
+		Seq:       r.seq,
+		Stream:    stream,
+		Line:      line,
+		Timestamp: time.Now(),
+		ElapsedMs: time.Since(r.start).Milliseconds(),
+	}
+}
+
+// flush writes the pending chunks to the database.
+func (r *outputRecorder) flush() {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.mu.Lock()
+	chunks := r.pending
+	r.pending = nil
+	r.flushing = chunks
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.mu.Unlock()
+
+	if len(chunks) == 0 {
+		return
+	}
+	if err := insertOutputChunks(r.db, r.executionID, chunks); err != nil {
+		log.Printf("[Executor] Failed to store %d output lines of execution %s: %v", len(chunks), r.executionID, err)
+	}
+
+	r.mu.Lock()
+	r.flushing = nil
+	r.mu.Unlock()
+}
+
+func insertOutputChunks(db *database.DB, executionID string, chunks []models.OutputChunk) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare("INSERT INTO execution_output (execution_id, seq, stream, line, elapsed_ms, created_at) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, c := range chunks {
+		if _, err := stmt.Exec(executionID, c.Seq, c.Stream, c.Line, c.ElapsedMs, c.Timestamp); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// startRecording begins numbering and storing the output of an execution that started at start,
+// masking secret values with masker, which may be nil.
+func (s *ExecutorService) startRecording(executionID string, start time.Time, masker *strings.Replacer) {
+	rec := newOutputRecorder(s.db, executionID, start, s.cfg.Execution.MaxOutputSize, masker, func(chunk models.OutputChunk) {
+		s.broadcastChunk(executionID, chunk)
+	})
+
+	s.recordersMu.Lock()
+	s.recorders[executionID] = rec
+	s.recordersMu.Unlock()
+}
+
+// stopRecording writes the remaining output of an execution to the database.
+func (s *ExecutorService) stopRecording(executionID string) {
+	s.recordersMu.Lock()
+	rec := s.recorders[executionID]
+	delete(s.recorders, executionID)
+	s.recordersMu.Unlock()
+
+	if rec != nil {
+		rec.flush()
+	}
+}
+
+// emit records a line of output of an execution and sends it to subscribers.
+// Lines of executions that are not recording, such as errors before the start, are only sent to subscribers.
+func (s *ExecutorService) emit(executionID string, stream models.OutputStream, line string) {
+	s.recordersMu.Lock()
+	rec := s.recorders[executionID]
+	s.recordersMu.Unlock()
+
+	if rec != nil {
+		rec.record(stream, line)
+		return
+	}
+	s.broadcastChunk(executionID, models.OutputChunk{Stream: stream, Line: line, Timestamp: time.Now()})
+}
+
+// GetOutput returns up to limit output chunks of an execution with a sequence number after afterSeq,
+// and whether more chunks follow. Output of executions that finished before chunks were stored is
+// returned as stdout lines split from the output text.
+func (s *ExecutorService) GetOutput(executionID string, afterSeq int64, limit int) ([]models.OutputChunk, bool, error) {
+	execution, err := s.GetExecutionByID(executionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	chunks, err := s.storedOutput(executionID, afterSeq, 0, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(chunks) > limit {
+		return chunks[:limit], true, nil
+	}
+	if len(chunks) > 0 || !execution.Status.IsFinished() {
+		return chunks, false, nil
+	}
+
+	var recorded bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM execution_output WHERE execution_id = ?)", executionID).Scan(&recorded); err != nil {
+		return nil, false, err
+	}
+	if recorded {
+		return chunks, false, nil
+	}
+	return legacyOutputChunks(execution, afterSeq, limit)
+}
+
+// storedOutput reads stored chunks with a sequence number after afterSeq and, if beforeSeq is positive, before beforeSeq.
+func (s *ExecutorService) storedOutput(executionID string, afterSeq, beforeSeq int64, limit int) ([]models.OutputChunk, error) {
+	query := "SELECT seq, stream, line, elapsed_ms, created_at FROM execution_output WHERE execution_id = ? AND seq > ?"
+	args := []interface{}{executionID, afterSeq}
+	if beforeSeq > 0 {
+		query += " AND seq < ?"
+		args = append(args, beforeSeq)
+	}
+	query += " ORDER BY seq LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	chunks := []models.OutputChunk{}
+	for rows.Next() {
+		var c models.OutputChunk
+		if err := rows.Scan(&c.Seq, &c.Stream, &c.Line, &c.ElapsedMs, &c.Timestamp); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// OutputSince returns the output of a running execution after afterSeq for stream clients that reconnect or
+// fell behind. Recent and unwritten lines come from memory and older ones from the database. dropped is the
+// number of lines in that range that are no longer available, because they were neither buffered nor stored.
+// For executions that are not running it returns the stored output.
+func (s *ExecutorService) OutputSince(executionID string, afterSeq int64) (chunks []models.OutputChunk, dropped int64, err error) {
+	s.recordersMu.Lock()
+	rec := s.recorders[executionID]
+	s.recordersMu.Unlock()
+
+	var buffered []models.OutputChunk
+	var before int64
+	if rec != nil {
+		buffered, before = rec.since(afterSeq)
+	}
+
+	// Older lines have left memory, read them from the database
+	for after := afterSeq; rec == nil || before > after+1; {
+		page, err := s.storedOutput(executionID, after, before, replayBatchSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		chunks = append(chunks, page...)
+		if len(page) < replayBatchSize {
+			break
+		}
+		after = page[len(page)-1].Seq
+	}
+	chunks = append(chunks, buffered...)
+
+	last := afterSeq
+	for _, c := range chunks {
+		dropped += c.Seq - last - 1
+		last = c.Seq
+	}
+	return chunks, dropped, nil
+}
+
+// replayBatchSize is the number of stored chunks read per query by OutputSince.
+const replayBatchSize = 1000
+
+// legacyOutputChunks pages through the output text of an execution without stored chunks.
+func legacyOutputChunks(execution *models.Execution, afterSeq int64, limit int) ([]models.OutputChunk, bool, error) {
+	chunks := []models.OutputChunk{}
+	if execution.Output == "" {
+		return chunks, false, nil
+	}
+
+	timestamp := execution.CreatedAt
+	if execution.StartedAt != nil {
+		timestamp = *execution.StartedAt
+	}
+	lines := strings.Split(strings.TrimSuffix(execution.Output, "\n"), "\n")
+	for i := afterSeq; i < int64(len(lines)); i++ {
+		if len(chunks) == limit {
+			return chunks, true, nil
+		}
+		chunks = append(chunks, models.OutputChunk{
+			Seq:       i + 1,
+			Stream:    models.StreamStdout,
+			Line:      lines[i],
+			Timestamp: timestamp,
+		})
+	}
+	return chunks, false, nil
+}
@@ -0,0 +1,195 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestExecutorService_OutputChunks(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Chunks", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:    "mixed",
+		Command: "echo out1; echo err1 >&2; sleep 0.1; echo out2",
+	})
+
+	execution, _ := execSvc.CreateExecution(cmd.ID, 1)
+	if err := execSvc.Execute(execution.ID); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	chunks, more, err := execSvc.GetOutput(execution.ID, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to get output: %v", err)
+	}
+	if more || len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks without more, got %d (more=%v)", len(chunks), more)
+	}
+
+	streams := map[string]models.OutputStream{}
+	for i, chunk := range chunks {
+		if chunk.Seq != int64(i+1) {
+			t.Errorf("expected seq %d, got %d", i+1, chunk.Seq)
+		}
+		if i > 0 && chunk.ElapsedMs < chunks[i-1].ElapsedMs {
+			t.Errorf("expected elapsed time to be monotonic, got %d after %d", chunk.ElapsedMs, chunks[i-1].ElapsedMs)
+		}
+		streams[chunk.Line] = chunk.Stream
+	}
+	want := map[string]models.OutputStream{"out1": models.StreamStdout, "err1": models.StreamStderr, "out2": models.StreamStdout}
+	for line, stream := range want {
+		if streams[line] != stream {
+			t.Errorf("expected %q on %s, got %q", line, stream, streams[line])
+		}
+	}
+	if chunks[2].Line != "out2" || chunks[2].ElapsedMs < 100 {
+		t.Errorf("expected out2 last after at least 100ms, got %+v", chunks[2])
+	}
+
+	page, more, err := execSvc.GetOutput(execution.ID, 1, 1)
+	if err != nil {
+		t.Fatalf("failed to get output page: %v", err)
+	}
+	if len(page) != 1 || page[0].Seq != 2 || !more {
+		t.Errorf("expected the second chunk with more to follow, got %+v (more=%v)", page, more)
+	}
+}
+
+func TestExecutorService_OutputChunksTruncated(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 20
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Truncated", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "loud", Command: "for i in 1 2 3 4 5 6 7 8; do echo line$i; done"})
+
+	execution, _ := execSvc.CreateExecution(cmd.ID, 1)
+	ch := execSvc.Subscribe(execution.ID)
+	defer execSvc.Unsubscribe(execution.ID, ch)
+	if err := execSvc.Execute(execution.ID); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+
+	chunks, _, err := execSvc.GetOutput(execution.ID, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to get output: %v", err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("expected 3 lines and a truncation marker, got %+v", chunks)
+	}
+	if last := chunks[3]; last.Stream != models.StreamSystem || !strings.Contains(last.Line, "TRUNCATED") {
+		t.Errorf("expected a truncation marker, got %+v", last)
+	}
+
+	// Subscribers still receive every line, and the marker where storage stopped
+	lines, markers := 0, 0
+	for msg := range ch {
+		if strings.HasPrefix(msg, "output:") {
+			if strings.Contains(msg, "TRUNCATED") {
+				markers++
+			} else {
+				lines++
+			}
+		}
+		if strings.HasPrefix(msg, "complete:") {
+			break
+		}
+	}
+	if lines != 8 || markers != 1 {
+		t.Errorf("expected 8 streamed lines and a marker, got %d and %d", lines, markers)
+	}
+}
+
+func TestExecutorService_LegacyOutput(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Legacy", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "old", Command: "true"})
+
+	// Executions recorded before output chunks existed only have the output text
+	if _, err := sqlDB.Exec(
+		"INSERT INTO executions (id, command_id, user_id, status, output) VALUES ('legacy', ?, 1, 'success', ?)",
+		cmd.ID, "first\nsecond\nthird\n",
+	); err != nil {
+		t.Fatalf("failed to insert execution: %v", err)
+	}
+
+	chunks, more, err := execSvc.GetOutput("legacy", 1, 1)
+	if err != nil {
+		t.Fatalf("failed to get output: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0].Line != "second" || chunks[0].Seq != 2 || chunks[0].Stream != models.StreamStdout || !more {
+		t.Errorf("unexpected legacy page: %+v (more=%v)", chunks, more)
+	}
+}
+
+func TestExecutorService_OutputSince(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 100
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Replay", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "flood", Command: "seq 1 6000; sleep 5"})
+
+	execution, _ := execSvc.CreateExecution(cmd.ID, 1)
+	go func() { _ = execSvc.Execute(execution.ID) }()
+	defer func() { _ = execSvc.Cancel(execution.ID, "test") }()
+
+	// 6000 lines and the truncation marker
+	var chunks []models.OutputChunk
+	var dropped int64
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var err error
+		chunks, dropped, err = execSvc.OutputSince(execution.ID, 0)
+		if err != nil {
+			t.Fatalf("failed to get output: %v", err)
+		}
+		if len(chunks) > 0 && chunks[len(chunks)-1].Seq == 6001 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for output, got %d chunks", len(chunks))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Stored lines come from the database, recent lines from the buffer and the rest is reported as dropped
+	if chunks[0].Seq != 1 || chunks[0].Line != "1" {
+		t.Errorf("expected the first stored line, got %+v", chunks[0])
+	}
+	if int64(len(chunks))+dropped != 6001 {
+		t.Errorf("expected %d chunks and dropped lines, got %d + %d", 6001, len(chunks), dropped)
+	}
+	if dropped == 0 {
+		t.Error("expected lines that were neither stored nor buffered to be reported as dropped")
+	}
+
+	recent, dropped, err := execSvc.OutputSince(execution.ID, 5990)
+	if err != nil {
+		t.Fatalf("failed to get output: %v", err)
+	}
+	if len(recent) != 11 || recent[0].Line != "5990" || dropped != 0 {
+		t.Errorf("expected the last 11 buffered chunks, got %d (dropped=%d)", len(recent), dropped)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// ErrInvalidParameter is returned when a parameter definition or a supplied parameter value is invalid.
+var ErrInvalidParameter = errors.New("invalid parameter")
+
+var parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateParameterDefinitions checks the parameters declared by a command.
+func validateParameterDefinitions(params []models.CommandParameter) error {
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if !parameterNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("%w: name %q must contain only letters, digits and underscores", ErrInvalidParameter, p.Name)
+		}
+		key := strings.ToUpper(p.Name)
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate parameter %q", ErrInvalidParameter, p.Name)
+		}
+		seen[key] = true
+
+		switch p.Type {
+		case models.ParameterString:
+			if p.Pattern != "" {
+				if _, err := regexp.Compile(p.Pattern); err != nil {
+					return fmt.Errorf("%w: %s has an invalid pattern: %v", ErrInvalidParameter, p.Name, err)
+				}
+			}
+		case models.ParameterEnum:
+			if len(p.Options) == 0 {
+				return fmt.Errorf("%w: enum %s needs at least one option", ErrInvalidParameter, p.Name)
+			}
+		case models.ParameterBoolean:
+		default:
+			return fmt.Errorf("%w: %s has unknown type %q", ErrInvalidParameter, p.Name, p.Type)
+		}
+
+		if p.Default != "" {
+			if _, err := validateParameterValue(p, p.Default); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateParameterValue checks a value against its parameter definition and returns the normalized value.
+func validateParameterValue(p models.CommandParameter, value string) (string, error) {
+	switch p.Type {
+	case models.ParameterBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s must be true or false", ErrInvalidParameter, p.Name)
+		}
+		return strconv.FormatBool(b), nil
+	case models.ParameterEnum:
+		for _, option := range p.Options {
+			if value == option {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s must be one of %s", ErrInvalidParameter, p.Name, strings.Join(p.Options, ", "))
+	default:
+		if p.Pattern != "" {
+			re, err := regexp.Compile(`^(?:` + p.Pattern + `)$`)
+			if err != nil || !re.MatchString(value) {
+				return "", fmt.Errorf("%w: %s does not match pattern %s", ErrInvalidParameter, p.Name, p.Pattern)
+			}
+		}
+		return value, nil
+	}
+}
+
+// ResolveParameters validates the values supplied for a command and fills in defaults.
+// Unknown parameters and missing required parameters are rejected.
+func ResolveParameters(params []models.CommandParameter, values map[string]string) (map[string]string, error) {
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		declared[p.Name] = true
+	}
+	for name := range values {
+		if !declared[name] {
+			return nil, fmt.Errorf("%w: unknown parameter %q", ErrInvalidParameter, name)
+		}
+	}
+
+	resolved := make(map[string]string, len(params))
+	for _, p := range params {
+		value, ok := values[p.Name]
+		if !ok {
+			if p.Required && p.Default == "" {
+				return nil, fmt.Errorf("%w: %s is required", ErrInvalidParameter, p.Name)
+			}
+			resolved[p.Name] = p.Default
+			continue
+		}
+
+		normalized, err := validateParameterValue(p, value)
+		if err != nil {
+			return nil, err
+		}
+		resolved[p.Name] = normalized
+	}
+	return resolved, nil
+}
+
+// ParameterValues converts parameter values decoded from JSON into strings.
+// Strings, booleans and numbers are accepted.
+func ParameterValues(raw map[string]interface{}) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	values := make(map[string]string, len(raw))
+	for name, v := range raw {
+		switch value := v.(type) {
+		case string:
+			values[name] = value
+		case bool:
+			values[name] = strconv.FormatBool(value)
+		case float64:
+			values[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("%w: %s must be a string, boolean or number", ErrInvalidParameter, name)
+		}
+	}
+	return values, nil
+}
+
+// parameterEnv returns the environment variables for the given parameter values.
+func parameterEnv(values map[string]string) []string {
+	env := make([]string, 0, len(values))
+	for name, value := range values {
+		env = append(env, "PARAM_"+strings.ToUpper(name)+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestResolveParameters(t *testing.T) {
+	params := []models.CommandParameter{
+		{Name: "branch", Type: models.ParameterString, Default: "main", Pattern: `[a-z0-9/_-]+`},
+		{Name: "env", Type: models.ParameterEnum, Options: []string{"staging", "production"}, Required: true},
+		{Name: "force", Type: models.ParameterBoolean},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "defaults applied",
+			values: map[string]string{"env": "staging"},
+			want:   map[string]string{"branch": "main", "env": "staging", "force": ""},
+		},
+		{
+			name:   "boolean normalized",
+			values: map[string]string{"env": "production", "branch": "feature/x", "force": "1"},
+			want:   map[string]string{"branch": "feature/x", "env": "production", "force": "true"},
+		},
+		{name: "missing required", values: map[string]string{}, wantErr: true},
+		{name: "unknown parameter", values: map[string]string{"env": "staging", "other": "x"}, wantErr: true},
+		{name: "invalid enum", values: map[string]string{"env": "dev"}, wantErr: true},
+		{name: "invalid boolean", values: map[string]string{"env": "staging", "force": "maybe"}, wantErr: true},
+		{name: "pattern must match whole value", values: map[string]string{"env": "staging", "branch": "main; rm -rf /"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := services.ResolveParameters(params, tt.values)
+			if tt.wantErr {
+				if !errors.Is(err, services.ErrInvalidParameter) {
+					t.Errorf("expected ErrInvalidParameter, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s: expected %q, got %q", name, want, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestParameterValues(t *testing.T) {
+	values, err := services.ParameterValues(map[string]interface{}{
+		"branch":   "main",
+		"force":    true,
+		"replicas": float64(3),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["branch"] != "main" || values["force"] != "true" || values["replicas"] != "3" {
+		t.Errorf("unexpected values: %v", values)
+	}
+
+	if _, err := services.ParameterValues(map[string]interface{}{"list": []interface{}{"a"}}); !errors.Is(err, services.ErrInvalidParameter) {
+		t.Errorf("expected ErrInvalidParameter for list value, got %v", err)
+	}
+}
+
+func TestAppService_CreateCommand_InvalidParameters(t *testing.T) {
+	db, sqlDB := setupAppTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Test App", WorkingDir: "/tmp/test"})
+
+	tests := []struct {
+		name  string
+		param models.CommandParameter
+	}{
+		{"invalid name", models.CommandParameter{Name: "my-param", Type: models.ParameterString}},
+		{"unknown type", models.CommandParameter{Name: "p", Type: "number"}},
+		{"enum without options", models.CommandParameter{Name: "p", Type: models.ParameterEnum}},
+		{"invalid pattern", models.CommandParameter{Name: "p", Type: models.ParameterString, Pattern: "("}},
+		{"default not in options", models.CommandParameter{Name: "p", Type: models.ParameterEnum, Options: []string{"a"}, Default: "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+				Name:       "deploy",
+				Command:    "echo deploy",
+				Parameters: []models.CommandParameter{tt.param},
+			})
+			if !errors.Is(err, services.ErrInvalidParameter) {
+				t.Errorf("expected ErrInvalidParameter, got %v", err)
+			}
+		})
+	}
+
+	cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:       "deploy",
+		Command:    "echo deploy",
+		Parameters: []models.CommandParameter{{Name: "branch", Type: models.ParameterString, Default: "main"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+	if len(cmd.Parameters) != 1 || cmd.Parameters[0].Default != "main" {
+		t.Errorf("expected stored parameter, got %+v", cmd.Parameters)
+	}
+}
@@ -0,0 +1,434 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrPolicyRuleNotFound indicates the requested policy rule was not found.
+	ErrPolicyRuleNotFound = errors.New("policy rule not found")
+	// ErrPolicyRuleExists indicates a policy rule with the same name already exists.
+	ErrPolicyRuleExists = errors.New("policy rule already exists")
+	// ErrInvalidPolicyRule indicates a policy rule has an invalid action, match type, pattern or role.
+	ErrInvalidPolicyRule = errors.New("invalid policy rule")
+	// ErrPolicyViolation is returned when the command policy rejects a command. The error names the rule.
+	ErrPolicyViolation = errors.New("command rejected by policy")
+)
+
+// SystemActor is recorded in the audit log as the actor of policy violations by executions
+// without a user, such as scheduled executions and deploys.
+const SystemActor = "system"
+
+// PolicyService stores the command policy rules and evaluates commands against them.
+type PolicyService struct {
+	db           *database.DB
+	auditService *AuditService
+}
+
+// NewPolicyService creates a new PolicyService instance. Violations are written to the audit log
+// of auditService, which may be nil.
+func NewPolicyService(db *database.DB, auditService *AuditService) *PolicyService {
+	return &PolicyService{db: db, auditService: auditService}
+}
+
+const policyRuleColumns = "id, name, description, action, match_type, pattern, roles, enabled, created_at, updated_at"
+
+func scanPolicyRule(row rowScanner) (*models.PolicyRule, error) {
+	var r models.PolicyRule
+	var roles string
+	if err := row.Scan(&r.ID, &r.Name, &r.Description, &r.Action, &r.MatchType, &r.Pattern, &roles, &r.Enabled, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(roles), &r.Roles); err != nil {
+		return nil, err
+	}
+	if r.Roles == nil {
+		r.Roles = []models.UserRole{}
+	}
+	return &r, nil
+}
+
+// ListRules returns all policy rules, ordered by name.
+func (s *PolicyService) ListRules() ([]models.PolicyRule, error) {
+	rows, err := s.db.Query("SELECT " + policyRuleColumns + " FROM command_policies ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	rules := []models.PolicyRule{}
+	for rows.Next() {
+		r, err := scanPolicyRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *r)
+	}
+	return rules, rows.Err()
+}
+
+// GetRule retrieves a policy rule.
+func (s *PolicyService) GetRule(id string) (*models.PolicyRule, error) {
+	r, err := scanPolicyRule(s.db.QueryRow("SELECT "+policyRuleColumns+" FROM command_policies WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPolicyRuleNotFound
+	}
+	return r, err
+}
+
+// CreateRule creates a policy rule.
+func (s *PolicyService) CreateRule(req *models.CreatePolicyRuleRequest) (*models.PolicyRule, error) {
+	rule := &models.PolicyRule{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Action:      req.Action,
+		MatchType:   req.MatchType,
+		Pattern:     req.Pattern,
+		Roles:       req.Roles,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	if err := validatePolicyRule(rule); err != nil {
+		return nil, err
+	}
+	roles, err := json.Marshal(rule.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	_, err = s.db.Exec(
+		"INSERT INTO command_policies (id, name, description, action, match_type, pattern, roles, enabled) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		id, rule.Name, rule.Description, rule.Action, rule.MatchType, rule.Pattern, string(roles), rule.Enabled,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrPolicyRuleExists
+		}
+		return nil, err
+	}
+	return s.GetRule(id)
+}
+
+// UpdateRule updates a policy rule.
+func (s *PolicyService) UpdateRule(id string, req *models.UpdatePolicyRuleRequest) (*models.PolicyRule, error) {
+	rule, err := s.GetRule(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		rule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		rule.Description = *req.Description
+	}
+	if req.Action != nil {
+		rule.Action = *req.Action
+	}
+	if req.MatchType != nil {
+		rule.MatchType = *req.MatchType
+	}
+	if req.Pattern != nil {
+		rule.Pattern = *req.Pattern
+	}
+	if req.Roles != nil {
+		rule.Roles = req.Roles
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if err := validatePolicyRule(rule); err != nil {
+		return nil, err
+	}
+	roles, err := json.Marshal(rule.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE command_policies SET name = ?, description = ?, action = ?, match_type = ?, pattern = ?, roles = ?, enabled = ?, updated_at = ? WHERE id = ?",
+		rule.Name, rule.Description, rule.Action, rule.MatchType, rule.Pattern, string(roles), rule.Enabled, time.Now(), id,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrPolicyRuleExists
+		}
+		return nil, err
+	}
+	return s.GetRule(id)
+}
+
+// DeleteRule deletes a policy rule.
+func (s *PolicyService) DeleteRule(id string) error {
+	result, err := s.db.Exec("DELETE FROM command_policies WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPolicyRuleNotFound
+	}
+	return nil
+}
+
+// validatePolicyRule checks the name, action, match type, pattern and roles of a rule.
+func validatePolicyRule(rule *models.PolicyRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPolicyRule)
+	}
+	if !rule.Action.IsValid() {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidPolicyRule, rule.Action)
+	}
+	if !rule.MatchType.IsValid() {
+		return fmt.Errorf("%w: unknown match type %q", ErrInvalidPolicyRule, rule.MatchType)
+	}
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return fmt.Errorf("%w: pattern is required", ErrInvalidPolicyRule)
+	}
+	if _, err := compilePolicyPattern(rule.MatchType, rule.Pattern); err != nil {
+		return fmt.Errorf("%w: invalid pattern: %v", ErrInvalidPolicyRule, err)
+	}
+	for _, role := range rule.Roles {
+		if role != models.RoleAdmin && role != models.RoleOperator && role != models.RoleViewer {
+			return fmt.Errorf("%w: unknown role %q", ErrInvalidPolicyRule, role)
+		}
+	}
+	if rule.Roles == nil {
+		rule.Roles = []models.UserRole{}
+	}
+	return nil
+}
+
+// compilePolicyPattern compiles the pattern of a rule. A glob pattern matches the whole command,
+// with runs of whitespace in the pattern matching runs of whitespace in the command.
+func compilePolicyPattern(matchType models.PolicyMatchType, pattern string) (*regexp.Regexp, error) {
+	if matchType == models.PolicyMatchRegex {
+		return regexp.Compile(pattern)
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range normalizeCommand(pattern) {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// normalizeCommand trims a command and collapses its runs of whitespace into single spaces.
+func normalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// commandSegments returns the simple commands that command chains with ;, &, | or newlines.
+// Quotes are not parsed, so a separator inside a quoted argument also splits the command.
+func commandSegments(command string) []string {
+	var segments []string
+	for _, segment := range strings.FieldsFunc(command, func(r rune) bool {
+		return r == ';' || r == '&' || r == '|' || r == '\n'
+	}) {
+		if segment = normalizeCommand(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// compiledPolicyRule is an enabled policy rule with its compiled pattern.
+type compiledPolicyRule struct {
+	*models.PolicyRule
+	pattern *regexp.Regexp
+}
+
+// matches reports whether the rule matches the whole command or one of its segments.
+func (r *compiledPolicyRule) matches(command string, segments []string) bool {
+	if r.pattern.MatchString(command) {
+		return true
+	}
+	for _, segment := range segments {
+		if r.pattern.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyDecision is the result of evaluating a command against the policy rules.
+type PolicyDecision struct {
+	// Rule names the rule that denied the command or requires approval of it
+	Rule string
+	// Reason describes why the command is rejected; it is empty if the command is allowed
+	Reason string
+	// RequiresApproval is set when an approval rule matches an allowed command
+	RequiresApproval bool
+}
+
+// rules returns the enabled rules that apply to role with their compiled patterns.
+func (s *PolicyService) rules(role models.UserRole) ([]compiledPolicyRule, error) {
+	all, err := s.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	var rules []compiledPolicyRule
+	for i := range all {
+		rule := &all[i]
+		if !rule.Enabled || !rule.AppliesTo(role) {
+			continue
+		}
+		pattern, err := compilePolicyPattern(rule.MatchType, rule.Pattern)
+		if err != nil {
+			log.Printf("[Policy] Skipping rule %q with an invalid pattern: %v", rule.Name, err)
+			continue
+		}
+		rules = append(rules, compiledPolicyRule{PolicyRule: rule, pattern: pattern})
+	}
+	return rules, nil
+}
+
+// Evaluate evaluates command against the enabled rules that apply to role, which is empty for
+// commands without a user. Deny rules reject the commands they match. If there are allow rules,
+// every simple command of a chain must match one of them. Approval rules require approval of the
+// commands they match.
+func (s *PolicyService) Evaluate(command string, role models.UserRole) (*PolicyDecision, error) {
+	rules, err := s.rules(role)
+	if err != nil {
+		return nil, err
+	}
+	normalized := normalizeCommand(command)
+	segments := commandSegments(command)
+
+	for i := range rules {
+		if rules[i].Action == models.PolicyDeny && rules[i].matches(normalized, segments) {
+			return &PolicyDecision{Rule: rules[i].Name, Reason: fmt.Sprintf("denied by rule %q", rules[i].Name)}, nil
+		}
+	}
+
+	var allow []compiledPolicyRule
+	for i := range rules {
+		if rules[i].Action == models.PolicyAllow {
+			allow = append(allow, rules[i])
+		}
+	}
+	if len(allow) > 0 {
+		for _, segment := range segments {
+			allowed := false
+			for i := range allow {
+				if allow[i].pattern.MatchString(segment) {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				reason := fmt.Sprintf("%q is not allowed by any allow rule", segment)
+				if role != "" {
+					reason = fmt.Sprintf("%q is not allowed by any allow rule of role %s", segment, role)
+				}
+				return &PolicyDecision{Reason: reason}, nil
+			}
+		}
+	}
+
+	for i := range rules {
+		if rules[i].Action == models.PolicyApproval && rules[i].matches(normalized, segments) {
+			return &PolicyDecision{Rule: rules[i].Name, RequiresApproval: true}, nil
+		}
+	}
+	return &PolicyDecision{}, nil
+}
+
+// PolicyCheck describes where a command is checked, for the audit log entry of a violation.
+// User is nil for executions without a user, which are recorded as SystemActor.
+// Approvable is set where approval rules hold the command for approval instead of rejecting it.
+// Redact keeps the command out of the audit log entry, the log and the error, for input that may hold
+// secrets such as passwords typed at a prompt.
+type PolicyCheck struct {
+	User         *models.User
+	Action       string
+	ResourceType string
+	ResourceID   string
+	IPAddress    string
+	UserAgent    string
+	Approvable   bool
+	Redact       bool
+}
+
+// redactedCommand replaces the command in the audit log entry of a redacted violation.
+const redactedCommand = "[redacted]"
+
+// Enforce evaluates command for check and reports whether it requires approval. A rejected command
+// is written to the audit log as a policy_violation and returned as an ErrPolicyViolation that
+// names the rule.
+func (s *PolicyService) Enforce(command string, check PolicyCheck) (bool, error) {
+	var role models.UserRole
+	if check.User != nil {
+		role = check.User.Role
+	}
+	decision, err := s.Evaluate(command, role)
+	if err != nil {
+		return false, err
+	}
+	if decision.RequiresApproval && !check.Approvable {
+		decision.Reason = fmt.Sprintf("rule %q requires approval", decision.Rule)
+	}
+	if decision.Reason == "" {
+		return decision.RequiresApproval, nil
+	}
+	if check.Redact {
+		// The reason of a command without allow rule quotes the command
+		command = redactedCommand
+		if decision.Rule == "" {
+			decision.Reason = "the command is not allowed by any allow rule"
+			if role != "" {
+				decision.Reason = fmt.Sprintf("the command is not allowed by any allow rule of role %s", role)
+			}
+		}
+	}
+
+	actor := SystemActor
+	var userID *int64
+	if check.User != nil {
+		actor = check.User.AuditName()
+		userID = &check.User.ID
+	}
+	if s.auditService != nil {
+		_ = s.auditService.Log(AuditLog{
+			UserID:       userID,
+			Username:     actor,
+			Action:       "policy_violation",
+			ResourceType: check.ResourceType,
+			ResourceID:   check.ResourceID,
+			IPAddress:    check.IPAddress,
+			UserAgent:    check.UserAgent,
+			Details: map[string]interface{}{
+				"rule":    decision.Rule,
+				"reason":  decision.Reason,
+				"command": command,
+				"action":  check.Action,
+			},
+		})
+	}
+	log.Printf("[Policy] Rejected %s by %s: %s", check.Action, actor, decision.Reason)
+	return false, fmt.Errorf("%w: %s", ErrPolicyViolation, decision.Reason)
+}
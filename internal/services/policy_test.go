@@ -0,0 +1,198 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestPolicyService_Rules(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	policy := services.NewPolicyService(db, nil)
+
+	invalid := []models.CreatePolicyRuleRequest{
+		{Name: "", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "mkfs*"},
+		{Name: "bad-action", Action: "block", MatchType: models.PolicyMatchGlob, Pattern: "mkfs*"},
+		{Name: "bad-match", Action: models.PolicyDeny, MatchType: "exact", Pattern: "mkfs"},
+		{Name: "bad-regex", Action: models.PolicyDeny, MatchType: models.PolicyMatchRegex, Pattern: "rm (-rf"},
+		{Name: "bad-role", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "mkfs*", Roles: []models.UserRole{"root"}},
+	}
+	for _, req := range invalid {
+		if _, err := policy.CreateRule(&req); !errors.Is(err, services.ErrInvalidPolicyRule) {
+			t.Errorf("expected rule %+v to be invalid, got %v", req, err)
+		}
+	}
+
+	rule, err := policy.CreateRule(&models.CreatePolicyRuleRequest{Name: "no-mkfs", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "mkfs*"})
+	if err != nil {
+		t.Fatalf("failed to create rule: %v", err)
+	}
+	if !rule.Enabled || len(rule.Roles) != 0 {
+		t.Errorf("expected an enabled rule for all roles, got %+v", rule)
+	}
+	if _, err := policy.CreateRule(&models.CreatePolicyRuleRequest{Name: "no-mkfs", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "dd *"}); err != services.ErrPolicyRuleExists {
+		t.Errorf("expected ErrPolicyRuleExists, got %v", err)
+	}
+
+	disabled := false
+	updated, err := policy.UpdateRule(rule.ID, &models.UpdatePolicyRuleRequest{Enabled: &disabled, Roles: []models.UserRole{models.RoleOperator}})
+	if err != nil {
+		t.Fatalf("failed to update rule: %v", err)
+	}
+	if updated.Enabled || updated.Pattern != "mkfs*" || len(updated.Roles) != 1 {
+		t.Errorf("unexpected updated rule %+v", updated)
+	}
+
+	if err := policy.DeleteRule(rule.ID); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	if err := policy.DeleteRule(rule.ID); err != services.ErrPolicyRuleNotFound {
+		t.Errorf("expected ErrPolicyRuleNotFound, got %v", err)
+	}
+}
+
+func TestPolicyService_Evaluate(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	policy := services.NewPolicyService(db, services.NewAuditService(db))
+	disabled := false
+	for _, req := range []models.CreatePolicyRuleRequest{
+		{Name: "no-rm-root", Action: models.PolicyDeny, MatchType: models.PolicyMatchRegex, Pattern: `rm\s+-\w*r\w*\s+/(\s|$)`},
+		{Name: "no-mkfs", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "mkfs*"},
+		{Name: "no-reboot", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "reboot", Enabled: &disabled},
+		{Name: "viewer-git", Action: models.PolicyAllow, MatchType: models.PolicyMatchGlob, Pattern: "git *", Roles: []models.UserRole{models.RoleViewer}},
+		{Name: "viewer-ls", Action: models.PolicyAllow, MatchType: models.PolicyMatchRegex, Pattern: `^ls( |$)`, Roles: []models.UserRole{models.RoleViewer}},
+		{Name: "restart-services", Action: models.PolicyApproval, MatchType: models.PolicyMatchGlob, Pattern: "systemctl restart *"},
+	} {
+		if _, err := policy.CreateRule(&req); err != nil {
+			t.Fatalf("failed to create rule %s: %v", req.Name, err)
+		}
+	}
+
+	tests := []struct {
+		command  string
+		role     models.UserRole
+		rule     string
+		allowed  bool
+		approval bool
+	}{
+		{command: "echo hello", role: models.RoleOperator, allowed: true},
+		{command: "rm  -rf   /", role: models.RoleAdmin, rule: "no-rm-root"},
+		{command: "rm -rf /tmp/build", role: models.RoleOperator, allowed: true},
+		{command: "cd /srv && mkfs.ext4 /dev/sdb", role: models.RoleOperator, rule: "no-mkfs"},
+		{command: "echo mkfs", role: models.RoleOperator, allowed: true},
+		{command: "reboot", role: models.RoleOperator, allowed: true},
+		{command: "git pull; ls -la", role: models.RoleViewer, allowed: true},
+		{command: "git pull && make", role: models.RoleViewer},
+		{command: "make", role: "", allowed: true},
+		{command: "systemctl restart nginx", role: models.RoleOperator, rule: "restart-services", allowed: true, approval: true},
+	}
+	for _, tt := range tests {
+		decision, err := policy.Evaluate(tt.command, tt.role)
+		if err != nil {
+			t.Fatalf("failed to evaluate %q: %v", tt.command, err)
+		}
+		if (decision.Reason == "") != tt.allowed || decision.Rule != tt.rule || decision.RequiresApproval != tt.approval {
+			t.Errorf("unexpected decision for %q as %q: %+v", tt.command, tt.role, decision)
+		}
+	}
+
+	// Violations name the rule and are written to the audit log
+	operator := &models.User{ID: 7, Username: "olivia", Role: models.RoleOperator}
+	_, err := policy.Enforce("mkfs /dev/sda", services.PolicyCheck{User: operator, Action: "container_exec", ResourceType: "container", ResourceID: "web"})
+	if !errors.Is(err, services.ErrPolicyViolation) || !strings.Contains(err.Error(), `"no-mkfs"`) {
+		t.Errorf("expected a violation of no-mkfs, got %v", err)
+	}
+	var username, details string
+	if err := sqlDB.QueryRow("SELECT username, details FROM audit_logs WHERE action = 'policy_violation' AND resource_id = 'web'").Scan(&username, &details); err != nil {
+		t.Fatalf("expected an audit log entry: %v", err)
+	}
+	if username != "olivia" || !strings.Contains(details, `"rule":"no-mkfs"`) || !strings.Contains(details, `"action":"container_exec"`) {
+		t.Errorf("unexpected audit log entry by %s: %s", username, details)
+	}
+
+	// Approval rules only reject commands where they cannot be held for approval
+	if approval, err := policy.Enforce("systemctl restart nginx", services.PolicyCheck{User: operator, Approvable: true}); err != nil || !approval {
+		t.Errorf("expected the command to require approval, got %v (err=%v)", approval, err)
+	}
+	if _, err := policy.Enforce("systemctl restart nginx", services.PolicyCheck{User: operator}); !errors.Is(err, services.ErrPolicyViolation) {
+		t.Errorf("expected a violation where approval is not available, got %v", err)
+	}
+
+	// Redacted commands appear neither in the error nor in the audit log
+	viewer := &models.User{ID: 8, Username: "vera", Role: models.RoleViewer}
+	_, err = policy.Enforce("hunter2", services.PolicyCheck{User: viewer, Action: "terminal_input", ResourceID: "term-1", Redact: true})
+	if !errors.Is(err, services.ErrPolicyViolation) || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected a violation without the command, got %v", err)
+	}
+	if err := sqlDB.QueryRow("SELECT details FROM audit_logs WHERE action = 'policy_violation' AND resource_id = 'term-1'").Scan(&details); err != nil {
+		t.Fatalf("expected an audit log entry: %v", err)
+	}
+	if strings.Contains(details, "hunter2") || !strings.Contains(details, "viewer") {
+		t.Errorf("expected the command to be redacted, got %s", details)
+	}
+}
+
+func TestExecutorService_Policy(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+	policy := services.NewPolicyService(db, services.NewAuditService(db))
+	execSvc.SetPolicyService(policy)
+
+	if _, err := sqlDB.Exec("INSERT INTO users (id, username, password_hash, role) VALUES (1, 'vera', 'x', 'viewer')"); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Policed", WorkingDir: t.TempDir()})
+	restart, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "restart", Command: "systemctl restart web"})
+	wipe, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "wipe", Command: "mkfs /dev/sdb"})
+	pipeline, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:  "release",
+		Steps: []models.PipelineStepRequest{{CommandID: restart.ID}, {CommandID: wipe.ID}},
+	})
+
+	for _, req := range []models.CreatePolicyRuleRequest{
+		{Name: "no-mkfs", Action: models.PolicyDeny, MatchType: models.PolicyMatchGlob, Pattern: "mkfs *"},
+		{Name: "restarts", Action: models.PolicyApproval, MatchType: models.PolicyMatchGlob, Pattern: "systemctl restart *"},
+		{Name: "viewer-status", Action: models.PolicyAllow, MatchType: models.PolicyMatchGlob, Pattern: "systemctl status *", Roles: []models.UserRole{models.RoleViewer}},
+	} {
+		if _, err := policy.CreateRule(&req); err != nil {
+			t.Fatalf("failed to create rule %s: %v", req.Name, err)
+		}
+	}
+
+	execution, err := execSvc.CreateExecution(restart.ID, services.SystemUserID)
+	if err != nil {
+		t.Fatalf("failed to create execution: %v", err)
+	}
+	if execution.Status != models.StatusAwaitingApproval {
+		t.Errorf("expected the approval rule to hold the execution, got %s", execution.Status)
+	}
+
+	// A pipeline is rejected if one of its steps is
+	for _, id := range []string{wipe.ID, pipeline.ID} {
+		if _, err := execSvc.CreateExecution(id, services.SystemUserID); !errors.Is(err, services.ErrPolicyViolation) {
+			t.Errorf("expected a policy violation, got %v", err)
+		}
+	}
+
+	// The allow-list of the viewer role applies to executions started by a viewer
+	if _, err := execSvc.CreateExecution(restart.ID, 1); !errors.Is(err, services.ErrPolicyViolation) || !strings.Contains(err.Error(), "role viewer") {
+		t.Errorf("expected the allow-list of viewers to reject the command, got %v", err)
+	}
+
+	var violations int
+	_ = sqlDB.QueryRow("SELECT COUNT(*) FROM audit_logs WHERE action = 'policy_violation'").Scan(&violations)
+	if violations != 3 {
+		t.Errorf("expected 3 violations in the audit log, got %d", violations)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// configureProcessGroup runs cmd in its own process group so that stopping it also stops
+// everything the shell spawned. When the command's context is done the whole group receives
+// SIGTERM, followed by SIGKILL if it is still alive after the grace period.
+func configureProcessGroup(cmd *exec.Cmd, grace time.Duration) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+			return err
+		}
+		time.AfterFunc(grace, func() {
+			_ = syscall.Kill(-pgid, syscall.SIGKILL)
+		})
+		return nil
+	}
+	// Stop waiting for output shortly after the SIGKILL in case a process escaped the group
+	cmd.WaitDelay = grace + 5*time.Second
+}
+
+// runAsIdentity is the user and group an app's processes run as.
+type runAsIdentity struct {
+	credential *syscall.Credential
+	env        []string // HOME, USER and LOGNAME of the user, if a user is set
+}
+
+// validateRunAs checks that the run_as user and group exist on this host.
+func validateRunAs(username, group string) error {
+	_, err := resolveRunAs(username, group)
+	return err
+}
+
+// resolveRunAs looks up the credential for a run_as user and group, given by name or numeric ID.
+// Without a group the user's primary group is used; without a user only the group changes.
+// It returns nil if neither is set.
+func resolveRunAs(username, group string) (*runAsIdentity, error) {
+	if username == "" && group == "" {
+		return nil, nil
+	}
+
+	// #nosec G115 - IDs returned by the OS fit in uint32
+	id := &runAsIdentity{credential: &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}}
+
+	if username != "" {
+		u, err := user.Lookup(username)
+		if err != nil {
+			u, err = user.LookupId(username)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown user %q", ErrInvalidRunAs, username)
+		}
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: user %q has a non-numeric uid", ErrInvalidRunAs, username)
+		}
+		gid, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: user %q has a non-numeric gid", ErrInvalidRunAs, username)
+		}
+		id.credential.Uid = uint32(uid)
+		id.credential.Gid = uint32(gid)
+
+		// Supplementary groups of the user; without them setgroups clears the inherited ones
+		if groupIDs, err := u.GroupIds(); err == nil {
+			for _, g := range groupIDs {
+				if n, err := strconv.ParseUint(g, 10, 32); err == nil {
+					id.credential.Groups = append(id.credential.Groups, uint32(n))
+				}
+			}
+		}
+		id.env = []string{"HOME=" + u.HomeDir, "USER=" + u.Username, "LOGNAME=" + u.Username}
+	}
+
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			g, err = user.LookupGroupId(group)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown group %q", ErrInvalidRunAs, group)
+		}
+		gid, err := strconv.ParseUint(g.Gid, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: group %q has a non-numeric gid", ErrInvalidRunAs, group)
+		}
+		id.credential.Gid = uint32(gid)
+	}
+
+	return id, nil
+}
+
+// applyRunAs makes cmd run with the given identity. cmd.SysProcAttr must already be set.
+func applyRunAs(cmd *exec.Cmd, id *runAsIdentity) {
+	if id == nil {
+		return
+	}
+	cmd.SysProcAttr.Credential = id.credential
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// interruptedMessage is recorded at the end of the output of executions interrupted by a restart.
+const interruptedMessage = "[INTERRUPTED - the server stopped before the execution finished]"
+
+// interruptedExecution is an execution left pending or running by a previous server process.
+type interruptedExecution struct {
+	id        string
+	commandID string
+	status    models.ExecutionStatus
+	userID    int64
+	params    map[string]string
+	gitRef    string
+	commitSHA string
+}
+
+// RecoverInterrupted marks executions that a previous server process left pending or running as
+// interrupted, keeping the output stored so far, and re-runs those whose command has
+// rerun_on_interrupt set. It must be called on startup before any execution is started.
+// It returns the IDs of the new executions.
+func (s *ExecutorService) RecoverInterrupted() ([]string, error) {
+	rows, err := s.db.Query(
+		"SELECT id, command_id, status, user_id, parameters, COALESCE(git_ref, ''), COALESCE(commit_sha, '') FROM executions WHERE status IN (?, ?) ORDER BY created_at",
+		models.StatusPending, models.StatusRunning,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var interrupted []interruptedExecution
+	for rows.Next() {
+		var e interruptedExecution
+		var parameters sql.NullString
+		if err := rows.Scan(&e.id, &e.commandID, &e.status, &e.userID, &parameters, &e.gitRef, &e.commitSHA); err != nil {
+			return nil, err
+		}
+		if parameters.String != "" {
+			if err := json.Unmarshal([]byte(parameters.String), &e.params); err != nil {
+				return nil, err
+			}
+		}
+		interrupted = append(interrupted, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	var reruns []string
+	for _, e := range interrupted {
+		if err := s.markInterrupted(e.id); err != nil {
+			return reruns, err
+		}
+		log.Printf("[Executor] Marked %s execution %s as interrupted", e.status, e.id)
+		if s.notifier != nil {
+			s.notifier.Notify(e.id)
+		}
+
+		command, err := s.appService.GetCommandByID(e.commandID)
+		if err != nil || !command.RerunOnInterrupt {
+			continue
+		}
+		rerun, err := s.CreateExecutionWithOptions(e.commandID, e.userID, ExecutionOptions{
+			Parameters: e.params,
+			Ref:        e.gitRef,
+			CommitSHA:  e.commitSHA,
+			// The interrupted execution was already approved, or did not need approval
+			SkipApproval: true,
+		})
+		if err != nil {
+			log.Printf("[Executor] Failed to re-run interrupted execution %s: %v", e.id, err)
+			continue
+		}
+		log.Printf("[Executor] Re-running interrupted execution %s as %s", e.id, rerun.ID)
+		reruns = append(reruns, rerun.ID)
+		go func(id string) { _ = s.Execute(id) }(rerun.ID)
+	}
+	return reruns, nil
+}
+
+// markInterrupted finishes an orphaned execution as interrupted. The output text is rebuilt from the
+// stored output lines, which were written while the execution was running.
+func (s *ExecutorService) markInterrupted(id string) error {
+	var lastSeq int64
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM execution_output WHERE execution_id = ?", id).Scan(&lastSeq); err != nil {
+		return err
+	}
+
+	output := newOutputBuffer(s.cfg.Execution.MaxOutputSize, nil)
+	for after := int64(0); ; {
+		chunks, err := s.storedOutput(id, after, 0, replayBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, c := range chunks {
+			if c.Line != outputTruncatedMessage {
+				output.appendLine(c.Line)
+			}
+			after = c.Seq
+		}
+		if len(chunks) < replayBatchSize {
+			break
+		}
+	}
+	output.appendLine(interruptedMessage)
+	text, _ := output.result()
+
+	now := time.Now()
+	if err := insertOutputChunks(s.db, id, []models.OutputChunk{{
+		Seq:       lastSeq + 1,
+		Stream:    models.StreamSystem,
+		Line:      interruptedMessage,
+		Timestamp: now,
+	}}); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(
+		"UPDATE execution_steps SET status = ?, finished_at = ? WHERE execution_id = ? AND status = ?",
+		models.StatusInterrupted, now, id, models.StatusRunning,
+	); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(
+		"UPDATE execution_steps SET status = ? WHERE execution_id = ? AND status = ?",
+		models.StatusSkipped, id, models.StatusPending,
+	); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(
+		"UPDATE executions SET status = ?, output = ?, finished_at = ? WHERE id = ?",
+		models.StatusInterrupted, text, now, id,
+	); err != nil {
+		return err
+	}
+	s.indexOutput(id, text)
+	return nil
+}
+
+// Drain waits until no execution is pending or running in this process, or until ctx is done.
+// It returns the number of executions still active.
+func (s *ExecutorService) Drain(ctx context.Context) int {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		s.runningMu.Lock()
+		active := len(s.running)
+		s.runningMu.Unlock()
+
+		if active == 0 || ctx.Err() != nil {
+			return active
+		}
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
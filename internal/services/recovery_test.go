@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestExecutorService_RecoverInterrupted(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Restarted", WorkingDir: t.TempDir()})
+	deploy, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:             "deploy",
+		Command:          `echo "deploying $PARAM_TARGET"`,
+		Parameters:       []models.CommandParameter{{Name: "TARGET", Type: models.ParameterString}},
+		RerunOnInterrupt: true,
+	})
+	report, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "report", Command: "echo report"})
+
+	// Left behind by a previous process: a running deploy with partial output and a pending report
+	if _, err := sqlDB.Exec(`
+		INSERT INTO executions (id, command_id, user_id, status, parameters, started_at) VALUES ('running', ?, 7, 'running', '{"TARGET":"prod"}', CURRENT_TIMESTAMP);
+		INSERT INTO executions (id, command_id, user_id, status) VALUES ('pending', ?, 7, 'pending');
+		INSERT INTO execution_output (execution_id, seq, stream, line, created_at) VALUES
+			('running', 1, 'stdout', 'step one', CURRENT_TIMESTAMP),
+			('running', 2, 'stderr', 'step two', CURRENT_TIMESTAMP);
+	`, deploy.ID, report.ID); err != nil {
+		t.Fatalf("failed to insert executions: %v", err)
+	}
+
+	reruns, err := execSvc.RecoverInterrupted()
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+
+	interrupted, _ := execSvc.GetExecutionByID("running")
+	if interrupted.Status != models.StatusInterrupted || interrupted.FinishedAt == nil {
+		t.Errorf("expected the running execution to be interrupted, got %s", interrupted.Status)
+	}
+	if !strings.HasPrefix(interrupted.Output, "step one\nstep two\n") || !strings.Contains(interrupted.Output, "INTERRUPTED") {
+		t.Errorf("expected the partial output to be kept, got %q", interrupted.Output)
+	}
+	chunks, _, _ := execSvc.GetOutput("running", 2, 10)
+	if len(chunks) != 1 || chunks[0].Seq != 3 || chunks[0].Stream != models.StreamSystem {
+		t.Errorf("expected an interruption line after the stored output, got %+v", chunks)
+	}
+
+	if pending, _ := execSvc.GetExecutionByID("pending"); pending.Status != models.StatusInterrupted {
+		t.Errorf("expected the pending execution to be interrupted, got %s", pending.Status)
+	}
+
+	// Only the command with rerun_on_interrupt runs again, with the same user and parameters
+	if len(reruns) != 1 {
+		t.Fatalf("expected 1 re-run, got %v", reruns)
+	}
+	rerun := waitForStatus(t, execSvc, reruns[0], models.ExecutionStatus.IsFinished)
+	if rerun.Status != models.StatusSuccess || rerun.CommandID != deploy.ID || rerun.UserID != 7 {
+		t.Errorf("unexpected re-run: %+v", rerun)
+	}
+	if !strings.Contains(rerun.Output, "deploying prod") {
+		t.Errorf("expected the re-run to receive the parameters, got %q", rerun.Output)
+	}
+
+	// Nothing is left to recover on the next start
+	if reruns, err := execSvc.RecoverInterrupted(); err != nil || len(reruns) != 0 {
+		t.Errorf("expected nothing to recover, got %v (err=%v)", reruns, err)
+	}
+}
+
+func TestExecutorService_Drain(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Draining", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "slow", Command: "sleep 0.5"})
+
+	execution, _ := execSvc.CreateExecution(cmd.ID, 1)
+	go func() { _ = execSvc.Execute(execution.ID) }()
+	waitForStatus(t, execSvc, execution.ID, func(s models.ExecutionStatus) bool { return s == models.StatusRunning })
+
+	short, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if active := execSvc.Drain(short); active != 1 {
+		t.Errorf("expected 1 active execution after the timeout, got %d", active)
+	}
+
+	long, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if active := execSvc.Drain(long); active != 0 {
+		t.Errorf("expected all executions to finish, got %d active", active)
+	}
+	if finished, _ := execSvc.GetExecutionByID(execution.ID); finished.Status != models.StatusSuccess {
+		t.Errorf("expected the execution to finish, got %s", finished.Status)
+	}
+}
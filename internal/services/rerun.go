@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// ErrNoRollbackTarget is returned when an app has no earlier successful execution to roll back to.
+var ErrNoRollbackTarget = errors.New("no earlier successful execution to roll back to")
+
+// Rerun creates a new execution of the same command with the same parameters, ref and commit as
+// executionID, linked back to it. Like any new execution, it is subject to the app's concurrency
+// policy and approval. The caller runs it with Execute.
+func (s *ExecutorService) Rerun(executionID string, userID int64) (*models.Execution, error) {
+	original, err := s.GetExecutionByID(executionID)
+	if err != nil {
+		return nil, err
+	}
+	// Repeats keep the ref and commit of the original, even where they were only recorded
+	return s.CreateExecutionWithOptions(original.CommandID, userID, ExecutionOptions{
+		Parameters: original.Parameters,
+		Ref:        original.GitRef,
+		CommitSHA:  original.CommitSHA,
+		RerunOf:    original.ID,
+		RecordRef:  true,
+	})
+}
+
+// RollbackTarget returns the execution that a rollback of an app re-runs: the last successful
+// execution of the same command before the app's latest successful execution. For executions with
+// a recorded commit, earlier executions of the same commit are skipped.
+func (s *ExecutorService) RollbackTarget(appID string) (*models.Execution, error) {
+	rows, err := s.db.Query(`
+		SELECT `+executionColumns+`
+		FROM executions e
+		JOIN commands c ON e.command_id = c.id
+		WHERE c.app_id = ? AND e.status = ?
+		ORDER BY e.created_at DESC, e.rowid DESC
+	`, appID, models.StatusSuccess)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var current *models.Execution
+	for rows.Next() {
+		execution, err := s.scanExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			current = execution
+			continue
+		}
+		if execution.CommandID != current.CommandID {
+			continue
+		}
+		if current.CommitSHA != "" && execution.CommitSHA == current.CommitSHA {
+			continue
+		}
+		return execution, nil
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrNoRollbackTarget
+}
+
+// Rollback re-runs the rollback target of an app with Rerun.
+func (s *ExecutorService) Rollback(appID string, userID int64) (*models.Execution, error) {
+	target, err := s.RollbackTarget(appID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Rerun(target.ID, userID)
+}
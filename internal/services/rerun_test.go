@@ -0,0 +1,96 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestExecutorService_Rerun(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	cfg.Execution.MaxOutputSize = 1024 * 1024
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Rerun", WorkingDir: t.TempDir()})
+	cmd, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:       "greet",
+		Command:    `echo "hello $PARAM_NAME"; exit 1`,
+		Parameters: []models.CommandParameter{{Name: "NAME", Type: models.ParameterString}},
+	})
+
+	original, _ := execSvc.CreateExecutionWithOptions(cmd.ID, 1, services.ExecutionOptions{Parameters: map[string]string{"NAME": "world"}})
+	_ = execSvc.Execute(original.ID)
+
+	rerun, err := execSvc.Rerun(original.ID, 2)
+	if err != nil {
+		t.Fatalf("failed to re-run: %v", err)
+	}
+	if rerun.ID == original.ID || rerun.RerunOf != original.ID || rerun.UserID != 2 || rerun.Parameters["NAME"] != "world" {
+		t.Errorf("unexpected re-run: %+v", rerun)
+	}
+
+	_ = execSvc.Execute(rerun.ID)
+	finished, _ := execSvc.GetExecutionByID(rerun.ID)
+	if finished.Status != models.StatusFailed || !strings.Contains(finished.Output, "hello world") {
+		t.Errorf("expected the re-run to run with the same parameters, got %s: %q", finished.Status, finished.Output)
+	}
+
+	if _, err := execSvc.Rerun("missing", 1); err != services.ErrExecutionNotFound {
+		t.Errorf("expected ErrExecutionNotFound, got %v", err)
+	}
+}
+
+func TestExecutorService_Rollback(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+
+	app, _ := appSvc.CreateApp(&models.CreateAppRequest{Name: "Rollback", WorkingDir: t.TempDir()})
+	deploy, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "deploy", Command: "true"})
+	restart, _ := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "restart", Command: "true"})
+
+	if _, err := execSvc.Rollback(app.ID, 1); err != services.ErrNoRollbackTarget {
+		t.Errorf("expected ErrNoRollbackTarget without executions, got %v", err)
+	}
+
+	// Oldest first: the target is the last successful deploy of another commit before the latest one
+	if _, err := sqlDB.Exec(`
+		INSERT INTO executions (id, command_id, user_id, status, git_ref, commit_sha, created_at) VALUES
+			('v1', ?, 1, 'success', 'main', 'aaaaaaa', '2026-01-01 10:00:00'),
+			('v2', ?, 1, 'success', 'main', 'bbbbbbb', '2026-01-01 11:00:00'),
+			('v3-failed', ?, 1, 'failed', 'main', 'ccccccc', '2026-01-01 12:00:00'),
+			('restart', ?, 1, 'success', NULL, NULL, '2026-01-01 12:30:00'),
+			('v3', ?, 1, 'success', 'main', 'ddddddd', '2026-01-01 13:00:00'),
+			('v3-again', ?, 1, 'success', 'main', 'ddddddd', '2026-01-01 14:00:00')
+	`, deploy.ID, deploy.ID, deploy.ID, restart.ID, deploy.ID, deploy.ID); err != nil {
+		t.Fatalf("failed to insert executions: %v", err)
+	}
+
+	target, err := execSvc.RollbackTarget(app.ID)
+	if err != nil {
+		t.Fatalf("failed to find the rollback target: %v", err)
+	}
+	if target.ID != "v2" {
+		t.Errorf("expected to roll back to v2, got %s", target.ID)
+	}
+
+	// Without a git source there is nothing to check out, the previous successful run of the command is repeated
+	if _, err := sqlDB.Exec("UPDATE executions SET git_ref = NULL, commit_sha = NULL"); err != nil {
+		t.Fatalf("failed to clear commits: %v", err)
+	}
+	rollback, err := execSvc.Rollback(app.ID, 2)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	if rollback.RerunOf != "v3" || rollback.CommandID != deploy.ID || rollback.Status != models.StatusPending {
+		t.Errorf("unexpected rollback: %+v", rollback)
+	}
+	_ = execSvc.Execute(rollback.ID)
+}
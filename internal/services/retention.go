@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// pruneBatchSize is the number of executions deleted per transaction by PruneExecutions.
+const pruneBatchSize = 500
+
+// finishedStatuses lists the statuses of executions that may be pruned.
+var finishedStatuses = []interface{}{
+	models.StatusSuccess, models.StatusFailed, models.StatusCancelled, models.StatusInterrupted, models.StatusRejected,
+}
+
+// PruneExecutions deletes finished executions created more than days days before now, and finished executions
+// beyond the perCommand most recent ones of each command, with their steps, output and search index entries.
+// A limit of 0 disables it. Pending, running and awaiting executions are never deleted.
+// It returns the number of executions deleted.
+func (s *ExecutorService) PruneExecutions(days, perCommand int, now time.Time) (int, error) {
+	finished := "status IN (?" + strings.Repeat(", ?", len(finishedStatuses)-1) + ")"
+
+	var conditions []string
+	var args []interface{}
+	if days > 0 {
+		conditions = append(conditions, "(created_at < ? AND "+finished+")")
+		args = append(args, sqliteTime(now.AddDate(0, 0, -days)))
+		args = append(args, finishedStatuses...)
+	}
+	if perCommand > 0 {
+		conditions = append(conditions, `id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY command_id ORDER BY created_at DESC, rowid DESC) AS n
+				FROM executions WHERE `+finished+`
+			) WHERE n > ?
+		)`)
+		args = append(args, finishedStatuses...)
+		args = append(args, perCommand)
+	}
+	if len(conditions) == 0 {
+		return 0, nil
+	}
+
+	rows, err := s.db.Query("SELECT id FROM executions WHERE "+strings.Join(conditions, " OR "), args...)
+	if err != nil {
+		return 0, err
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for start := 0; start < len(ids); start += pruneBatchSize {
+		batch := ids[start:min(start+pruneBatchSize, len(ids))]
+		if err := s.deleteExecutions(batch); err != nil {
+			return deleted, err
+		}
+		deleted += len(batch)
+	}
+	return deleted, nil
+}
+
+// deleteExecutions deletes executions and everything recorded for them in one transaction.
+func (s *ExecutorService) deleteExecutions(ids []interface{}) error {
+	placeholders := "(?" + strings.Repeat(", ?", len(ids)-1) + ")"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	statements := []string{
+		"DELETE FROM execution_output WHERE execution_id IN " + placeholders,
+		"DELETE FROM execution_steps WHERE execution_id IN " + placeholders,
+		"DELETE FROM executions WHERE id IN " + placeholders,
+	}
+	if s.search {
+		statements = append(statements, "DELETE FROM executions_fts WHERE execution_id IN "+placeholders)
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, ids...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ExecutionRetention periodically prunes execution history according to the execution retention settings.
+type ExecutionRetention struct {
+	executor *ExecutorService
+	config   *config.ExecutionConfig
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewExecutionRetention creates a new ExecutionRetention instance.
+func NewExecutionRetention(executor *ExecutorService, cfg *config.ExecutionConfig) *ExecutionRetention {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ExecutionRetention{
+		executor: executor,
+		config:   cfg,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start begins pruning in the background, unless no retention limit is configured.
+func (r *ExecutionRetention) Start() {
+	if r.config.RetentionDays <= 0 && r.config.RetentionPerCommand <= 0 {
+		log.Println("[ExecutionRetention] Execution retention is disabled")
+		return
+	}
+
+	log.Printf("[ExecutionRetention] Keeping executions for %d days and %d per command (0 = unlimited)",
+		r.config.RetentionDays, r.config.RetentionPerCommand)
+
+	r.wg.Add(1)
+	go r.cleanupLoop()
+}
+
+// Stop stops pruning.
+func (r *ExecutionRetention) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// cleanupLoop prunes on start and then daily.
+func (r *ExecutionRetention) cleanupLoop() {
+	defer r.wg.Done()
+
+	r.cleanup()
+
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.cleanup()
+		}
+	}
+}
+
+// cleanup prunes executions beyond the retention limits.
+func (r *ExecutionRetention) cleanup() {
+	deleted, err := r.executor.PruneExecutions(r.config.RetentionDays, r.config.RetentionPerCommand, time.Now())
+	if err != nil {
+		log.Printf("[ExecutionRetention] Error pruning executions: %v", err)
+	}
+	if deleted > 0 {
+		log.Printf("[ExecutionRetention] Pruned %d old executions", deleted)
+	}
+}
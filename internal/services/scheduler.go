@@ -0,0 +1,473 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+var (
+	// ErrScheduleNotFound indicates the requested schedule was not found.
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrInvalidSchedule indicates a schedule has an invalid cron expression, timezone or parameter values.
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// SchedulerActor is recorded as the actor of scheduled executions in the audit log and when cancelling them.
+const SchedulerActor = "scheduler"
+
+// schedulerMaxSleep bounds how long the scheduler sleeps, so that clock changes are picked up.
+const schedulerMaxSleep = time.Minute
+
+// SchedulerService stores command schedules and runs them in the background.
+// Scheduled executions are created as the system user like token deployments.
+// The scheduler loop also rejects executions whose approval expired.
+type SchedulerService struct {
+	db         *database.DB
+	appService *AppService
+	executor   *ExecutorService
+	audit      *AuditService
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wake       chan struct{}
+	wg         sync.WaitGroup
+	runMu      sync.Mutex
+}
+
+// NewSchedulerService creates a new SchedulerService instance. auditService may be nil.
+func NewSchedulerService(db *database.DB, appService *AppService, executor *ExecutorService, auditService *AuditService) *SchedulerService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SchedulerService{
+		db:         db,
+		appService: appService,
+		executor:   executor,
+		audit:      auditService,
+		ctx:        ctx,
+		cancel:     cancel,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Start begins running schedules in the background.
+// Runs missed while the server was down are skipped; each schedule continues at its next run from now.
+func (s *SchedulerService) Start() {
+	if err := s.rescheduleMissed(time.Now()); err != nil {
+		log.Printf("[Scheduler] Failed to reschedule missed runs: %v", err)
+	}
+
+	log.Println("[Scheduler] Starting scheduler")
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop stops the scheduler. Executions already started keep running.
+func (s *SchedulerService) Stop() {
+	log.Println("[Scheduler] Stopping scheduler")
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *SchedulerService) loop() {
+	defer s.wg.Done()
+
+	for {
+		now := time.Now()
+		s.RunDue(now)
+		s.executor.ExpireApprovals(now)
+
+		wait := schedulerMaxSleep
+		if next, ok := s.nextRun(); ok {
+			if d := time.Until(next); d < wait {
+				wait = d
+			}
+		}
+
+		timer := time.NewTimer(max(wait, 0))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// notify wakes the scheduler loop up after a schedule changed.
+func (s *SchedulerService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+const scheduleColumns = `s.id, s.command_id, c.app_id, s.cron, s.timezone, s.overlap_policy, s.parameters, s.enabled,
+	s.next_run_at, s.last_run_at, COALESCE(s.last_execution_id, ''), s.last_error, s.created_at, s.updated_at`
+
+const scheduleFrom = " FROM command_schedules s JOIN commands c ON c.id = s.command_id"
+
+func scanSchedule(row rowScanner) (*models.Schedule, error) {
+	var sch models.Schedule
+	var params sql.NullString
+	var nextRun, lastRun sql.NullTime
+	if err := row.Scan(&sch.ID, &sch.CommandID, &sch.AppID, &sch.Cron, &sch.Timezone, &sch.OverlapPolicy, &params, &sch.Enabled,
+		&nextRun, &lastRun, &sch.LastExecutionID, &sch.LastError, &sch.CreatedAt, &sch.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if params.Valid && params.String != "" {
+		if err := json.Unmarshal([]byte(params.String), &sch.Parameters); err != nil {
+			return nil, err
+		}
+	}
+	if nextRun.Valid {
+		sch.NextRunAt = &nextRun.Time
+	}
+	if lastRun.Valid {
+		sch.LastRunAt = &lastRun.Time
+	}
+	return &sch, nil
+}
+
+func (s *SchedulerService) querySchedules(where string, args ...interface{}) ([]models.Schedule, error) {
+	rows, err := s.db.Query("SELECT "+scheduleColumns+scheduleFrom+" "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	schedules := []models.Schedule{}
+	for rows.Next() {
+		sch, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *sch)
+	}
+	return schedules, rows.Err()
+}
+
+// ListSchedules returns the schedules of a command.
+func (s *SchedulerService) ListSchedules(commandID string) ([]models.Schedule, error) {
+	return s.querySchedules("WHERE s.command_id = ? ORDER BY s.created_at", commandID)
+}
+
+// GetSchedule retrieves a schedule by ID.
+func (s *SchedulerService) GetSchedule(id string) (*models.Schedule, error) {
+	sch, err := scanSchedule(s.db.QueryRow("SELECT "+scheduleColumns+scheduleFrom+" WHERE s.id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrScheduleNotFound
+	}
+	return sch, err
+}
+
+// CreateSchedule creates a schedule for a command.
+func (s *SchedulerService) CreateSchedule(commandID string, req *models.CreateScheduleRequest) (*models.Schedule, error) {
+	command, err := s.appService.GetCommandByID(commandID)
+	if err != nil {
+		return nil, err
+	}
+
+	sch := &models.Schedule{
+		CommandID:     commandID,
+		Cron:          strings.TrimSpace(req.Cron),
+		Timezone:      req.Timezone,
+		OverlapPolicy: req.OverlapPolicy,
+		Parameters:    req.Parameters,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+	}
+	nextRun, err := s.prepare(sch, command, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	params, err := marshalScheduleParameters(sch.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	_, err = s.db.Exec(
+		"INSERT INTO command_schedules (id, command_id, cron, timezone, overlap_policy, parameters, enabled, next_run_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		id, commandID, sch.Cron, sch.Timezone, sch.OverlapPolicy, params, sch.Enabled, nextRun,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return s.GetSchedule(id)
+}
+
+// UpdateSchedule updates a schedule. The next run is recomputed from now.
+func (s *SchedulerService) UpdateSchedule(id string, req *models.UpdateScheduleRequest) (*models.Schedule, error) {
+	sch, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	command, err := s.appService.GetCommandByID(sch.CommandID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Cron != nil {
+		sch.Cron = strings.TrimSpace(*req.Cron)
+	}
+	if req.Timezone != nil {
+		sch.Timezone = *req.Timezone
+	}
+	if req.OverlapPolicy != "" {
+		sch.OverlapPolicy = req.OverlapPolicy
+	}
+	if req.Parameters != nil {
+		sch.Parameters = req.Parameters
+	}
+	if req.Enabled != nil {
+		sch.Enabled = *req.Enabled
+	}
+
+	nextRun, err := s.prepare(sch, command, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	params, err := marshalScheduleParameters(sch.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(
+		"UPDATE command_schedules SET cron = ?, timezone = ?, overlap_policy = ?, parameters = ?, enabled = ?, next_run_at = ?, updated_at = ? WHERE id = ?",
+		sch.Cron, sch.Timezone, sch.OverlapPolicy, params, sch.Enabled, nextRun, time.Now(), id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return s.GetSchedule(id)
+}
+
+// DeleteSchedule deletes a schedule. Executions it started are kept.
+func (s *SchedulerService) DeleteSchedule(id string) error {
+	result, err := s.db.Exec("DELETE FROM command_schedules WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// Preview returns the next count run times of a cron expression in a timezone, starting after from.
+func (s *SchedulerService) Preview(expr, timezone string, from time.Time, count int) ([]time.Time, error) {
+	cron, loc, err := parseSchedule(expr, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]time.Time, 0, count)
+	t := from.In(loc)
+	for len(runs) < count {
+		t = cron.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
+
+// prepare applies defaults, validates the schedule against its command and returns its next run.
+// The next run is nil for disabled schedules.
+func (s *SchedulerService) prepare(sch *models.Schedule, command *models.Command, now time.Time) (interface{}, error) {
+	if sch.Timezone == "" {
+		sch.Timezone = "UTC"
+	}
+	if sch.OverlapPolicy == "" {
+		sch.OverlapPolicy = models.OverlapSkip
+	}
+	if !sch.OverlapPolicy.IsValid() {
+		return nil, fmt.Errorf("%w: unknown overlap policy %q", ErrInvalidSchedule, sch.OverlapPolicy)
+	}
+	if _, err := ResolveParameters(command.Parameters, sch.Parameters); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+
+	cron, loc, err := parseSchedule(sch.Cron, sch.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	next := cron.Next(now.In(loc))
+	if next.IsZero() {
+		return nil, fmt.Errorf("%w: %q never runs", ErrInvalidSchedule, sch.Cron)
+	}
+	if !sch.Enabled {
+		return nil, nil
+	}
+	return next.UTC(), nil
+}
+
+// parseSchedule parses a cron expression and loads its timezone.
+func parseSchedule(expr, timezone string) (*CronSchedule, *time.Location, error) {
+	cron, err := ParseCron(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSchedule, timezone)
+	}
+	return cron, loc, nil
+}
+
+func marshalScheduleParameters(params map[string]string) (interface{}, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// nextRun returns the earliest next run of all enabled schedules.
+func (s *SchedulerService) nextRun() (time.Time, bool) {
+	var next sql.NullTime
+	err := s.db.QueryRow("SELECT next_run_at FROM command_schedules WHERE enabled = 1 AND next_run_at IS NOT NULL ORDER BY next_run_at LIMIT 1").Scan(&next)
+	if err != nil || !next.Valid {
+		return time.Time{}, false
+	}
+	return next.Time, true
+}
+
+// rescheduleMissed moves the next run of enabled schedules that are overdue to their first run after now.
+func (s *SchedulerService) rescheduleMissed(now time.Time) error {
+	schedules, err := s.querySchedules("WHERE s.enabled = 1 AND s.next_run_at IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	for _, sch := range schedules {
+		if sch.NextRunAt.After(now) {
+			continue
+		}
+		log.Printf("[Scheduler] Skipping missed run of schedule %s at %s", sch.ID, sch.NextRunAt.Format(time.RFC3339))
+		if err := s.advance(&sch, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// advance stores the first run of a schedule after now.
+func (s *SchedulerService) advance(sch *models.Schedule, now time.Time) error {
+	var next interface{}
+	if cron, loc, err := parseSchedule(sch.Cron, sch.Timezone); err == nil {
+		if t := cron.Next(now.In(loc)); !t.IsZero() {
+			next = t.UTC()
+		}
+	}
+	_, err := s.db.Exec("UPDATE command_schedules SET next_run_at = ? WHERE id = ?", next, sch.ID)
+	return err
+}
+
+// RunDue starts an execution for every enabled schedule whose next run is at or before now
+// and returns the number of executions started. The background loop calls it on every tick.
+func (s *SchedulerService) RunDue(now time.Time) int {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	schedules, err := s.querySchedules("WHERE s.enabled = 1 AND s.next_run_at IS NOT NULL ORDER BY s.next_run_at")
+	if err != nil {
+		log.Printf("[Scheduler] Failed to load schedules: %v", err)
+		return 0
+	}
+
+	started := 0
+	for i := range schedules {
+		sch := &schedules[i]
+		if sch.NextRunAt.After(now) {
+			break
+		}
+		if s.run(sch, now) {
+			started++
+		}
+	}
+	return started
+}
+
+// run starts one scheduled execution according to the overlap policy and records the outcome.
+func (s *SchedulerService) run(sch *models.Schedule, now time.Time) bool {
+	executionID, runErr := s.start(sch)
+	if runErr != nil {
+		log.Printf("[Scheduler] Schedule %s did not start: %v", sch.ID, runErr)
+	}
+
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+	lastExecution := nullString(sch.LastExecutionID)
+	if executionID != "" {
+		lastExecution = executionID
+	}
+	if _, err := s.db.Exec(
+		"UPDATE command_schedules SET last_run_at = ?, last_execution_id = ?, last_error = ? WHERE id = ?",
+		now, lastExecution, lastError, sch.ID,
+	); err != nil {
+		log.Printf("[Scheduler] Failed to record run of schedule %s: %v", sch.ID, err)
+	}
+	if err := s.advance(sch, now); err != nil {
+		log.Printf("[Scheduler] Failed to advance schedule %s: %v", sch.ID, err)
+	}
+	return executionID != ""
+}
+
+// start creates and launches the execution of a schedule, returning its ID.
+func (s *SchedulerService) start(sch *models.Schedule) (string, error) {
+	if sch.LastExecutionID != "" && sch.OverlapPolicy != models.OverlapQueue {
+		previous, err := s.executor.GetExecutionByID(sch.LastExecutionID)
+		if err == nil && !previous.Status.IsFinished() {
+			if sch.OverlapPolicy == models.OverlapSkip {
+				return "", fmt.Errorf("skipped: previous run %s is still %s", previous.ID, previous.Status)
+			}
+			if err := s.executor.Cancel(previous.ID, SchedulerActor); err != nil && err != ErrExecutionNotRunning {
+				log.Printf("[Scheduler] Failed to cancel previous run %s of schedule %s: %v", previous.ID, sch.ID, err)
+			}
+		}
+	}
+
+	execution, err := s.executor.CreateExecutionWithOptions(sch.CommandID, SystemUserID, ExecutionOptions{Parameters: sch.Parameters})
+	if err != nil {
+		return "", err
+	}
+
+	if s.audit != nil {
+		commandName, appName := "", ""
+		if command, err := s.appService.GetCommandByID(sch.CommandID); err == nil {
+			commandName = command.Name
+		}
+		if app, err := s.appService.GetAppByID(sch.AppID); err == nil {
+			appName = app.Name
+		}
+		s.audit.LogScheduledExecute(sch.ID, execution.ID, commandName, appName, sch.Cron)
+	}
+
+	go func() {
+		_ = s.executor.Execute(execution.ID)
+	}()
+	return execution.ID, nil
+}
@@ -0,0 +1,166 @@
+package services_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestSchedulerService_Schedules(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+	scheduler := services.NewSchedulerService(db, appSvc, execSvc, nil)
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Scheduled", WorkingDir: "/tmp"})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{
+		Name:       "Rotate",
+		Command:    "echo rotate",
+		Parameters: []models.CommandParameter{{Name: "TARGET", Type: models.ParameterString, Required: true}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+
+	invalid := []models.CreateScheduleRequest{
+		{Cron: "61 * * * *", Parameters: map[string]string{"TARGET": "logs"}},
+		{Cron: "0 3 * * *", Timezone: "Mars/Olympus", Parameters: map[string]string{"TARGET": "logs"}},
+		{Cron: "0 3 * * *"},
+		{Cron: "0 3 * * *", Parameters: map[string]string{"TARGET": "logs", "OTHER": "x"}},
+		{Cron: "0 0 31 2 *", Parameters: map[string]string{"TARGET": "logs"}},
+	}
+	for _, req := range invalid {
+		if _, err := scheduler.CreateSchedule(cmd.ID, &req); !errors.Is(err, services.ErrInvalidSchedule) {
+			t.Errorf("%q: expected ErrInvalidSchedule, got %v", req.Cron, err)
+		}
+	}
+
+	schedule, err := scheduler.CreateSchedule(cmd.ID, &models.CreateScheduleRequest{
+		Cron:       "0 3 * * *",
+		Timezone:   "Asia/Jakarta",
+		Parameters: map[string]string{"TARGET": "logs"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create schedule: %v", err)
+	}
+	if schedule.AppID != app.ID || schedule.OverlapPolicy != models.OverlapSkip || !schedule.Enabled {
+		t.Errorf("unexpected schedule: %+v", schedule)
+	}
+	if schedule.NextRunAt == nil {
+		t.Fatal("expected a next run")
+	}
+	if local := schedule.NextRunAt.In(time.FixedZone("WIB", 7*3600)); local.Hour() != 3 || local.Minute() != 0 {
+		t.Errorf("expected the next run at 03:00 Asia/Jakarta, got %s", local)
+	}
+
+	disabled := false
+	schedule, err = scheduler.UpdateSchedule(schedule.ID, &models.UpdateScheduleRequest{Enabled: &disabled})
+	if err != nil {
+		t.Fatalf("failed to update schedule: %v", err)
+	}
+	if schedule.Enabled || schedule.NextRunAt != nil {
+		t.Errorf("expected a disabled schedule without a next run, got %+v", schedule)
+	}
+
+	runs, err := scheduler.Preview("0 3 * * *", "UTC", time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), 3)
+	if err != nil {
+		t.Fatalf("failed to preview: %v", err)
+	}
+	if len(runs) != 3 || !runs[0].Equal(time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)) || !runs[2].Equal(time.Date(2026, 1, 4, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected preview: %v", runs)
+	}
+
+	if err := scheduler.DeleteSchedule(schedule.ID); err != nil {
+		t.Fatalf("failed to delete schedule: %v", err)
+	}
+	if _, err := scheduler.GetSchedule(schedule.ID); err != services.ErrScheduleNotFound {
+		t.Errorf("expected ErrScheduleNotFound, got %v", err)
+	}
+}
+
+func TestSchedulerService_RunDue(t *testing.T) {
+	db, sqlDB, cfg := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+	sqlDB.SetMaxOpenConns(1)
+
+	appSvc := services.NewAppService(db)
+	execSvc := services.NewExecutorService(db, cfg, appSvc, nil, nil)
+	scheduler := services.NewSchedulerService(db, appSvc, execSvc, nil)
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Nightly", WorkingDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	cmd, err := appSvc.CreateCommand(app.ID, &models.CreateCommandRequest{Name: "Slow", Command: "sleep 5"})
+	if err != nil {
+		t.Fatalf("failed to create command: %v", err)
+	}
+
+	schedule, err := scheduler.CreateSchedule(cmd.ID, &models.CreateScheduleRequest{Cron: "* * * * *"})
+	if err != nil {
+		t.Fatalf("failed to create schedule: %v", err)
+	}
+
+	if started := scheduler.RunDue(schedule.NextRunAt.Add(-time.Second)); started != 0 {
+		t.Fatalf("expected nothing to run before the next run, started %d", started)
+	}
+
+	// tick runs the schedule at its next run and returns the updated schedule
+	tick := func(wantStarted int) *models.Schedule {
+		t.Helper()
+		current, err := scheduler.GetSchedule(schedule.ID)
+		if err != nil {
+			t.Fatalf("failed to get schedule: %v", err)
+		}
+		if started := scheduler.RunDue(*current.NextRunAt); started != wantStarted {
+			t.Fatalf("expected %d started executions, got %d", wantStarted, started)
+		}
+		updated, err := scheduler.GetSchedule(schedule.ID)
+		if err != nil {
+			t.Fatalf("failed to get schedule: %v", err)
+		}
+		if !updated.NextRunAt.After(*current.NextRunAt) {
+			t.Fatalf("expected the next run to advance past %s, got %s", current.NextRunAt, updated.NextRunAt)
+		}
+		return updated
+	}
+
+	first := tick(1)
+	execution := waitForStatus(t, execSvc, first.LastExecutionID, func(s models.ExecutionStatus) bool { return s == models.StatusRunning })
+	if execution.UserID != services.SystemUserID {
+		t.Errorf("expected the system user, got %d", execution.UserID)
+	}
+
+	// The previous run is still active, so the skip policy does not start another one
+	skipped := tick(0)
+	if skipped.LastExecutionID != first.LastExecutionID || !strings.Contains(skipped.LastError, "skipped") {
+		t.Errorf("expected a skipped run, got %+v", skipped)
+	}
+
+	if _, err := scheduler.UpdateSchedule(schedule.ID, &models.UpdateScheduleRequest{OverlapPolicy: models.OverlapCancelPrevious}); err != nil {
+		t.Fatalf("failed to update schedule: %v", err)
+	}
+	replaced := tick(1)
+	if replaced.LastExecutionID == first.LastExecutionID || replaced.LastError != "" {
+		t.Errorf("expected a new run, got %+v", replaced)
+	}
+	previous := waitForStatus(t, execSvc, first.LastExecutionID, models.ExecutionStatus.IsFinished)
+	if previous.Status != models.StatusCancelled || previous.CancelledBy != services.SchedulerActor {
+		t.Errorf("expected the previous run to be cancelled by the scheduler, got %s by %q", previous.Status, previous.CancelledBy)
+	}
+
+	waitForStatus(t, execSvc, replaced.LastExecutionID, func(s models.ExecutionStatus) bool { return s == models.StatusRunning })
+	if err := execSvc.Cancel(replaced.LastExecutionID, "test"); err != nil {
+		t.Fatalf("failed to cancel: %v", err)
+	}
+	waitForStatus(t, execSvc, replaced.LastExecutionID, models.ExecutionStatus.IsFinished)
+}
@@ -0,0 +1,462 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// terminalHelperProtocol is the version of the protocol between the server and the session helper.
+// A server does not use a helper speaking another version, such as one started by an older release.
+const terminalHelperProtocol = 1
+
+// terminalHelperIdleTimeout is how long the session helper keeps running without any session.
+const terminalHelperIdleTimeout = time.Minute
+
+// Session helper requests. A connection carries one request; after an attach request it carries the
+// frames of the session until either side closes it.
+const (
+	helperOpList   = "list"
+	helperOpCreate = "create"
+	helperOpAttach = "attach"
+	helperOpClose  = "close"
+)
+
+// helperSessionInfo describes a shell running in the session helper.
+type helperSessionInfo struct {
+	CreatedAt    time.Time `json:"created_at"`
+	LastActivity time.Time `json:"last_activity"`
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	Shell        string    `json:"shell"`
+	Args         []string  `json:"args,omitempty"`
+	Env          []string  `json:"env,omitempty"`
+	UserID       int64     `json:"user_id"`
+	Cols         int       `json:"cols"`
+	Rows         int       `json:"rows"`
+	BufferSize   int       `json:"buffer_size"`
+}
+
+// helperRequest is the first message on a connection to the session helper.
+type helperRequest struct {
+	Session *helperSessionInfo `json:"session,omitempty"`
+	Op      string             `json:"op"`
+	ID      string             `json:"id,omitempty"`
+}
+
+// helperResponse answers a helperRequest. History is the scrollback of an attached session.
+type helperResponse struct {
+	Sessions []helperSessionInfo `json:"sessions,omitempty"`
+	History  []byte              `json:"history,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	Version  int                 `json:"version"`
+}
+
+// helperFrame is a message on an attached connection. The server sends input in Data or a resize in
+// Cols and Rows; the helper sends output in Data, and Exited once the shell has ended.
+type helperFrame struct {
+	Data   []byte `json:"data,omitempty"`
+	Cols   int    `json:"cols,omitempty"`
+	Rows   int    `json:"rows,omitempty"`
+	Exited bool   `json:"exited,omitempty"`
+}
+
+// TerminalHelper runs the shells of persistent terminal sessions in a process of its own, so that
+// they keep running while the server restarts. The server reaches it over a unix socket and attaches
+// to a session to relay its output and input, and a restarted server attaches to the sessions again.
+type TerminalHelper struct {
+	socket   string
+	shells   []string // shells that sessions may run
+	listener net.Listener
+	mu       sync.Mutex
+	sessions map[string]*helperSession
+	idleAt   time.Time // when the last session ended, or the helper started
+	done     chan struct{}
+}
+
+// helperSession is a shell running in the session helper, with the scrollback of its output.
+type helperSession struct {
+	info     helperSessionInfo
+	cmd      *exec.Cmd
+	ptmx     *os.File
+	buffer   *RingBuffer
+	mu       sync.Mutex
+	attached net.Conn
+	encoder  *json.Encoder
+}
+
+// NewTerminalHelper creates a session helper listening on socket, running sessions with one of shells.
+func NewTerminalHelper(socket string, shells []string) *TerminalHelper {
+	return &TerminalHelper{
+		socket:   socket,
+		shells:   shells,
+		sessions: make(map[string]*helperSession),
+		done:     make(chan struct{}),
+	}
+}
+
+// Listen creates the socket of the helper, which only the user running it may connect to.
+// The socket is created without permissions for others, and connections of other users are
+// refused where the peer can be checked. It fails if another helper is already listening on it.
+func (h *TerminalHelper) Listen() error {
+	if err := os.MkdirAll(filepath.Dir(h.socket), 0o700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if conn, err := net.DialTimeout("unix", h.socket, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("a session helper is already listening on %s", h.socket)
+	}
+	// A socket left behind by a helper that did not exit cleanly
+	if err := os.Remove(h.socket); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// The socket must not be reachable by others between creating it and changing its mode
+	oldMask := syscall.Umask(0o077)
+	listener, err := net.Listen("unix", h.socket)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(h.socket, 0o600); err != nil {
+		_ = listener.Close()
+		return err
+	}
+	h.listener = listener
+	h.idleAt = time.Now()
+	return nil
+}
+
+// Serve accepts connections until Stop is called, or until the helper has had no session for
+// terminalHelperIdleTimeout. Listen must have been called.
+func (h *TerminalHelper) Serve() error {
+	go h.idleLoop()
+
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			select {
+			case <-h.done:
+				return nil
+			default:
+			}
+			return err
+		}
+		go h.handle(conn)
+	}
+}
+
+// Stop stops accepting connections and ends all sessions.
+func (h *TerminalHelper) Stop() {
+	h.mu.Lock()
+	select {
+	case <-h.done:
+		h.mu.Unlock()
+		return
+	default:
+	}
+	close(h.done)
+	sessions := make([]*helperSession, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.mu.Unlock()
+
+	_ = h.listener.Close()
+	_ = os.Remove(h.socket)
+	for _, session := range sessions {
+		session.kill()
+	}
+}
+
+// idleLoop stops the helper once it has had no session for terminalHelperIdleTimeout.
+func (h *TerminalHelper) idleLoop() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			h.mu.Lock()
+			idle := len(h.sessions) == 0 && time.Since(h.idleAt) > terminalHelperIdleTimeout
+			h.mu.Unlock()
+			if idle {
+				log.Printf("[TerminalHelper] No sessions for %v, exiting", terminalHelperIdleTimeout)
+				h.Stop()
+				return
+			}
+		}
+	}
+}
+
+// handle serves one connection, if it is from the user running the helper.
+func (h *TerminalHelper) handle(conn net.Conn) {
+	if err := checkHelperPeer(conn); err != nil {
+		log.Printf("[TerminalHelper] Refused connection: %v", err)
+		_ = conn.Close()
+		return
+	}
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	var req helperRequest
+	if err := decoder.Decode(&req); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	var resp helperResponse
+	switch req.Op {
+	case helperOpList:
+		resp.Sessions = h.list()
+	case helperOpCreate:
+		if err := h.create(req.Session); err != nil {
+			resp.Error = err.Error()
+		}
+	case helperOpAttach:
+		session, ok := h.get(req.ID)
+		if !ok {
+			resp.Error = "session not found"
+			break
+		}
+		// The connection stays open and is served by the session from here on
+		session.attach(conn, decoder, encoder)
+		return
+	case helperOpClose:
+		session, ok := h.get(req.ID)
+		if !ok {
+			resp.Error = "session not found"
+			break
+		}
+		session.kill()
+	default:
+		resp.Error = fmt.Sprintf("unknown request %q", req.Op)
+	}
+
+	resp.Version = terminalHelperProtocol
+	_ = encoder.Encode(resp)
+	_ = conn.Close()
+}
+
+func (h *TerminalHelper) get(id string) (*helperSession, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	session, ok := h.sessions[id]
+	return session, ok
+}
+
+// list returns the sessions of the helper.
+func (h *TerminalHelper) list() []helperSessionInfo {
+	h.mu.Lock()
+	sessions := make([]*helperSession, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.mu.Unlock()
+
+	infos := make([]helperSessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		session.mu.Lock()
+		infos = append(infos, session.info)
+		session.mu.Unlock()
+	}
+	return infos
+}
+
+// create starts the shell of a session.
+func (h *TerminalHelper) create(info *helperSessionInfo) error {
+	if info == nil || info.ID == "" || info.Shell == "" {
+		return errors.New("invalid session")
+	}
+	if !slices.Contains(h.shells, info.Shell) {
+		return fmt.Errorf("%w: %s", ErrShellNotAllowed, info.Shell)
+	}
+	if info.BufferSize <= 0 {
+		info.BufferSize = 64 * 1024
+	}
+	if info.Cols <= 0 || info.Rows <= 0 {
+		info.Cols, info.Rows = defaultCols, defaultRows
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.sessions[info.ID]; ok {
+		return fmt.Errorf("session %s already exists", info.ID)
+	}
+
+	// #nosec G204 - shell execution is expected behavior for terminal service
+	cmd := exec.Command(info.Shell, info.Args...)
+	cmd.Env = info.Env
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: uint16(info.Cols), Rows: uint16(info.Rows)})
+	if err != nil {
+		return fmt.Errorf("failed to start PTY: %w", err)
+	}
+
+	now := time.Now()
+	info.LastActivity = now
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = now
+	}
+	session := &helperSession{
+		info:   *info,
+		cmd:    cmd,
+		ptmx:   ptmx,
+		buffer: NewRingBuffer(info.BufferSize),
+	}
+	h.sessions[info.ID] = session
+	go h.readLoop(session)
+
+	log.Printf("[TerminalHelper] Started session %s for user %s", info.ID, info.Username)
+	return nil
+}
+
+// readLoop keeps the output of a session in its scrollback and sends it to the attached server,
+// and removes the session once its shell has ended.
+func (h *TerminalHelper) readLoop(session *helperSession) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := session.ptmx.Read(buf)
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			session.output(data)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	_ = session.cmd.Wait()
+	_ = session.ptmx.Close()
+
+	session.mu.Lock()
+	if session.attached != nil {
+		_ = session.encoder.Encode(helperFrame{Exited: true})
+		_ = session.attached.Close()
+		session.attached = nil
+	}
+	session.mu.Unlock()
+
+	h.mu.Lock()
+	delete(h.sessions, session.info.ID)
+	if len(h.sessions) == 0 {
+		h.idleAt = time.Now()
+	}
+	h.mu.Unlock()
+
+	log.Printf("[TerminalHelper] Session %s ended", session.info.ID)
+}
+
+// output stores output of the shell and sends it to the attached server, if any.
+func (s *helperSession) output(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, _ = s.buffer.Write(data)
+	s.info.LastActivity = time.Now()
+	if s.attached == nil {
+		return
+	}
+
+	// A server that stops reading is detached rather than blocking the shell
+	_ = s.attached.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err := s.encoder.Encode(helperFrame{Data: data}); err != nil {
+		log.Printf("[TerminalHelper] Detaching session %s: %v", s.info.ID, err)
+		_ = s.attached.Close()
+		s.attached = nil
+	}
+}
+
+// attach makes conn the connection of the server to the session, replacing an earlier one, sends it
+// the scrollback and passes on the input and resizes it sends until it is closed.
+func (s *helperSession) attach(conn net.Conn, decoder *json.Decoder, encoder *json.Encoder) {
+	s.mu.Lock()
+	if s.attached != nil {
+		_ = s.attached.Close()
+	}
+	// Sent while holding the lock, so that no output is missed or sent twice
+	err := encoder.Encode(helperResponse{Version: terminalHelperProtocol, History: s.buffer.ReadAll()})
+	if err != nil {
+		s.mu.Unlock()
+		_ = conn.Close()
+		return
+	}
+	s.attached, s.encoder = conn, encoder
+	s.mu.Unlock()
+
+	log.Printf("[TerminalHelper] Server attached to session %s", s.info.ID)
+
+	for {
+		var frame helperFrame
+		if err := decoder.Decode(&frame); err != nil {
+			break
+		}
+		if len(frame.Data) > 0 {
+			s.mu.Lock()
+			s.info.LastActivity = time.Now()
+			s.mu.Unlock()
+			if _, err := s.ptmx.Write(frame.Data); err != nil {
+				break
+			}
+		}
+		if frame.Cols > 0 && frame.Rows > 0 && frame.Cols <= 0xffff && frame.Rows <= 0xffff {
+			if err := pty.Setsize(s.ptmx, &pty.Winsize{Cols: uint16(frame.Cols), Rows: uint16(frame.Rows)}); err == nil {
+				s.mu.Lock()
+				s.info.Cols, s.info.Rows = frame.Cols, frame.Rows
+				s.mu.Unlock()
+			}
+		}
+	}
+
+	s.mu.Lock()
+	if s.attached == conn {
+		s.attached = nil
+		log.Printf("[TerminalHelper] Server detached from session %s", s.info.ID)
+	}
+	s.mu.Unlock()
+	_ = conn.Close()
+}
+
+// kill ends the shell of the session; readLoop then removes it.
+func (s *helperSession) kill() {
+	if s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+	}
+}
+
+// RunTerminalHelper runs a session helper on socket, running sessions with one of shells, until it has
+// had no session for a while or receives SIGTERM or SIGINT, which end all of its sessions.
+func RunTerminalHelper(socket string, shells []string, signals <-chan os.Signal) error {
+	helper := NewTerminalHelper(socket, shells)
+	if err := helper.Listen(); err != nil {
+		return err
+	}
+	log.Printf("[TerminalHelper] Listening on %s", socket)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("[TerminalHelper] Received %v, ending all sessions", sig)
+			helper.Stop()
+		case <-helper.done:
+		}
+	}()
+
+	return helper.Serve()
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/service"
+)
+
+// terminalHelperUnit is the systemd unit the session helper runs in when the server is a systemd
+// service, since stopping the service kills every process left in its own cgroup.
+const terminalHelperUnit = "http-remote-terminal"
+
+// terminalHelperClient connects the server to the session helper on socket, starting it when needed.
+type terminalHelperClient struct {
+	socket string
+	shells []string // shells a started helper may run
+}
+
+// newTerminalHelperClient creates a client of the session helper on socket, which starts the helper
+// with shells as the shells its sessions may run.
+func newTerminalHelperClient(socket string, shells []string) *terminalHelperClient {
+	if abs, err := filepath.Abs(socket); err == nil {
+		socket = abs
+	}
+	return &terminalHelperClient{socket: socket, shells: shells}
+}
+
+// request sends req to the helper and returns its response with the connection, which the caller
+// must close. A helper speaking another protocol version is reported as an error.
+func (c *terminalHelperClient) request(req helperRequest) (*helperResponse, net.Conn, *json.Decoder, error) {
+	conn, err := net.DialTimeout("unix", c.socket, 2*time.Second)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	decoder := json.NewDecoder(conn)
+	var resp helperResponse
+	if err := json.NewEncoder(conn).Encode(req); err == nil {
+		err = decoder.Decode(&resp)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, nil, fmt.Errorf("session helper: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	if resp.Version != terminalHelperProtocol {
+		_ = conn.Close()
+		return nil, nil, nil, fmt.Errorf("session helper speaks protocol %d, expected %d", resp.Version, terminalHelperProtocol)
+	}
+	if resp.Error != "" {
+		_ = conn.Close()
+		return nil, nil, nil, fmt.Errorf("session helper: %s", resp.Error)
+	}
+	return &resp, conn, decoder, nil
+}
+
+// call sends a request that is answered with a single response.
+func (c *terminalHelperClient) call(req helperRequest) (*helperResponse, error) {
+	resp, conn, _, err := c.request(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.Close()
+	return resp, nil
+}
+
+// running reports whether a helper is listening on the socket.
+func (c *terminalHelperClient) running() bool {
+	conn, err := net.DialTimeout("unix", c.socket, time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// ensureRunning starts the helper unless one is already listening on the socket.
+func (c *terminalHelperClient) ensureRunning() error {
+	if c.running() {
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find executable for the session helper: %w", err)
+	}
+	args := []string{"terminal-helper", "-socket", c.socket, "-shells", strings.Join(c.shells, ",")}
+
+	started := false
+	if service.IsRunningAsService() {
+		if _, err := exec.LookPath("systemd-run"); err == nil {
+			// #nosec G204 - runs this binary as the session helper
+			out, err := exec.Command("systemd-run", append([]string{"--unit", terminalHelperUnit, "--collect", "--quiet", executable}, args...)...).CombinedOutput()
+			if err != nil {
+				log.Printf("[TerminalSession] Failed to start the session helper with systemd-run, it will not survive a restart of the service: %v: %s", err, out)
+			} else {
+				started = true
+			}
+		}
+	}
+	if !started {
+		// #nosec G204 - runs this binary as the session helper
+		cmd := exec.Command(executable, args...)
+		// A session of its own, so that signals to the server's process group do not reach it
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		logFile, err := os.OpenFile(c.socket+".log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err == nil {
+			cmd.Stdout, cmd.Stderr = logFile, logFile
+		}
+		err = cmd.Start()
+		if logFile != nil {
+			_ = logFile.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to start the session helper: %w", err)
+		}
+		go func() { _ = cmd.Wait() }()
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if c.running() {
+			log.Printf("[TerminalSession] Started the session helper on %s", c.socket)
+			return nil
+		}
+	}
+	return errors.New("the session helper did not start listening in time")
+}
+
+// list returns the sessions of the helper, or none if no helper is running.
+func (c *terminalHelperClient) list() ([]helperSessionInfo, error) {
+	if !c.running() {
+		return nil, nil
+	}
+	resp, err := c.call(helperRequest{Op: helperOpList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+// create starts the shell of a session in the helper, starting the helper if needed.
+func (c *terminalHelperClient) create(info helperSessionInfo) error {
+	if err := c.ensureRunning(); err != nil {
+		return err
+	}
+	_, err := c.call(helperRequest{Op: helperOpCreate, Session: &info})
+	return err
+}
+
+// attach connects to a session of the helper and returns it with its scrollback.
+func (c *terminalHelperClient) attach(id string) (*helperProcess, []byte, error) {
+	resp, conn, decoder, err := c.request(helperRequest{Op: helperOpAttach, ID: id})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &helperProcess{
+		client:  c,
+		id:      id,
+		conn:    conn,
+		decoder: decoder,
+		encoder: json.NewEncoder(conn),
+	}, resp.History, nil
+}
+
+// terminalProcess is the shell of a terminal session with its PTY.
+type terminalProcess interface {
+	io.ReadWriter
+	// Resize sets the size of the PTY.
+	Resize(cols, rows int) error
+	// Kill ends the shell.
+	Kill() error
+	// Detach stops relaying the shell, leaving it running if it can outlive the server.
+	Detach() error
+}
+
+// helperProcess is the shell of a session running in the session helper.
+type helperProcess struct {
+	client  *terminalHelperClient
+	id      string
+	conn    net.Conn
+	decoder *json.Decoder
+	encoder *json.Encoder
+	writeMu sync.Mutex
+	pending []byte
+}
+
+// Read reads output of the shell. It returns io.EOF once the shell has ended.
+func (p *helperProcess) Read(b []byte) (int, error) {
+	for len(p.pending) == 0 {
+		var frame helperFrame
+		if err := p.decoder.Decode(&frame); err != nil {
+			return 0, err
+		}
+		if frame.Exited {
+			return 0, io.EOF
+		}
+		p.pending = frame.Data
+	}
+	n := copy(b, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+// Write sends input to the shell.
+func (p *helperProcess) Write(b []byte) (int, error) {
+	if err := p.send(helperFrame{Data: b}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Resize sets the size of the PTY.
+func (p *helperProcess) Resize(cols, rows int) error {
+	return p.send(helperFrame{Cols: cols, Rows: rows})
+}
+
+func (p *helperProcess) send(frame helperFrame) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	return p.encoder.Encode(frame)
+}
+
+// Kill ends the shell in the helper.
+func (p *helperProcess) Kill() error {
+	_, err := p.client.call(helperRequest{Op: helperOpClose, ID: p.id})
+	_ = p.conn.Close()
+	return err
+}
+
+// Detach closes the connection to the helper, which keeps the shell running.
+func (p *helperProcess) Detach() error {
+	return p.conn.Close()
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package services
+
+import "net"
+
+// checkHelperPeer accepts every connection, the peer cannot be checked on this platform. Only the
+// permissions of the socket keep other users out.
+func checkHelperPeer(_ net.Conn) error { return nil }
@@ -0,0 +1,135 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// waitForOutput reads output until it contains want.
+func waitForOutput(t *testing.T, output <-chan []byte, history []byte, want string) {
+	t.Helper()
+
+	received := append([]byte(nil), history...)
+	timeout := time.After(5 * time.Second)
+	for !bytes.Contains(received, []byte(want)) {
+		select {
+		case data, ok := <-output:
+			if !ok {
+				t.Fatalf("output closed before %q, got %q", want, received)
+			}
+			received = append(received, data...)
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q, got %q", want, received)
+		}
+	}
+}
+
+func TestTerminalSessionManager_Detached(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "terminal.sock")
+	helper := services.NewTerminalHelper(socket, []string{"/bin/sh"})
+	if err := helper.Listen(); err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() { _ = helper.Serve() }()
+	defer helper.Stop()
+
+	if err := services.NewTerminalHelper(socket, []string{"/bin/sh"}).Listen(); err == nil {
+		t.Error("expected a second helper on the same socket to fail")
+	}
+	if stat, err := os.Stat(socket); err != nil || stat.Mode().Perm() != 0o600 {
+		t.Errorf("expected the socket to be private to its user, got %v", stat.Mode())
+	}
+
+	// The helper only runs the shells it was started with, whatever the request asks for
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	var resp struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewEncoder(conn).Encode(map[string]interface{}{
+		"op":      "create",
+		"session": map[string]interface{}{"id": "term-evil", "shell": "/usr/bin/env", "args": []string{"touch", "/tmp/x"}},
+	})
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil || !strings.Contains(resp.Error, "shell not allowed") {
+		t.Errorf("expected a shell that is not allowed to be rejected, got %q (%v)", resp.Error, err)
+	}
+	_ = conn.Close()
+
+	cfg := &config.TerminalConfig{Shell: "/bin/sh", Detached: config.TerminalDetachedConfig{Enabled: true, Socket: socket}}
+	manager := services.NewTerminalSessionManager(cfg, nil)
+	alice := &models.User{ID: 1, Username: "alice", Role: models.RoleOperator}
+
+	session, err := manager.CreateSession(alice, "")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	ephemeral, err := manager.CreateEphemeralSession(alice, "")
+	if err != nil {
+		t.Fatalf("failed to create ephemeral session: %v", err)
+	}
+
+	output, _, history := session.Subscribe("client-1", 1, "alice")
+	if err := session.Write([]byte("echo before-$((1+1))\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	waitForOutput(t, output, history, "before-2")
+
+	// A restarted server attaches to the session again, with its scrollback
+	manager.Shutdown()
+
+	restarted := services.NewTerminalSessionManager(cfg, nil)
+	defer restarted.Shutdown()
+
+	if _, ok := restarted.GetSession(ephemeral.ID); ok {
+		t.Error("expected the ephemeral session not to survive the restart")
+	}
+	restored, ok := restarted.GetSession(session.ID)
+	if !ok {
+		t.Fatal("expected the session to be restored")
+	}
+	if restored.UserID != 1 || restored.Username != "alice" || !restored.CreatedAt.Equal(session.CreatedAt) {
+		t.Errorf("unexpected restored session %+v", restored.Info())
+	}
+
+	output, _, history = restored.Subscribe("client-2", 1, "alice")
+	if !bytes.Contains(history, []byte("before-2")) {
+		t.Errorf("expected the scrollback to be restored, got %q", history)
+	}
+	if err := restored.Resize(100, 30); err != nil {
+		t.Fatalf("failed to resize: %v", err)
+	}
+	if err := restored.Write([]byte("echo after-$((2+2))\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	waitForOutput(t, output, history, "after-4")
+
+	// Closing the session ends its shell in the helper
+	if err := restarted.CloseSession(session.ID); err != nil {
+		t.Fatalf("failed to close session: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		check := services.NewTerminalSessionManager(cfg, nil)
+		_, ok := check.GetSession(session.ID)
+		check.Shutdown()
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the closed session to end in the helper")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrInvalidParticipant indicates a user cannot be invited into a terminal session with the requested role.
+var ErrInvalidParticipant = errors.New("invalid participant")
+
+// ParticipantRole is the role of a user in a terminal session.
+type ParticipantRole string
+
+const (
+	// ParticipantOwner is the user who created the session.
+	ParticipantOwner ParticipantRole = "owner"
+	// ParticipantDriver may type into and resize the session, like the owner.
+	ParticipantDriver ParticipantRole = "driver"
+	// ParticipantObserver only sees the output of the session.
+	ParticipantObserver ParticipantRole = "observer"
+)
+
+// CanWrite reports whether the role may send input to the session.
+func (r ParticipantRole) CanWrite() bool {
+	return r == ParticipantOwner || r == ParticipantDriver
+}
+
+// Participant is a user the owner of a terminal session invited into it.
+type Participant struct {
+	InvitedAt time.Time       `json:"invited_at"`
+	UserID    int64           `json:"user_id"`
+	Username  string          `json:"username"`
+	Role      ParticipantRole `json:"role"`
+}
+
+// Presence is a user connected to a terminal session.
+type Presence struct {
+	ConnectedAt time.Time       `json:"connected_at"`
+	UserID      int64           `json:"user_id"`
+	Username    string          `json:"username"`
+	Role        ParticipantRole `json:"role"`
+	Connections int             `json:"connections"`
+}
+
+// terminalClient is a connection subscribed to the output of a terminal session.
+type terminalClient struct {
+	connectedAt time.Time
+	userID      int64
+	username    string
+	output      chan []byte
+	presence    chan struct{}
+}
+
+// RoleOf returns the role of a user in the session, and false if the user may not join it.
+func (s *TerminalSession) RoleOf(userID int64) (ParticipantRole, bool) {
+	if userID == s.UserID {
+		return ParticipantOwner, true
+	}
+
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	if participant, ok := s.participants[userID]; ok {
+		return participant.Role, true
+	}
+	return "", false
+}
+
+// Invite lets a user join the session with role, or changes the role of a participant.
+func (s *TerminalSession) Invite(userID int64, username string, role ParticipantRole) (*Participant, error) {
+	if userID == s.UserID {
+		return nil, fmt.Errorf("%w: the owner is already in the session", ErrInvalidParticipant)
+	}
+	if role != ParticipantDriver && role != ParticipantObserver {
+		return nil, fmt.Errorf("%w: role must be %s or %s", ErrInvalidParticipant, ParticipantDriver, ParticipantObserver)
+	}
+
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	participant := &Participant{UserID: userID, Username: username, Role: role, InvitedAt: time.Now()}
+	if existing, ok := s.participants[userID]; ok {
+		participant.InvitedAt = existing.InvitedAt
+	}
+	s.participants[userID] = participant
+	s.notifyPresence()
+
+	copied := *participant
+	return &copied, nil
+}
+
+// Kick removes a participant from the session and disconnects their clients.
+// It returns false if the user was not a participant.
+func (s *TerminalSession) Kick(userID int64) bool {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	if _, ok := s.participants[userID]; !ok {
+		return false
+	}
+	delete(s.participants, userID)
+
+	for id, client := range s.clients {
+		if client.userID == userID {
+			close(client.output)
+			delete(s.clients, id)
+		}
+	}
+	s.notifyPresence()
+	return true
+}
+
+// Participants returns the users invited into the session, in the order they were invited.
+func (s *TerminalSession) Participants() []Participant {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	participants := make([]Participant, 0, len(s.participants))
+	for _, participant := range s.participants {
+		participants = append(participants, *participant)
+	}
+	sort.Slice(participants, func(i, j int) bool { return participants[i].InvitedAt.Before(participants[j].InvitedAt) })
+	return participants
+}
+
+// Presence returns the users connected to the session, in the order they connected.
+func (s *TerminalSession) Presence() []Presence {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	byUser := map[int64]*Presence{}
+	for _, client := range s.clients {
+		if presence, ok := byUser[client.userID]; ok {
+			presence.Connections++
+			if client.connectedAt.Before(presence.ConnectedAt) {
+				presence.ConnectedAt = client.connectedAt
+			}
+			continue
+		}
+
+		role := ParticipantOwner
+		if client.userID != s.UserID {
+			role = ""
+			if participant, ok := s.participants[client.userID]; ok {
+				role = participant.Role
+			}
+		}
+		byUser[client.userID] = &Presence{
+			UserID:      client.userID,
+			Username:    client.username,
+			Role:        role,
+			ConnectedAt: client.connectedAt,
+			Connections: 1,
+		}
+	}
+
+	presence := make([]Presence, 0, len(byUser))
+	for _, p := range byUser {
+		presence = append(presence, *p)
+	}
+	sort.Slice(presence, func(i, j int) bool { return presence[i].ConnectedAt.Before(presence[j].ConnectedAt) })
+	return presence
+}
+
+// notifyPresence signals all clients that the presence list changed. The caller must hold clientsMu.
+func (s *TerminalSession) notifyPresence() {
+	for _, client := range s.clients {
+		select {
+		case client.presence <- struct{}{}:
+		default:
+			// A change is already pending for this client
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestTerminalSession_Participants(t *testing.T) {
+	manager := services.NewTerminalSessionManager(&config.TerminalConfig{Shell: "/bin/sh"}, nil)
+	defer manager.Shutdown()
+
+	session, err := manager.CreateSession(&models.User{ID: 1, Username: "alice", Role: models.RoleOperator}, "")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if _, err := session.Invite(1, "alice", services.ParticipantObserver); !errors.Is(err, services.ErrInvalidParticipant) {
+		t.Errorf("expected inviting the owner to fail, got %v", err)
+	}
+	if _, err := session.Invite(2, "bob", services.ParticipantOwner); !errors.Is(err, services.ErrInvalidParticipant) {
+		t.Errorf("expected inviting as owner to fail, got %v", err)
+	}
+	if _, ok := session.RoleOf(2); ok {
+		t.Error("expected bob not to be a participant before the invite")
+	}
+
+	if _, err := session.Invite(2, "bob", services.ParticipantObserver); err != nil {
+		t.Fatalf("failed to invite: %v", err)
+	}
+	if role, ok := session.RoleOf(2); !ok || role.CanWrite() {
+		t.Errorf("expected bob to be a read-only observer, got %q", role)
+	}
+	if shared := manager.GetSharedSessions(2); len(shared) != 1 || shared[0].ID != session.ID {
+		t.Errorf("expected the session to be shared with bob, got %d sessions", len(shared))
+	}
+
+	_, ownerPresence, _ := session.Subscribe("owner-client", 1, "alice")
+	output, _, _ := session.Subscribe("bob-client", 2, "bob")
+	presence := session.Presence()
+	if len(presence) != 2 || presence[0].Username != "alice" || presence[0].Role != services.ParticipantOwner ||
+		presence[1].Role != services.ParticipantObserver {
+		t.Errorf("unexpected presence %+v", presence)
+	}
+
+	// Changing the role notifies the connected clients
+	select {
+	case <-ownerPresence:
+	default:
+	}
+	if _, err := session.Invite(2, "bob", services.ParticipantDriver); err != nil {
+		t.Fatalf("failed to change role: %v", err)
+	}
+	select {
+	case <-ownerPresence:
+	case <-time.After(time.Second):
+		t.Error("expected a presence notification after the role change")
+	}
+	if role, _ := session.RoleOf(2); !role.CanWrite() {
+		t.Errorf("expected bob to be a driver, got %q", role)
+	}
+
+	if !session.Kick(2) {
+		t.Fatal("expected bob to be kicked")
+	}
+	if session.Kick(2) {
+		t.Error("expected kicking bob twice to report no participant")
+	}
+	for range output {
+		// Drain until the kick closes the output of bob
+	}
+	if _, ok := session.RoleOf(2); ok {
+		t.Error("expected bob not to be a participant after the kick")
+	}
+	if presence := session.Presence(); len(presence) != 1 || presence[0].UserID != 1 {
+		t.Errorf("expected only alice to be connected, got %+v", presence)
+	}
+}
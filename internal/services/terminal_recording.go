@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/database"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// ErrRecordingNotFound indicates the requested terminal recording was not found.
+var ErrRecordingNotFound = errors.New("recording not found")
+
+// recordingColumns lists the terminal_recordings columns read by scanRecording, in order.
+const recordingColumns = `id, session_id, user_id, username, shell, path, size, started_at, ended_at`
+
+// Asciicast v2 event types.
+const (
+	castOutput = "o"
+	castInput  = "i"
+	castResize = "r"
+)
+
+// TerminalRecordingService records terminal sessions as asciicast v2 files, one per session.
+type TerminalRecordingService struct {
+	db     *database.DB
+	config *config.TerminalRecordingConfig
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// NewTerminalRecordingService creates a new TerminalRecordingService instance.
+func NewTerminalRecordingService(db *database.DB, cfg *config.TerminalRecordingConfig) *TerminalRecordingService {
+	return &TerminalRecordingService{
+		db:     db,
+		config: cfg,
+		active: make(map[string]bool),
+	}
+}
+
+// Enabled reports whether new terminal sessions are recorded.
+func (s *TerminalRecordingService) Enabled() bool {
+	return s != nil && s.config.Enabled
+}
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Start starts recording a terminal session of a user with a terminal of cols columns and rows rows.
+func (s *TerminalRecordingService) Start(sessionID string, userID int64, username, shell string, cols, rows int) (*TerminalRecorder, error) {
+	if err := os.MkdirAll(s.config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	id := uuid.New().String()
+	path := filepath.Join(s.config.Dir, id+".cast")
+	// #nosec G304 - the file name is a generated UUID
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	start := time.Now()
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: start.Unix(),
+		Title:     fmt.Sprintf("%s@%s", username, sessionID),
+		Env:       map[string]string{"SHELL": shell, "TERM": "xterm-256color"},
+	})
+	if err == nil {
+		_, err = file.Write(append(header, '\n'))
+	}
+	if err == nil {
+		_, err = s.db.Exec(
+			"INSERT INTO terminal_recordings (id, session_id, user_id, username, shell, path, size, started_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			id, sessionID, userID, username, shell, path, len(header)+1, sqliteTime(start),
+		)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.active[id] = true
+	s.mu.Unlock()
+
+	return &TerminalRecorder{
+		ID:      id,
+		service: s,
+		file:    file,
+		start:   start,
+		size:    int64(len(header) + 1),
+	}, nil
+}
+
+// ListRecordings returns the recordings of the user with userID, or of all users if userID is nil, newest first.
+func (s *TerminalRecordingService) ListRecordings(userID *int64) ([]models.TerminalRecording, error) {
+	query := "SELECT " + recordingColumns + " FROM terminal_recordings"
+	var args []interface{}
+	if userID != nil {
+		query += " WHERE user_id = ?"
+		args = append(args, *userID)
+	}
+	rows, err := s.db.Query(query+" ORDER BY started_at DESC, rowid DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	recordings := []models.TerminalRecording{}
+	for rows.Next() {
+		recording, err := scanRecording(rows)
+		if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, *recording)
+	}
+	return recordings, rows.Err()
+}
+
+// GetRecording returns a recording by ID.
+func (s *TerminalRecordingService) GetRecording(id string) (*models.TerminalRecording, error) {
+	recording, err := scanRecording(s.db.QueryRow("SELECT "+recordingColumns+" FROM terminal_recordings WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrRecordingNotFound
+	}
+	return recording, err
+}
+
+// PruneRecordings deletes recordings started more than days days before now, with their files.
+// Recordings of sessions that are still running are kept. A limit of 0 disables it.
+// It returns the number of recordings deleted.
+func (s *TerminalRecordingService) PruneRecordings(days int, now time.Time) (int, error) {
+	if days <= 0 {
+		return 0, nil
+	}
+
+	rows, err := s.db.Query("SELECT id, path FROM terminal_recordings WHERE started_at < ?", sqliteTime(now.AddDate(0, 0, -days)))
+	if err != nil {
+		return 0, err
+	}
+	paths := map[string]string{}
+	for rows.Next() {
+		var id, path string
+		if err := rows.Scan(&id, &path); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		paths[id] = path
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for id, path := range paths {
+		s.mu.Lock()
+		active := s.active[id]
+		s.mu.Unlock()
+		if active {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return deleted, err
+		}
+		if _, err := s.db.Exec("DELETE FROM terminal_recordings WHERE id = ?", id); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// finish records the final size of a recording and marks it as ended.
+func (s *TerminalRecordingService) finish(id string, size int64, endedAt time.Time) {
+	s.mu.Lock()
+	delete(s.active, id)
+	s.mu.Unlock()
+
+	if _, err := s.db.Exec("UPDATE terminal_recordings SET size = ?, ended_at = ? WHERE id = ?", size, sqliteTime(endedAt), id); err != nil {
+		log.Printf("[TerminalRecording] Failed to finish recording %s: %v", id, err)
+	}
+}
+
+func scanRecording(row rowScanner) (*models.TerminalRecording, error) {
+	var recording models.TerminalRecording
+	var userID sql.NullInt64
+	var endedAt sql.NullTime
+	if err := row.Scan(&recording.ID, &recording.SessionID, &userID, &recording.Username, &recording.Shell,
+		&recording.Path, &recording.Size, &recording.StartedAt, &endedAt); err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		recording.UserID = &userID.Int64
+	}
+	if endedAt.Valid {
+		recording.EndedAt = &endedAt.Time
+	}
+	return &recording, nil
+}
+
+// TerminalRecorder writes the events of one terminal session to its asciicast file.
+// Its methods are safe for concurrent use and do nothing after Close.
+type TerminalRecorder struct {
+	ID      string
+	service *TerminalRecordingService
+	file    *os.File
+	start   time.Time
+	mu      sync.Mutex
+	pending []byte // start of a UTF-8 sequence split across output chunks
+	size    int64
+	closed  bool
+}
+
+// Output records output of the terminal.
+func (r *TerminalRecorder) Output(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Events hold text, so a character split across two reads is recorded with the second one
+	data = append(r.pending, data...)
+	n := len(data) - incompleteUTF8(data)
+	r.pending = append([]byte(nil), data[n:]...)
+	if n > 0 {
+		r.event(castOutput, string(data[:n]))
+	}
+}
+
+// Input records input to the terminal.
+func (r *TerminalRecorder) Input(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event(castInput, string(data))
+}
+
+// Resize records a resize of the terminal to cols columns and rows rows.
+func (r *TerminalRecorder) Resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event(castResize, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close ends the recording.
+func (r *TerminalRecorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	if len(r.pending) > 0 {
+		r.event(castOutput, string(r.pending))
+	}
+	r.closed = true
+	_ = r.file.Close()
+	r.service.finish(r.ID, r.size, time.Now())
+}
+
+// event appends an event line. The caller must hold r.mu.
+func (r *TerminalRecorder) event(code, data string) {
+	if r.closed {
+		return
+	}
+
+	line, err := json.Marshal([]interface{}{
+		float64(time.Since(r.start).Microseconds()) / 1e6, code, data,
+	})
+	if err != nil {
+		return
+	}
+	n, err := r.file.Write(append(line, '\n'))
+	r.size += int64(n)
+	if err != nil {
+		log.Printf("[TerminalRecording] Failed to write recording %s, stopping it: %v", r.ID, err)
+		r.closed = true
+		_ = r.file.Close()
+		r.service.finish(r.ID, r.size, time.Now())
+	}
+}
+
+// incompleteUTF8 returns the number of bytes at the end of data that start a UTF-8 sequence without completing it.
+func incompleteUTF8(data []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if utf8.FullRune(data[len(data)-i:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// TerminalRecordingRetention periodically prunes terminal recordings according to the recording retention settings.
+type TerminalRecordingRetention struct {
+	recordings *TerminalRecordingService
+	config     *config.TerminalRecordingConfig
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewTerminalRecordingRetention creates a new TerminalRecordingRetention instance.
+func NewTerminalRecordingRetention(recordings *TerminalRecordingService, cfg *config.TerminalRecordingConfig) *TerminalRecordingRetention {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TerminalRecordingRetention{
+		recordings: recordings,
+		config:     cfg,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start begins pruning in the background, unless no retention limit is configured.
+func (r *TerminalRecordingRetention) Start() {
+	if r.config.RetentionDays <= 0 {
+		return
+	}
+
+	log.Printf("[TerminalRecording] Keeping recordings for %d days", r.config.RetentionDays)
+
+	r.wg.Add(1)
+	go r.cleanupLoop()
+}
+
+// Stop stops pruning.
+func (r *TerminalRecordingRetention) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// cleanupLoop prunes on start and then daily.
+func (r *TerminalRecordingRetention) cleanupLoop() {
+	defer r.wg.Done()
+
+	r.cleanup()
+
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			r.cleanup()
+		}
+	}
+}
+
+// cleanup prunes recordings beyond the retention limit.
+func (r *TerminalRecordingRetention) cleanup() {
+	deleted, err := r.recordings.PruneRecordings(r.config.RetentionDays, time.Now())
+	if err != nil {
+		log.Printf("[TerminalRecording] Error pruning recordings: %v", err)
+	}
+	if deleted > 0 {
+		log.Printf("[TerminalRecording] Pruned %d old recordings", deleted)
+	}
+}
@@ -0,0 +1,176 @@
+package services_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+// readCast returns the header and events of an asciicast v2 file.
+func readCast(t *testing.T, path string) (map[string]interface{}, [][]interface{}) {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open recording: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	var header map[string]interface{}
+	var events [][]interface{}
+	for scanner.Scan() {
+		if header == nil {
+			if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+				t.Fatalf("invalid header %q: %v", scanner.Text(), err)
+			}
+			continue
+		}
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return header, events
+}
+
+func TestTerminalRecordingService_Record(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	cfg := &config.TerminalRecordingConfig{Enabled: true, Dir: t.TempDir()}
+	recordings := services.NewTerminalRecordingService(db, cfg)
+
+	recorder, err := recordings.Start("term-1", 7, "alice", "/bin/bash", 80, 24)
+	if err != nil {
+		t.Fatalf("failed to start recording: %v", err)
+	}
+	recorder.Output([]byte("h\xc3"))
+	recorder.Output([]byte("\xa9llo\r\n"))
+	recorder.Input([]byte("ls\r"))
+	recorder.Resize(120, 40)
+	recorder.Close()
+	recorder.Output([]byte("after close"))
+
+	recording, err := recordings.GetRecording(recorder.ID)
+	if err != nil {
+		t.Fatalf("failed to get recording: %v", err)
+	}
+	if recording.SessionID != "term-1" || recording.Username != "alice" || recording.UserID == nil || *recording.UserID != 7 {
+		t.Errorf("unexpected recording %+v", recording)
+	}
+	if recording.EndedAt == nil {
+		t.Error("expected the recording to be ended")
+	}
+	if info, err := os.Stat(recording.Path); err != nil || info.Size() != recording.Size {
+		t.Errorf("expected the recorded size to match the file, got %d (err=%v)", recording.Size, err)
+	}
+
+	header, events := readCast(t, recording.Path)
+	if header["version"] != float64(2) || header["width"] != float64(80) || header["height"] != float64(24) {
+		t.Errorf("unexpected header %v", header)
+	}
+
+	// A character split across two chunks is recorded with the second one
+	want := [][2]string{{"o", "h"}, {"o", "éllo\r\n"}, {"i", "ls\r"}, {"r", "120x40"}}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %v", len(want), events)
+	}
+	last := 0.0
+	for i, event := range events {
+		elapsed, _ := event[0].(float64)
+		if elapsed < last || event[1] != want[i][0] || event[2] != want[i][1] {
+			t.Errorf("expected event %d to be %v, got %v", i, want[i], event)
+		}
+		last = elapsed
+	}
+}
+
+func TestTerminalRecordingService_ListAndPrune(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	recordings := services.NewTerminalRecordingService(db, &config.TerminalRecordingConfig{Enabled: true, Dir: t.TempDir()})
+
+	old, _ := recordings.Start("term-1", 1, "alice", "/bin/sh", 80, 24)
+	old.Close()
+	running, _ := recordings.Start("term-2", 1, "alice", "/bin/sh", 80, 24)
+	defer running.Close()
+	other, _ := recordings.Start("term-3", 2, "bob", "/bin/sh", 80, 24)
+	other.Close()
+
+	alice := int64(1)
+	if list, err := recordings.ListRecordings(&alice); err != nil || len(list) != 2 {
+		t.Errorf("expected 2 recordings of alice, got %d (err=%v)", len(list), err)
+	}
+	if list, err := recordings.ListRecordings(nil); err != nil || len(list) != 3 {
+		t.Errorf("expected 3 recordings, got %d (err=%v)", len(list), err)
+	}
+
+	if _, err := sqlDB.Exec("UPDATE terminal_recordings SET started_at = '2000-01-01 00:00:00' WHERE session_id IN ('term-1', 'term-2')"); err != nil {
+		t.Fatalf("failed to backdate recordings: %v", err)
+	}
+	if n, err := recordings.PruneRecordings(0, time.Now()); err != nil || n != 0 {
+		t.Fatalf("expected nothing to be pruned, got %d (err=%v)", n, err)
+	}
+
+	// The running recording is kept even though it is old
+	n, err := recordings.PruneRecordings(30, time.Now())
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 pruned recording, got %d (err=%v)", n, err)
+	}
+	if _, err := recordings.GetRecording(old.ID); err != services.ErrRecordingNotFound {
+		t.Errorf("expected the old recording to be deleted, got %v", err)
+	}
+	for _, id := range []string{running.ID, other.ID} {
+		if _, err := recordings.GetRecording(id); err != nil {
+			t.Errorf("expected recording %s to be kept, got %v", id, err)
+		}
+	}
+}
+
+func TestTerminalSessionManager_Recording(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	recordings := services.NewTerminalRecordingService(db, &config.TerminalRecordingConfig{Enabled: true, Dir: t.TempDir()})
+	manager := services.NewTerminalSessionManager(&config.TerminalConfig{Shell: "/bin/sh"}, recordings)
+	defer manager.Shutdown()
+
+	session, err := manager.CreateSession(&models.User{ID: 1, Username: "alice", Role: models.RoleOperator}, "")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if session.RecordingID() == "" {
+		t.Fatal("expected the session to be recorded")
+	}
+	if err := session.Resize(100, 30); err != nil {
+		t.Fatalf("failed to resize: %v", err)
+	}
+	if err := session.Write([]byte("exit\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if err := manager.CloseSession(session.ID); err != nil {
+		t.Fatalf("failed to close session: %v", err)
+	}
+
+	recording, err := recordings.GetRecording(session.RecordingID())
+	if err != nil || recording.SessionID != session.ID || recording.EndedAt == nil {
+		t.Fatalf("expected an ended recording of the session, got %+v (err=%v)", recording, err)
+	}
+	_, events := readCast(t, recording.Path)
+	codes := map[interface{}]bool{}
+	for _, event := range events {
+		codes[event[1]] = true
+	}
+	if !codes["r"] || !codes["i"] {
+		t.Errorf("expected resize and input events, got %v", events)
+	}
+}
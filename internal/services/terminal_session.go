@@ -1,35 +1,57 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/creack/pty"
 	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
 )
 
+// ErrShellNotAllowed indicates a terminal session was requested with a shell that is not allowed.
+var ErrShellNotAllowed = errors.New("shell not allowed")
+
+// TerminalLimits are the limits of the terminal sessions of a user, by their role, for API responses.
+// Durations are in whole seconds; an IdleTimeout or MaxLifetime of 0 disables it.
+type TerminalLimits struct {
+	SessionTTL  int `json:"session_ttl"`
+	IdleTimeout int `json:"idle_timeout"`
+	MaxLifetime int `json:"max_lifetime"`
+	MaxSessions int `json:"max_sessions"`
+	BufferSize  int `json:"buffer_size"`
+}
+
 // TerminalSession represents a persistent terminal session.
 type TerminalSession struct {
 	ID           string
 	UserID       int64
 	Username     string
+	Role         models.UserRole // role of the owner, which the limits of the session are taken from
 	Shell        string
 	CreatedAt    time.Time
 	LastActivity time.Time
 
-	cmd       *exec.Cmd
-	ptmx      *os.File
+	process   terminalProcess
 	mu        sync.Mutex
 	buffer    *RingBuffer
-	clients   map[string]chan []byte
+	clients   map[string]*terminalClient
 	clientsMu sync.RWMutex
 	done      chan struct{}
 	closed    bool
+	recorder  *TerminalRecorder
+	limits    TerminalLimits
+
+	// participants are the users other than the owner invited into the session, guarded by clientsMu
+	participants map[int64]*Participant
 }
 
 // RingBuffer is a circular buffer for storing terminal output history.
@@ -87,33 +109,66 @@ func (r *RingBuffer) ReadAll() []byte {
 	return result
 }
 
-// TerminalSessionManager manages persistent terminal sessions.
+// Size of the terminal of new sessions until a client resizes it.
+const (
+	defaultCols = 80
+	defaultRows = 24
+)
+
+// localProcess is the shell of a session running in the server process.
+type localProcess struct {
+	cmd  *exec.Cmd
+	ptmx *os.File
+}
+
+func (p *localProcess) Read(b []byte) (int, error)  { return p.ptmx.Read(b) }
+func (p *localProcess) Write(b []byte) (int, error) { return p.ptmx.Write(b) }
+
+func (p *localProcess) Resize(cols, rows int) error {
+	return pty.Setsize(p.ptmx, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+}
+
+func (p *localProcess) Kill() error {
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+	return p.ptmx.Close()
+}
+
+// Detach ends the shell, which cannot outlive the server.
+func (p *localProcess) Detach() error { return p.Kill() }
+
+// TerminalSessionManager manages persistent terminal sessions. If the session helper is enabled,
+// the shells of persistent sessions run in it and the manager attaches to them again on start.
 type TerminalSessionManager struct {
 	sessions      map[string]*TerminalSession
 	userSessions  map[int64][]string // userID -> sessionIDs
 	mu            sync.RWMutex
 	cfg           *config.TerminalConfig
-	maxSessions   int
-	bufferSize    int
-	sessionTTL    time.Duration
+	recordings    *TerminalRecordingService
+	helper        *terminalHelperClient
 	cleanupTicker *time.Ticker
 	done          chan struct{}
 }
 
-// NewTerminalSessionManager creates a new session manager.
-func NewTerminalSessionManager(cfg *config.TerminalConfig) *TerminalSessionManager {
+// NewTerminalSessionManager creates a new session manager, attaching to the sessions of a running
+// session helper if it is enabled. Sessions are recorded if recordings is enabled; it may be nil.
+func NewTerminalSessionManager(cfg *config.TerminalConfig, recordings *TerminalRecordingService) *TerminalSessionManager {
 	m := &TerminalSessionManager{
 		sessions:     make(map[string]*TerminalSession),
 		userSessions: make(map[int64][]string),
 		cfg:          cfg,
-		maxSessions:  10,             // Max sessions per user
-		bufferSize:   64 * 1024,      // 64KB buffer per session
-		sessionTTL:   24 * time.Hour, // Sessions expire after 24 hours of inactivity
+		recordings:   recordings,
 		done:         make(chan struct{}),
 	}
 
+	if cfg.Detached.Enabled {
+		m.helper = newTerminalHelperClient(cfg.Detached.Socket, cfg.Shells())
+		m.restoreSessions()
+	}
+
 	// Start cleanup goroutine
-	m.cleanupTicker = time.NewTicker(5 * time.Minute)
+	m.cleanupTicker = time.NewTicker(time.Minute)
 	go m.cleanupLoop()
 
 	return m
@@ -131,22 +186,50 @@ func (m *TerminalSessionManager) cleanupLoop() {
 	}
 }
 
-// cleanupExpiredSessions removes sessions that have been inactive too long.
+// cleanupExpiredSessions removes sessions that have been inactive too long or reached their maximum lifetime.
 func (m *TerminalSessionManager) cleanupExpiredSessions() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	now := time.Now()
 	for id, session := range m.sessions {
-		if now.Sub(session.LastActivity) > m.sessionTTL {
-			log.Printf("[TerminalSession] Cleaning up expired session %s for user %s", id, session.Username)
-			session.Close()
-			delete(m.sessions, id)
-			m.removeUserSession(session.UserID, id)
+		info := session.Info()
+		reason := ""
+		switch {
+		case now.Sub(info.LastActivity) > time.Duration(session.limits.SessionTTL)*time.Second:
+			reason = "expired"
+		case info.ExpiresAt != nil && !now.Before(*info.ExpiresAt):
+			reason = "reached its maximum lifetime"
+		default:
+			continue
 		}
+
+		log.Printf("[TerminalSession] Cleaning up session %s for user %s: %s", id, session.Username, reason)
+		session.Close()
+		delete(m.sessions, id)
+		m.removeUserSession(session.UserID, id)
 	}
 }
 
+// Limits returns the limits of the terminal sessions of users with role.
+func (m *TerminalSessionManager) Limits(role models.UserRole) TerminalLimits {
+	limits := m.cfg.LimitsFor(string(role))
+	return TerminalLimits{
+		SessionTTL:  int(limits.GetSessionTTL().Seconds()),
+		IdleTimeout: int(limits.GetIdleTimeout().Seconds()),
+		MaxLifetime: int(limits.GetMaxLifetime().Seconds()),
+		MaxSessions: limits.GetMaxSessions(),
+		BufferSize:  limits.GetBufferSize(),
+	}
+}
+
+// IdleTimeout returns how long the connections of users with role stay connected to a session without
+// activity, or 0 if they stay connected.
+func (m *TerminalSessionManager) IdleTimeout(role models.UserRole) time.Duration {
+	limits := m.cfg.LimitsFor(string(role))
+	return limits.GetIdleTimeout()
+}
+
 // removeUserSession removes a session ID from the user's session list.
 func (m *TerminalSessionManager) removeUserSession(userID int64, sessionID string) {
 	sessions := m.userSessions[userID]
@@ -158,57 +241,183 @@ func (m *TerminalSessionManager) removeUserSession(userID int64, sessionID strin
 	}
 }
 
-// CreateSession creates a new persistent terminal session.
-func (m *TerminalSessionManager) CreateSession(userID int64, username string) (*TerminalSession, error) {
+// CreateSession creates a new persistent terminal session of user running shell, or the default shell
+// if shell is empty. It runs in the session helper if that is enabled.
+func (m *TerminalSessionManager) CreateSession(user *models.User, shell string) (*TerminalSession, error) {
+	return m.createSession(user, shell, m.helper != nil)
+}
+
+// CreateEphemeralSession creates a terminal session that always runs in the server process,
+// for a connection that closes it when it ends.
+func (m *TerminalSessionManager) CreateEphemeralSession(user *models.User, shell string) (*TerminalSession, error) {
+	return m.createSession(user, shell, false)
+}
+
+func (m *TerminalSessionManager) createSession(user *models.User, shell string, detached bool) (*TerminalSession, error) {
+	if shell == "" {
+		shell = m.cfg.Shell
+	}
+	if !slices.Contains(m.cfg.Shells(), shell) {
+		return nil, fmt.Errorf("%w: %s", ErrShellNotAllowed, shell)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Check session limit per user
-	if len(m.userSessions[userID]) >= m.maxSessions {
-		return nil, fmt.Errorf("maximum sessions (%d) reached for user", m.maxSessions)
+	limits := m.Limits(user.Role)
+	if len(m.userSessions[user.ID]) >= limits.MaxSessions {
+		return nil, fmt.Errorf("maximum sessions (%d) reached for user", limits.MaxSessions)
 	}
 
 	// Generate session ID
-	sessionID := fmt.Sprintf("term-%d-%d", userID, time.Now().UnixNano())
+	sessionID := fmt.Sprintf("term-%d-%d", user.ID, time.Now().UnixNano())
 
 	// Create the session
-	session := &TerminalSession{
-		ID:           sessionID,
+	session := m.newSession(sessionID, user.ID, user.Username, user.Role, shell, time.Now())
+
+	// Sessions are not started unrecorded while recording is enabled
+	if err := m.startRecording(session, defaultCols, defaultRows); err != nil {
+		return nil, err
+	}
+
+	env := append(os.Environ(), "TERM=xterm-256color")
+	env = append(env, m.cfg.Env...)
+
+	var err error
+	if detached {
+		session.process, err = m.startDetached(session, env)
+	} else {
+		session.process, err = m.startLocal(session.Shell, env)
+	}
+	if err != nil {
+		if session.recorder != nil {
+			session.recorder.Close()
+		}
+		return nil, err
+	}
+
+	m.addSession(session)
+
+	log.Printf("[TerminalSession] Created session %s for user %s (shell: %s, detached: %v)", sessionID, user.Username, shell, detached)
+
+	return session, nil
+}
+
+// newSession returns a session that is not started yet, with the limits of role.
+func (m *TerminalSessionManager) newSession(id string, userID int64, username string, role models.UserRole, shell string, createdAt time.Time) *TerminalSession {
+	limits := m.Limits(role)
+	return &TerminalSession{
+		ID:           id,
 		UserID:       userID,
 		Username:     username,
-		Shell:        m.cfg.Shell,
-		CreatedAt:    time.Now(),
+		Role:         role,
+		Shell:        shell,
+		CreatedAt:    createdAt,
 		LastActivity: time.Now(),
-		buffer:       NewRingBuffer(m.bufferSize),
-		clients:      make(map[string]chan []byte),
+		limits:       limits,
+		buffer:       NewRingBuffer(limits.BufferSize),
+		clients:      make(map[string]*terminalClient),
+		participants: make(map[int64]*Participant),
 		done:         make(chan struct{}),
 	}
+}
 
-	// Start shell with PTY
-	// #nosec G204 - shell execution is expected behavior for terminal service
-	cmd := exec.Command(m.cfg.Shell, m.cfg.Args...)
-	env := append(os.Environ(), "TERM=xterm-256color")
-	env = append(env, m.cfg.Env...)
+// startRecording starts recording a session if recording is enabled.
+func (m *TerminalSessionManager) startRecording(session *TerminalSession, cols, rows int) error {
+	if !m.recordings.Enabled() {
+		return nil
+	}
+	recorder, err := m.recordings.Start(session.ID, session.UserID, session.Username, session.Shell, cols, rows)
+	if err != nil {
+		return fmt.Errorf("failed to start recording: %w", err)
+	}
+	session.recorder = recorder
+	return nil
+}
+
+// startLocal starts shell with a PTY in the server process.
+func (m *TerminalSessionManager) startLocal(shell string, env []string) (terminalProcess, error) {
+	// #nosec G204 - shell execution is expected behavior for terminal service; shell is one of the allowed shells
+	cmd := exec.Command(shell, m.cfg.Args...)
 	cmd.Env = env
 
-	ptmx, err := pty.Start(cmd)
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: defaultCols, Rows: defaultRows})
 	if err != nil {
 		return nil, fmt.Errorf("failed to start PTY: %w", err)
 	}
+	return &localProcess{cmd: cmd, ptmx: ptmx}, nil
+}
+
+// startDetached starts the shell of a session in the session helper and attaches to it.
+func (m *TerminalSessionManager) startDetached(session *TerminalSession, env []string) (terminalProcess, error) {
+	err := m.helper.create(helperSessionInfo{
+		ID:         session.ID,
+		UserID:     session.UserID,
+		Username:   session.Username,
+		Role:       string(session.Role),
+		Shell:      session.Shell,
+		Args:       m.cfg.Args,
+		Env:        env,
+		Cols:       defaultCols,
+		Rows:       defaultRows,
+		BufferSize: session.limits.BufferSize,
+		CreatedAt:  session.CreatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start PTY in the session helper: %w", err)
+	}
 
-	session.cmd = cmd
-	session.ptmx = ptmx
+	process, _, err := m.helper.attach(session.ID)
+	if err != nil {
+		_, _ = m.helper.call(helperRequest{Op: helperOpClose, ID: session.ID})
+		return nil, fmt.Errorf("failed to attach to the session helper: %w", err)
+	}
+	return process, nil
+}
 
-	// Start reading from PTY
-	go session.readLoop()
+// restoreSessions attaches to the sessions already running in the session helper, such as those
+// created before the server restarted, with their scrollback. Invited participants are not restored.
+func (m *TerminalSessionManager) restoreSessions() {
+	infos, err := m.helper.list()
+	if err != nil {
+		log.Printf("[TerminalSession] Failed to list the sessions of the session helper: %v", err)
+		return
+	}
 
-	// Store session
-	m.sessions[sessionID] = session
-	m.userSessions[userID] = append(m.userSessions[userID], sessionID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	log.Printf("[TerminalSession] Created session %s for user %s", sessionID, username)
+	for _, info := range infos {
+		session := m.newSession(info.ID, info.UserID, info.Username, models.UserRole(info.Role), info.Shell, info.CreatedAt)
+		session.LastActivity = info.LastActivity
 
-	return session, nil
+		process, history, err := m.helper.attach(info.ID)
+		if err != nil {
+			log.Printf("[TerminalSession] Failed to attach to session %s: %v", info.ID, err)
+			continue
+		}
+		session.process = process
+		_, _ = session.buffer.Write(history)
+
+		// A session that cannot be recorded is not kept running unrecorded
+		if err := m.startRecording(session, info.Cols, info.Rows); err != nil {
+			log.Printf("[TerminalSession] Closing session %s: %v", info.ID, err)
+			_ = process.Kill()
+			continue
+		}
+
+		m.addSession(session)
+		log.Printf("[TerminalSession] Restored session %s of user %s", info.ID, info.Username)
+	}
+}
+
+// addSession stores a started session and starts relaying its output. The caller must hold m.mu.
+func (m *TerminalSessionManager) addSession(session *TerminalSession) {
+	go session.readLoop()
+
+	m.sessions[session.ID] = session
+	m.userSessions[session.UserID] = append(m.userSessions[session.UserID], session.ID)
 }
 
 // GetSession returns a session by ID.
@@ -235,6 +444,21 @@ func (m *TerminalSessionManager) GetUserSessions(userID int64) []*TerminalSessio
 	return sessions
 }
 
+// GetSharedSessions returns the sessions of other users that a user has been invited into.
+func (m *TerminalSessionManager) GetSharedSessions(userID int64) []*TerminalSession {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []*TerminalSession
+	for _, session := range m.sessions {
+		if role, ok := session.RoleOf(userID); ok && role != ParticipantOwner {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions
+}
+
 // CloseSession closes and removes a session.
 func (m *TerminalSessionManager) CloseSession(sessionID string) error {
 	m.mu.Lock()
@@ -254,7 +478,8 @@ func (m *TerminalSessionManager) CloseSession(sessionID string) error {
 	return nil
 }
 
-// Shutdown closes all sessions and stops the manager.
+// Shutdown closes all sessions and stops the manager. Sessions running in the session helper are
+// detached and keep running.
 func (m *TerminalSessionManager) Shutdown() {
 	close(m.done)
 	m.cleanupTicker.Stop()
@@ -263,7 +488,7 @@ func (m *TerminalSessionManager) Shutdown() {
 	defer m.mu.Unlock()
 
 	for _, session := range m.sessions {
-		session.Close()
+		session.Detach()
 	}
 	m.sessions = make(map[string]*TerminalSession)
 	m.userSessions = make(map[int64][]string)
@@ -277,9 +502,9 @@ func (s *TerminalSession) readLoop() {
 		case <-s.done:
 			return
 		default:
-			n, err := s.ptmx.Read(buf)
+			n, err := s.process.Read(buf)
 			if err != nil {
-				if err != io.EOF {
+				if err != io.EOF && !s.IsClosed() {
 					log.Printf("[TerminalSession] Read error for session %s: %v", s.ID, err)
 				}
 				s.Close()
@@ -291,6 +516,9 @@ func (s *TerminalSession) readLoop() {
 
 			// Store in buffer for replay
 			_, _ = s.buffer.Write(data)
+			if s.recorder != nil {
+				s.recorder.Output(data)
+			}
 
 			// Update activity
 			s.mu.Lock()
@@ -308,29 +536,38 @@ func (s *TerminalSession) broadcast(data []byte) {
 	s.clientsMu.RLock()
 	defer s.clientsMu.RUnlock()
 
-	for _, ch := range s.clients {
+	for _, client := range s.clients {
 		select {
-		case ch <- data:
+		case client.output <- data:
 		default:
 			// Client channel is full, skip
 		}
 	}
 }
 
-// Subscribe adds a client to receive output from this session.
-func (s *TerminalSession) Subscribe(clientID string) (<-chan []byte, []byte) {
+// Subscribe adds a client of a user to receive output from this session. It returns the output channel,
+// which is closed when the session closes or the user is removed from it, a channel that signals changes
+// of the presence list, and the buffer history for replay.
+func (s *TerminalSession) Subscribe(clientID string, userID int64, username string) (<-chan []byte, <-chan struct{}, []byte) {
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
 
-	ch := make(chan []byte, 256)
-	s.clients[clientID] = ch
+	client := &terminalClient{
+		userID:      userID,
+		username:    username,
+		connectedAt: time.Now(),
+		output:      make(chan []byte, 256),
+		presence:    make(chan struct{}, 1),
+	}
+	s.clients[clientID] = client
+	s.notifyPresence()
 
 	// Return channel and buffer history for replay
 	history := s.buffer.ReadAll()
 
 	log.Printf("[TerminalSession] Client %s subscribed to session %s", clientID, s.ID)
 
-	return ch, history
+	return client.output, client.presence, history
 }
 
 // Unsubscribe removes a client from this session.
@@ -338,9 +575,10 @@ func (s *TerminalSession) Unsubscribe(clientID string) {
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
 
-	if ch, ok := s.clients[clientID]; ok {
-		close(ch)
+	if client, ok := s.clients[clientID]; ok {
+		close(client.output)
 		delete(s.clients, clientID)
+		s.notifyPresence()
 		log.Printf("[TerminalSession] Client %s unsubscribed from session %s", clientID, s.ID)
 	}
 }
@@ -355,12 +593,54 @@ func (s *TerminalSession) Write(data []byte) error {
 	}
 
 	s.LastActivity = time.Now()
-	_, err := s.ptmx.Write(data)
+	if s.recorder != nil {
+		s.recorder.Input(data)
+	}
+	_, err := s.process.Write(data)
 	return err
 }
 
-// Close closes the terminal session.
+// Resize sets the size of the terminal to cols columns and rows rows.
+func (s *TerminalSession) Resize(cols, rows int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("session is closed")
+	}
+	if cols <= 0 || rows <= 0 || cols > 0xffff || rows > 0xffff {
+		return fmt.Errorf("invalid terminal size %dx%d", cols, rows)
+	}
+
+	if err := s.process.Resize(cols, rows); err != nil {
+		return err
+	}
+	if s.recorder != nil {
+		s.recorder.Resize(cols, rows)
+	}
+	return nil
+}
+
+// RecordingID returns the ID of the recording of the session, or "" if it is not recorded.
+func (s *TerminalSession) RecordingID() string {
+	if s.recorder == nil {
+		return ""
+	}
+	return s.recorder.ID
+}
+
+// Close closes the terminal session and ends its shell.
 func (s *TerminalSession) Close() {
+	s.end(func(process terminalProcess) { _ = process.Kill() })
+}
+
+// Detach closes the terminal session, leaving its shell running if it runs in the session helper.
+func (s *TerminalSession) Detach() {
+	s.end(func(process terminalProcess) { _ = process.Detach() })
+}
+
+// end closes the session, disconnecting its clients, and stops its process with stop.
+func (s *TerminalSession) end(stop func(terminalProcess)) {
 	s.mu.Lock()
 	if s.closed {
 		s.mu.Unlock()
@@ -373,20 +653,18 @@ func (s *TerminalSession) Close() {
 
 	// Close all client channels
 	s.clientsMu.Lock()
-	for id, ch := range s.clients {
-		close(ch)
+	for id, client := range s.clients {
+		close(client.output)
 		delete(s.clients, id)
 	}
 	s.clientsMu.Unlock()
 
-	// Kill the process
-	if s.cmd != nil && s.cmd.Process != nil {
-		_ = s.cmd.Process.Kill()
+	if s.process != nil {
+		stop(s.process)
 	}
 
-	// Close PTY
-	if s.ptmx != nil {
-		_ = s.ptmx.Close()
+	if s.recorder != nil {
+		s.recorder.Close()
 	}
 }
 
@@ -405,23 +683,47 @@ func (s *TerminalSession) ClientCount() int {
 }
 
 // SessionInfo returns information about a session (for API responses).
+// Role is the role of the user the information is for, if any. ExpiresAt is set if the session has
+// a maximum lifetime.
 type SessionInfo struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	CreatedAt    time.Time `json:"created_at"`
-	LastActivity time.Time `json:"last_activity"`
-	ClientCount  int       `json:"client_count"`
-	IsActive     bool      `json:"is_active"`
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Owner        string          `json:"owner"`
+	Role         ParticipantRole `json:"role,omitempty"`
+	Shell        string          `json:"shell"`
+	CreatedAt    time.Time       `json:"created_at"`
+	LastActivity time.Time       `json:"last_activity"`
+	ExpiresAt    *time.Time      `json:"expires_at,omitempty"`
+	ClientCount  int             `json:"client_count"`
+	IsActive     bool            `json:"is_active"`
 }
 
 // Info returns session information.
 func (s *TerminalSession) Info() SessionInfo {
-	return SessionInfo{
+	s.mu.Lock()
+	lastActivity, closed := s.LastActivity, s.closed
+	s.mu.Unlock()
+
+	info := SessionInfo{
 		ID:           s.ID,
 		Name:         fmt.Sprintf("Session %s", s.ID[len(s.ID)-8:]),
+		Owner:        s.Username,
+		Shell:        s.Shell,
 		CreatedAt:    s.CreatedAt,
-		LastActivity: s.LastActivity,
+		LastActivity: lastActivity,
 		ClientCount:  s.ClientCount(),
-		IsActive:     !s.IsClosed(),
+		IsActive:     !closed,
 	}
+	if s.limits.MaxLifetime > 0 {
+		expiresAt := s.CreatedAt.Add(time.Duration(s.limits.MaxLifetime) * time.Second)
+		info.ExpiresAt = &expiresAt
+	}
+	return info
+}
+
+// InfoFor returns session information for the user with userID, including their role in the session.
+func (s *TerminalSession) InfoFor(userID int64) SessionInfo {
+	info := s.Info()
+	info.Role, _ = s.RoleOf(userID)
+	return info
 }
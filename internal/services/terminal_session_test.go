@@ -0,0 +1,65 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pandeptwidyaop/http-remote/internal/config"
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+func TestTerminalSessionManager_Limits(t *testing.T) {
+	cfg := &config.TerminalConfig{
+		Shell:         "/bin/sh",
+		AllowedShells: []string{"/bin/bash"},
+		Limits:        config.TerminalLimitsConfig{MaxSessions: 3, IdleTimeout: "10m"},
+		RoleLimits: map[string]config.TerminalLimitsConfig{
+			"operator": {MaxSessions: 1, MaxLifetime: "1h", BufferSize: 1024},
+		},
+	}
+	manager := services.NewTerminalSessionManager(cfg, nil)
+	defer manager.Shutdown()
+
+	want := services.TerminalLimits{SessionTTL: 86400, IdleTimeout: 600, MaxLifetime: 3600, MaxSessions: 1, BufferSize: 1024}
+	if limits := manager.Limits(models.RoleOperator); limits != want {
+		t.Errorf("expected operator limits %+v, got %+v", want, limits)
+	}
+	if timeout := manager.IdleTimeout(models.RoleOperator); timeout != 10*time.Minute {
+		t.Errorf("expected an idle timeout of 10m, got %v", timeout)
+	}
+
+	operator := &models.User{ID: 1, Username: "alice", Role: models.RoleOperator}
+	admin := &models.User{ID: 2, Username: "root", Role: models.RoleAdmin}
+
+	if _, err := manager.CreateSession(operator, "/usr/bin/python3"); !errors.Is(err, services.ErrShellNotAllowed) {
+		t.Errorf("expected a shell that is not allowed to be rejected, got %v", err)
+	}
+
+	session, err := manager.CreateSession(operator, "")
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	info := session.Info()
+	if info.Shell != "/bin/sh" || info.ExpiresAt == nil || !info.ExpiresAt.Equal(session.CreatedAt.Add(time.Hour)) {
+		t.Errorf("expected the default shell and a lifetime of an hour, got %+v", info)
+	}
+	if _, err := manager.CreateSession(operator, ""); err == nil {
+		t.Error("expected the operator to be limited to one session")
+	}
+
+	// Admins have the limits of every role
+	for i := 0; i < 3; i++ {
+		session, err := manager.CreateSession(admin, "/bin/bash")
+		if err != nil {
+			t.Fatalf("failed to create admin session %d: %v", i, err)
+		}
+		if info := session.Info(); info.Shell != "/bin/bash" || info.ExpiresAt != nil {
+			t.Errorf("expected a bash session without lifetime, got %+v", info)
+		}
+	}
+	if _, err := manager.CreateSession(admin, ""); err == nil {
+		t.Error("expected the admin to be limited to three sessions")
+	}
+}
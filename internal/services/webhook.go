@@ -0,0 +1,219 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+)
+
+// WebhookProvider identifies the git hosting service that sent a webhook.
+type WebhookProvider string
+
+const (
+	// WebhookGitHub verifies X-Hub-Signature-256 and handles push events.
+	WebhookGitHub WebhookProvider = "github"
+	// WebhookGitLab verifies X-Gitlab-Token and handles push and tag push events.
+	WebhookGitLab WebhookProvider = "gitlab"
+	// WebhookGitea verifies X-Gitea-Signature and handles push events.
+	WebhookGitea WebhookProvider = "gitea"
+)
+
+var (
+	// ErrWebhookSecretNotSet indicates a provider webhook was received for an app without a webhook secret.
+	ErrWebhookSecretNotSet = errors.New("webhook secret is not configured for this app")
+	// ErrInvalidWebhookSignature indicates the signature or token of a webhook does not match the app's secret.
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	// ErrInvalidWebhookPayload indicates a webhook body could not be parsed.
+	ErrInvalidWebhookPayload = errors.New("invalid webhook payload")
+)
+
+// WebhookEvent is a branch or tag push parsed from a provider webhook.
+type WebhookEvent struct {
+	Provider  WebhookProvider
+	Ref       string
+	Branch    string
+	Tag       string
+	CommitSHA string
+	Pusher    string
+	Deleted   bool
+}
+
+// Name returns the pushed branch or tag name.
+func (e *WebhookEvent) Name() string {
+	if e.Tag != "" {
+		return e.Tag
+	}
+	return e.Branch
+}
+
+// Matches reports whether the push passes the webhook filters of app.
+// Without any filter every push matches. Once branch or tag patterns are set,
+// only pushes matching one of the patterns for their kind do.
+func (e *WebhookEvent) Matches(app *models.App) bool {
+	if len(app.WebhookBranches) == 0 && len(app.WebhookTags) == 0 {
+		return true
+	}
+	patterns, name := app.WebhookBranches, e.Branch
+	if e.Tag != "" {
+		patterns, name = app.WebhookTags, e.Tag
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalWebhookFilter validates branch or tag glob patterns and encodes them for storage, using NULL for none.
+func marshalWebhookFilter(patterns []string) (interface{}, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return nil, fmt.Errorf("%w: empty pattern", ErrInvalidWebhookFilter)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: malformed pattern %q", ErrInvalidWebhookFilter, pattern)
+		}
+	}
+	data, err := json.Marshal(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// SetWebhookSecret stores the webhook secret of an app encrypted. An empty secret removes it.
+func (s *GitService) SetWebhookSecret(appID, secret string) error {
+	return s.setEncrypted(appID, "webhook_secret", secret)
+}
+
+// VerifyWebhook checks the signature or token of a provider webhook against the app's webhook secret.
+func (s *GitService) VerifyWebhook(app *models.App, provider WebhookProvider, header http.Header, body []byte) error {
+	secret, err := s.decrypted(app.ID, "webhook_secret")
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return ErrWebhookSecretNotSet
+	}
+
+	switch provider {
+	case WebhookGitHub:
+		signature, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok || !validHMAC(secret, body, signature) {
+			return ErrInvalidWebhookSignature
+		}
+	case WebhookGitea:
+		if !validHMAC(secret, body, header.Get("X-Gitea-Signature")) {
+			return ErrInvalidWebhookSignature
+		}
+	case WebhookGitLab:
+		// GitLab sends the secret itself rather than a signature
+		token := header.Get("X-Gitlab-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return ErrInvalidWebhookSignature
+		}
+	default:
+		return fmt.Errorf("unknown webhook provider %q", provider)
+	}
+	return nil
+}
+
+// validHMAC reports whether signature is the hex encoded HMAC-SHA256 of body.
+func validHMAC(secret string, body []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// webhookPayload holds the push event fields shared by GitHub, GitLab and Gitea.
+type webhookPayload struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Deleted bool   `json:"deleted"`
+	Pusher  struct {
+		Name     string `json:"name"`
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	} `json:"pusher"`
+	UserUsername string `json:"user_username"`
+}
+
+// ParseWebhook parses a verified provider webhook. It returns nil without an error
+// for events other than pushes, such as GitHub's ping.
+func ParseWebhook(provider WebhookProvider, header http.Header, body []byte) (*WebhookEvent, error) {
+	switch provider {
+	case WebhookGitHub:
+		if header.Get("X-GitHub-Event") != "push" {
+			return nil, nil
+		}
+		// GitHub can be configured to send the payload form encoded
+		if strings.HasPrefix(header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			form, err := url.ParseQuery(string(body))
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+			}
+			body = []byte(form.Get("payload"))
+		}
+	case WebhookGitLab:
+		if event := header.Get("X-Gitlab-Event"); event != "Push Hook" && event != "Tag Push Hook" {
+			return nil, nil
+		}
+	case WebhookGitea:
+		if header.Get("X-Gitea-Event") != "push" {
+			return nil, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown webhook provider %q", provider)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+
+	event := &WebhookEvent{
+		Provider:  provider,
+		Ref:       payload.Ref,
+		CommitSHA: payload.After,
+		// Deleted refs are reported with an all-zero commit by every provider
+		Deleted: payload.Deleted || strings.Trim(payload.After, "0") == "",
+	}
+	for _, pusher := range []string{payload.Pusher.Login, payload.Pusher.Username, payload.Pusher.Name, payload.UserUsername} {
+		if pusher != "" {
+			event.Pusher = pusher
+			break
+		}
+	}
+
+	if tag, ok := strings.CutPrefix(payload.Ref, "refs/tags/"); ok {
+		event.Tag = tag
+	} else if branch, ok := strings.CutPrefix(payload.Ref, "refs/heads/"); ok {
+		event.Branch = branch
+	} else {
+		return nil, fmt.Errorf("%w: unsupported ref %q", ErrInvalidWebhookPayload, payload.Ref)
+	}
+	if event.Deleted {
+		event.CommitSHA = ""
+	} else if !commitSHAPattern.MatchString(event.CommitSHA) {
+		return nil, fmt.Errorf("%w: malformed commit %q", ErrInvalidWebhookPayload, event.CommitSHA)
+	}
+	return event, nil
+}
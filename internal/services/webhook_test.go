@@ -0,0 +1,217 @@
+package services_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pandeptwidyaop/http-remote/internal/models"
+	"github.com/pandeptwidyaop/http-remote/internal/services"
+)
+
+const testPushSHA = "0123456789abcdef0123456789abcdef01234567"
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitService_VerifyWebhook(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+	gitSvc := services.NewGitService(db, newTestCryptoService(t))
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Hook App", WorkingDir: "/tmp"})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	body := []byte(`{"ref":"refs/heads/main"}`)
+
+	if err := gitSvc.VerifyWebhook(app, services.WebhookGitHub, http.Header{}, body); err != services.ErrWebhookSecretNotSet {
+		t.Errorf("expected ErrWebhookSecretNotSet, got %v", err)
+	}
+
+	if err := gitSvc.SetWebhookSecret(app.ID, "hook-secret"); err != nil {
+		t.Fatalf("failed to set webhook secret: %v", err)
+	}
+	app, _ = appSvc.GetAppByID(app.ID)
+	if !app.HasWebhookSecret {
+		t.Error("expected app to report a webhook secret")
+	}
+
+	tests := []struct {
+		name     string
+		provider services.WebhookProvider
+		header   string
+		value    string
+		valid    bool
+	}{
+		{"github", services.WebhookGitHub, "X-Hub-Signature-256", "sha256=" + sign("hook-secret", body), true},
+		{"github without prefix", services.WebhookGitHub, "X-Hub-Signature-256", sign("hook-secret", body), false},
+		{"github wrong secret", services.WebhookGitHub, "X-Hub-Signature-256", "sha256=" + sign("other", body), false},
+		{"gitea", services.WebhookGitea, "X-Gitea-Signature", sign("hook-secret", body), true},
+		{"gitea missing", services.WebhookGitea, "X-Hub-Signature-256", "sha256=" + sign("hook-secret", body), false},
+		{"gitlab", services.WebhookGitLab, "X-Gitlab-Token", "hook-secret", true},
+		{"gitlab wrong token", services.WebhookGitLab, "X-Gitlab-Token", "hook-secre", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set(tt.header, tt.value)
+			err := gitSvc.VerifyWebhook(app, tt.provider, header, body)
+			if tt.valid && err != nil {
+				t.Errorf("expected valid signature, got %v", err)
+			}
+			if !tt.valid && err != services.ErrInvalidWebhookSignature {
+				t.Errorf("expected ErrInvalidWebhookSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider services.WebhookProvider
+		header   http.Header
+		body     string
+		want     *services.WebhookEvent
+	}{
+		{
+			name:     "github branch push",
+			provider: services.WebhookGitHub,
+			header:   http.Header{"X-Github-Event": {"push"}},
+			body:     `{"ref":"refs/heads/main","after":"` + testPushSHA + `","pusher":{"name":"octocat"}}`,
+			want:     &services.WebhookEvent{Provider: services.WebhookGitHub, Ref: "refs/heads/main", Branch: "main", CommitSHA: testPushSHA, Pusher: "octocat"},
+		},
+		{
+			name:     "github form encoded",
+			provider: services.WebhookGitHub,
+			header:   http.Header{"X-Github-Event": {"push"}, "Content-Type": {"application/x-www-form-urlencoded"}},
+			body:     `payload=%7B%22ref%22%3A%22refs%2Ftags%2Fv1.2.0%22%2C%22after%22%3A%22` + testPushSHA + `%22%7D`,
+			want:     &services.WebhookEvent{Provider: services.WebhookGitHub, Ref: "refs/tags/v1.2.0", Tag: "v1.2.0", CommitSHA: testPushSHA},
+		},
+		{
+			name:     "github ping",
+			provider: services.WebhookGitHub,
+			header:   http.Header{"X-Github-Event": {"ping"}},
+			body:     `{"zen":"Keep it logically awesome."}`,
+		},
+		{
+			name:     "github branch deletion",
+			provider: services.WebhookGitHub,
+			header:   http.Header{"X-Github-Event": {"push"}},
+			body:     `{"ref":"refs/heads/old","after":"0000000000000000000000000000000000000000","deleted":true}`,
+			want:     &services.WebhookEvent{Provider: services.WebhookGitHub, Ref: "refs/heads/old", Branch: "old", Deleted: true},
+		},
+		{
+			name:     "gitlab tag push",
+			provider: services.WebhookGitLab,
+			header:   http.Header{"X-Gitlab-Event": {"Tag Push Hook"}},
+			body:     `{"object_kind":"tag_push","ref":"refs/tags/v2","after":"` + testPushSHA + `","user_username":"jdoe"}`,
+			want:     &services.WebhookEvent{Provider: services.WebhookGitLab, Ref: "refs/tags/v2", Tag: "v2", CommitSHA: testPushSHA, Pusher: "jdoe"},
+		},
+		{
+			name:     "gitlab merge request",
+			provider: services.WebhookGitLab,
+			header:   http.Header{"X-Gitlab-Event": {"Merge Request Hook"}},
+			body:     `{}`,
+		},
+		{
+			name:     "gitea push",
+			provider: services.WebhookGitea,
+			header:   http.Header{"X-Gitea-Event": {"push"}},
+			body:     `{"ref":"refs/heads/release/1.x","after":"` + testPushSHA + `","pusher":{"login":"gitea-user","username":"gitea-user"}}`,
+			want:     &services.WebhookEvent{Provider: services.WebhookGitea, Ref: "refs/heads/release/1.x", Branch: "release/1.x", CommitSHA: testPushSHA, Pusher: "gitea-user"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := services.ParseWebhook(tt.provider, tt.header, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected event to be ignored, got %+v", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	for _, body := range []string{`not json`, `{"ref":"refs/heads/main","after":"zzz"}`, `{"ref":"refs/pull/1/head","after":"` + testPushSHA + `"}`} {
+		if _, err := services.ParseWebhook(services.WebhookGitea, http.Header{"X-Gitea-Event": {"push"}}, []byte(body)); !errors.Is(err, services.ErrInvalidWebhookPayload) {
+			t.Errorf("expected ErrInvalidWebhookPayload for %s, got %v", body, err)
+		}
+	}
+}
+
+func TestWebhookEvent_Matches(t *testing.T) {
+	branch := &services.WebhookEvent{Branch: "release/1.2"}
+	tag := &services.WebhookEvent{Tag: "v1.2.0"}
+
+	tests := []struct {
+		name     string
+		branches []string
+		tags     []string
+		branch   bool
+		tag      bool
+	}{
+		{"no filters", nil, nil, true, true},
+		{"branch filter ignores tags", []string{"main", "release/*"}, nil, true, false},
+		{"tag filter ignores branches", nil, []string{"v*"}, false, true},
+		{"no match", []string{"main"}, []string{"v2.*"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &models.App{WebhookBranches: tt.branches, WebhookTags: tt.tags}
+			if got := branch.Matches(app); got != tt.branch {
+				t.Errorf("branch push: expected %v, got %v", tt.branch, got)
+			}
+			if got := tag.Matches(app); got != tt.tag {
+				t.Errorf("tag push: expected %v, got %v", tt.tag, got)
+			}
+		})
+	}
+}
+
+func TestAppService_WebhookFilters(t *testing.T) {
+	db, sqlDB, _ := setupTestDB(t)
+	defer func() { _ = sqlDB.Close() }()
+
+	appSvc := services.NewAppService(db)
+
+	if _, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Bad", WorkingDir: "/tmp", WebhookBranches: []string{"[main"}}); !errors.Is(err, services.ErrInvalidWebhookFilter) {
+		t.Errorf("expected ErrInvalidWebhookFilter, got %v", err)
+	}
+
+	app, err := appSvc.CreateApp(&models.CreateAppRequest{Name: "Filtered", WorkingDir: "/tmp", WebhookBranches: []string{"main"}})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	app, err = appSvc.UpdateApp(app.ID, &models.UpdateAppRequest{WebhookTags: []string{"v*"}})
+	if err != nil {
+		t.Fatalf("failed to update app: %v", err)
+	}
+	if len(app.WebhookBranches) != 1 || app.WebhookBranches[0] != "main" || len(app.WebhookTags) != 1 || app.WebhookTags[0] != "v*" {
+		t.Errorf("expected filters to be stored, got branches %v tags %v", app.WebhookBranches, app.WebhookTags)
+	}
+
+	app, err = appSvc.UpdateApp(app.ID, &models.UpdateAppRequest{WebhookBranches: []string{}})
+	if err != nil {
+		t.Fatalf("failed to update app: %v", err)
+	}
+	if len(app.WebhookBranches) != 0 {
+		t.Errorf("expected branch filter to be cleared, got %v", app.WebhookBranches)
+	}
+}
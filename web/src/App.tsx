@@ -17,8 +17,10 @@ import Executions from './pages/Executions';
 import AuditLogs from './pages/AuditLogs';
 import Settings from './pages/Settings';
 import Terminal from './pages/Terminal';
+import TerminalRecordings from './pages/TerminalRecordings';
 import Files from './pages/Files';
 import Users from './pages/Users';
+import Policies from './pages/Policies';
 import Monitoring from './pages/Monitoring';
 import Containers from './pages/Containers';
 
@@ -145,6 +147,15 @@ function App() {
           }
         />
 
+        <Route
+          path="/terminal/recordings"
+          element={
+            <ProtectedRoute>
+              <TerminalRecordings />
+            </ProtectedRoute>
+          }
+        />
+
         <Route
           path="/files"
           element={
@@ -163,6 +174,15 @@ function App() {
           }
         />
 
+        <Route
+          path="/policies"
+          element={
+            <ProtectedRoute>
+              <Policies />
+            </ProtectedRoute>
+          }
+        />
+
         <Route
           path="/monitoring"
           element={
@@ -1,6 +1,6 @@
 import { useEffect, useState } from 'react';
 import { Link, useLocation } from 'react-router-dom';
-import { LogOut, LayoutDashboard, Package, History, FileText, Settings, Terminal, X, ArrowUpCircle, FolderOpen, Users, Menu, Activity } from 'lucide-react';
+import { LogOut, LayoutDashboard, Package, History, FileText, Settings, Terminal, X, ArrowUpCircle, FolderOpen, Users, Menu, Activity, ShieldAlert } from 'lucide-react';
 import { useAuthStore } from '@/store/authStore';
 import { useVersionStore } from '@/store/versionStore';
 import Button from '@/components/ui/Button';
@@ -26,6 +26,7 @@ export default function Navbar() {
   };
 
   const isAdmin = user?.role === 'admin' || user?.is_admin;
+  const isOperator = isAdmin || user?.role === 'operator';
 
   // Primary links show label, secondary links are icon-only on desktop
   const navLinks = [
@@ -33,10 +34,11 @@ export default function Navbar() {
     { path: '/apps', label: 'Apps', icon: Package, primary: true },
     { path: '/executions', label: 'History', icon: History, primary: false },
     { path: '/monitoring', label: 'Monitoring', icon: Activity, primary: true },
-    { path: '/terminal', label: 'Terminal', icon: Terminal, primary: false },
+    ...(isOperator ? [{ path: '/terminal', label: 'Terminal', icon: Terminal, primary: false }] : []),
     { path: '/files', label: 'Files', icon: FolderOpen, primary: false },
     { path: '/audit-logs', label: 'Audit Logs', icon: FileText, primary: false },
     ...(isAdmin ? [{ path: '/users', label: 'Users', icon: Users, primary: false }] : []),
+    ...(isAdmin ? [{ path: '/policies', label: 'Command Policy', icon: ShieldAlert, primary: false }] : []),
     { path: '/settings', label: 'Settings', icon: Settings, primary: false },
   ];
 
@@ -17,6 +17,7 @@ import {
   ChevronLeft,
   ChevronRight,
   Box,
+  ShieldAlert,
 } from 'lucide-react';
 import { useAuthStore } from '@/store/authStore';
 import { useVersionStore } from '@/store/versionStore';
@@ -44,6 +45,7 @@ export default function Sidebar() {
   };
 
   const isAdmin = user?.role === 'admin' || user?.is_admin;
+  const isOperator = isAdmin || user?.role === 'operator';
 
   const navLinks = [
     { path: '/dashboard', label: 'Dashboard', icon: LayoutDashboard },
@@ -51,10 +53,11 @@ export default function Sidebar() {
     { path: '/executions', label: 'History', icon: History },
     { path: '/monitoring', label: 'Monitoring', icon: Activity },
     { path: '/containers', label: 'Containers', icon: Box },
-    { path: '/terminal', label: 'Terminal', icon: Terminal },
+    ...(isOperator ? [{ path: '/terminal', label: 'Terminal', icon: Terminal }] : []),
     { path: '/files', label: 'Files', icon: FolderOpen },
     { path: '/audit-logs', label: 'Audit Logs', icon: FileText },
     ...(isAdmin ? [{ path: '/users', label: 'Users', icon: Users }] : []),
+    ...(isAdmin ? [{ path: '/policies', label: 'Command Policy', icon: ShieldAlert }] : []),
     { path: '/settings', label: 'Settings', icon: Settings },
   ];
 
@@ -1,8 +1,13 @@
 import { useEffect, useRef, useState, useCallback } from 'react';
 import { api } from '@/api/client';
+import type { OutputChunk } from '@/types';
 
 interface UseSSEOptions {
   onMessage?: (data: string) => void;
+  // Receives lines of streams opened with ?format=chunks
+  onChunk?: (chunk: OutputChunk) => void;
+  // Receives the number of lines that could not be replayed after a reconnect or a slow read
+  onDropped?: (data: { count: number; after: number }) => void;
   onComplete?: (data: { status: string; exit_code: number }) => void;
   onError?: (error: Event) => void;
 }
@@ -39,6 +44,22 @@ export function useSSE(endpoint: string | null, options: UseSSEOptions = {}) {
         optionsRef.current.onMessage?.(event.data);
       });
 
+      eventSource.addEventListener('chunk', (event) => {
+        try {
+          optionsRef.current.onChunk?.(JSON.parse(event.data));
+        } catch (err) {
+          console.error('Failed to parse chunk event data:', err);
+        }
+      });
+
+      eventSource.addEventListener('dropped', (event) => {
+        try {
+          optionsRef.current.onDropped?.(JSON.parse(event.data));
+        } catch (err) {
+          console.error('Failed to parse dropped event data:', err);
+        }
+      });
+
       eventSource.addEventListener('complete', (event) => {
         try {
           const data = JSON.parse(event.data);
@@ -51,8 +72,13 @@ export function useSSE(endpoint: string | null, options: UseSSEOptions = {}) {
       });
 
       eventSource.onerror = (event) => {
-        setError('Connection error');
         setConnected(false);
+        // The browser reconnects with Last-Event-ID and the server replays the missed lines
+        if (eventSource.readyState === EventSource.CONNECTING) {
+          setError('Reconnecting...');
+          return;
+        }
+        setError('Connection error');
         optionsRef.current.onError?.(event);
         eventSource.close();
       };
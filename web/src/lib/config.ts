@@ -51,27 +51,60 @@ export const API_ENDPOINTS = {
   apps: '/api/apps',
   app: (id: string) => `/api/apps/${id}`,
   regenerateToken: (id: string) => `/api/apps/${id}/regenerate-token`,
+  appTokens: (id: string) => `/api/apps/${id}/tokens`,
+  appToken: (id: string, tokenId: string) => `/api/apps/${id}/tokens/${tokenId}`,
+  appRollback: (id: string) => `/api/apps/${id}/rollback`,
+  appMembers: (id: string) => `/api/apps/${id}/members`,
+  appMember: (id: string, memberId: string) => `/api/apps/${id}/members/${memberId}`,
   appCommands: (id: string) => `/api/apps/${id}/commands`,
   reorderCommands: (id: string) => `/api/apps/${id}/commands/reorder`,
+  appNotifications: (id: string) => `/api/apps/${id}/notifications`,
+  appNotificationDeliveries: (id: string) => `/api/apps/${id}/notifications/deliveries`,
 
   // Commands
   commands: '/api/commands',
   command: (id: string) => `/api/commands/${id}`,
   executeCommand: (id: string) => `/api/commands/${id}/execute`,
+  commandSchedules: (id: string) => `/api/commands/${id}/schedules`,
+
+  // Schedules
+  schedule: (id: string) => `/api/schedules/${id}`,
+  schedulePreview: '/api/schedules/preview',
 
   // Executions
   executions: '/api/executions',
   execution: (id: string) => `/api/executions/${id}`,
   executionStream: (id: string) => `/api/executions/${id}/stream`,
+  executionOutput: (id: string) => `/api/executions/${id}/output`,
+  executionRerun: (id: string) => `/api/executions/${id}/rerun`,
+  executionApprove: (id: string) => `/api/executions/${id}/approve`,
+  executionReject: (id: string) => `/api/executions/${id}/reject`,
 
   // Deploy
   deploy: (appId: string) => `/deploy/${appId}`,
   deployStatus: (appId: string, execId: string) => `/deploy/${appId}/status/${execId}`,
   deployStream: (appId: string, execId: string) => `/deploy/${appId}/stream/${execId}`,
 
+  // Users and groups
+  users: '/api/users',
+  groups: '/api/groups',
+  group: (id: number) => `/api/groups/${id}`,
+
+  // Command policy
+  policies: '/api/policies',
+  policy: (id: string) => `/api/policies/${id}`,
+  policyEvaluate: '/api/policies/evaluate',
+
   // Audit Logs
   auditLogs: '/api/audit-logs',
 
+  // Terminal
+  terminalRecordings: '/api/terminal/recordings',
+  terminalRecording: (id: string) => `/api/terminal/recordings/${id}`,
+  terminalParticipants: (sessionId: string) => `/api/terminal/sessions/${sessionId}/participants`,
+  terminalParticipant: (sessionId: string, userId: number) =>
+    `/api/terminal/sessions/${sessionId}/participants/${userId}`,
+
   // Version
   version: '/api/version',
   versionCheck: '/api/version/check',
@@ -3,7 +3,7 @@ import { useParams, Link, useNavigate } from 'react-router-dom';
 import { ArrowLeft, Plus, Play, Trash2, Copy, RefreshCw, GripVertical, PlayCircle } from 'lucide-react';
 import { api } from '@/api/client';
 import { API_ENDPOINTS, getBaseUrl } from '@/lib/config';
-import type { App, Command, CreateCommandRequest } from '@/types';
+import type { AddAppMemberRequest, App, AppMember, Command, CreateCommandRequest, CreateDeployTokenRequest, DeployToken, DeployTokenScope, Group, NotificationDelivery, User } from '@/types';
 import Button from '@/components/ui/Button';
 import Card from '@/components/ui/Card';
 import Modal from '@/components/ui/Modal';
@@ -11,12 +11,16 @@ import Input from '@/components/ui/Input';
 import Textarea from '@/components/ui/Textarea';
 import ConfirmDialog from '@/components/ui/ConfirmDialog';
 import { toast } from '@/store/toastStore';
+import { useAuthStore } from '@/store/authStore';
+import { formatDate } from '@/lib/utils';
 
 export default function AppDetail() {
   const { id } = useParams<{ id: string }>();
   const navigate = useNavigate();
   const [app, setApp] = useState<App | null>(null);
   const [commands, setCommands] = useState<Command[]>([]);
+  const [deliveries, setDeliveries] = useState<NotificationDelivery[]>([]);
+  const [tokens, setTokens] = useState<DeployToken[]>([]);
   const [loading, setLoading] = useState(true);
   const [isCreateModalOpen, setIsCreateModalOpen] = useState(false);
   const [formData, setFormData] = useState<CreateCommandRequest>({
@@ -39,22 +43,103 @@ export default function AppDetail() {
   const [showRegenerateConfirm, setShowRegenerateConfirm] = useState(false);
   const [regenerating, setRegenerating] = useState(false);
 
+  // Deploy tokens state; the plaintext of a new token is only available until the dialog is closed
+  const [isTokenModalOpen, setIsTokenModalOpen] = useState(false);
+  const [tokenForm, setTokenForm] = useState<CreateDeployTokenRequest>({ name: '', scopes: ['deploy', 'status', 'cancel'] });
+  const [tokenExpiry, setTokenExpiry] = useState('');
+  const [tokenError, setTokenError] = useState<string | null>(null);
+  const [creatingToken, setCreatingToken] = useState(false);
+  const [newToken, setNewToken] = useState<DeployToken | null>(null);
+  const [revokeTokenTarget, setRevokeTokenTarget] = useState<DeployToken | null>(null);
+  const [revokingToken, setRevokingToken] = useState(false);
+
+  // Members are managed by admins, who have access to every app
+  const { user } = useAuthStore();
+  const isAdmin = user?.role === 'admin' || user?.is_admin;
+  const [members, setMembers] = useState<AppMember[]>([]);
+  const [memberCandidates, setMemberCandidates] = useState<{ users: User[]; groups: Group[] }>({ users: [], groups: [] });
+  const [memberTarget, setMemberTarget] = useState('');
+  const [memberRole, setMemberRole] = useState<AddAppMemberRequest['role']>('operator');
+
   useEffect(() => {
     if (id) {
       fetchData();
     }
   }, [id]);
 
+  useEffect(() => {
+    if (id && isAdmin) {
+      fetchMembers();
+    }
+  }, [id, isAdmin]);
+
+  const fetchMembers = async () => {
+    if (!id) return;
+
+    try {
+      const [membersData, usersData, groupsData] = await Promise.all([
+        api.get<AppMember[]>(API_ENDPOINTS.appMembers(id)),
+        api.get<{ users: User[] }>(`${API_ENDPOINTS.users}?limit=100`),
+        api.get<Group[]>(API_ENDPOINTS.groups),
+      ]);
+      setMembers(membersData || []);
+      setMemberCandidates({
+        users: (usersData?.users || []).filter((u) => u.role !== 'admin' && !u.is_admin),
+        groups: groupsData || [],
+      });
+    } catch (error) {
+      console.error('Failed to fetch app members:', error);
+    }
+  };
+
+  const handleAddMember = async (e: FormEvent) => {
+    e.preventDefault();
+    if (!id || !memberTarget) return;
+
+    const [kind, memberId] = memberTarget.split(':');
+    const req: AddAppMemberRequest = { role: memberRole };
+    if (kind === 'group') {
+      req.group_id = Number(memberId);
+    } else {
+      req.user_id = Number(memberId);
+    }
+
+    try {
+      const member = await api.post<AppMember>(API_ENDPOINTS.appMembers(id), req);
+      toast.success('Member added', `${member?.name} is now ${member?.role} of this app`);
+      setMemberTarget('');
+      fetchMembers();
+    } catch (error: any) {
+      toast.error('Failed', error.message || 'Failed to add member');
+    }
+  };
+
+  const handleRemoveMember = async (member: AppMember) => {
+    if (!id) return;
+
+    try {
+      await api.delete(API_ENDPOINTS.appMember(id, member.id));
+      toast.success('Member removed', `${member.name} no longer has access to this app`);
+      fetchMembers();
+    } catch (error: any) {
+      toast.error('Failed', error.message || 'Failed to remove member');
+    }
+  };
+
   const fetchData = async () => {
     if (!id) return;
 
     try {
-      const [appData, commandsData] = await Promise.all([
+      const [appData, commandsData, deliveriesData, tokensData] = await Promise.all([
         api.get<App>(API_ENDPOINTS.app(id)),
         api.get<Command[]>(API_ENDPOINTS.appCommands(id)),
+        api.get<NotificationDelivery[]>(API_ENDPOINTS.appNotificationDeliveries(id)),
+        api.get<DeployToken[]>(API_ENDPOINTS.appTokens(id)),
       ]);
       setApp(appData || null);
       setCommands(commandsData || []);
+      setDeliveries(deliveriesData || []);
+      setTokens(tokensData || []);
     } catch (error: any) {
       console.error('Failed to fetch app details:', error);
       setApp(null);
@@ -109,8 +194,8 @@ export default function AppDetail() {
   };
 
   const handleCopyToken = () => {
-    if (app?.token) {
-      navigator.clipboard.writeText(app.token);
+    if (newToken?.token) {
+      navigator.clipboard.writeText(newToken.token);
       toast.success('Copied!', 'Token copied to clipboard');
     }
   };
@@ -120,10 +205,13 @@ export default function AppDetail() {
 
     setRegenerating(true);
     try {
-      await api.post(API_ENDPOINTS.regenerateToken(id));
+      const updated = await api.post<App>(API_ENDPOINTS.regenerateToken(id));
       fetchData();
       setShowRegenerateConfirm(false);
-      toast.success('Token regenerated', 'The new token is now active');
+      if (updated?.token) {
+        setNewToken({ id: '', app_id: id, name: 'default', prefix: '', token: updated.token, scopes: ['deploy', 'status', 'cancel'], created_at: '' });
+      }
+      toast.success('Token regenerated', 'The new default token is now active');
     } catch (error: any) {
       toast.error('Failed', error.message || 'Failed to regenerate token');
     } finally {
@@ -131,6 +219,62 @@ export default function AppDetail() {
     }
   };
 
+  const toggleTokenScope = (scope: DeployTokenScope) => {
+    const scopes = tokenForm.scopes.includes(scope)
+      ? tokenForm.scopes.filter((s) => s !== scope)
+      : [...tokenForm.scopes, scope];
+    setTokenForm({ ...tokenForm, scopes });
+  };
+
+  const toggleTokenCommand = (commandId: string) => {
+    const commandIds = tokenForm.command_ids || [];
+    setTokenForm({
+      ...tokenForm,
+      command_ids: commandIds.includes(commandId)
+        ? commandIds.filter((c) => c !== commandId)
+        : [...commandIds, commandId],
+    });
+  };
+
+  const handleCreateToken = async (e: FormEvent) => {
+    e.preventDefault();
+    if (!id) return;
+
+    setCreatingToken(true);
+    setTokenError(null);
+    try {
+      const created = await api.post<DeployToken>(API_ENDPOINTS.appTokens(id), {
+        ...tokenForm,
+        expires_at: tokenExpiry ? new Date(tokenExpiry).toISOString() : undefined,
+      });
+      setIsTokenModalOpen(false);
+      setTokenForm({ name: '', scopes: ['deploy', 'status', 'cancel'] });
+      setTokenExpiry('');
+      setNewToken(created || null);
+      fetchData();
+    } catch (error: any) {
+      setTokenError(error.message || 'Failed to create token');
+    } finally {
+      setCreatingToken(false);
+    }
+  };
+
+  const handleRevokeToken = async () => {
+    if (!id || !revokeTokenTarget) return;
+
+    setRevokingToken(true);
+    try {
+      await api.delete(API_ENDPOINTS.appToken(id, revokeTokenTarget.id));
+      toast.success('Token revoked', `${revokeTokenTarget.name} no longer works`);
+      setRevokeTokenTarget(null);
+      fetchData();
+    } catch (error: any) {
+      toast.error('Revoke failed', error.message || 'Failed to revoke token');
+    } finally {
+      setRevokingToken(false);
+    }
+  };
+
   // Drag and drop handlers
   const handleDragStart = (index: number) => {
     setDraggedIndex(index);
@@ -241,22 +385,104 @@ export default function AppDetail() {
         </p>
         <div className="bg-gray-900 text-green-400 p-4 rounded-md font-mono text-sm overflow-x-auto mb-3">
           curl -X POST {deployUrl} \<br />
-          &nbsp;&nbsp;-H "X-Deploy-Token: {app.token}"
+          &nbsp;&nbsp;-H "X-Deploy-Token: &lt;token&gt;"
+        </div>
+        <div className="flex items-center justify-between mb-2">
+          <span className="text-sm font-medium text-gray-700">Deploy Tokens</span>
+          <div className="flex items-center space-x-2">
+            <Button variant="secondary" size="sm" onClick={() => setIsTokenModalOpen(true)}>
+              <Plus className="h-4 w-4 mr-1" />
+              New Token
+            </Button>
+            <Button variant="danger" size="sm" onClick={() => setShowRegenerateConfirm(true)}>
+              <RefreshCw className="h-4 w-4 mr-1" />
+              Regenerate Default
+            </Button>
+          </div>
         </div>
-        <div className="flex items-center space-x-2">
-          <span className="text-sm font-medium text-gray-700">Token:</span>
-          <code className="bg-white px-3 py-1 rounded border text-sm flex-1">
-            {app.token}
-          </code>
-          <Button variant="secondary" size="sm" onClick={handleCopyToken}>
-            <Copy className="h-4 w-4" />
-          </Button>
-          <Button variant="danger" size="sm" onClick={() => setShowRegenerateConfirm(true)}>
-            <RefreshCw className="h-4 w-4" />
-          </Button>
+        <div className="bg-white rounded border divide-y divide-gray-200">
+          {tokens.map((token) => (
+            <div key={token.id} className="flex items-center justify-between px-3 py-2 text-sm">
+              <div>
+                <span className="font-medium text-gray-900">{token.name}</span>
+                <code className="ml-2 text-gray-500">{token.prefix}…</code>
+                <span className="ml-2 text-gray-500">{token.scopes.join(', ')}</span>
+                {token.command_ids && token.command_ids.length > 0 && (
+                  <span className="ml-2 text-gray-500">
+                    ({token.command_ids.map((cid) => commands.find((c) => c.id === cid)?.name || cid).join(', ')})
+                  </span>
+                )}
+                <p className="text-xs text-gray-500">
+                  {token.expires_at ? `Expires ${formatDate(token.expires_at)}` : 'Never expires'}
+                  {' · '}
+                  {token.last_used_at ? `Last used ${formatDate(token.last_used_at)} from ${token.last_used_ip}` : 'Never used'}
+                </p>
+              </div>
+              <Button variant="ghost" size="sm" onClick={() => setRevokeTokenTarget(token)}>
+                <Trash2 className="h-4 w-4 text-red-600" />
+              </Button>
+            </div>
+          ))}
+          {tokens.length === 0 && (
+            <p className="px-3 py-2 text-sm text-gray-500">No deploy tokens</p>
+          )}
         </div>
       </Card>
 
+      {/* Members */}
+      {isAdmin && (
+        <Card className="p-6">
+          <h3 className="text-lg font-semibold text-gray-900 mb-1">Members</h3>
+          <p className="text-sm text-gray-600 mb-3">
+            Only admins and members can see this app. A member acts on this app with their role here, even if it is above or below their own role.
+          </p>
+          <div className="bg-white rounded border divide-y divide-gray-200 mb-3">
+            {members.map((member) => (
+              <div key={member.id} className="flex items-center justify-between px-3 py-2 text-sm">
+                <div>
+                  <span className="font-medium text-gray-900">{member.name}</span>
+                  <span className="ml-2 text-gray-500">{member.group_id ? 'group' : 'user'}</span>
+                  <span className="ml-2 text-gray-500">{member.role}</span>
+                </div>
+                <Button variant="ghost" size="sm" onClick={() => handleRemoveMember(member)}>
+                  <Trash2 className="h-4 w-4 text-red-600" />
+                </Button>
+              </div>
+            ))}
+            {members.length === 0 && (
+              <p className="px-3 py-2 text-sm text-gray-500">No members</p>
+            )}
+          </div>
+          <form onSubmit={handleAddMember} className="flex items-center gap-2">
+            <select
+              value={memberTarget}
+              onChange={(e) => setMemberTarget(e.target.value)}
+              className="flex-1 px-3 py-2 border border-gray-300 rounded-md shadow-sm text-sm"
+            >
+              <option value="">Select a user or group</option>
+              {memberCandidates.users.map((u) => (
+                <option key={`user:${u.id}`} value={`user:${u.id}`}>{u.username}</option>
+              ))}
+              {memberCandidates.groups.map((g) => (
+                <option key={`group:${g.id}`} value={`group:${g.id}`}>{g.name} (group)</option>
+              ))}
+            </select>
+            <select
+              value={memberRole}
+              onChange={(e) => setMemberRole(e.target.value as AddAppMemberRequest['role'])}
+              className="px-3 py-2 border border-gray-300 rounded-md shadow-sm text-sm"
+            >
+              <option value="operator">operator</option>
+              <option value="viewer">viewer</option>
+            </select>
+            <Button type="submit" variant="secondary" size="sm" disabled={!memberTarget}>
+              <Plus className="h-4 w-4 mr-1" />
+              Add
+            </Button>
+          </form>
+        </Card>
+      )}
+
       {/* Commands */}
       <div>
         <div className="flex items-center justify-between mb-4">
@@ -359,6 +585,60 @@ export default function AppDetail() {
         )}
       </div>
 
+      {/* Notification Deliveries */}
+      {deliveries.length > 0 && (
+        <div>
+          <h2 className="text-2xl font-bold text-gray-900 mb-4">Notification Deliveries</h2>
+          <Card>
+            <div className="overflow-x-auto">
+              <table className="min-w-full divide-y divide-gray-200">
+                <thead className="bg-gray-50">
+                  <tr>
+                    <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Rule</th>
+                    <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Execution</th>
+                    <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Status</th>
+                    <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Attempts</th>
+                    <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Timestamp</th>
+                  </tr>
+                </thead>
+                <tbody className="bg-white divide-y divide-gray-200">
+                  {deliveries.map((delivery) => (
+                    <tr key={delivery.id} className="hover:bg-gray-50">
+                      <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900">
+                        {delivery.rule_name}
+                        <span className="text-gray-400 ml-1">({delivery.channel})</span>
+                      </td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600">
+                        {delivery.execution_status}
+                        <span className="text-gray-400 ml-1">({delivery.execution_id.substring(0, 8)}...)</span>
+                      </td>
+                      <td className="px-6 py-4 text-sm text-gray-600">
+                        <span
+                          className={`inline-flex items-center px-2.5 py-0.5 rounded-full text-xs font-medium ${
+                            delivery.status === 'sent'
+                              ? 'bg-green-100 text-green-800'
+                              : delivery.status === 'failed'
+                              ? 'bg-red-100 text-red-800'
+                              : 'bg-yellow-100 text-yellow-800'
+                          }`}
+                        >
+                          {delivery.status}
+                        </span>
+                        {delivery.last_error && (
+                          <p className="text-xs text-red-600 mt-1">{delivery.last_error}</p>
+                        )}
+                      </td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600">{delivery.attempts}</td>
+                      <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600">{formatDate(delivery.updated_at)}</td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+            </div>
+          </Card>
+        </div>
+      )}
+
       {/* Create Command Modal */}
       <Modal
         isOpen={isCreateModalOpen}
@@ -437,13 +717,119 @@ export default function AppDetail() {
         loading={deletingCommand}
       />
 
+      {/* Create Token Modal */}
+      <Modal
+        isOpen={isTokenModalOpen}
+        onClose={() => {
+          setIsTokenModalOpen(false);
+          setTokenError(null);
+        }}
+        title="Create Deploy Token"
+      >
+        <form onSubmit={handleCreateToken} className="space-y-4">
+          {tokenError && (
+            <div className="bg-red-50 border border-red-200 text-red-700 px-4 py-3 rounded-md text-sm">
+              {tokenError}
+            </div>
+          )}
+
+          <Input
+            label="Name"
+            required
+            value={tokenForm.name}
+            onChange={(e) => setTokenForm({ ...tokenForm, name: e.target.value })}
+            placeholder="e.g., github-actions"
+          />
+
+          <div>
+            <span className="block text-sm font-medium text-gray-700 mb-1">Scopes</span>
+            {(['deploy', 'status', 'cancel'] as DeployTokenScope[]).map((scope) => (
+              <label key={scope} className="inline-flex items-center space-x-2 text-sm text-gray-600 mr-4">
+                <input
+                  type="checkbox"
+                  checked={tokenForm.scopes.includes(scope)}
+                  onChange={() => toggleTokenScope(scope)}
+                  className="rounded border-gray-300 text-blue-600 focus:ring-blue-500"
+                />
+                <span>{scope}</span>
+              </label>
+            ))}
+          </div>
+
+          {commands.length > 0 && (
+            <div>
+              <span className="block text-sm font-medium text-gray-700 mb-1">Commands (none selected allows all)</span>
+              {commands.map((command) => (
+                <label key={command.id} className="flex items-center space-x-2 text-sm text-gray-600">
+                  <input
+                    type="checkbox"
+                    checked={(tokenForm.command_ids || []).includes(command.id)}
+                    onChange={() => toggleTokenCommand(command.id)}
+                    className="rounded border-gray-300 text-blue-600 focus:ring-blue-500"
+                  />
+                  <span>{command.name}</span>
+                </label>
+              ))}
+            </div>
+          )}
+
+          <Input
+            label="Expires at (optional)"
+            type="datetime-local"
+            value={tokenExpiry}
+            onChange={(e) => setTokenExpiry(e.target.value)}
+          />
+
+          <div className="flex items-center justify-end space-x-3 pt-4">
+            <Button type="button" variant="secondary" onClick={() => setIsTokenModalOpen(false)}>
+              Cancel
+            </Button>
+            <Button type="submit" variant="primary" loading={creatingToken}>
+              Create Token
+            </Button>
+          </div>
+        </form>
+      </Modal>
+
+      {/* New Token, shown once */}
+      <Modal isOpen={!!newToken} onClose={() => setNewToken(null)} title={`Token "${newToken?.name}"`}>
+        <div className="space-y-4">
+          <p className="text-sm text-gray-600">
+            Copy the token now. It is stored hashed and cannot be shown again.
+          </p>
+          <div className="flex items-center space-x-2">
+            <code className="bg-gray-100 px-3 py-1 rounded border text-sm flex-1 break-all">{newToken?.token}</code>
+            <Button variant="secondary" size="sm" onClick={handleCopyToken}>
+              <Copy className="h-4 w-4" />
+            </Button>
+          </div>
+          <div className="flex justify-end">
+            <Button variant="primary" onClick={() => setNewToken(null)}>
+              Done
+            </Button>
+          </div>
+        </div>
+      </Modal>
+
+      {/* Revoke Token Confirmation */}
+      <ConfirmDialog
+        isOpen={!!revokeTokenTarget}
+        onClose={() => setRevokeTokenTarget(null)}
+        onConfirm={handleRevokeToken}
+        title="Revoke Token"
+        message={`Are you sure you want to revoke "${revokeTokenTarget?.name}"? Other tokens keep working.`}
+        confirmText="Revoke"
+        variant="danger"
+        loading={revokingToken}
+      />
+
       {/* Regenerate Token Confirmation */}
       <ConfirmDialog
         isOpen={showRegenerateConfirm}
         onClose={() => setShowRegenerateConfirm(false)}
         onConfirm={handleRegenerateToken}
         title="Regenerate Token"
-        message="Are you sure you want to regenerate the token? The old token will stop working immediately."
+        message="Are you sure you want to regenerate the default token? The old default token will stop working immediately; other tokens are not affected."
         confirmText="Regenerate"
         variant="warning"
         loading={regenerating}
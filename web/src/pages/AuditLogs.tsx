@@ -1,11 +1,22 @@
 import { useState, useEffect } from 'react';
-import { FileText } from 'lucide-react';
+import { Link } from 'react-router-dom';
+import { FileText, Film } from 'lucide-react';
 import { api } from '@/api/client';
 import { API_ENDPOINTS } from '@/lib/config';
 import { formatDate } from '@/lib/utils';
 import type { AuditLog } from '@/types';
 import Card from '@/components/ui/Card';
 
+// recordingId returns the ID of the terminal recording an audit log links to, if any.
+function recordingId(log: AuditLog): string | undefined {
+  if (!log.details) return undefined;
+  try {
+    return JSON.parse(log.details).recording_id;
+  } catch {
+    return undefined;
+  }
+}
+
 export default function AuditLogs() {
   const [logs, setLogs] = useState<AuditLog[]>([]);
   const [loading, setLoading] = useState(true);
@@ -90,6 +101,15 @@ export default function AuditLogs() {
                           ({log.resource_id.substring(0, 8)}...)
                         </span>
                       )}
+                      {recordingId(log) && (
+                        <Link
+                          to={`/terminal/recordings?id=${recordingId(log)}`}
+                          className="inline-flex items-center ml-2 text-blue-600 hover:text-blue-800"
+                          title="Play recording"
+                        >
+                          <Film className="h-4 w-4" />
+                        </Link>
+                      )}
                     </td>
                     <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600 font-mono">
                       {log.ip_address}
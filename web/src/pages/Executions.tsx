@@ -1,23 +1,40 @@
 import { useState, useEffect } from 'react';
-import { History } from 'lucide-react';
+import { History, Search } from 'lucide-react';
 import { api } from '@/api/client';
 import { API_ENDPOINTS } from '@/lib/config';
 import { formatDate, formatDuration } from '@/lib/utils';
-import type { ExecutionWithDetails } from '@/types';
+import type { ExecutionFilters, ExecutionStatus, ExecutionWithDetails } from '@/types';
 import Card from '@/components/ui/Card';
 import Badge from '@/components/ui/Badge';
+import Input from '@/components/ui/Input';
+
+const STATUSES: ExecutionStatus[] = [
+  'pending', 'running', 'success', 'failed', 'cancelled', 'interrupted', 'awaiting_approval', 'rejected',
+];
+
+function executionsURL(filters: ExecutionFilters): string {
+  const params = new URLSearchParams();
+  Object.entries(filters).forEach(([key, value]) => {
+    if (value === undefined || value === '' || (Array.isArray(value) && value.length === 0)) return;
+    params.set(key, Array.isArray(value) ? value.join(',') : String(value));
+  });
+  const query = params.toString();
+  return query ? `${API_ENDPOINTS.executions}?${query}` : API_ENDPOINTS.executions;
+}
 
 export default function Executions() {
   const [executions, setExecutions] = useState<ExecutionWithDetails[]>([]);
   const [loading, setLoading] = useState(true);
+  const [search, setSearch] = useState('');
+  const [filters, setFilters] = useState<ExecutionFilters>({});
 
   useEffect(() => {
-    fetchExecutions();
-  }, []);
+    fetchExecutions(filters);
+  }, [filters]);
 
-  const fetchExecutions = async () => {
+  const fetchExecutions = async (filters: ExecutionFilters) => {
     try {
-      const data = await api.get<ExecutionWithDetails[]>(API_ENDPOINTS.executions);
+      const data = await api.get<ExecutionWithDetails[]>(executionsURL(filters));
       setExecutions(data || []);
     } catch (error) {
       console.error('Failed to fetch executions:', error);
@@ -43,13 +60,52 @@ export default function Executions() {
         <p className="text-gray-600 mt-1">View all command execution history</p>
       </div>
 
+      {/* Filters */}
+      <form
+        className="flex flex-col sm:flex-row gap-3"
+        onSubmit={(e) => {
+          e.preventDefault();
+          setFilters({ ...filters, q: search.trim() });
+        }}
+      >
+        <div className="relative flex-1">
+          <Search className="absolute left-3 top-1/2 -translate-y-1/2 h-4 w-4 text-gray-400" />
+          <Input
+            className="pl-9"
+            placeholder="Search output..."
+            value={search}
+            onChange={(e) => setSearch(e.target.value)}
+          />
+        </div>
+        <select
+          className="px-3 py-2 border border-gray-300 rounded-md shadow-sm text-sm"
+          value={filters.status?.[0] ?? ''}
+          onChange={(e) =>
+            setFilters({ ...filters, status: e.target.value ? [e.target.value as ExecutionStatus] : undefined })
+          }
+        >
+          <option value="">All statuses</option>
+          {STATUSES.map((status) => (
+            <option key={status} value={status}>
+              {status}
+            </option>
+          ))}
+        </select>
+      </form>
+
       {/* Executions Table */}
       <Card>
         {executions.length === 0 ? (
           <div className="text-center py-12 text-gray-500">
             <History className="h-16 w-16 mx-auto mb-4 text-gray-400" />
-            <h3 className="text-lg font-medium text-gray-900 mb-2">No executions yet</h3>
-            <p>Execution history will appear here once you run commands</p>
+            {filters.q || filters.status ? (
+              <h3 className="text-lg font-medium text-gray-900 mb-2">No matching executions</h3>
+            ) : (
+              <>
+                <h3 className="text-lg font-medium text-gray-900 mb-2">No executions yet</h3>
+                <p>Execution history will appear here once you run commands</p>
+              </>
+            )}
           </div>
         ) : (
           <div className="overflow-x-auto">
@@ -0,0 +1,425 @@
+import { useEffect, useState } from 'react';
+import { Plus, Pencil, Trash2, ShieldAlert } from 'lucide-react';
+import Button from '@/components/ui/Button';
+import { api } from '@/api/client';
+import { API_ENDPOINTS } from '@/lib/config';
+import type { PolicyAction, PolicyDecision, PolicyMatchType, PolicyRule, UserRole } from '@/types';
+
+const actionLabels: Record<PolicyAction, { label: string; color: string }> = {
+  deny: { label: 'Deny', color: 'bg-red-100 text-red-800' },
+  allow: { label: 'Allow', color: 'bg-green-100 text-green-800' },
+  approval: { label: 'Approval', color: 'bg-yellow-100 text-yellow-800' },
+};
+
+const roles: UserRole[] = ['admin', 'operator', 'viewer'];
+
+export default function Policies() {
+  const [rules, setRules] = useState<PolicyRule[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  // Form states
+  const [showModal, setShowModal] = useState(false);
+  const [editingRule, setEditingRule] = useState<PolicyRule | null>(null);
+  const [formName, setFormName] = useState('');
+  const [formDescription, setFormDescription] = useState('');
+  const [formAction, setFormAction] = useState<PolicyAction>('deny');
+  const [formMatchType, setFormMatchType] = useState<PolicyMatchType>('glob');
+  const [formPattern, setFormPattern] = useState('');
+  const [formRoles, setFormRoles] = useState<UserRole[]>([]);
+  const [formEnabled, setFormEnabled] = useState(true);
+  const [formError, setFormError] = useState<string | null>(null);
+  const [submitting, setSubmitting] = useState(false);
+
+  // Evaluating a command against the rules without running it
+  const [testCommand, setTestCommand] = useState('');
+  const [testRole, setTestRole] = useState<UserRole | ''>('operator');
+  const [decision, setDecision] = useState<PolicyDecision | null>(null);
+  const [testError, setTestError] = useState<string | null>(null);
+
+  const fetchRules = async () => {
+    try {
+      setLoading(true);
+      const data = await api.get<PolicyRule[]>(API_ENDPOINTS.policies);
+      setRules(data || []);
+      setError(null);
+    } catch (err: any) {
+      if (err.status === 403) {
+        setError('You do not have permission to manage command policies');
+      } else {
+        setError(err.message || 'Failed to fetch policy rules');
+      }
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    fetchRules();
+  }, []);
+
+  const openModal = (rule: PolicyRule | null) => {
+    setEditingRule(rule);
+    setFormName(rule?.name || '');
+    setFormDescription(rule?.description || '');
+    setFormAction(rule?.action || 'deny');
+    setFormMatchType(rule?.match_type || 'glob');
+    setFormPattern(rule?.pattern || '');
+    setFormRoles(rule?.roles || []);
+    setFormEnabled(rule ? rule.enabled : true);
+    setFormError(null);
+    setShowModal(true);
+  };
+
+  const handleSave = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setFormError(null);
+    setSubmitting(true);
+
+    try {
+      const body = {
+        name: formName,
+        description: formDescription,
+        action: formAction,
+        match_type: formMatchType,
+        pattern: formPattern,
+        roles: formRoles,
+        enabled: formEnabled,
+      };
+      if (editingRule) {
+        await api.put(API_ENDPOINTS.policy(editingRule.id), body);
+      } else {
+        await api.post(API_ENDPOINTS.policies, body);
+      }
+      setShowModal(false);
+      fetchRules();
+    } catch (err: any) {
+      setFormError(err.message || 'Failed to save policy rule');
+    } finally {
+      setSubmitting(false);
+    }
+  };
+
+  const handleDelete = async (rule: PolicyRule) => {
+    if (!confirm(`Delete policy rule "${rule.name}"?`)) return;
+    try {
+      await api.delete(API_ENDPOINTS.policy(rule.id));
+      fetchRules();
+    } catch (err: any) {
+      setError(err.message || 'Failed to delete policy rule');
+    }
+  };
+
+  const handleToggle = async (rule: PolicyRule) => {
+    try {
+      await api.put(API_ENDPOINTS.policy(rule.id), { enabled: !rule.enabled });
+      fetchRules();
+    } catch (err: any) {
+      setError(err.message || 'Failed to update policy rule');
+    }
+  };
+
+  const handleEvaluate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setTestError(null);
+    setDecision(null);
+    try {
+      const data = await api.post<PolicyDecision>(API_ENDPOINTS.policyEvaluate, {
+        command: testCommand,
+        role: testRole,
+      });
+      setDecision(data);
+    } catch (err: any) {
+      setTestError(err.message || 'Failed to evaluate command');
+    }
+  };
+
+  const toggleRole = (role: UserRole) => {
+    setFormRoles(formRoles.includes(role) ? formRoles.filter((r) => r !== role) : [...formRoles, role]);
+  };
+
+  if (loading) {
+    return (
+      <div className="flex items-center justify-center h-64">
+        <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-blue-600"></div>
+      </div>
+    );
+  }
+
+  if (error) {
+    return (
+      <div className="bg-red-50 border border-red-200 rounded-md p-4">
+        <p className="text-red-800">{error}</p>
+      </div>
+    );
+  }
+
+  return (
+    <div className="space-y-6">
+      <div className="flex justify-between items-center">
+        <div>
+          <h1 className="text-2xl font-bold text-gray-900">Command Policy</h1>
+          <p className="mt-1 text-sm text-gray-600">
+            Deny, allow or require approval of commands, executions, container exec and terminal input
+          </p>
+        </div>
+        <Button onClick={() => openModal(null)}>
+          <Plus className="h-4 w-4 mr-2" />
+          Add Rule
+        </Button>
+      </div>
+
+      {/* Rule Legend */}
+      <div className="bg-white shadow rounded-lg p-4">
+        <h3 className="text-sm font-medium text-gray-700 mb-2">How rules are evaluated</h3>
+        <div className="grid grid-cols-1 md:grid-cols-3 gap-4 text-sm">
+          <div className="flex items-start gap-2">
+            <span className={`inline-flex px-2 py-1 rounded-full text-xs font-medium ${actionLabels.deny.color}`}>Deny</span>
+            <span className="text-gray-600">Rejects every matching command</span>
+          </div>
+          <div className="flex items-start gap-2">
+            <span className={`inline-flex px-2 py-1 rounded-full text-xs font-medium ${actionLabels.allow.color}`}>Allow</span>
+            <span className="text-gray-600">Once a role has allow rules, its commands must match one of them</span>
+          </div>
+          <div className="flex items-start gap-2">
+            <span className={`inline-flex px-2 py-1 rounded-full text-xs font-medium ${actionLabels.approval.color}`}>Approval</span>
+            <span className="text-gray-600">Executions wait for approval; container exec and terminal input are rejected</span>
+          </div>
+        </div>
+        <p className="mt-3 text-xs text-gray-500">
+          Commands chained with ;, &amp;&amp;, || or | are also checked part by part. Rules without roles apply to everyone,
+          including scheduled executions and deploys.
+        </p>
+      </div>
+
+      {/* Rules Table */}
+      <div className="bg-white shadow rounded-lg overflow-hidden">
+        <table className="min-w-full divide-y divide-gray-200">
+          <thead className="bg-gray-50">
+            <tr>
+              <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Rule</th>
+              <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Action</th>
+              <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Pattern</th>
+              <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Roles</th>
+              <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Status</th>
+              <th className="px-6 py-3 text-right text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+            </tr>
+          </thead>
+          <tbody className="bg-white divide-y divide-gray-200">
+            {rules.map((rule) => (
+              <tr key={rule.id} className="hover:bg-gray-50">
+                <td className="px-6 py-4">
+                  <div className="text-sm font-medium text-gray-900">{rule.name}</div>
+                  {rule.description && <div className="text-sm text-gray-500">{rule.description}</div>}
+                </td>
+                <td className="px-6 py-4 whitespace-nowrap">
+                  <span className={`inline-flex px-2.5 py-0.5 rounded-full text-xs font-medium ${actionLabels[rule.action]?.color}`}>
+                    {actionLabels[rule.action]?.label || rule.action}
+                  </span>
+                </td>
+                <td className="px-6 py-4">
+                  <code className="text-sm text-gray-800 break-all">{rule.pattern}</code>
+                  <div className="text-xs text-gray-500">{rule.match_type}</div>
+                </td>
+                <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600">
+                  {rule.roles.length > 0 ? rule.roles.join(', ') : 'All'}
+                </td>
+                <td className="px-6 py-4 whitespace-nowrap">
+                  <button
+                    onClick={() => handleToggle(rule)}
+                    className={`inline-flex px-2.5 py-0.5 rounded-full text-xs font-medium ${
+                      rule.enabled ? 'bg-green-100 text-green-800' : 'bg-gray-100 text-gray-600'
+                    }`}
+                    title={rule.enabled ? 'Disable rule' : 'Enable rule'}
+                  >
+                    {rule.enabled ? 'Enabled' : 'Disabled'}
+                  </button>
+                </td>
+                <td className="px-6 py-4 whitespace-nowrap text-right text-sm font-medium">
+                  <div className="flex justify-end gap-2">
+                    <button onClick={() => openModal(rule)} className="text-blue-600 hover:text-blue-900" title="Edit rule">
+                      <Pencil className="h-4 w-4" />
+                    </button>
+                    <button onClick={() => handleDelete(rule)} className="text-red-600 hover:text-red-900" title="Delete rule">
+                      <Trash2 className="h-4 w-4" />
+                    </button>
+                  </div>
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+
+        {rules.length === 0 && (
+          <div className="text-center py-12">
+            <ShieldAlert className="h-8 w-8 text-gray-400 mx-auto mb-2" />
+            <p className="text-gray-500">No policy rules, every command is allowed</p>
+          </div>
+        )}
+      </div>
+
+      {/* Test a command */}
+      <div className="bg-white shadow rounded-lg p-4">
+        <h3 className="text-sm font-medium text-gray-700 mb-1">Test a Command</h3>
+        <p className="text-sm text-gray-600 mb-3">Evaluates a command against the rules without running it.</p>
+        <form onSubmit={handleEvaluate} className="flex flex-col md:flex-row gap-2">
+          <input
+            type="text"
+            value={testCommand}
+            onChange={(e) => setTestCommand(e.target.value)}
+            placeholder="e.g. rm -rf /var/www"
+            className="flex-1 px-3 py-2 border border-gray-300 rounded-md font-mono text-sm focus:outline-none focus:ring-2 focus:ring-blue-500"
+          />
+          <select
+            value={testRole}
+            onChange={(e) => setTestRole(e.target.value as UserRole | '')}
+            className="px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+          >
+            {roles.map((role) => (
+              <option key={role} value={role}>
+                {role}
+              </option>
+            ))}
+            <option value="">no user (schedules, deploys)</option>
+          </select>
+          <Button type="submit" disabled={!testCommand}>
+            Evaluate
+          </Button>
+        </form>
+        {testError && <p className="mt-2 text-sm text-red-600">{testError}</p>}
+        {decision && (
+          <div
+            className={`mt-3 p-3 rounded-md text-sm border ${
+              !decision.allowed
+                ? 'bg-red-50 border-red-200 text-red-800'
+                : decision.requires_approval
+                  ? 'bg-yellow-50 border-yellow-200 text-yellow-800'
+                  : 'bg-green-50 border-green-200 text-green-800'
+            }`}
+          >
+            {!decision.allowed
+              ? `Rejected: ${decision.reason}`
+              : decision.requires_approval
+                ? `Allowed, executions require approval (rule "${decision.rule}")`
+                : 'Allowed'}
+          </div>
+        )}
+      </div>
+
+      {/* Create/Edit Modal */}
+      {showModal && (
+        <div className="fixed inset-0 bg-black bg-opacity-50 flex items-center justify-center z-50">
+          <div className="bg-white rounded-lg shadow-xl max-w-lg w-full mx-4">
+            <form onSubmit={handleSave}>
+              <div className="p-6">
+                <h2 className="text-lg font-semibold text-gray-900 mb-4">
+                  {editingRule ? 'Edit Policy Rule' : 'Create Policy Rule'}
+                </h2>
+
+                {formError && (
+                  <div className="mb-4 p-3 bg-red-50 border border-red-200 rounded-md text-red-800 text-sm">
+                    {formError}
+                  </div>
+                )}
+
+                <div className="space-y-4">
+                  <div>
+                    <label className="block text-sm font-medium text-gray-700 mb-1">Name</label>
+                    <input
+                      type="text"
+                      value={formName}
+                      onChange={(e) => setFormName(e.target.value)}
+                      className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                      required
+                      maxLength={100}
+                    />
+                  </div>
+
+                  <div>
+                    <label className="block text-sm font-medium text-gray-700 mb-1">Description</label>
+                    <input
+                      type="text"
+                      value={formDescription}
+                      onChange={(e) => setFormDescription(e.target.value)}
+                      className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                    />
+                  </div>
+
+                  <div className="grid grid-cols-2 gap-4">
+                    <div>
+                      <label className="block text-sm font-medium text-gray-700 mb-1">Action</label>
+                      <select
+                        value={formAction}
+                        onChange={(e) => setFormAction(e.target.value as PolicyAction)}
+                        className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                      >
+                        <option value="deny">Deny</option>
+                        <option value="allow">Allow</option>
+                        <option value="approval">Require approval</option>
+                      </select>
+                    </div>
+                    <div>
+                      <label className="block text-sm font-medium text-gray-700 mb-1">Match</label>
+                      <select
+                        value={formMatchType}
+                        onChange={(e) => setFormMatchType(e.target.value as PolicyMatchType)}
+                        className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+                      >
+                        <option value="glob">Glob</option>
+                        <option value="regex">Regex</option>
+                      </select>
+                    </div>
+                  </div>
+
+                  <div>
+                    <label className="block text-sm font-medium text-gray-700 mb-1">Pattern</label>
+                    <input
+                      type="text"
+                      value={formPattern}
+                      onChange={(e) => setFormPattern(e.target.value)}
+                      placeholder={formMatchType === 'glob' ? 'mkfs*' : '\\brm\\s+-\\w*r\\w*\\s+/(\\s|$)'}
+                      className="w-full px-3 py-2 border border-gray-300 rounded-md font-mono text-sm focus:outline-none focus:ring-2 focus:ring-blue-500"
+                      required
+                    />
+                    <p className="mt-1 text-xs text-gray-500">
+                      {formMatchType === 'glob'
+                        ? 'Matches the whole command, * matches any text and ? any single character.'
+                        : 'Matches anywhere in the command, anchor with ^ and $ to match the whole command.'}
+                    </p>
+                  </div>
+
+                  <div>
+                    <label className="block text-sm font-medium text-gray-700 mb-1">Roles</label>
+                    <div className="flex flex-wrap gap-3">
+                      {roles.map((role) => (
+                        <label key={role} className="flex items-center gap-1 text-sm text-gray-700">
+                          <input type="checkbox" checked={formRoles.includes(role)} onChange={() => toggleRole(role)} />
+                          {role}
+                        </label>
+                      ))}
+                    </div>
+                    <p className="mt-1 text-xs text-gray-500">Leave empty to apply the rule to everyone.</p>
+                  </div>
+
+                  <label className="flex items-center gap-2 text-sm text-gray-700">
+                    <input type="checkbox" checked={formEnabled} onChange={(e) => setFormEnabled(e.target.checked)} />
+                    Enabled
+                  </label>
+                </div>
+              </div>
+
+              <div className="px-6 py-4 bg-gray-50 rounded-b-lg flex justify-end gap-3">
+                <Button type="button" variant="secondary" onClick={() => setShowModal(false)}>
+                  Cancel
+                </Button>
+                <Button type="submit" disabled={submitting}>
+                  {submitting ? 'Saving...' : editingRule ? 'Save Rule' : 'Create Rule'}
+                </Button>
+              </div>
+            </form>
+          </div>
+        </div>
+      )}
+    </div>
+  );
+}
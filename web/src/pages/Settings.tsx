@@ -1,10 +1,14 @@
 import { useState, useEffect } from 'react';
-import { Shield, Key, AlertCircle, Lock, Server, Download, RotateCcw, RefreshCw, Database, Trash2 } from 'lucide-react';
+import { Shield, Key, AlertCircle, Lock, Server, Download, RotateCcw, RefreshCw, Database, Trash2, Copy, Plus } from 'lucide-react';
 import { api } from '@/api/client';
 import Button from '@/components/ui/Button';
 import Card from '@/components/ui/Card';
 import Input from '@/components/ui/Input';
 import Modal from '@/components/ui/Modal';
+import { formatDate } from '@/lib/utils';
+import type { APIToken, APITokenScope } from '@/types';
+
+const API_TOKEN_SCOPES: APITokenScope[] = ['read', 'apps', 'execute', 'files', 'containers', 'terminal', 'admin'];
 
 interface TwoFAStatus {
   enabled: boolean;
@@ -91,12 +95,64 @@ export default function Settings() {
   const [vacuuming, setVacuuming] = useState(false);
   const [storageMessage, setStorageMessage] = useState<string | null>(null);
 
+  // Personal API tokens state; the plaintext of a new token is only shown once
+  const [apiTokens, setAPITokens] = useState<APIToken[]>([]);
+  const [isTokenModalOpen, setIsTokenModalOpen] = useState(false);
+  const [tokenName, setTokenName] = useState('');
+  const [tokenScopes, setTokenScopes] = useState<APITokenScope[]>([]);
+  const [tokenExpiry, setTokenExpiry] = useState('');
+  const [tokenError, setTokenError] = useState<string | null>(null);
+  const [creatingToken, setCreatingToken] = useState(false);
+  const [newToken, setNewToken] = useState<APIToken | null>(null);
+
   useEffect(() => {
     fetchStatus();
     fetchSystemStatus();
     fetchStorageInfo();
+    fetchAPITokens();
   }, []);
 
+  const fetchAPITokens = async () => {
+    try {
+      const data = await api.get<APIToken[]>('/api/auth/tokens');
+      setAPITokens(data || []);
+    } catch (error) {
+      console.error('Failed to fetch API tokens:', error);
+    }
+  };
+
+  const handleCreateToken = async () => {
+    setCreatingToken(true);
+    setTokenError(null);
+    try {
+      const created = await api.post<APIToken>('/api/auth/tokens', {
+        name: tokenName,
+        scopes: tokenScopes,
+        expires_at: tokenExpiry ? new Date(tokenExpiry).toISOString() : undefined,
+      });
+      setIsTokenModalOpen(false);
+      setTokenName('');
+      setTokenScopes([]);
+      setTokenExpiry('');
+      setNewToken(created || null);
+      fetchAPITokens();
+    } catch (error: any) {
+      setTokenError(error.message || 'Failed to create token');
+    } finally {
+      setCreatingToken(false);
+    }
+  };
+
+  const handleRevokeToken = async (token: APIToken) => {
+    if (!confirm(`Revoke API token "${token.name}"?`)) return;
+    try {
+      await api.delete(`/api/auth/tokens/${token.id}`);
+      fetchAPITokens();
+    } catch (error: any) {
+      console.error('Failed to revoke API token:', error);
+    }
+  };
+
   const fetchStatus = async () => {
     try {
       const data = await api.get<TwoFAStatus>('/api/2fa/status');
@@ -603,6 +659,137 @@ export default function Settings() {
         </div>
       </Card>
 
+      {/* Personal API Tokens */}
+      <Card className="p-6">
+        <div className="flex items-start space-x-4">
+          <div className="flex-shrink-0">
+            <div className="w-12 h-12 bg-indigo-100 rounded-lg flex items-center justify-center">
+              <Key className="h-6 w-6 text-indigo-600" />
+            </div>
+          </div>
+          <div className="flex-1">
+            <h3 className="text-lg font-semibold text-gray-900">API Tokens</h3>
+            <p className="text-sm text-gray-600 mt-1">
+              Personal tokens for scripting the API with <code>Authorization: Bearer &lt;token&gt;</code>.
+              A token acts with your role, limited to its scopes if any are selected.
+            </p>
+
+            <div className="mt-4 bg-white rounded border divide-y divide-gray-200">
+              {apiTokens.map((token) => (
+                <div key={token.id} className="flex items-center justify-between px-3 py-2 text-sm">
+                  <div>
+                    <span className="font-medium text-gray-900">{token.name}</span>
+                    <code className="ml-2 text-gray-500">{token.prefix}…</code>
+                    <span className="ml-2 text-gray-500">
+                      {token.scopes.length > 0 ? token.scopes.join(', ') : 'full role'}
+                    </span>
+                    <p className="text-xs text-gray-500">
+                      Expires {formatDate(token.expires_at)}
+                      {' · '}
+                      {token.last_used_at ? `Last used ${formatDate(token.last_used_at)} from ${token.last_used_ip}` : 'Never used'}
+                    </p>
+                  </div>
+                  <Button variant="ghost" size="sm" onClick={() => handleRevokeToken(token)}>
+                    <Trash2 className="h-4 w-4 text-red-600" />
+                  </Button>
+                </div>
+              ))}
+              {apiTokens.length === 0 && (
+                <p className="px-3 py-2 text-sm text-gray-500">No API tokens</p>
+              )}
+            </div>
+
+            <div className="mt-4">
+              <Button
+                variant="primary"
+                size="sm"
+                onClick={() => {
+                  setTokenError(null);
+                  setIsTokenModalOpen(true);
+                }}
+              >
+                <Plus className="h-4 w-4 mr-2" />
+                New Token
+              </Button>
+            </div>
+          </div>
+        </div>
+      </Card>
+
+      {/* Create API Token Modal */}
+      <Modal isOpen={isTokenModalOpen} onClose={() => setIsTokenModalOpen(false)} title="Create API Token">
+        <div className="space-y-4">
+          {tokenError && (
+            <div className="bg-red-50 border border-red-200 text-red-700 px-4 py-3 rounded-md text-sm">
+              {tokenError}
+            </div>
+          )}
+          <Input
+            label="Name"
+            required
+            value={tokenName}
+            onChange={(e) => setTokenName(e.target.value)}
+            placeholder="e.g., ansible"
+          />
+          <div>
+            <span className="block text-sm font-medium text-gray-700 mb-1">Scopes (none selected uses your full role)</span>
+            {API_TOKEN_SCOPES.map((scope) => (
+              <label key={scope} className="inline-flex items-center space-x-2 text-sm text-gray-600 mr-4">
+                <input
+                  type="checkbox"
+                  checked={tokenScopes.includes(scope)}
+                  onChange={() =>
+                    setTokenScopes(
+                      tokenScopes.includes(scope) ? tokenScopes.filter((s) => s !== scope) : [...tokenScopes, scope]
+                    )
+                  }
+                  className="rounded border-gray-300 text-blue-600 focus:ring-blue-500"
+                />
+                <span>{scope}</span>
+              </label>
+            ))}
+          </div>
+          <Input
+            label="Expires at (default: 90 days)"
+            type="datetime-local"
+            value={tokenExpiry}
+            onChange={(e) => setTokenExpiry(e.target.value)}
+          />
+          <div className="flex justify-end space-x-3">
+            <Button variant="secondary" onClick={() => setIsTokenModalOpen(false)}>
+              Cancel
+            </Button>
+            <Button variant="primary" onClick={handleCreateToken} loading={creatingToken} disabled={!tokenName}>
+              Create Token
+            </Button>
+          </div>
+        </div>
+      </Modal>
+
+      {/* New API Token, shown once */}
+      <Modal isOpen={!!newToken} onClose={() => setNewToken(null)} title={`API token "${newToken?.name}"`}>
+        <div className="space-y-4">
+          <p className="text-sm text-gray-600">
+            Copy the token now. It is stored hashed and cannot be shown again.
+          </p>
+          <div className="flex items-center space-x-2">
+            <code className="bg-gray-100 px-3 py-1 rounded border text-sm flex-1 break-all">{newToken?.token}</code>
+            <Button
+              variant="secondary"
+              size="sm"
+              onClick={() => newToken?.token && navigator.clipboard.writeText(newToken.token)}
+            >
+              <Copy className="h-4 w-4" />
+            </Button>
+          </div>
+          <div className="flex justify-end">
+            <Button variant="primary" onClick={() => setNewToken(null)}>
+              Done
+            </Button>
+          </div>
+        </div>
+      </Modal>
+
       {/* Two-Factor Authentication */}
       <Card className="p-6">
         <div className="flex items-start space-x-4">
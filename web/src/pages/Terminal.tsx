@@ -1,10 +1,12 @@
 import { useEffect, useRef, useState, useCallback } from 'react';
 import { Terminal as XTerm } from '@xterm/xterm';
 import { FitAddon } from '@xterm/addon-fit';
-import { Maximize2, Minimize2, Plus, X, Circle, Settings, RefreshCw, Trash2 } from 'lucide-react';
+import { Link } from 'react-router-dom';
+import { Maximize2, Minimize2, Plus, X, Circle, Settings, RefreshCw, Trash2, Film, Users, UserMinus, Eye } from 'lucide-react';
 import '@xterm/xterm/css/xterm.css';
 import { api } from '@/api/client';
-import { getPathPrefix } from '@/lib/config';
+import { API_ENDPOINTS, getPathPrefix } from '@/lib/config';
+import type { TerminalLimits, TerminalParticipant, TerminalParticipantRole, TerminalPresence } from '@/types';
 
 interface ServerSession {
   id: string;
@@ -13,6 +15,10 @@ interface ServerSession {
   last_activity: string;
   client_count: number;
   is_active: boolean;
+  owner?: string;
+  role?: TerminalParticipantRole;
+  shell: string;
+  expires_at?: string;
 }
 
 interface TerminalSession {
@@ -24,9 +30,43 @@ interface TerminalSession {
   fitAddon: FitAddon | null;
   isConnected: boolean;
   isPersistent: boolean;
+  role: TerminalParticipantRole;
+  owner?: string;
+  presence: TerminalPresence[];
 }
 
 const PERSISTENT_MODE_KEY = 'terminal_persistent_mode';
+const SHELL_KEY = 'terminal_shell';
+
+function formatSeconds(seconds: number): string {
+  if (seconds % 3600 === 0) return `${seconds / 3600}h`;
+  if (seconds % 60 === 0) return `${seconds / 60}m`;
+  return `${seconds}s`;
+}
+
+// Messages for the reasons the server gives when it disconnects a client
+function closeMessage(reason: string): string | null {
+  switch (reason) {
+    case 'removed from session':
+      return 'You were removed from this session by its owner.';
+    case 'idle timeout':
+      return 'Disconnected after being idle for too long.';
+    default:
+      return null;
+  }
+}
+
+// Tell the server the size of the terminal; all other messages are input
+function sendResize(ws: WebSocket, cols: number, rows: number) {
+  if (ws.readyState === WebSocket.OPEN) {
+    ws.send(JSON.stringify({ type: 'resize', cols, rows }));
+  }
+}
+
+// Observers only watch a session, so their terminal does not take input
+function applyRole(xterm: XTerm, role: TerminalParticipantRole) {
+  xterm.options.disableStdin = role === 'observer';
+}
 
 function generateSessionId(): string {
   return `local-${Date.now()}-${Math.random().toString(36).substr(2, 9)}`;
@@ -45,15 +85,25 @@ function Terminal() {
   const [showSettings, setShowSettings] = useState(false);
   const [serverSessions, setServerSessions] = useState<ServerSession[]>([]);
   const [loadingServerSessions, setLoadingServerSessions] = useState(false);
+  const [limits, setLimits] = useState<TerminalLimits | null>(null);
+  const [shells, setShells] = useState<string[]>([]);
+  const [shell, setShell] = useState(() => localStorage.getItem(SHELL_KEY) || '');
+  const [showShare, setShowShare] = useState(false);
+  const [participants, setParticipants] = useState<TerminalParticipant[]>([]);
+  const [inviteUsername, setInviteUsername] = useState('');
+  const [inviteRole, setInviteRole] = useState<'observer' | 'driver'>('observer');
+  const [shareError, setShareError] = useState('');
 
   // Fetch server-side sessions
   const fetchServerSessions = useCallback(async () => {
-    if (!persistentMode) return;
-
     setLoadingServerSessions(true);
     try {
-      const response = await api.get<{ sessions: ServerSession[] }>('/api/terminal/sessions');
+      const response = await api.get<{ sessions: ServerSession[]; limits: TerminalLimits; shells: string[] }>(
+        '/api/terminal/sessions'
+      );
       setServerSessions(response.sessions || []);
+      setLimits(response.limits);
+      setShells(response.shells || []);
     } catch (error) {
       console.error('Failed to fetch server sessions:', error);
     } finally {
@@ -78,6 +128,8 @@ function Terminal() {
       fitAddon: null,
       isConnected: false,
       isPersistent: persistentMode,
+      role: 'owner',
+      presence: [],
     };
 
     setSessions((prev) => [...prev, newSession]);
@@ -118,6 +170,9 @@ function Terminal() {
     let wsUrl = `${protocol}//${window.location.host}${pathPrefix}/api/terminal/ws`;
 
     const params = new URLSearchParams();
+    if (shell && !session.serverId) {
+      params.set('shell', shell);
+    }
     if (session.isPersistent) {
       params.set('persistent', 'true');
       if (session.serverId) {
@@ -132,6 +187,7 @@ function Terminal() {
     const ws = new WebSocket(wsUrl);
 
     ws.onopen = () => {
+      sendResize(ws, xterm.cols, xterm.rows);
       const modeText = session.isPersistent ? 'persistent' : 'ephemeral';
       xterm.writeln(`\x1b[1;32mConnected to remote terminal (${modeText} mode)\x1b[0m\r\n`);
       setSessions((prev) =>
@@ -146,10 +202,12 @@ function Terminal() {
           try {
             const info = JSON.parse(data);
             if (info.session && info.session.id) {
+              const role: TerminalParticipantRole = info.session.role || 'owner';
+              applyRole(xterm, role);
               setSessions((prev) =>
                 prev.map((s) =>
                   s.id === sessionId
-                    ? { ...s, serverId: info.session.id, name: info.session.name || s.name }
+                    ? { ...s, serverId: info.session.id, name: info.session.name || s.name, role, owner: info.session.owner }
                     : s
                 )
               );
@@ -158,6 +216,22 @@ function Terminal() {
             // Not JSON, treat as terminal output
             xterm.write(data);
           }
+        } else if (data.startsWith('{"type":"presence"')) {
+          try {
+            const presence = JSON.parse(data);
+            setSessions((prev) =>
+              prev.map((s) => (s.id === sessionId ? { ...s, presence: presence.participants || [] } : s))
+            );
+          } catch {
+            xterm.write(data);
+          }
+        } else if (data.startsWith('{"type":"policy_violation"')) {
+          try {
+            const violation = JSON.parse(data);
+            xterm.writeln(`\r\n\x1b[1;31m${violation.error}\x1b[0m`);
+          } catch {
+            xterm.write(data);
+          }
         } else {
           xterm.write(data);
         }
@@ -177,9 +251,12 @@ function Terminal() {
       xterm.writeln('\r\n\x1b[1;31mWebSocket error occurred\x1b[0m\r\n');
     };
 
-    ws.onclose = () => {
+    ws.onclose = (event) => {
       const session = sessions.find((s) => s.id === sessionId);
-      if (session?.isPersistent) {
+      const message = closeMessage(event.reason);
+      if (message) {
+        xterm.writeln(`\r\n\x1b[1;31m${message}\x1b[0m\r\n`);
+      } else if (session?.isPersistent) {
         xterm.writeln('\r\n\x1b[1;33mConnection closed. Session is still running on server.\x1b[0m');
         xterm.writeln('\x1b[1;33mReconnect to resume.\x1b[0m\r\n');
       } else {
@@ -196,6 +273,7 @@ function Terminal() {
         ws.send(data);
       }
     });
+    xterm.onResize(({ cols, rows }) => sendResize(ws, cols, rows));
 
     // Update session with terminal instances
     setSessions((prev) =>
@@ -203,7 +281,8 @@ function Terminal() {
         s.id === sessionId ? { ...s, xterm, ws, fitAddon } : s
       )
     );
-  }, [sessions]);
+    applyRole(xterm, session.role);
+  }, [sessions, shell]);
 
   // Reconnect to a server session
   const reconnectSession = useCallback((sessionId: string) => {
@@ -231,6 +310,7 @@ function Terminal() {
       const ws = new WebSocket(wsUrl);
 
       ws.onopen = () => {
+        if (session.xterm) sendResize(ws, session.xterm.cols, session.xterm.rows);
         session.xterm?.writeln('\x1b[1;32mReconnected!\x1b[0m\r\n');
         setSessions((prev) =>
           prev.map((s) => (s.id === sessionId ? { ...s, ws, isConnected: true } : s))
@@ -243,10 +323,21 @@ function Terminal() {
             const decoder = new TextDecoder();
             session.xterm?.write(decoder.decode(buffer));
           });
+        } else if (event.data.startsWith('{"type":"presence"')) {
+          const presence = JSON.parse(event.data);
+          setSessions((prev) =>
+            prev.map((s) => (s.id === sessionId ? { ...s, presence: presence.participants || [] } : s))
+          );
+        } else if (event.data.startsWith('{"type":"session_info"')) {
+          const info = JSON.parse(event.data);
+          const role: TerminalParticipantRole = info.session?.role || 'owner';
+          if (session.xterm) applyRole(session.xterm, role);
+          setSessions((prev) => prev.map((s) => (s.id === sessionId ? { ...s, role } : s)));
+        } else if (event.data.startsWith('{"type":"policy_violation"')) {
+          const violation = JSON.parse(event.data);
+          session.xterm?.writeln(`\r\n\x1b[1;31m${violation.error}\x1b[0m`);
         } else {
-          if (!event.data.startsWith('{"type":"session_info"')) {
-            session.xterm?.write(event.data);
-          }
+          session.xterm?.write(event.data);
         }
       };
 
@@ -254,8 +345,13 @@ function Terminal() {
         session.xterm?.writeln('\r\n\x1b[1;31mReconnection failed\x1b[0m\r\n');
       };
 
-      ws.onclose = () => {
-        session.xterm?.writeln('\r\n\x1b[1;33mConnection closed\x1b[0m\r\n');
+      ws.onclose = (event) => {
+        const message = closeMessage(event.reason);
+        if (message) {
+          session.xterm?.writeln(`\r\n\x1b[1;31m${message}\x1b[0m\r\n`);
+        } else {
+          session.xterm?.writeln('\r\n\x1b[1;33mConnection closed\x1b[0m\r\n');
+        }
         setSessions((prev) =>
           prev.map((s) => (s.id === sessionId ? { ...s, isConnected: false } : s))
         );
@@ -266,6 +362,7 @@ function Terminal() {
           ws.send(data);
         }
       });
+      session.xterm.onResize(({ cols, rows }) => sendResize(ws, cols, rows));
 
       setSessions((prev) =>
         prev.map((s) => (s.id === sessionId ? { ...s, ws } : s))
@@ -281,7 +378,7 @@ function Terminal() {
       session.xterm?.dispose();
 
       // Delete persistent session on server if requested
-      if (deleteOnServer && session.serverId && session.isPersistent) {
+      if (deleteOnServer && session.serverId && session.isPersistent && session.role === 'owner') {
         try {
           await api.delete(`/api/terminal/sessions/${session.serverId}`);
         } catch (error) {
@@ -324,12 +421,61 @@ function Terminal() {
       fitAddon: null,
       isConnected: false,
       isPersistent: true,
+      role: serverSession.role || 'owner',
+      owner: serverSession.owner,
+      presence: [],
     };
 
     setSessions((prev) => [...prev, newSession]);
     setActiveSessionId(sessionId);
   }, [sessions]);
 
+  // Fetch the participants of the active session
+  const activeServerId = sessions.find((s) => s.id === activeSessionId)?.serverId;
+  const fetchParticipants = useCallback(async () => {
+    if (!activeServerId) return;
+    try {
+      const response = await api.get<{ participants: TerminalParticipant[] }>(
+        API_ENDPOINTS.terminalParticipants(activeServerId)
+      );
+      setParticipants(response.participants || []);
+    } catch (error) {
+      console.error('Failed to fetch participants:', error);
+    }
+  }, [activeServerId]);
+
+  const inviteParticipant = useCallback(async () => {
+    if (!activeServerId || !inviteUsername.trim()) return;
+    setShareError('');
+    try {
+      await api.post(API_ENDPOINTS.terminalParticipants(activeServerId), {
+        username: inviteUsername.trim(),
+        role: inviteRole,
+      });
+      setInviteUsername('');
+      fetchParticipants();
+    } catch (error) {
+      setShareError(error instanceof Error ? error.message : 'Failed to invite user');
+    }
+  }, [activeServerId, inviteUsername, inviteRole, fetchParticipants]);
+
+  const kickParticipant = useCallback(async (userId: number) => {
+    if (!activeServerId) return;
+    try {
+      await api.delete(API_ENDPOINTS.terminalParticipant(activeServerId, userId));
+      fetchParticipants();
+    } catch (error) {
+      setShareError(error instanceof Error ? error.message : 'Failed to remove user');
+    }
+  }, [activeServerId, fetchParticipants]);
+
+  useEffect(() => {
+    if (showShare) {
+      setShareError('');
+      fetchParticipants();
+    }
+  }, [showShare, fetchParticipants]);
+
   // Toggle persistent mode
   const togglePersistentMode = useCallback((enabled: boolean) => {
     setPersistentMode(enabled);
@@ -346,11 +492,9 @@ function Terminal() {
     }
   }, []);
 
-  // Fetch server sessions when persistent mode is enabled
+  // Fetch server sessions, limits and shells
   useEffect(() => {
-    if (persistentMode) {
-      fetchServerSessions();
-    }
+    fetchServerSessions();
   }, [persistentMode, fetchServerSessions]);
 
   // Initialize terminal when container is available
@@ -436,15 +580,24 @@ function Terminal() {
               {persistentMode && ' Sessions persist even when you navigate away.'}
             </p>
           </div>
-          <button
-            onClick={() => setShowSettings(!showSettings)}
-            className={`p-2 rounded-md transition-colors ${
-              showSettings ? 'bg-blue-100 text-blue-700' : 'text-gray-500 hover:bg-gray-100'
-            }`}
-            title="Terminal Settings"
-          >
-            <Settings className="h-5 w-5" />
-          </button>
+          <div className="flex items-center space-x-1">
+            <Link
+              to="/terminal/recordings"
+              className="p-2 rounded-md text-gray-500 hover:bg-gray-100 transition-colors"
+              title="Recordings"
+            >
+              <Film className="h-5 w-5" />
+            </Link>
+            <button
+              onClick={() => setShowSettings(!showSettings)}
+              className={`p-2 rounded-md transition-colors ${
+                showSettings ? 'bg-blue-100 text-blue-700' : 'text-gray-500 hover:bg-gray-100'
+              }`}
+              title="Terminal Settings"
+            >
+              <Settings className="h-5 w-5" />
+            </button>
+          </div>
         </div>
       )}
 
@@ -474,6 +627,35 @@ function Terminal() {
             </button>
           </div>
 
+          {shells.length > 1 && (
+            <div className="flex items-center justify-between">
+              <div>
+                <label className="text-sm font-medium text-gray-700">Shell</label>
+                <p className="text-xs text-gray-500">Shell of new sessions</p>
+              </div>
+              <select
+                value={shell || shells[0]}
+                onChange={(e) => {
+                  setShell(e.target.value);
+                  localStorage.setItem(SHELL_KEY, e.target.value);
+                }}
+                className="rounded-md border-gray-300 text-sm"
+              >
+                {shells.map((s) => (
+                  <option key={s} value={s}>{s}</option>
+                ))}
+              </select>
+            </div>
+          )}
+
+          {limits && (
+            <p className="text-xs text-gray-500">
+              Up to {limits.max_sessions} sessions, closed after {formatSeconds(limits.session_ttl)} without activity
+              {limits.max_lifetime > 0 && ` or ${formatSeconds(limits.max_lifetime)} after they start`}
+              {limits.idle_timeout > 0 && `; your connections are closed after ${formatSeconds(limits.idle_timeout)} without activity`}.
+            </p>
+          )}
+
           {/* Server Sessions List */}
           {persistentMode && (
             <div className="border-t pt-4">
@@ -508,8 +690,18 @@ function Terminal() {
                           />
                           <span className="text-sm font-medium">{serverSession.name}</span>
                           <span className="text-xs text-gray-500">
-                            ({serverSession.client_count} clients)
+                            ({serverSession.client_count} clients, {serverSession.shell})
                           </span>
+                          {serverSession.expires_at && (
+                            <span className="text-xs text-gray-500">
+                              ends {new Date(serverSession.expires_at).toLocaleTimeString()}
+                            </span>
+                          )}
+                          {serverSession.role && serverSession.role !== 'owner' && (
+                            <span className="text-xs text-blue-600">
+                              shared by {serverSession.owner} ({serverSession.role})
+                            </span>
+                          )}
                         </div>
                         <div className="flex items-center gap-1">
                           {!isAttached && (
@@ -520,25 +712,27 @@ function Terminal() {
                               Attach
                             </button>
                           )}
-                          <button
-                            onClick={async () => {
-                              try {
-                                await api.delete(`/api/terminal/sessions/${serverSession.id}`);
-                                fetchServerSessions();
-                                // Close local session if attached
-                                const localSession = sessions.find((s) => s.serverId === serverSession.id);
-                                if (localSession) {
-                                  closeSession(localSession.id, false);
+                          {(serverSession.role ?? 'owner') === 'owner' && (
+                            <button
+                              onClick={async () => {
+                                try {
+                                  await api.delete(`/api/terminal/sessions/${serverSession.id}`);
+                                  fetchServerSessions();
+                                  // Close local session if attached
+                                  const localSession = sessions.find((s) => s.serverId === serverSession.id);
+                                  if (localSession) {
+                                    closeSession(localSession.id, false);
+                                  }
+                                } catch (error) {
+                                  console.error('Failed to delete session:', error);
                                 }
-                              } catch (error) {
-                                console.error('Failed to delete session:', error);
-                              }
-                            }}
-                            className="p-1 text-red-500 hover:text-red-700"
-                            title="Delete session"
-                          >
-                            <Trash2 className="h-4 w-4" />
-                          </button>
+                              }}
+                              className="p-1 text-red-500 hover:text-red-700"
+                              title="Delete session"
+                            >
+                              <Trash2 className="h-4 w-4" />
+                            </button>
+                          )}
                         </div>
                       </div>
                     );
@@ -636,6 +830,35 @@ function Terminal() {
           <div className={`flex items-center gap-2 px-3 ${isFullscreen ? 'border-l border-gray-700' : 'border-l border-gray-200'}`}>
             {activeSession && (
               <>
+                {activeSession.role === 'observer' && (
+                  <span className="inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-gray-100 text-gray-800">
+                    <Eye className="h-3 w-3 mr-1" />
+                    Read-only
+                  </span>
+                )}
+                {activeSession.presence.length > 1 && (
+                  <span
+                    className={`text-xs ${isFullscreen ? 'text-gray-300' : 'text-gray-600'}`}
+                    title={activeSession.presence.map((p) => `${p.username} (${p.role})`).join(', ')}
+                  >
+                    {activeSession.presence.map((p) => p.username).join(', ')}
+                  </span>
+                )}
+                {activeSession.isPersistent && activeSession.serverId && activeSession.role === 'owner' && (
+                  <button
+                    onClick={() => setShowShare(!showShare)}
+                    className={`p-1 rounded-md transition-colors ${
+                      showShare
+                        ? 'bg-blue-100 text-blue-700'
+                        : isFullscreen
+                        ? 'text-gray-300 hover:text-white hover:bg-gray-700'
+                        : 'text-gray-500 hover:text-gray-700 hover:bg-gray-100'
+                    }`}
+                    title="Share session"
+                  >
+                    <Users className="h-4 w-4" />
+                  </button>
+                )}
                 {activeSession.isPersistent && (
                   <span className={`inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium ${
                     isFullscreen ? 'bg-blue-900 text-blue-200' : 'bg-blue-100 text-blue-800'
@@ -670,6 +893,64 @@ function Terminal() {
           </div>
         </div>
 
+        {/* Share Panel */}
+        {showShare && activeSession?.serverId && activeSession.role === 'owner' && !isFullscreen && (
+          <div className="border-b border-gray-200 p-4 space-y-3">
+            <div className="flex items-center gap-2">
+              <input
+                type="text"
+                value={inviteUsername}
+                onChange={(e) => setInviteUsername(e.target.value)}
+                onKeyDown={(e) => e.key === 'Enter' && inviteParticipant()}
+                placeholder="Username"
+                className="rounded-md border-gray-300 text-sm"
+              />
+              <select
+                value={inviteRole}
+                onChange={(e) => setInviteRole(e.target.value as 'observer' | 'driver')}
+                className="rounded-md border-gray-300 text-sm"
+              >
+                <option value="observer">Observer (read-only)</option>
+                <option value="driver">Co-driver</option>
+              </select>
+              <button
+                onClick={inviteParticipant}
+                className="px-3 py-1.5 text-sm bg-blue-600 text-white rounded-md hover:bg-blue-700"
+              >
+                Invite
+              </button>
+            </div>
+            {shareError && <p className="text-sm text-red-600">{shareError}</p>}
+            {participants.length === 0 ? (
+              <p className="text-sm text-gray-500">Nobody else is invited into this session</p>
+            ) : (
+              <div className="space-y-1">
+                {participants.map((participant) => {
+                  const online = activeSession.presence.some((p) => p.user_id === participant.user_id);
+                  return (
+                    <div key={participant.user_id} className="flex items-center justify-between p-2 bg-gray-50 rounded-md">
+                      <div className="flex items-center gap-2">
+                        <Circle
+                          className={`h-2 w-2 ${online ? 'text-green-500 fill-green-500' : 'text-gray-400 fill-gray-400'}`}
+                        />
+                        <span className="text-sm font-medium">{participant.username}</span>
+                        <span className="text-xs text-gray-500">{participant.role}</span>
+                      </div>
+                      <button
+                        onClick={() => kickParticipant(participant.user_id)}
+                        className="p-1 text-red-500 hover:text-red-700"
+                        title="Remove from session"
+                      >
+                        <UserMinus className="h-4 w-4" />
+                      </button>
+                    </div>
+                  );
+                })}
+              </div>
+            )}
+          </div>
+        )}
+
         {/* Terminal Container */}
         <div className={`${isFullscreen ? 'flex-1' : 'p-4'}`}>
           {sessions.map((session) => (
@@ -0,0 +1,245 @@
+import { useEffect, useRef, useState, useCallback } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import { Terminal as XTerm } from '@xterm/xterm';
+import { ArrowLeft, Film, Play, Square } from 'lucide-react';
+import '@xterm/xterm/css/xterm.css';
+import { api } from '@/api/client';
+import { API_ENDPOINTS, getApiUrl } from '@/lib/config';
+import { formatDate, formatDuration } from '@/lib/utils';
+import type { TerminalRecording } from '@/types';
+import Card from '@/components/ui/Card';
+
+// An asciicast v2 event: seconds since the start, event type and data
+type CastEvent = [number, string, string];
+
+interface Cast {
+  width: number;
+  height: number;
+  events: CastEvent[];
+}
+
+function parseCast(text: string): Cast {
+  const lines = text.split('\n').filter((line) => line.trim() !== '');
+  const header = JSON.parse(lines[0] || '{}');
+  const events: CastEvent[] = [];
+  for (const line of lines.slice(1)) {
+    try {
+      events.push(JSON.parse(line));
+    } catch {
+      // A recording that is still being written may end with a partial line
+    }
+  }
+  return { width: header.width || 80, height: header.height || 24, events };
+}
+
+function formatSize(bytes: number): string {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+}
+
+export default function TerminalRecordings() {
+  const [searchParams, setSearchParams] = useSearchParams();
+  const [recordings, setRecordings] = useState<TerminalRecording[]>([]);
+  const [enabled, setEnabled] = useState(true);
+  const [loading, setLoading] = useState(true);
+  const [speed, setSpeed] = useState(1);
+  const [playing, setPlaying] = useState(false);
+  const [error, setError] = useState('');
+  const playerRef = useRef<HTMLDivElement>(null);
+  const xtermRef = useRef<XTerm | null>(null);
+  const timersRef = useRef<number[]>([]);
+
+  const selectedId = searchParams.get('id');
+
+  useEffect(() => {
+    const fetchRecordings = async () => {
+      try {
+        const data = await api.get<{ recordings: TerminalRecording[]; enabled: boolean }>(
+          API_ENDPOINTS.terminalRecordings
+        );
+        setRecordings(data.recordings || []);
+        setEnabled(data.enabled);
+      } catch (err) {
+        console.error('Failed to fetch recordings:', err);
+      } finally {
+        setLoading(false);
+      }
+    };
+    fetchRecordings();
+  }, []);
+
+  const stop = useCallback(() => {
+    timersRef.current.forEach((timer) => window.clearTimeout(timer));
+    timersRef.current = [];
+    setPlaying(false);
+  }, []);
+
+  const play = useCallback(async (id: string) => {
+    stop();
+    setError('');
+    xtermRef.current?.dispose();
+    xtermRef.current = null;
+
+    let cast: Cast;
+    try {
+      const response = await fetch(getApiUrl(API_ENDPOINTS.terminalRecording(id)), { credentials: 'include' });
+      if (!response.ok) {
+        throw new Error(response.status === 404 ? 'Recording not found' : response.statusText);
+      }
+      cast = parseCast(await response.text());
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to load recording');
+      return;
+    }
+    if (!playerRef.current) return;
+
+    const xterm = new XTerm({
+      cols: cast.width,
+      rows: cast.height,
+      fontSize: 14,
+      fontFamily: 'Menlo, Monaco, "Courier New", monospace',
+      theme: {
+        background: '#1e1e1e',
+        foreground: '#d4d4d4',
+      },
+      disableStdin: true,
+    });
+    xterm.open(playerRef.current);
+    xtermRef.current = xterm;
+
+    setPlaying(true);
+    for (const [time, type, data] of cast.events) {
+      timersRef.current.push(
+        window.setTimeout(() => {
+          if (type === 'o') {
+            xterm.write(data);
+          } else if (type === 'r') {
+            const [cols, rows] = data.split('x').map(Number);
+            if (cols > 0 && rows > 0) xterm.resize(cols, rows);
+          }
+        }, (time * 1000) / speed)
+      );
+    }
+    const end = cast.events.length > 0 ? cast.events[cast.events.length - 1][0] : 0;
+    timersRef.current.push(window.setTimeout(() => setPlaying(false), (end * 1000) / speed));
+  }, [speed, stop]);
+
+  useEffect(() => {
+    if (selectedId && !loading) {
+      play(selectedId);
+    }
+  }, [selectedId, loading]);
+
+  useEffect(() => {
+    return () => {
+      stop();
+      xtermRef.current?.dispose();
+    };
+  }, [stop]);
+
+  if (loading) {
+    return (
+      <div className="flex items-center justify-center h-64">
+        <div className="animate-spin rounded-full h-12 w-12 border-b-2 border-blue-600"></div>
+      </div>
+    );
+  }
+
+  return (
+    <div className="space-y-6">
+      <div className="flex items-center justify-between">
+        <div>
+          <h1 className="text-2xl font-bold text-gray-900">Terminal Recordings</h1>
+          <p className="mt-1 text-sm text-gray-600">
+            Play back recorded terminal sessions.
+            {!enabled && ' Recording is disabled, so new sessions are not recorded.'}
+          </p>
+        </div>
+        <Link to="/terminal" className="inline-flex items-center text-sm text-blue-600 hover:text-blue-800">
+          <ArrowLeft className="h-4 w-4 mr-1" />
+          Back to terminal
+        </Link>
+      </div>
+
+      {selectedId && (
+        <Card>
+          <div className="flex items-center justify-between mb-4">
+            <h3 className="text-lg font-medium text-gray-900">Playback</h3>
+            <div className="flex items-center space-x-2">
+              <select
+                value={speed}
+                onChange={(e) => setSpeed(Number(e.target.value))}
+                className="rounded-md border-gray-300 text-sm"
+              >
+                {[1, 2, 4, 8].map((s) => (
+                  <option key={s} value={s}>{s}x</option>
+                ))}
+              </select>
+              {playing ? (
+                <button onClick={stop} className="inline-flex items-center px-3 py-1.5 rounded-md text-sm bg-gray-100 hover:bg-gray-200">
+                  <Square className="h-4 w-4 mr-1" />
+                  Stop
+                </button>
+              ) : (
+                <button onClick={() => play(selectedId)} className="inline-flex items-center px-3 py-1.5 rounded-md text-sm bg-blue-600 text-white hover:bg-blue-700">
+                  <Play className="h-4 w-4 mr-1" />
+                  Replay
+                </button>
+              )}
+            </div>
+          </div>
+          {error && <p className="text-sm text-red-600 mb-2">{error}</p>}
+          <div ref={playerRef} className="overflow-auto bg-[#1e1e1e] rounded p-2" />
+        </Card>
+      )}
+
+      <Card>
+        {recordings.length === 0 ? (
+          <div className="text-center py-12 text-gray-500">
+            <Film className="h-16 w-16 mx-auto mb-4 text-gray-400" />
+            <h3 className="text-lg font-medium text-gray-900 mb-2">No recordings yet</h3>
+            <p>Recorded terminal sessions will appear here</p>
+          </div>
+        ) : (
+          <div className="overflow-x-auto">
+            <table className="min-w-full divide-y divide-gray-200">
+              <thead className="bg-gray-50">
+                <tr>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">User</th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Session</th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Started</th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Duration</th>
+                  <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Size</th>
+                  <th className="px-6 py-3" />
+                </tr>
+              </thead>
+              <tbody className="bg-white divide-y divide-gray-200">
+                {recordings.map((recording) => (
+                  <tr key={recording.id} className={recording.id === selectedId ? 'bg-blue-50' : 'hover:bg-gray-50'}>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900">{recording.username}</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600 font-mono">{recording.session_id}</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600">{formatDate(recording.started_at)}</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600">
+                      {recording.ended_at ? formatDuration(recording.started_at, recording.ended_at) : 'In progress'}
+                    </td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-600">{formatSize(recording.size)}</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-right text-sm">
+                      <button
+                        onClick={() => setSearchParams({ id: recording.id })}
+                        className="inline-flex items-center text-blue-600 hover:text-blue-800"
+                      >
+                        <Play className="h-4 w-4 mr-1" />
+                        Play
+                      </button>
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        )}
+      </Card>
+    </div>
+  );
+}
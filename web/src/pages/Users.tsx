@@ -2,6 +2,8 @@ import { useEffect, useState } from 'react';
 import { Plus, Pencil, Trash2, Key, Shield, UserCog, Eye } from 'lucide-react';
 import Button from '@/components/ui/Button';
 import { api } from '@/api/client';
+import { API_ENDPOINTS } from '@/lib/config';
+import type { Group } from '@/types';
 
 interface User {
   id: number;
@@ -45,6 +47,62 @@ export default function Users() {
   const [formError, setFormError] = useState<string | null>(null);
   const [submitting, setSubmitting] = useState(false);
 
+  // Groups share app memberships among their users
+  const [groups, setGroups] = useState<Group[]>([]);
+  const [groupName, setGroupName] = useState('');
+  const [groupUserIds, setGroupUserIds] = useState<number[]>([]);
+  const [editingGroup, setEditingGroup] = useState<Group | null>(null);
+  const [groupError, setGroupError] = useState<string | null>(null);
+
+  const fetchGroups = async () => {
+    try {
+      const data = await api.get<Group[]>(API_ENDPOINTS.groups);
+      setGroups(data || []);
+    } catch (err) {
+      console.error('Failed to fetch groups:', err);
+    }
+  };
+
+  const resetGroupForm = () => {
+    setGroupName('');
+    setGroupUserIds([]);
+    setEditingGroup(null);
+    setGroupError(null);
+  };
+
+  const handleSaveGroup = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setGroupError(null);
+    try {
+      const body = { name: groupName, user_ids: groupUserIds };
+      if (editingGroup) {
+        await api.put(API_ENDPOINTS.group(editingGroup.id), body);
+      } else {
+        await api.post(API_ENDPOINTS.groups, body);
+      }
+      resetGroupForm();
+      fetchGroups();
+    } catch (err: any) {
+      setGroupError(err.message || 'Failed to save group');
+    }
+  };
+
+  const handleDeleteGroup = async (group: Group) => {
+    try {
+      await api.delete(API_ENDPOINTS.group(group.id));
+      if (editingGroup?.id === group.id) {
+        resetGroupForm();
+      }
+      fetchGroups();
+    } catch (err: any) {
+      setGroupError(err.message || 'Failed to delete group');
+    }
+  };
+
+  const toggleGroupUser = (userId: number) => {
+    setGroupUserIds(groupUserIds.includes(userId) ? groupUserIds.filter((id) => id !== userId) : [...groupUserIds, userId]);
+  };
+
   const fetchUsers = async () => {
     try {
       setLoading(true);
@@ -65,6 +123,7 @@ export default function Users() {
 
   useEffect(() => {
     fetchUsers();
+    fetchGroups();
   }, []);
 
   const handleCreate = async (e: React.FormEvent) => {
@@ -330,6 +389,71 @@ export default function Users() {
         )}
       </div>
 
+      {/* Groups */}
+      <div className="bg-white shadow rounded-lg p-4">
+        <h3 className="text-sm font-medium text-gray-700 mb-1">Groups</h3>
+        <p className="text-sm text-gray-600 mb-3">
+          Groups can be added as members of apps. Users other than admins only see the apps they or their groups are members of.
+        </p>
+        <div className="divide-y divide-gray-200 mb-3">
+          {groups.map((group) => (
+            <div key={group.id} className="flex items-center justify-between py-2 text-sm">
+              <div>
+                <span className="font-medium text-gray-900">{group.name}</span>
+                <span className="ml-2 text-gray-500">
+                  {group.user_ids.map((id) => users.find((u) => u.id === id)?.username || `#${id}`).join(', ') || 'No members'}
+                </span>
+              </div>
+              <div className="flex gap-2">
+                <button
+                  onClick={() => { setEditingGroup(group); setGroupName(group.name); setGroupUserIds(group.user_ids); }}
+                  className="text-blue-600 hover:text-blue-900"
+                  title="Edit group"
+                >
+                  <Pencil className="h-4 w-4" />
+                </button>
+                <button
+                  onClick={() => handleDeleteGroup(group)}
+                  className="text-red-600 hover:text-red-900"
+                  title="Delete group"
+                >
+                  <Trash2 className="h-4 w-4" />
+                </button>
+              </div>
+            </div>
+          ))}
+          {groups.length === 0 && <p className="py-2 text-sm text-gray-500">No groups</p>}
+        </div>
+        <form onSubmit={handleSaveGroup} className="space-y-2">
+          {groupError && <p className="text-sm text-red-600">{groupError}</p>}
+          <input
+            type="text"
+            value={groupName}
+            onChange={(e) => setGroupName(e.target.value)}
+            placeholder="Group name"
+            className="w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+          />
+          <div className="flex flex-wrap gap-3">
+            {users.filter((u) => u.role !== 'admin').map((u) => (
+              <label key={u.id} className="flex items-center gap-1 text-sm text-gray-700">
+                <input type="checkbox" checked={groupUserIds.includes(u.id)} onChange={() => toggleGroupUser(u.id)} />
+                {u.username}
+              </label>
+            ))}
+          </div>
+          <div className="flex gap-2">
+            <Button type="submit" size="sm" disabled={!groupName}>
+              {editingGroup ? 'Save Group' : 'Add Group'}
+            </Button>
+            {editingGroup && (
+              <Button type="button" variant="secondary" size="sm" onClick={resetGroupForm}>
+                Cancel
+              </Button>
+            )}
+          </div>
+        </form>
+      </div>
+
       {/* Create Modal */}
       {showCreateModal && (
         <div className="fixed inset-0 bg-black bg-opacity-50 flex items-center justify-center z-50">
@@ -12,17 +12,122 @@ export interface User {
   updated_at: string;
 }
 
+export type ConcurrencyPolicy = 'queue' | 'reject' | 'cancel-previous';
+
+export interface ResourceLimits {
+  max_memory_mb?: number;
+  max_cpu_seconds?: number;
+  max_open_files?: number;
+  max_processes?: number;
+}
+
 export interface App {
   id: string;
   name: string;
   description: string;
   working_dir: string;
   token?: string;
+  concurrency_policy: ConcurrencyPolicy;
+  run_as_user?: string;
+  run_as_group?: string;
+  resource_limits: ResourceLimits;
+  git_repo_url?: string;
+  git_ref?: string;
+  has_deploy_key: boolean;
+  webhook_branches?: string[];
+  webhook_tags?: string[];
+  has_webhook_secret: boolean;
+  requires_approval: boolean;
   command_count?: number;
   created_at: string;
   updated_at: string;
 }
 
+export type DeployTokenScope = 'deploy' | 'status' | 'cancel';
+
+export interface DeployToken {
+  id: string;
+  app_id: string;
+  name: string;
+  prefix: string;
+  token?: string;
+  scopes: DeployTokenScope[];
+  command_ids?: string[];
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+  created_by?: string;
+  created_at: string;
+}
+
+export interface CreateDeployTokenRequest {
+  name: string;
+  scopes: DeployTokenScope[];
+  command_ids?: string[];
+  expires_at?: string;
+}
+
+export interface Group {
+  id: number;
+  name: string;
+  user_ids: number[];
+  created_at: string;
+}
+
+export type PolicyAction = 'deny' | 'allow' | 'approval';
+export type PolicyMatchType = 'regex' | 'glob';
+
+export interface PolicyRule {
+  id: string;
+  name: string;
+  description: string;
+  action: PolicyAction;
+  match_type: PolicyMatchType;
+  pattern: string;
+  roles: UserRole[];
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PolicyDecision {
+  allowed: boolean;
+  rule: string;
+  reason: string;
+  requires_approval: boolean;
+}
+
+export interface AppMember {
+  id: string;
+  app_id: string;
+  user_id?: number;
+  group_id?: number;
+  name: string;
+  role: 'operator' | 'viewer';
+  created_at: string;
+}
+
+export interface AddAppMemberRequest {
+  user_id?: number;
+  group_id?: number;
+  role: 'operator' | 'viewer';
+}
+
+export type APITokenScope = 'read' | 'apps' | 'execute' | 'files' | 'containers' | 'terminal' | 'admin';
+
+export interface APIToken {
+  id: string;
+  user_id: number;
+  name: string;
+  prefix: string;
+  token?: string;
+  scopes: APITokenScope[];
+  expires_at: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+  created_at: string;
+}
+
 export interface Command {
   id: string;
   app_id: string;
@@ -31,10 +136,153 @@ export interface Command {
   command: string;
   timeout_seconds: number;
   sort_order: number;
+  steps?: PipelineStep[];
+  parameters?: CommandParameter[];
+  rerun_on_interrupt: boolean;
+  requires_approval: boolean;
+  created_at: string;
+}
+
+export interface EnvVar {
+  id: string;
+  app_id: string;
+  command_id?: string;
+  name: string;
+  value: string;
+  is_secret: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export type OverlapPolicy = 'skip' | 'queue' | 'cancel-previous';
+
+export interface Schedule {
+  id: string;
+  command_id: string;
+  app_id: string;
+  cron: string;
+  timezone: string;
+  overlap_policy: OverlapPolicy;
+  parameters?: Record<string, string>;
+  enabled: boolean;
+  next_run_at?: string;
+  last_run_at?: string;
+  last_execution_id?: string;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateScheduleRequest {
+  cron: string;
+  timezone?: string;
+  overlap_policy?: OverlapPolicy;
+  parameters?: Record<string, string>;
+  enabled?: boolean;
+}
+
+export interface UpdateScheduleRequest {
+  cron?: string;
+  timezone?: string;
+  overlap_policy?: OverlapPolicy;
+  parameters?: Record<string, string>;
+  enabled?: boolean;
+}
+
+export interface SchedulePreview {
+  next_runs: string[];
+}
+
+export type NotificationChannel = 'webhook' | 'slack' | 'email';
+
+export interface NotificationRule {
+  id: string;
+  app_id: string;
+  name: string;
+  channel: NotificationChannel;
+  target: string;
+  template?: string;
+  statuses: ExecutionStatus[];
+  has_secret: boolean;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export type DeliveryStatus = 'pending' | 'sent' | 'failed';
+
+export interface NotificationDelivery {
+  id: string;
+  rule_id?: string;
+  rule_name: string;
+  app_id: string;
+  execution_id: string;
+  channel: NotificationChannel;
+  status: DeliveryStatus;
+  execution_status: ExecutionStatus;
+  last_error?: string;
+  attempts: number;
   created_at: string;
+  updated_at: string;
+}
+
+export type ParameterType = 'string' | 'enum' | 'boolean';
+
+export interface CommandParameter {
+  name: string;
+  type: ParameterType;
+  description?: string;
+  default?: string;
+  pattern?: string;
+  options?: string[];
+  required?: boolean;
+}
+
+export interface PipelineStep {
+  id: string;
+  command_id: string;
+  command_name: string;
+  step_order: number;
+  continue_on_error: boolean;
+}
+
+export interface PipelineStepRequest {
+  command_id: string;
+  continue_on_error?: boolean;
+}
+
+export type ExecutionStatus = 'pending' | 'running' | 'success' | 'failed' | 'skipped' | 'cancelled' | 'interrupted' | 'awaiting_approval' | 'rejected';
+
+export type OutputStream = 'stdout' | 'stderr' | 'system';
+
+export interface OutputChunk {
+  seq: number;
+  stream: OutputStream;
+  line: string;
+  timestamp: string;
+  elapsed_ms: number;
 }
 
-export type ExecutionStatus = 'pending' | 'running' | 'success' | 'failed';
+export interface ExecutionOutputPage {
+  chunks: OutputChunk[];
+  next: number;
+  has_more: boolean;
+}
+
+export interface ExecutionStep {
+  id: string;
+  execution_id: string;
+  command_id: string;
+  name: string;
+  step_order: number;
+  continue_on_error: boolean;
+  status: ExecutionStatus;
+  output: string;
+  exit_code?: number;
+  duration_ms: number;
+  started_at?: string;
+  finished_at?: string;
+}
 
 export interface Execution {
   id: string;
@@ -45,9 +293,32 @@ export interface Execution {
   exit_code?: number;
   started_at?: string;
   finished_at?: string;
+  cancelled_by?: string;
+  reviewed_by?: string;
+  reviewed_at?: string;
+  review_comment?: string;
+  approval_expires_at?: string;
+  rerun_of?: string;
+  queue_position?: number;
+  parameters?: Record<string, string>;
+  git_ref?: string;
+  commit_sha?: string;
+  steps?: ExecutionStep[];
   created_at: string;
 }
 
+export interface ExecutionFilters {
+  app_id?: string;
+  command_id?: string;
+  user_id?: number;
+  status?: ExecutionStatus[];
+  from?: string;
+  to?: string;
+  q?: string;
+  limit?: number;
+  offset?: number;
+}
+
 export interface ExecutionWithDetails extends Execution {
   command_name: string;
   app_name: string;
@@ -67,6 +338,43 @@ export interface AuditLog {
   created_at: string;
 }
 
+export interface TerminalRecording {
+  id: string;
+  session_id: string;
+  user_id?: number;
+  username: string;
+  shell: string;
+  size: number;
+  started_at: string;
+  ended_at?: string;
+}
+
+// Limits of the terminal sessions of the current user; durations are in seconds, 0 disables them
+export interface TerminalLimits {
+  session_ttl: number;
+  idle_timeout: number;
+  max_lifetime: number;
+  max_sessions: number;
+  buffer_size: number;
+}
+
+export type TerminalParticipantRole = 'owner' | 'driver' | 'observer';
+
+export interface TerminalParticipant {
+  user_id: number;
+  username: string;
+  role: TerminalParticipantRole;
+  invited_at: string;
+}
+
+export interface TerminalPresence {
+  user_id: number;
+  username: string;
+  role: TerminalParticipantRole;
+  connections: number;
+  connected_at: string;
+}
+
 export interface VersionInfo {
   version: string;
   build_time: string;
@@ -91,12 +399,34 @@ export interface CreateAppRequest {
   name: string;
   description: string;
   working_dir: string;
+  concurrency_policy?: ConcurrencyPolicy;
+  run_as_user?: string;
+  run_as_group?: string;
+  resource_limits?: ResourceLimits;
+  git_repo_url?: string;
+  git_ref?: string;
+  git_deploy_key?: string;
+  webhook_secret?: string;
+  webhook_branches?: string[];
+  webhook_tags?: string[];
+  requires_approval?: boolean;
 }
 
 export interface UpdateAppRequest {
   name?: string;
   description?: string;
   working_dir?: string;
+  concurrency_policy?: ConcurrencyPolicy;
+  run_as_user?: string;
+  run_as_group?: string;
+  resource_limits?: ResourceLimits;
+  git_repo_url?: string;
+  git_ref?: string;
+  git_deploy_key?: string;
+  webhook_secret?: string;
+  webhook_branches?: string[];
+  webhook_tags?: string[];
+  requires_approval?: boolean;
 }
 
 export interface CreateCommandRequest {
@@ -104,6 +434,10 @@ export interface CreateCommandRequest {
   description: string;
   command: string;
   timeout_seconds: number;
+  steps?: PipelineStepRequest[];
+  parameters?: CommandParameter[];
+  rerun_on_interrupt?: boolean;
+  requires_approval?: boolean;
 }
 
 export interface UpdateCommandRequest {
@@ -111,6 +445,47 @@ export interface UpdateCommandRequest {
   description?: string;
   command?: string;
   timeout_seconds?: number;
+  steps?: PipelineStepRequest[];
+  parameters?: CommandParameter[];
+  rerun_on_interrupt?: boolean;
+  requires_approval?: boolean;
+}
+
+export interface CreateEnvVarRequest {
+  name: string;
+  value: string;
+  command_id?: string;
+  is_secret?: boolean;
+}
+
+export interface UpdateEnvVarRequest {
+  value?: string;
+  is_secret?: boolean;
+}
+
+export interface CreateNotificationRuleRequest {
+  name: string;
+  channel: NotificationChannel;
+  target: string;
+  secret?: string;
+  template?: string;
+  statuses: ExecutionStatus[];
+  enabled?: boolean;
+}
+
+export interface UpdateNotificationRuleRequest {
+  name?: string;
+  target?: string;
+  secret?: string;
+  template?: string;
+  statuses?: ExecutionStatus[];
+  enabled?: boolean;
+}
+
+export interface ExecuteCommandRequest {
+  parameters?: Record<string, string | boolean | number>;
+  ref?: string;
+  commit_sha?: string;
 }
 
 export interface ExecuteCommandResponse {
@@ -124,6 +499,8 @@ export interface ExecuteCommandResponse {
 
 export interface DeployRequest {
   command_id?: string;
+  ref?: string;
+  commit_sha?: string;
 }
 
 export interface DeployResponse {
@@ -144,3 +521,7 @@ export interface ApiConfig {
   baseUrl: string;
   pathPrefix: string;
 }
+
+export interface ReviewExecutionRequest {
+  comment?: string;
+}